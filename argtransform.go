@@ -0,0 +1,187 @@
+package sqlz
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ArgTransformer is a function that transforms an argument of a query, see
+// DB.SetArgTransformer. It receives a best-effort guess of the name of the
+// column the argument is compared with or assigned to (unqualified, e.g.
+// "ssn" for "u.ssn = ?"), or an empty string if there is none (e.g. for
+// LIMIT values), and returns the value to use instead.
+type ArgTransformer func(colHint string, value interface{}) interface{}
+
+// SetArgTransformer sets a function that transforms every argument of the
+// queries executed through sqlz before they are passed to the query logger
+// and the slow query handler, e.g. to redact personal information in logs:
+//
+//	db.SetArgTransformer(func(col string, v interface{}) interface{} {
+//		if col == "ssn" {
+//			return "[REDACTED]"
+//		}
+//		return v
+//	})
+//
+// By default, the original arguments are still sent to the database; see
+// SetTransformArgsBeforeExec. Pass nil to disable it.
+func (db *DB) SetArgTransformer(transformer ArgTransformer) {
+	db.argTransformer = transformer
+}
+
+// SetTransformArgsBeforeExec sets whether the function set with
+// SetArgTransformer also transforms the arguments sent to the database
+// (e.g. to normalize values), rather than only those passed to the query
+// logger and the slow query handler.
+func (db *DB) SetTransformArgsBeforeExec(enabled bool) {
+	db.argTransformExec = enabled
+}
+
+// transformArgs applies an argument transformer to the arguments of a
+// query, returning a new slice
+func transformArgs(transformer ArgTransformer, query string, args []interface{}) []interface{} {
+	hints := argHints(query, len(args))
+
+	transformed := make([]interface{}, len(args))
+	for i, arg := range args {
+		transformed[i] = transformer(hints[i], arg)
+	}
+
+	return transformed
+}
+
+// insertColumns matches the column list of an INSERT statement
+var insertColumns = regexp.MustCompile(`(?i)\bINSERT\s+(?:OR\s+\w+\s+)?INTO\s+\S+\s*\(([^)]*)\)`)
+
+// hintKeywords are the keywords that may appear between a column and the
+// placeholder it is compared with, e.g. "col NOT LIKE ?"
+var hintKeywords = map[string]bool{
+	"NOT": true, "LIKE": true, "ILIKE": true, "IN": true, "IS": true, "BETWEEN": true,
+	"AND": true, "DISTINCT": true, "FROM": true, "ANY": true, "ALL": true, "SOME": true,
+	"SIMILAR": true, "TO": true, "ARRAY": true,
+}
+
+// clauseKeywords are the keywords that start a clause or expression whose
+// placeholders are not associated with a preceding column
+var clauseKeywords = map[string]bool{
+	"SELECT": true, "WHERE": true, "HAVING": true, "ON": true, "SET": true, "OR": true,
+	"LIMIT": true, "OFFSET": true, "FETCH": true, "FIRST": true, "NEXT": true, "TOP": true,
+	"VALUES": true, "CASE": true, "WHEN": true, "THEN": true, "ELSE": true, "END": true,
+	"ORDER": true, "GROUP": true, "BY": true, "RETURNING": true, "UNION": true,
+}
+
+// argHints returns a best-effort guess of the column each of the arguments
+// of a query is compared with or assigned to: the last column name before
+// its placeholder (e.g. "a" for "a = ?" or "a IN (?, ?)"), or, in the
+// VALUES clause of an INSERT statement, the column at the placeholder's
+// position in the column list. Placeholders may be question marks or
+// ordinal ("$1", "@p1"). Quoted strings and identifiers are skipped.
+func argHints(query string, count int) []string {
+	hints := make([]string, count)
+
+	var cols []string
+	if match := insertColumns.FindStringSubmatch(query); match != nil {
+		for _, col := range strings.Split(match[1], ",") {
+			cols = append(cols, unqualified(strings.TrimSpace(col)))
+		}
+	}
+
+	var (
+		hint     string
+		quote    byte
+		depth    int
+		inValues bool
+		position int
+		next     int
+	)
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		index := -1
+
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case isWordByte(c):
+			j := i
+			for j < len(query) && (isWordByte(query[j]) || query[j] == '.') {
+				j++
+			}
+
+			word := strings.ToUpper(query[i:j])
+
+			switch {
+			case word == "VALUES" && len(cols) > 0 && depth == 0:
+				inValues = true
+			case inValues && depth == 0:
+				inValues = false
+			}
+
+			switch {
+			case hintKeywords[word]:
+			case clauseKeywords[word]:
+				hint = ""
+			case c >= '0' && c <= '9':
+			default:
+				hint = unqualified(query[i:j])
+			}
+
+			i = j - 1
+		case c == '(':
+			depth++
+			if depth == 1 {
+				position = 0
+			}
+		case c == ')':
+			depth--
+		case c == ',' && depth == 1:
+			position++
+		case c == '?':
+			index = next
+			next++
+		case (c == '$' || c == '@') && i+1 < len(query):
+			j := i + 1
+			if c == '@' && query[j] == 'p' {
+				j++
+			}
+
+			k := j
+			for k < len(query) && query[k] >= '0' && query[k] <= '9' {
+				k++
+			}
+
+			if n, err := strconv.Atoi(query[j:k]); err == nil && k > j {
+				index = n - 1
+				i = k - 1
+			}
+		}
+
+		if index < 0 || index >= count || hints[index] != "" {
+			continue
+		}
+
+		if inValues && depth >= 1 && position < len(cols) {
+			hints[index] = cols[position]
+		} else {
+			hints[index] = hint
+		}
+	}
+
+	return hints
+}
+
+// isWordByte returns true if the provided byte may be part of an
+// identifier or keyword
+func isWordByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// unqualified returns a column name without its table qualifier
+func unqualified(col string) string {
+	return col[strings.LastIndex(col, ".")+1:]
+}
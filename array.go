@@ -0,0 +1,265 @@
+package sqlz
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArrayValue wraps a slice so that it is bound as a single PostgreSQL
+// array parameter (e.g. for a text[] column), rather than expanded into
+// multiple parameters. It implements driver.Valuer, encoding the slice in
+// PostgreSQL's array literal format, similarly to pq.Array.
+type ArrayValue struct {
+	Elements interface{}
+}
+
+// Array wraps the provided slice (or array) as a PostgreSQL array value,
+// e.g. Values(Array([]string{"a", "b"})) or Eq("tags", Array(tags)). Note
+// that slices bound on PostgreSQL databases are wrapped automatically;
+// use In for "IN (...)" semantics instead.
+func Array(elements interface{}) ArrayValue {
+	return ArrayValue{elements}
+}
+
+// Value implements the driver.Valuer interface
+func (arr ArrayValue) Value() (driver.Value, error) {
+	val := reflect.ValueOf(arr.Elements)
+	if !val.IsValid() || (val.Kind() == reflect.Slice && val.IsNil()) {
+		return nil, nil
+	}
+
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot bind %T as an array", arr.Elements)
+	}
+
+	var b strings.Builder
+	if err := writeArray(&b, val); err != nil {
+		return nil, err
+	}
+
+	return b.String(), nil
+}
+
+// writeArray writes a slice or array in PostgreSQL's array literal format,
+// e.g. {"a","b"} or {{1,2},{3,4}}
+func writeArray(b *strings.Builder, val reflect.Value) error {
+	b.WriteByte('{')
+
+	for i := 0; i < val.Len(); i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		if err := writeArrayElement(b, val.Index(i)); err != nil {
+			return err
+		}
+	}
+
+	b.WriteByte('}')
+
+	return nil
+}
+
+func writeArrayElement(b *strings.Builder, elem reflect.Value) error {
+	for elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface {
+		if elem.IsNil() {
+			b.WriteString("NULL")
+			return nil
+		}
+
+		if valuer, ok := elem.Interface().(driver.Valuer); ok {
+			return writeArrayValuer(b, valuer)
+		}
+
+		elem = elem.Elem()
+	}
+
+	if valuer, ok := elem.Interface().(driver.Valuer); ok {
+		return writeArrayValuer(b, valuer)
+	}
+
+	switch v := elem.Interface().(type) {
+	case []byte:
+		if v == nil {
+			b.WriteString("NULL")
+		} else {
+			writeQuoted(b, `\x`+hex.EncodeToString(v))
+		}
+
+		return nil
+	case time.Time:
+		writeQuoted(b, v.Format(time.RFC3339Nano))
+		return nil
+	}
+
+	switch elem.Kind() {
+	case reflect.String:
+		writeQuoted(b, elem.String())
+	case reflect.Bool:
+		if elem.Bool() {
+			b.WriteByte('t')
+		} else {
+			b.WriteByte('f')
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b.WriteString(strconv.FormatInt(elem.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		b.WriteString(strconv.FormatUint(elem.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		b.WriteString(strconv.FormatFloat(elem.Float(), 'g', -1, elem.Type().Bits()))
+	case reflect.Slice:
+		if elem.IsNil() {
+			b.WriteString("NULL")
+			return nil
+		}
+
+		return writeArray(b, elem)
+	case reflect.Array:
+		return writeArray(b, elem)
+	default:
+		return fmt.Errorf("cannot bind %s as an array element", elem.Type())
+	}
+
+	return nil
+}
+
+// writeArrayValuer writes the value of a driver.Valuer array element
+func writeArrayValuer(b *strings.Builder, valuer driver.Valuer) error {
+	v, err := valuer.Value()
+	if err != nil {
+		return err
+	} else if v == nil {
+		b.WriteString("NULL")
+		return nil
+	}
+
+	return writeArrayElement(b, reflect.ValueOf(v))
+}
+
+// writeQuoted writes a double-quoted array element, escaping backslashes
+// and double quotes
+func writeQuoted(b *strings.Builder, s string) {
+	b.WriteByte('"')
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' || s[i] == '"' {
+			b.WriteByte('\\')
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	b.WriteByte('"')
+}
+
+// ArrayOpCondition represents a condition comparing a native PostgreSQL
+// array column to an array of values with an array operator, such as "&&"
+// (overlap), "@>" (contains) or "<@" (is contained by). Unlike the JSONB
+// conditions, these operate on array columns (e.g. text[]).
+type ArrayOpCondition struct {
+	Column   string
+	Operator string
+	Values   interface{}
+}
+
+// ArrayOverlap creates a condition checking that the array in the provided
+// column has any element in common with the provided slice, rendering
+// "col && ?" with the slice bound as an array. If the slice is empty, the
+// condition is always false, and renders "1 = 0" instead. This is only
+// supported on PostgreSQL.
+func ArrayOverlap(col string, values interface{}) ArrayOpCondition {
+	return ArrayOpCondition{col, "&&", values}
+}
+
+// ArrayContains creates a condition checking that the array in the provided
+// column contains all elements of the provided slice, rendering "col @> ?".
+// This is only supported on PostgreSQL.
+func ArrayContains(col string, values interface{}) ArrayOpCondition {
+	return ArrayOpCondition{col, "@>", values}
+}
+
+// ArrayContainedBy creates a condition checking that all elements of the
+// array in the provided column are in the provided slice, rendering
+// "col <@ ?". This is only supported on PostgreSQL.
+func ArrayContainedBy(col string, values interface{}) ArrayOpCondition {
+	return ArrayOpCondition{col, "<@", values}
+}
+
+// Parse implements the WhereCondition interface, generating SQL from
+// the condition
+func (cond ArrayOpCondition) Parse() (asSQL string, bindings []interface{}) {
+	if valuer, ok := cond.Values.(driver.Valuer); ok {
+		return cond.Column + " " + cond.Operator + " ?", []interface{}{valuer}
+	}
+
+	val := reflect.ValueOf(cond.Values)
+	isList := val.Kind() == reflect.Slice || val.Kind() == reflect.Array
+	if cond.Operator == "&&" && isList && val.Len() == 0 {
+		return "1 = 0", nil
+	}
+
+	return cond.Column + " " + cond.Operator + " ?", []interface{}{Array(cond.Values)}
+}
+
+// Err returns an error if the condition's values are not a slice (or an
+// array, or a value implementing driver.Valuer)
+func (cond ArrayOpCondition) Err() error {
+	if _, ok := cond.Values.(driver.Valuer); ok {
+		return nil
+	}
+
+	if kind := reflect.ValueOf(cond.Values).Kind(); kind != reflect.Slice && kind != reflect.Array {
+		return fmt.Errorf("array condition on %s requires a slice, got %T", cond.Column, cond.Values)
+	}
+
+	return nil
+}
+
+func (cond ArrayOpCondition) checkDialect(driverName string) error {
+	if !isPostgres(driverName) {
+		return fmt.Errorf("array operator %s is only supported on PostgreSQL", cond.Operator)
+	}
+
+	return nil
+}
+
+// wrapArrays wraps slice arguments as PostgreSQL arrays, if the queryer's
+// driver is a PostgreSQL driver. Values implementing driver.Valuer and
+// slices of bytes, including named types such as json.RawMessage and
+// net.IP, are passed as they are.
+func wrapArrays(driverName string, args []interface{}) []interface{} {
+	if !isPostgres(driverName) {
+		return args
+	}
+
+	var wrapped []interface{}
+
+	for i, arg := range args {
+		if _, isValuer := arg.(driver.Valuer); isValuer {
+			continue
+		}
+
+		typ := reflect.TypeOf(arg)
+		if typ == nil || typ.Kind() != reflect.Slice || typ.Elem().Kind() == reflect.Uint8 {
+			continue
+		}
+
+		if wrapped == nil {
+			wrapped = make([]interface{}, len(args))
+			copy(wrapped, args)
+		}
+
+		wrapped[i] = Array(arg)
+	}
+
+	if wrapped == nil {
+		return args
+	}
+
+	return wrapped
+}
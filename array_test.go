@@ -0,0 +1,180 @@
+package sqlz
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestArrayValue(t *testing.T) {
+	var nilSlice []string
+
+	for _, c := range []struct {
+		name     string
+		value    ArrayValue
+		expected interface{}
+	}{
+		{"strings", Array([]string{"a", `b "c"`, `d\e`}), `{"a","b \"c\"","d\\e"}`},
+		{"integers", Array([]int64{1, -2, 3}), "{1,-2,3}"},
+		{"booleans", Array([]bool{true, false}), "{t,f}"},
+		{"null elements", Array([]*string{nil}), "{NULL}"},
+		{"nested", Array([][]int{{1, 2}, {3, 4}}), "{{1,2},{3,4}}"},
+		{"empty", Array([]string{}), "{}"},
+		{"nil", Array(nilSlice), nil},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			value, err := c.value.Value()
+			if err != nil {
+				t.Fatalf("Failed encoding array: %s", err)
+			}
+
+			if value != c.expected {
+				t.Errorf("Expected %v, got %v", c.expected, value)
+			}
+		})
+	}
+
+	if _, err := Array("a").Value(); err == nil {
+		t.Error("Expected encoding a non-slice to fail")
+	}
+}
+
+func TestArrayBindings(t *testing.T) {
+	tags := []string{"go", "sql"}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	for _, c := range []struct {
+		name        string
+		stmt        SQLStmt
+		expectedSQL string
+	}{
+		{
+			"insert an array value",
+			dbz.InsertInto("posts").Columns("title", "tags").Values("hello", Array(tags)),
+			"INSERT INTO posts (title, tags) VALUES ($1, $2)",
+		},
+		{
+			"compare with an array value",
+			dbz.Select("title").From("posts").Where(Eq("tags", Array(tags))),
+			"SELECT title FROM posts WHERE tags = $1",
+		},
+		{
+			"in condition with an array value",
+			dbz.Select("title").From("posts").Where(In("tags", Array(tags))),
+			"SELECT title FROM posts WHERE tags IN ($1)",
+		},
+	} {
+		asSQL, bindings := c.stmt.ToSQL(true)
+		if asSQL != c.expectedSQL {
+			t.Errorf("Failed %s: expected %s, got %s", c.name, c.expectedSQL, asSQL)
+		}
+
+		if _, ok := bindings[len(bindings)-1].(ArrayValue); !ok {
+			t.Errorf("Failed %s: expected an array-wrapped binding, got %T", c.name, bindings[len(bindings)-1])
+		}
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO posts (title, tags) VALUES ($1, $2)")).
+		WithArgs("hello", `{"go","sql"}`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO posts (title, tags) VALUES ($1, $2)")).
+		WithArgs("hello", `{"go","sql"}`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if _, err := dbz.InsertInto("posts").Columns("title", "tags").Values("hello", Array(tags)).Exec(); err != nil {
+		t.Errorf("Failed inserting an array value: %s", err)
+	}
+
+	// plain slices are wrapped automatically on PostgreSQL
+	if _, err := dbz.InsertInto("posts").Columns("title", "tags").Values("hello", tags).Exec(); err != nil {
+		t.Errorf("Failed inserting a slice: %s", err)
+	}
+
+	// byte slices, including named types, are not arrays
+	raw := json.RawMessage(`{"a":1}`)
+	ip := net.IPv4(127, 0, 0, 1)
+
+	if args := wrapArrays("postgres", []interface{}{raw, ip, []byte("x")}); !reflect.DeepEqual(args, []interface{}{raw, ip, []byte("x")}) {
+		t.Errorf("Expected byte slices to be passed as they are, got %v", args)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestArrayOperators(t *testing.T) {
+	tags := []string{"go", "sql"}
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	for _, c := range []struct {
+		name        string
+		cond        WhereCondition
+		expectedSQL string
+		expectedArg interface{}
+	}{
+		{"overlap", ArrayOverlap("tags", tags), "SELECT id FROM posts WHERE tags && $1", `{"go","sql"}`},
+		{"contains", ArrayContains("tags", tags), "SELECT id FROM posts WHERE tags @> $1", `{"go","sql"}`},
+		{"contained by", ArrayContainedBy("tags", tags), "SELECT id FROM posts WHERE tags <@ $1", `{"go","sql"}`},
+		{"overlap with an empty slice", ArrayOverlap("tags", []string{}), "SELECT id FROM posts WHERE 1 = 0", nil},
+		{"contains an empty slice", ArrayContains("tags", []string{}), "SELECT id FROM posts WHERE tags @> $1", "{}"},
+	} {
+		stmt := dbz.Select("id").From("posts").Where(c.cond)
+		if err := stmt.Err(); err != nil {
+			t.Errorf("Failed %s: %s", c.name, err)
+			continue
+		}
+
+		asSQL, bindings := stmt.ToSQL(true)
+		if asSQL != c.expectedSQL {
+			t.Errorf("Failed %s: expected %s, got %s", c.name, c.expectedSQL, asSQL)
+		}
+
+		if c.expectedArg == nil {
+			if len(bindings) != 0 {
+				t.Errorf("Failed %s: expected no bindings, got %v", c.name, bindings)
+			}
+
+			continue
+		}
+
+		if len(bindings) != 1 {
+			t.Errorf("Failed %s: expected a single binding, got %v", c.name, bindings)
+			continue
+		}
+
+		arr, ok := bindings[0].(ArrayValue)
+		if !ok {
+			t.Errorf("Failed %s: expected an array-wrapped binding, got %T", c.name, bindings[0])
+			continue
+		}
+
+		if value, _ := arr.Value(); value != c.expectedArg {
+			t.Errorf("Failed %s: expected %v, got %v", c.name, c.expectedArg, value)
+		}
+	}
+
+	if err := New(db, "mysql").Select("id").From("posts").Where(ArrayOverlap("tags", tags)).Err(); err == nil {
+		t.Error("Expected array operators to fail on MySQL")
+	}
+
+	if err := dbz.Select("id").From("posts").Where(ArrayContains("tags", "go")).Err(); err == nil {
+		t.Error("Expected array operators to fail with a non-slice value")
+	}
+}
@@ -0,0 +1,107 @@
+package sqlz
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// CreateTableAsStmt represents a CREATE TABLE ... AS SELECT statement,
+// creating a table (possibly a temporary one) from the results of a query
+type CreateTableAsStmt struct {
+	*Statement
+	Table       string
+	IsTemporary bool
+	SelectStmt  *SelectStmt
+	execer      Ext
+}
+
+// IntoTable creates a statement that creates a new table with the provided
+// name from the results of the SELECT statement, e.g. "CREATE TEMP TABLE
+// name AS SELECT ..." in PostgreSQL and SQLite, or "CREATE TEMPORARY TABLE
+// name AS SELECT ..." in MySQL. If temporary is false, a regular table is
+// created. The bindings of the SELECT statement are carried into the
+// CREATE statement. This is not supported on SQL Server, where SELECT ...
+// INTO should be used instead.
+func (stmt *SelectStmt) IntoTable(name string, temporary bool) *CreateTableAsStmt {
+	create := &CreateTableAsStmt{
+		Table:       name,
+		IsTemporary: temporary,
+		SelectStmt:  stmt,
+		Statement:   &Statement{},
+	}
+
+	if stmt.Statement != nil {
+		create.ErrHandlers = stmt.ErrHandlers
+		create.timeout = stmt.timeout
+	}
+
+	if err := stmt.Err(); err != nil {
+		create.setErr(err)
+	}
+
+	if execer, ok := stmt.queryer.(Ext); ok {
+		create.execer = execer
+	} else {
+		create.setErr(errors.New("statement cannot be executed"))
+	}
+
+	if isSQLServer(driverNameOf(stmt.queryer)) {
+		create.setErr(errors.New("CREATE TABLE AS is not supported on SQL Server, use SELECT ... INTO"))
+	}
+
+	return create
+}
+
+// WithTimeout sets a timeout for executing the statement (see
+// SelectStmt.WithTimeout).
+func (stmt *CreateTableAsStmt) WithTimeout(timeout time.Duration) *CreateTableAsStmt {
+	stmt.timeout = timeout
+	return stmt
+}
+
+// ToSQL generates the statement's SQL and returns a list of bindings, which
+// are those of the SELECT statement
+func (stmt *CreateTableAsStmt) ToSQL(rebind bool) (asSQL string, bindings []interface{}) {
+	asSQL = "CREATE TABLE "
+
+	if stmt.IsTemporary && isMySQL(driverNameOf(stmt.execer)) {
+		asSQL = "CREATE TEMPORARY TABLE "
+	} else if stmt.IsTemporary {
+		asSQL = "CREATE TEMP TABLE "
+	}
+
+	selectSQL, bindings := stmt.SelectStmt.ToSQL(false)
+	asSQL += stmt.Table + " AS " + selectSQL
+
+	if rebind {
+		asSQL = stmt.commented(stmt.execer, rebindFor(stmt.execer, asSQL))
+	}
+
+	return asSQL, bindings
+}
+
+// Exec executes the statement, creating the table
+func (stmt *CreateTableAsStmt) Exec() (res sql.Result, err error) {
+	return stmt.ExecContext(context.Background())
+}
+
+// ExecContext executes the statement, creating the table, using the
+// provided context
+func (stmt *CreateTableAsStmt) ExecContext(ctx context.Context) (res sql.Result, err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if err = stmt.Err(); err != nil {
+		stmt.HandleError(err)
+		return res, err
+	}
+
+	asSQL, bindings := stmt.ToSQL(true)
+
+	res, err = stmt.execer.ExecContext(ctx, asSQL, bindings...)
+	stmt.HandleError(err)
+
+	return res, err
+}
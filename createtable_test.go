@@ -0,0 +1,73 @@
+package sqlz
+
+import (
+	"regexp"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestIntoTable(t *testing.T) {
+	for driverName, expected := range map[string][2]string{
+		"postgres": {
+			"CREATE TEMP TABLE recent AS SELECT id, total FROM orders WHERE created_at > $1",
+			"CREATE TABLE recent AS SELECT id, total FROM orders WHERE created_at > $1",
+		},
+		"mysql": {
+			"CREATE TEMPORARY TABLE recent AS SELECT id, total FROM orders WHERE created_at > ?",
+			"CREATE TABLE recent AS SELECT id, total FROM orders WHERE created_at > ?",
+		},
+		"sqlite3": {
+			"CREATE TEMP TABLE recent AS SELECT id, total FROM orders WHERE created_at > ?",
+			"CREATE TABLE recent AS SELECT id, total FROM orders WHERE created_at > ?",
+		},
+	} {
+		expected := expected
+
+		runDriverTests(t, driverName, func(dbz *DB) []test {
+			return []test{
+				{
+					"create temporary table from select on " + driverName,
+					dbz.Select("id", "total").From("orders").Where(Gt("created_at", "2024-01-01")).IntoTable("recent", true),
+					expected[0],
+					[]interface{}{"2024-01-01"},
+				},
+
+				{
+					"create table from select on " + driverName,
+					dbz.Select("id", "total").From("orders").Where(Gt("created_at", "2024-01-01")).IntoTable("recent", false),
+					expected[1],
+					[]interface{}{"2024-01-01"},
+				},
+			}
+		})
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TEMP TABLE totals AS SELECT user_id, SUM(total) FROM orders WHERE status = $1 GROUP BY user_id")).
+		WithArgs("paid").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	_, err = New(db, "postgres").
+		Select("user_id", "SUM(total)").
+		From("orders").
+		Where(Eq("status", "paid")).
+		GroupBy("user_id").
+		IntoTable("totals", true).
+		Exec()
+	if err != nil {
+		t.Errorf("Failed creating table: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	if err := New(db, "sqlserver").Select("id").From("orders").IntoTable("recent", true).Err(); err == nil {
+		t.Error("Expected CREATE TABLE AS to fail on SQL Server")
+	}
+}
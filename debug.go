@@ -0,0 +1,134 @@
+package sqlz
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ToSQLDebug generates the statement's SQL with its bindings interpolated
+// as literals (strings quoted and escaped, nils as NULL, numbers as they
+// are), for copying into a database shell when debugging. The result is
+// meant for humans only: it must NEVER be executed, as interpolating values
+// into SQL is open to SQL injection no matter how values are escaped. Use
+// ToSQL and bound parameters for execution.
+func (stmt *SelectStmt) ToSQLDebug() string {
+	asSQL, bindings := stmt.ToSQL(false)
+	return interpolate(driverNameOf(stmt.queryer), asSQL, bindings)
+}
+
+// interpolate replaces the question mark placeholders of a query with the
+// provided bindings as SQL literals, skipping quoted strings and
+// identifiers (see countPlaceholders). It is only meant for debugging.
+func interpolate(driverName, query string, bindings []interface{}) string {
+	var (
+		b     strings.Builder
+		n     int
+		quote rune
+	)
+
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?' && n < len(bindings):
+			b.WriteString(debugLiteral(driverName, bindings[n]))
+			n++
+
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// debugLiteral formats a bound value as an SQL literal for the provided
+// driver
+func debugLiteral(driverName string, value interface{}) string {
+	if named, ok := value.(sql.NamedArg); ok {
+		value = named.Value
+	}
+
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "NULL"
+		}
+
+		value = v
+	}
+
+	if value == nil {
+		return "NULL"
+	}
+
+	switch v := value.(type) {
+	case string:
+		return quoteLiteral(driverName, v)
+	case []byte:
+		switch {
+		case isPostgres(driverName):
+			return `'\x` + hex.EncodeToString(v) + "'"
+		case isSQLServer(driverName):
+			return "0x" + hex.EncodeToString(v)
+		default:
+			return "X'" + hex.EncodeToString(v) + "'"
+		}
+	case bool:
+		switch {
+		case isSQLServer(driverName) && v:
+			return "1"
+		case isSQLServer(driverName):
+			return "0"
+		case v:
+			return "TRUE"
+		default:
+			return "FALSE"
+		}
+	case time.Time:
+		return quoteLiteral(driverName, v.Format("2006-01-02 15:04:05.999999999-07:00"))
+	}
+
+	val := reflect.ValueOf(value)
+
+	switch val.Kind() {
+	case reflect.Ptr:
+		if val.IsNil() {
+			return "NULL"
+		}
+
+		return debugLiteral(driverName, val.Elem().Interface())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(val.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(val.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(val.Float(), 'g', -1, val.Type().Bits())
+	case reflect.String:
+		return quoteLiteral(driverName, val.String())
+	default:
+		return quoteLiteral(driverName, fmt.Sprint(value))
+	}
+}
+
+// quoteLiteral quotes a string literal, doubling single quotes (and, in
+// MySQL, where backslashes are escape characters by default, doubling
+// backslashes as well)
+func quoteLiteral(driverName, s string) string {
+	if isMySQL(driverName) {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -3,7 +3,9 @@ package sqlz
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -15,6 +17,8 @@ type DeleteStmt struct {
 	Conditions  []WhereCondition
 	UsingTables []string
 	Return      []string
+	SoftColumn  string
+	SoftValue   interface{}
 	execer      Ext
 }
 
@@ -23,8 +27,8 @@ type DeleteStmt struct {
 func (db *DB) DeleteFrom(table string) *DeleteStmt {
 	return &DeleteStmt{
 		Table:     table,
-		execer:    db.DB,
-		Statement: &Statement{db.ErrHandlers},
+		execer:    db.handle(),
+		Statement: &Statement{ErrHandlers: db.ErrHandlers},
 	}
 }
 
@@ -33,12 +37,16 @@ func (db *DB) DeleteFrom(table string) *DeleteStmt {
 func (tx *Tx) DeleteFrom(table string) *DeleteStmt {
 	return &DeleteStmt{
 		Table:     table,
-		execer:    tx.Tx,
-		Statement: &Statement{tx.ErrHandlers},
+		execer:    tx.handle(),
+		Statement: &Statement{ErrHandlers: tx.ErrHandlers},
 	}
 }
 
-// Using adds a USING clause for joining in a delete statement
+// Using adds a USING clause for joining in a delete statement, e.g.
+// "DELETE FROM t USING other WHERE t.id = other.id". The WHERE conditions
+// may reference the joined tables. As MySQL doesn't support USING clauses,
+// there the tables are joined using its multiple-table syntax instead, e.g.
+// "DELETE t FROM t JOIN other WHERE t.id = other.id".
 func (stmt *DeleteStmt) Using(tables ...string) *DeleteStmt {
 	stmt.UsingTables = append(stmt.UsingTables, tables...)
 	return stmt
@@ -47,75 +55,216 @@ func (stmt *DeleteStmt) Using(tables ...string) *DeleteStmt {
 // Where creates one or more WHERE conditions for the DELETE statement.
 // If multiple conditions are passed, they are considered AND conditions.
 func (stmt *DeleteStmt) Where(conds ...WhereCondition) *DeleteStmt {
+	conds, err := emptyInPolicyOf(stmt.execer).apply(conds)
+	if err != nil {
+		stmt.setErr(err)
+	}
+
+	if err := condErr(conds, driverNameOf(stmt.execer)); err != nil {
+		stmt.setErr(err)
+	}
+
 	stmt.Conditions = append(stmt.Conditions, conds...)
+
+	return stmt
+}
+
+// ApplyIf calls fn with the statement if cond is true, and returns its
+// result; otherwise the statement is returned unchanged (see
+// SelectStmt.ApplyIf).
+func (stmt *DeleteStmt) ApplyIf(cond bool, fn func(*DeleteStmt) *DeleteStmt) *DeleteStmt {
+	if !cond {
+		return stmt
+	}
+
+	return fn(stmt)
+}
+
+// WithTimeout sets a timeout for executing the DELETE statement (see
+// SelectStmt.WithTimeout).
+func (stmt *DeleteStmt) WithTimeout(timeout time.Duration) *DeleteStmt {
+	stmt.timeout = timeout
+	return stmt
+}
+
+// Comment adds a comment with the provided tag to the start of the DELETE
+// statement's SQL (see SelectStmt.Comment).
+func (stmt *DeleteStmt) Comment(tag string) *DeleteStmt {
+	stmt.comment = tag
 	return stmt
 }
 
 // Returning sets a RETURNING clause to receive values back from the
 // database once executing the DELETE statement. Note that GetRow or
 // GetAll must be used to execute the query rather than Exec to get
-// back the values.
+// back the values. On SQL Server, an OUTPUT clause with the columns
+// qualified by the DELETED pseudo-table is generated instead, e.g.
+// "OUTPUT DELETED.id", before the WHERE clause.
 func (stmt *DeleteStmt) Returning(cols ...string) *DeleteStmt {
 	stmt.Return = append(stmt.Return, cols...)
 	return stmt
 }
 
+// Soft turns the statement into a soft delete: rather than deleting the
+// matching rows, they are updated to set the provided column to the
+// provided value (e.g. Soft("deleted_at", Indirect("NOW()"))). The
+// statement's WHERE conditions, USING tables (as a FROM clause, or joined
+// in MySQL, e.g. "UPDATE t JOIN other SET t.col = ? WHERE ...") and
+// RETURNING clause are preserved. See SelectStmt.ExcludeSoftDeleted for
+// the reading side.
+func (stmt *DeleteStmt) Soft(column string, value interface{}) *DeleteStmt {
+	stmt.SoftColumn = column
+	stmt.SoftValue = value
+	return stmt
+}
+
 // ToSQL generates the DELETE statement's SQL and returns a list of
 // bindings. It is used internally by Exec, but is exported if you
 // wish to use it directly.
 func (stmt *DeleteStmt) ToSQL(rebind bool) (asSQL string, bindings []interface{}) {
 	var clauses = []string{"DELETE FROM " + stmt.Table}
 
-	if len(stmt.UsingTables) > 0 {
+	if stmt.SoftColumn != "" {
+		clauses = []string{"UPDATE " + stmt.Table}
+		column := stmt.SoftColumn
+
+		// MySQL has no UPDATE ... FROM, the tables are joined instead, and
+		// the column is qualified with the table (or its alias) in case the
+		// joined tables have it too
+		mysqlJoin := len(stmt.UsingTables) > 0 && isMySQL(driverNameOf(stmt.execer))
+		if mysqlJoin {
+			for _, table := range stmt.UsingTables {
+				clauses[0] += " JOIN " + table
+			}
+
+			if !strings.Contains(column, ".") {
+				fields := strings.Fields(stmt.Table)
+				column = fields[len(fields)-1] + "." + column
+			}
+		}
+
+		if indirect, isIndirect := stmt.SoftValue.(IndirectValue); isIndirect {
+			clauses = append(clauses, "SET "+column+" = "+indirect.Reference)
+			bindings = append(bindings, indirect.Bindings...)
+		} else {
+			clauses = append(clauses, "SET "+column+" = ?")
+			bindings = append(bindings, stmt.SoftValue)
+		}
+
+		if len(stmt.UsingTables) > 0 && !mysqlJoin {
+			clauses = append(clauses, "FROM "+strings.Join(stmt.UsingTables, ", "))
+		}
+	} else if len(stmt.UsingTables) > 0 && isMySQL(driverNameOf(stmt.execer)) {
+		// delete rows from the table (or its alias, if it has one) only
+		fields := strings.Fields(stmt.Table)
+		clauses = []string{"DELETE " + fields[len(fields)-1] + " FROM " + stmt.Table}
+
+		for _, table := range stmt.UsingTables {
+			clauses = append(clauses, "JOIN "+table)
+		}
+	} else if len(stmt.UsingTables) > 0 {
 		clauses = append(clauses, "USING "+strings.Join(stmt.UsingTables, ", "))
 	}
 
+	// SQL Server returns values with an OUTPUT clause before the WHERE
+	// clause, soft deletes return the updated rows
+	if len(stmt.Return) > 0 && isSQLServer(driverNameOf(stmt.execer)) {
+		if stmt.SoftColumn != "" {
+			clauses = append(clauses[:2], append([]string{outputClause("INSERTED", stmt.Return)}, clauses[2:]...)...)
+		} else {
+			clauses = append(clauses[:1], append([]string{outputClause("DELETED", stmt.Return)}, clauses[1:]...)...)
+		}
+	}
+
 	if len(stmt.Conditions) > 0 {
-		whereClause, whereBindings := parseConditions(stmt.Conditions)
+		whereClause, whereBindings := parseConditions(stmt.Conditions, driverNameOf(stmt.execer))
 		bindings = append(bindings, whereBindings...)
 		clauses = append(clauses, "WHERE "+whereClause)
 	}
 
-	if len(stmt.Return) > 0 {
+	if len(stmt.Return) > 0 && !isSQLServer(driverNameOf(stmt.execer)) {
 		clauses = append(clauses, "RETURNING "+strings.Join(stmt.Return, ", "))
 	}
 
 	asSQL = strings.Join(clauses, " ")
 
 	if rebind {
-		if db, ok := stmt.execer.(*sqlx.DB); ok {
-			asSQL = db.Rebind(asSQL)
-		} else if tx, ok := stmt.execer.(*sqlx.Tx); ok {
-			asSQL = tx.Rebind(asSQL)
-		}
+		asSQL = stmt.commented(stmt.execer, rebindFor(stmt.execer, asSQL))
 	}
 
 	return asSQL, bindings
 }
 
+// ToSQLChecked generates the DELETE statement's SQL like ToSQL, but fails
+// if the number of placeholders doesn't match the number of bindings (see
+// SelectStmt.ToSQLChecked).
+func (stmt *DeleteStmt) ToSQLChecked(rebind bool) (asSQL string, bindings []interface{}, err error) {
+	return checkedSQL(stmt, rebind)
+}
+
+// Args returns the values bound to the statement (see SelectStmt.Args)
+func (stmt *DeleteStmt) Args() []interface{} {
+	_, bindings := stmt.ToSQL(false)
+	return bindings
+}
+
 // Exec executes the DELETE statement, returning the standard
 // sql.Result struct and an error if the query failed.
 func (stmt *DeleteStmt) Exec() (res sql.Result, err error) {
-	asSQL, bindings := stmt.ToSQL(true)
-
-	res, err = stmt.execer.Exec(asSQL, bindings...)
-	stmt.HandleError(err)
-
-	return res, err
+	return stmt.ExecContext(context.Background())
 }
 
 // ExecContext executes the DELETE statement, returning the standard
 // sql.Result struct and an error if the query failed.
-func (stmt *DeleteStmt) ExecContext(ctx context.Context) (
+func (stmt *DeleteStmt) ExecContext(ctx context.Context) (res sql.Result, err error) {
+	res, _, _, err = stmt.ExecWithSQLContext(ctx)
+	return res, err
+}
+
+// ExecWithSQL executes the DELETE statement like Exec, but also returns the
+// SQL and arguments that were sent to the database, after placeholders
+// were rebound and arguments converted, e.g. for audit logging of the
+// exact statements executed. If the statement fails before being sent,
+// the SQL is empty.
+func (stmt *DeleteStmt) ExecWithSQL() (res sql.Result, asSQL string, args []interface{}, err error) {
+	return stmt.ExecWithSQLContext(context.Background())
+}
+
+// ExecWithSQLContext is the same as ExecWithSQL, but receives a context.
+func (stmt *DeleteStmt) ExecWithSQLContext(ctx context.Context) (
 	res sql.Result,
+	asSQL string,
+	args []interface{},
 	err error,
 ) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if err = stmt.Err(); err != nil {
+		stmt.HandleError(err)
+		return res, "", nil, err
+	}
+
 	asSQL, bindings := stmt.ToSQL(true)
+	args = sentArgs(stmt.execer, asSQL, bindings)
 
 	res, err = stmt.execer.ExecContext(ctx, asSQL, bindings...)
 	stmt.HandleError(err)
 
-	return res, err
+	return res, asSQL, args, err
+}
+
+// ExecAffected executes the DELETE statement, returning the number of
+// rows it affected. If the driver cannot report that number, the returned
+// error wraps ErrRowsAffectedUnavailable.
+func (stmt *DeleteStmt) ExecAffected() (int64, error) {
+	return stmt.ExecAffectedContext(context.Background())
+}
+
+// ExecAffectedContext executes the DELETE statement, returning the number
+// of rows it affected. See ExecAffected for more information.
+func (stmt *DeleteStmt) ExecAffectedContext(ctx context.Context) (int64, error) {
+	return rowsAffected(stmt.ExecContext(ctx))
 }
 
 // GetRow executes a DELETE statement with a RETURNING clause
@@ -124,12 +273,7 @@ func (stmt *DeleteStmt) ExecContext(ctx context.Context) (
 // only one column is returned, or a struct if multiple columns
 // are returned)
 func (stmt *DeleteStmt) GetRow(into interface{}) error {
-	asSQL, bindings := stmt.ToSQL(true)
-
-	err := sqlx.Get(stmt.execer, into, asSQL, bindings...)
-	stmt.HandleError(err)
-
-	return err
+	return stmt.GetRowContext(context.Background(), into)
 }
 
 // GetRowContext executes a DELETE statement with a RETURNING clause
@@ -141,9 +285,17 @@ func (stmt *DeleteStmt) GetRowContext(
 	ctx context.Context,
 	into interface{},
 ) error {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if err := stmt.Err(); err != nil {
+		stmt.HandleError(err)
+		return err
+	}
+
 	asSQL, bindings := stmt.ToSQL(true)
 
-	err := sqlx.GetContext(ctx, stmt.execer, into, asSQL, bindings...)
+	err := mapErrorOf(stmt.execer, sqlx.GetContext(ctx, stmt.execer, into, asSQL, bindings...))
 	stmt.HandleError(err)
 
 	return err
@@ -153,18 +305,21 @@ func (stmt *DeleteStmt) GetRowContext(
 // expected to return multiple rows, and loads the result into
 // the provided slice variable
 func (stmt *DeleteStmt) GetAll(into interface{}) error {
-	asSQL, bindings := stmt.ToSQL(true)
-
-	err := sqlx.Select(stmt.execer, into, asSQL, bindings...)
-	stmt.HandleError(err)
-
-	return err
+	return stmt.GetAllContext(context.Background(), into)
 }
 
 // GetAllContext executes a DELETE statement with a RETURNING clause
 // expected to return multiple rows, and loads the result into
 // the provided slice variable
 func (stmt *DeleteStmt) GetAllContext(ctx context.Context, into interface{}) error {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if err := stmt.Err(); err != nil {
+		stmt.HandleError(err)
+		return err
+	}
+
 	asSQL, bindings := stmt.ToSQL(true)
 
 	err := sqlx.SelectContext(ctx, stmt.execer, into, asSQL, bindings...)
@@ -172,3 +327,88 @@ func (stmt *DeleteStmt) GetAllContext(ctx context.Context, into interface{}) err
 
 	return err
 }
+
+// WhereInChunked deletes the rows whose column col has one of the provided
+// values, in chunks of at most chunkSize values, so that long lists (e.g.
+// millions of primary keys) don't exceed the database's limit on the number
+// of placeholders in a query. A DELETE statement with the statement's
+// conditions and an IN condition for the chunk is executed for each chunk,
+// and the total number of affected rows is returned. If the statement was
+// created from a DB object, the chunks are deleted inside a transaction of
+// their own, which is rolled back if any chunk fails. If it was created
+// from a Tx object, they are deleted in that transaction, and rolling it
+// back is up to the caller.
+func (stmt *DeleteStmt) WhereInChunked(col string, values []interface{}, chunkSize int) (int64, error) {
+	return stmt.WhereInChunkedContext(context.Background(), col, values, chunkSize)
+}
+
+// WhereInChunkedContext is the same as WhereInChunked, but receives a
+// context.
+func (stmt *DeleteStmt) WhereInChunkedContext(
+	ctx context.Context,
+	col string,
+	values []interface{},
+	chunkSize int,
+) (total int64, err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	defer func() { stmt.HandleError(err) }()
+
+	if err = stmt.Err(); err != nil {
+		return 0, err
+	}
+
+	if chunkSize <= 0 {
+		return 0, fmt.Errorf("invalid chunk size %d", chunkSize)
+	}
+
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	var (
+		execer = stmt.execer
+		tx     *sqlx.Tx
+	)
+
+	if h, ok := execer.(*handle); ok {
+		if db, isDB := h.Ext.(*sqlx.DB); isDB {
+			if tx, err = db.BeginTxx(ctx, nil); err != nil {
+				return 0, fmt.Errorf("failed starting transaction: %w", err)
+			}
+
+			defer tx.Rollback() // nolint: errcheck
+
+			execer = &handle{Ext: tx, db: h.db}
+		}
+	}
+
+	for start := 0; start < len(values); start += chunkSize {
+		end := start + chunkSize
+		if end > len(values) {
+			end = len(values)
+		}
+
+		chunk := *stmt
+		chunk.Conditions = append(append([]WhereCondition{}, stmt.Conditions...), In(col, values[start:end]...))
+		chunk.execer = execer
+
+		asSQL, bindings := chunk.ToSQL(true)
+
+		affected, err := rowsAffected(execer.ExecContext(ctx, asSQL, bindings...))
+		if err != nil {
+			return 0, fmt.Errorf("failed deleting chunk %d: %w", start/chunkSize, err)
+		}
+
+		total += affected
+	}
+
+	if tx != nil {
+		if err = tx.Commit(); err != nil {
+			return 0, fmt.Errorf("failed committing transaction: %w", err)
+		}
+	}
+
+	return total, nil
+}
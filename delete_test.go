@@ -1,7 +1,11 @@
 package sqlz
 
 import (
+	"errors"
+	"regexp"
 	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
 )
 
 func TestDelete(t *testing.T) {
@@ -34,6 +38,143 @@ func TestDelete(t *testing.T) {
 				"DELETE FROM table USING other, another WHERE other.fk_id = table.id AND another.fk_id = table.id",
 				[]interface{}{},
 			},
+
+			{
+				"soft delete",
+				dbz.DeleteFrom("table").Soft("deleted_at", Indirect("NOW()")).Where(Eq("id", 1), Gt("integer", 3)),
+				"UPDATE table SET deleted_at = NOW() WHERE id = ? AND integer > ?",
+				[]interface{}{1, 3},
+			},
+
+			{
+				"soft delete with value and returning clause",
+				dbz.DeleteFrom("table").Soft("deleted", true).Where(Eq("id", 2)).Returning("name"),
+				"UPDATE table SET deleted = ? WHERE id = ? RETURNING name",
+				[]interface{}{true, 2},
+			},
 		}
 	})
 }
+
+func TestDeleteUsing(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"delete using another table",
+				dbz.DeleteFrom("orders").Using("customers").
+					Where(Eq("orders.customer_id", Indirect("customers.id")), Eq("customers.status", "closed")),
+				"DELETE FROM orders USING customers WHERE orders.customer_id = customers.id AND customers.status = $1",
+				[]interface{}{"closed"},
+			},
+
+			{
+				"soft delete using another table",
+				dbz.DeleteFrom("orders").Using("customers").Soft("deleted", true).
+					Where(Eq("orders.customer_id", Indirect("customers.id")), Eq("customers.status", "closed")),
+				"UPDATE orders SET deleted = $1 FROM customers WHERE orders.customer_id = customers.id AND customers.status = $2",
+				[]interface{}{true, "closed"},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"delete joined with another table",
+				dbz.DeleteFrom("orders").Using("customers").
+					Where(Eq("orders.customer_id", Indirect("customers.id")), Eq("customers.status", "closed")),
+				"DELETE orders FROM orders JOIN customers WHERE orders.customer_id = customers.id AND customers.status = ?",
+				[]interface{}{"closed"},
+			},
+
+			{
+				"delete from aliased table joined with another table",
+				dbz.DeleteFrom("orders o").Using("customers c").
+					Where(Eq("o.customer_id", Indirect("c.id")), Eq("c.status", "closed")),
+				"DELETE o FROM orders o JOIN customers c WHERE o.customer_id = c.id AND c.status = ?",
+				[]interface{}{"closed"},
+			},
+
+			{
+				"soft delete joined with another table",
+				dbz.DeleteFrom("orders o").Using("customers c").Soft("deleted", true).
+					Where(Eq("o.customer_id", Indirect("c.id")), Eq("c.status", "closed")),
+				"UPDATE orders o JOIN customers c SET o.deleted = ? WHERE o.customer_id = c.id AND c.status = ?",
+				[]interface{}{true, "closed"},
+			},
+		}
+	})
+}
+
+func TestDeleteOutputSQLServer(t *testing.T) {
+	runDriverTests(t, "sqlserver", func(dbz *DB) []test {
+		return []test{
+			{
+				"delete with output clause",
+				dbz.DeleteFrom("users").Where(Eq("id", 1)).Returning("id", "email"),
+				"DELETE FROM users OUTPUT DELETED.id, DELETED.email WHERE id = @p1",
+				[]interface{}{1},
+			},
+
+			{
+				"soft delete with output clause",
+				dbz.DeleteFrom("users").Soft("deleted", true).Where(Eq("id", 1)).Returning("id"),
+				"UPDATE users SET deleted = @p1 OUTPUT INSERTED.id WHERE id = @p2",
+				[]interface{}{true, 1},
+			},
+		}
+	})
+}
+
+func TestDeleteWhereInChunked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM events WHERE archived = $1 AND id IN ($2, $3)")).
+		WithArgs(true, 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM events WHERE archived = $1 AND id IN ($2, $3)")).
+		WithArgs(true, 3, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM events WHERE archived = $1 AND id IN ($2)")).
+		WithArgs(true, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	total, err := dbz.DeleteFrom("events").
+		Where(Eq("archived", true)).
+		WhereInChunked("id", []interface{}{1, 2, 3, 4, 5}, 2)
+	if err != nil {
+		t.Fatalf("Failed deleting in chunks: %s", err)
+	}
+
+	if total != 4 {
+		t.Errorf("Expected 4 deleted rows, got %d", total)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM events WHERE id IN ($1, $2)")).
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM events WHERE id IN ($1)")).
+		WithArgs(3).
+		WillReturnError(errors.New("deadlock detected"))
+	mock.ExpectRollback()
+
+	if _, err = dbz.DeleteFrom("events").WhereInChunked("id", []interface{}{1, 2, 3}, 2); err == nil {
+		t.Error("Expected failing chunk to fail the delete")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	if _, err = dbz.DeleteFrom("events").WhereInChunked("id", []interface{}{1}, 0); err == nil {
+		t.Error("Expected invalid chunk size to fail")
+	}
+}
@@ -0,0 +1,391 @@
+package sqlz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// driverSpecific is implemented by expressions and conditions whose SQL
+// depends on the database driver in use. When statements generate SQL,
+// they prefer sqlFor over ToSQL/Parse, providing the name of the driver
+// they were created with.
+type driverSpecific interface {
+	sqlFor(driverName string) (asSQL string, bindings []interface{})
+}
+
+// dialectChecker is implemented by expressions that are only supported by
+// some database systems. Statements check such expressions when they are
+// added, failing if the driver they were created with is not supported.
+type dialectChecker interface {
+	checkDialect(driverName string) error
+}
+
+// exprErr returns an error if the provided expression is invalid, or not
+// supported by the provided driver
+func exprErr(expr interface{}, driverName string) error {
+	if withErr, ok := expr.(interface{ Err() error }); ok {
+		if err := withErr.Err(); err != nil {
+			return err
+		}
+	}
+
+	if checker, ok := expr.(dialectChecker); ok {
+		return checker.checkDialect(driverName)
+	}
+
+	return nil
+}
+
+// condErr returns an error if any of the provided conditions, including
+// conditions nested in AND/OR groups, is not supported by the provided driver
+func condErr(conds []WhereCondition, driverName string) error {
+	for _, cond := range conds {
+		var err error
+
+		switch c := cond.(type) {
+		case AndOrCondition:
+			err = condErr(c.Conditions, driverName)
+		case PreCondition:
+			err = condErr([]WhereCondition{c.Condition}, driverName)
+		case NegatedCondition:
+			err = condErr([]WhereCondition{c.Condition}, driverName)
+		default:
+			err = exprErr(c, driverName)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isPostgres returns true if the provided driver name belongs to a
+// PostgreSQL driver
+func isPostgres(driverName string) bool {
+	switch driverName {
+	case "postgres", "pgx", "pq-timeouts", "cloudsqlpostgres":
+		return true
+	default:
+		return false
+	}
+}
+
+// isMySQL returns true if the provided driver name belongs to a MySQL
+// driver
+func isMySQL(driverName string) bool {
+	return driverName == "mysql"
+}
+
+// isSQLite returns true if the provided driver name belongs to an SQLite
+// driver
+func isSQLite(driverName string) bool {
+	return driverName == "sqlite3" || driverName == "sqlite"
+}
+
+// isSQLServer returns true if the provided driver name belongs to an SQL
+// Server driver
+func isSQLServer(driverName string) bool {
+	return driverName == "sqlserver" || driverName == "mssql"
+}
+
+// outputClause generates a T-SQL OUTPUT clause, SQL Server's equivalent of
+// RETURNING, qualifying the provided columns with the provided pseudo-table
+// ("INSERTED" or "DELETED"), e.g. "OUTPUT INSERTED.id, INSERTED.name".
+// Columns that are already qualified with either pseudo-table are kept as
+// they are, so that updates can return previous values as well.
+func outputClause(table string, cols []string) string {
+	output := make([]string, len(cols))
+
+	for i, col := range cols {
+		upper := strings.ToUpper(col)
+		if strings.HasPrefix(upper, "INSERTED.") || strings.HasPrefix(upper, "DELETED.") {
+			output[i] = col
+		} else {
+			output[i] = table + "." + col
+		}
+	}
+
+	return "OUTPUT " + strings.Join(output, ", ")
+}
+
+// bindTypeOf returns the bindvar type used by the provided driver. Known
+// dialects are detected with the same helpers used for generating SQL, so
+// that all drivers of a dialect (e.g. "postgres" and "pgx") are treated
+// identically.
+func bindTypeOf(driverName string) int {
+	switch {
+	case isPostgres(driverName):
+		return sqlx.DOLLAR
+	case isMySQL(driverName), isSQLite(driverName):
+		return sqlx.QUESTION
+	case isSQLServer(driverName):
+		return sqlx.AT
+	default:
+		return sqlx.BindType(driverName)
+	}
+}
+
+// PlaceholderFormat is a format of the placeholders for bound values in
+// generated SQL, see DB.SetPlaceholderFormat
+type PlaceholderFormat int
+
+const (
+	// DriverPlaceholders uses the format expected by the database driver
+	// (e.g. "$1" for PostgreSQL drivers, "?" for MySQL drivers). This is
+	// the default.
+	DriverPlaceholders PlaceholderFormat = iota
+	// QuestionPlaceholders uses question marks, e.g. "?"
+	QuestionPlaceholders
+	// DollarPlaceholders uses numbered dollar signs, e.g. "$1"
+	DollarPlaceholders
+	// AtPlaceholders uses numbered at signs, as in SQL Server, e.g. "@p1"
+	AtPlaceholders
+	// ColonPlaceholders uses numbered colons, as in Oracle, e.g. ":1"
+	ColonPlaceholders
+)
+
+// SetPlaceholderFormat sets the format of placeholders in SQL generated by
+// statements created from the DB (and its transactions) with rebinding
+// enabled, e.g. by ToSQL(true), and therefore in the queries they execute.
+// This allows using databases and tools that expect a different format than
+// the one detected from the driver name.
+func (db *DB) SetPlaceholderFormat(format PlaceholderFormat) {
+	db.placeholderFormat = format
+}
+
+// rebind transforms a query from question mark placeholders to the format
+func (format PlaceholderFormat) rebind(query string) string {
+	switch format {
+	case DollarPlaceholders:
+		return rebindQuery(query, "$")
+	case AtPlaceholders:
+		return rebindQuery(query, "@p")
+	case ColonPlaceholders:
+		return rebindQuery(query, ":")
+	default:
+		return query
+	}
+}
+
+// Rebind transforms a query from question mark placeholders to the
+// placeholder format used by the provided driver, e.g. "$1" for PostgreSQL
+// drivers or "@p1" for SQL Server drivers. Question marks inside quoted
+// strings and identifiers are left as they are. This is the same rebinding
+// applied to statements built by sqlz, exposed for reuse with raw SQL.
+// Queries must only be rebound once, as rebinding is not aware of
+// placeholders that were already rebound (see DB.SetNativePgx).
+func Rebind(driverName, query string) string {
+	switch bindTypeOf(driverName) {
+	case sqlx.DOLLAR:
+		return rebindQuery(query, "$")
+	case sqlx.AT:
+		return rebindQuery(query, "@p")
+	case sqlx.NAMED:
+		return rebindQuery(query, ":arg")
+	default:
+		return query
+	}
+}
+
+// hasOrdinalPlaceholders returns true if the provided query contains
+// ordinal placeholders such as "$1" outside of quoted strings and
+// identifiers, i.e. it was already rebound for PostgreSQL
+func hasOrdinalPlaceholders(query string) bool {
+	var quote byte
+
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '$' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			return true
+		}
+	}
+
+	return false
+}
+
+// rebindQuery replaces the question mark placeholders of a query with the
+// provided prefix followed by the placeholder's number, skipping quoted
+// strings and identifiers (see countPlaceholders)
+func rebindQuery(query, prefix string) string {
+	var (
+		b     strings.Builder
+		n     int
+		quote rune
+	)
+
+	b.Grow(len(query) + 8)
+
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteString(prefix + strconv.Itoa(n))
+
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// DetectDriver queries the database for its version in order to detect
+// its dialect, returning a canonical driver name for it ("postgres",
+// "mysql", "sqlite3" or "sqlserver"). If the detected dialect differs from
+// the dialect of the driver name the DB was created with (see
+// DB.DriverName), an error is returned as well, since the generated SQL
+// (e.g. its placeholders) would not suit the database. MariaDB is detected
+// as "mysql", and CockroachDB, which speaks PostgreSQL's protocol, as
+// "postgres". Databases answering "SELECT VERSION()" with a version that
+// identifies none of these fail detection. Use DB.PingContext to merely
+// check connectivity (a DB.Ping(ctx) method would shadow the Ping method
+// of the embedded sqlx.DB).
+func (db *DB) DetectDriver() (string, error) {
+	return db.DetectDriverContext(context.Background())
+}
+
+// DetectDriverContext is the same as DetectDriver, but receives a context.
+func (db *DB) DetectDriverContext(ctx context.Context) (detected string, err error) {
+	var version string
+
+	switch {
+	case db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version) == nil:
+		if detected = db.versionDialect(ctx, version); detected == "" {
+			return "", fmt.Errorf("failed detecting the database's dialect from version %q", version)
+		}
+	case db.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&version) == nil:
+		detected = "sqlite3"
+	case db.QueryRowContext(ctx, "SELECT @@VERSION").Scan(&version) == nil:
+		detected = "sqlserver"
+	default:
+		return "", errors.New("failed detecting the database's dialect")
+	}
+
+	driverName := db.DriverName()
+
+	if isPostgres(detected) != isPostgres(driverName) ||
+		isMySQL(detected) != isMySQL(driverName) ||
+		isSQLite(detected) != isSQLite(driverName) ||
+		isSQLServer(detected) != isSQLServer(driverName) {
+		return detected, fmt.Errorf(
+			"database appears to be %s, but the DB was created with the %s driver",
+			detected, driverName,
+		)
+	}
+
+	return detected, nil
+}
+
+// versionDialect returns the canonical driver name of a database whose
+// VERSION() function returned the provided version, or an empty string if
+// the version doesn't identify a known database. MySQL versions are plain
+// numbers (e.g. "8.0.23"), so MySQL is identified by its version comment.
+func (db *DB) versionDialect(ctx context.Context, version string) string {
+	switch {
+	case strings.Contains(version, "PostgreSQL"), strings.Contains(version, "CockroachDB"):
+		return "postgres"
+	case strings.Contains(version, "MariaDB"):
+		return "mysql"
+	}
+
+	var comment string
+	if err := db.QueryRowContext(ctx, "SELECT @@version_comment").Scan(&comment); err != nil {
+		return ""
+	}
+
+	comment = strings.ToLower(comment)
+	if strings.Contains(comment, "mysql") || strings.Contains(comment, "mariadb") ||
+		strings.Contains(comment, "percona") {
+		return "mysql"
+	}
+
+	return ""
+}
+
+// driverNameOf returns the name of the driver used by the provided database
+// object, if known
+func driverNameOf(db interface{}) string {
+	if named, ok := db.(interface{ DriverName() string }); ok {
+		return named.DriverName()
+	}
+
+	return ""
+}
+
+// exprSQL generates SQL for an expression, in the dialect of the provided
+// driver if the expression is driver-specific
+func exprSQL(expr SQLStmt, driverName string) (asSQL string, bindings []interface{}) {
+	if specific, ok := expr.(driverSpecific); ok {
+		return specific.sqlFor(driverName)
+	}
+
+	return expr.ToSQL(false)
+}
+
+// condSQL generates SQL for a condition, in the dialect of the provided
+// driver if the condition is driver-specific
+func condSQL(cond WhereCondition, driverName string) (asSQL string, bindings []interface{}) {
+	if specific, ok := cond.(driverSpecific); ok {
+		return specific.sqlFor(driverName)
+	}
+
+	return cond.Parse()
+}
+
+// Ident quotes an identifier (e.g. a table or column name) for use in
+// statements created by the DB, so that reserved words (e.g. "order") and
+// user-supplied names are not interpreted as SQL. Identifiers are quoted
+// with double quotes in PostgreSQL and SQLite, backticks in MySQL and
+// brackets in SQL Server. Qualified names such as "schema.table" have each
+// of their parts quoted separately, except for "*" (e.g. "t.*").
+func (db *DB) Ident(name string) string {
+	return quoteIdent(db.DriverName(), name)
+}
+
+// Ident quotes an identifier for use in statements created by the
+// transaction (see DB.Ident)
+func (tx *Tx) Ident(name string) string {
+	return quoteIdent(tx.DriverName(), name)
+}
+
+// quoteIdent quotes an identifier according to the provided driver,
+// escaping quote characters inside it by doubling them
+func quoteIdent(driverName, name string) string {
+	left, right := `"`, `"`
+
+	switch {
+	case isMySQL(driverName):
+		left, right = "`", "`"
+	case isSQLServer(driverName):
+		left, right = "[", "]"
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if part != "*" {
+			parts[i] = left + strings.ReplaceAll(part, right, right+right) + right
+		}
+	}
+
+	return strings.Join(parts, ".")
+}
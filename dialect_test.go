@@ -0,0 +1,250 @@
+package sqlz
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestPostgresDrivers(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	builders := map[string]func(dbz *DB) SQLStmt{
+		"rebinding and ilike": func(dbz *DB) SQLStmt {
+			return dbz.Select("*").From("table").Where(ILike("name", "a%"), In("id", 1, 2))
+		},
+		"distinct on": func(dbz *DB) SQLStmt {
+			return dbz.Select("*").From("table").Distinct("category").OrderBy(Asc("category"))
+		},
+		"returning": func(dbz *DB) SQLStmt {
+			return dbz.Update("table").Set("name", "a").Where(Eq("id", 1)).Returning("id", "name")
+		},
+		"json array containment": func(dbz *DB) SQLStmt {
+			return dbz.Select("*").From("table").Where(JSONBArrayContains("tags", "go"))
+		},
+		"tuple in": func(dbz *DB) SQLStmt {
+			return dbz.Select("*").From("table").Where(InTuple([]string{"a", "b"}, [][]interface{}{{1, 2}}))
+		},
+		"update from values": func(dbz *DB) SQLStmt {
+			return dbz.Update("table").SetFromValues("id", []map[string]interface{}{{"id": 1, "name": "a"}})
+		},
+		"returning previous values": func(dbz *DB) SQLStmt {
+			stmt, err := dbz.InsertInto("table").
+				Columns("id", "value").
+				Values(1, 2).
+				OnConflict(OnConflict("id").DoUpdate().Set("value", 2)).
+				ReturningPrevious("value")
+			if err != nil {
+				t.Fatalf("Failed creating statement for %s: %s", dbz.DriverName(), err)
+			}
+
+			return stmt
+		},
+	}
+
+	for name, build := range builders {
+		t.Run(name, func(t *testing.T) {
+			postgresSQL, postgresBindings := build(New(db, "postgres")).ToSQL(true)
+			pgxSQL, pgxBindings := build(New(db, "pgx")).ToSQL(true)
+
+			if postgresSQL != pgxSQL {
+				t.Errorf("Expected identical SQL, got %s for postgres and %s for pgx", postgresSQL, pgxSQL)
+			}
+
+			if len(postgresBindings) != len(pgxBindings) {
+				t.Errorf("Expected identical bindings, got %v for postgres and %v for pgx", postgresBindings, pgxBindings)
+			}
+		})
+	}
+}
+
+func TestBindTypeOf(t *testing.T) {
+	for driverName, expected := range map[string]int{
+		"postgres":  sqlx.DOLLAR,
+		"pgx":       sqlx.DOLLAR,
+		"mysql":     sqlx.QUESTION,
+		"sqlite":    sqlx.QUESTION,
+		"sqlite3":   sqlx.QUESTION,
+		"sqlserver": sqlx.AT,
+		"mssql":     sqlx.AT,
+		"sqlmock":   sqlx.UNKNOWN,
+	} {
+		if got := bindTypeOf(driverName); got != expected {
+			t.Errorf("Expected bind type of %s to be %d, got %d", driverName, expected, got)
+		}
+	}
+}
+
+func TestRebind(t *testing.T) {
+	query := "SELECT * FROM t WHERE a = ? AND b::text = 'what?' AND \"c?\" = ?::int"
+
+	for driverName, expected := range map[string]string{
+		"postgres":  "SELECT * FROM t WHERE a = $1 AND b::text = 'what?' AND \"c?\" = $2::int",
+		"pgx":       "SELECT * FROM t WHERE a = $1 AND b::text = 'what?' AND \"c?\" = $2::int",
+		"sqlserver": "SELECT * FROM t WHERE a = @p1 AND b::text = 'what?' AND \"c?\" = @p2::int",
+		"mysql":     query,
+		"sqlite3":   query,
+	} {
+		if got := Rebind(driverName, query); got != expected {
+			t.Errorf("Expected %s to rebind to %s, got %s", driverName, expected, got)
+		}
+	}
+
+	if got := Rebind("postgres", "SELECT 'it''s?', ?"); got != "SELECT 'it''s?', $1" {
+		t.Errorf("Expected escaped quotes to be skipped, got %s", got)
+	}
+}
+
+func TestSetNativePgx(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "pgx")
+	raw := "SELECT * FROM docs WHERE data ? 'tags' AND id = $1"
+
+	if asSQL, _ := dbz.RawSelect(raw, 1).ToSQL(true); asSQL != "SELECT * FROM docs WHERE data $1 'tags' AND id = $1" {
+		t.Errorf("Expected question marks to be rebound by default, got %s", asSQL)
+	}
+
+	dbz.SetNativePgx(true)
+
+	if asSQL, _ := dbz.RawSelect(raw, 1).ToSQL(true); asSQL != raw {
+		t.Errorf("Expected an ordinal query not to be rebound, got %s", asSQL)
+	}
+
+	// rebinding a query that was already rebound doesn't change it
+	once, _ := dbz.Select("*").From("docs").Where(Eq("id", 1), Eq("owner", "a")).ToSQL(true)
+	if twice, _ := dbz.RawSelect(once, 1, "a").ToSQL(true); twice != once {
+		t.Errorf("Expected %s not to be rebound twice, got %s", once, twice)
+	}
+
+	if once != "SELECT * FROM docs WHERE id = $1 AND owner = $2" {
+		t.Errorf("Expected question marks to be rebound, got %s", once)
+	}
+}
+
+func TestIdent(t *testing.T) {
+	for driverName, expected := range map[string]string{
+		"postgres":  `SELECT "order", "u".* FROM "public"."users" "u" WHERE "order" = $1`,
+		"sqlite3":   `SELECT "order", "u".* FROM "public"."users" "u" WHERE "order" = ?`,
+		"mysql":     "SELECT `order`, `u`.* FROM `public`.`users` `u` WHERE `order` = ?",
+		"sqlserver": `SELECT [order], [u].* FROM [public].[users] [u] WHERE [order] = @p1`,
+	} {
+		runDriverTests(t, driverName, func(dbz *DB) []test {
+			return []test{
+				{
+					"select with quoted identifiers",
+					dbz.Select(dbz.Ident("order"), dbz.Ident("u.*")).
+						From(dbz.Ident("public.users") + " " + dbz.Ident("u")).
+						Where(Eq(dbz.Ident("order"), 1)),
+					expected,
+					[]interface{}{1},
+				},
+			}
+		})
+	}
+
+	for name, expected := range map[string]string{
+		`weird"name`:  `"weird""name"`,
+		"a.b.c":       `"a"."b"."c"`,
+		"plain_table": `"plain_table"`,
+	} {
+		if got := quoteIdent("postgres", name); got != expected {
+			t.Errorf("Expected %s to be quoted as %s, got %s", name, expected, got)
+		}
+	}
+}
+
+func TestSetPlaceholderFormat(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "mysql")
+
+	for format, expected := range map[PlaceholderFormat]string{
+		DriverPlaceholders:   "SELECT * FROM users WHERE id = ? AND name IN (?, ?)",
+		QuestionPlaceholders: "SELECT * FROM users WHERE id = ? AND name IN (?, ?)",
+		DollarPlaceholders:   "SELECT * FROM users WHERE id = $1 AND name IN ($2, $3)",
+		AtPlaceholders:       "SELECT * FROM users WHERE id = @p1 AND name IN (@p2, @p3)",
+		ColonPlaceholders:    "SELECT * FROM users WHERE id = :1 AND name IN (:2, :3)",
+	} {
+		dbz.SetPlaceholderFormat(format)
+
+		asSQL, bindings := dbz.Select("*").From("users").
+			Where(Eq("id", 1), In("name", "a", "b")).
+			ToSQL(true)
+		if asSQL != expected {
+			t.Errorf("Expected %s, got %s", expected, asSQL)
+		}
+
+		if !reflect.DeepEqual(bindings, []interface{}{1, "a", "b"}) {
+			t.Errorf("Unexpected bindings %v", bindings)
+		}
+	}
+}
+
+func TestDetectDriver(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	version := regexp.QuoteMeta("SELECT VERSION()")
+
+	mock.ExpectQuery(version).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("PostgreSQL 13.2 on x86_64-pc-linux-gnu"))
+	mock.ExpectQuery(version).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("8.0.23"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT @@version_comment")).
+		WillReturnRows(sqlmock.NewRows([]string{"comment"}).AddRow("MySQL Community Server - GPL"))
+	mock.ExpectQuery(version).
+		WillReturnError(errors.New("no such function: VERSION"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT sqlite_version()")).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("3.34.0"))
+	mock.ExpectQuery(version).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("CockroachDB CCL v23.1.11 (x86_64-pc-linux-gnu)"))
+	mock.ExpectQuery(version).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("1.0.0"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT @@version_comment")).
+		WillReturnError(errors.New("syntax error"))
+
+	detected, err := New(db, "pgx").DetectDriver()
+	if err != nil || detected != "postgres" {
+		t.Errorf("Expected postgres to be detected without errors, got %s (%v)", detected, err)
+	}
+
+	detected, err = New(db, "postgres").DetectDriver()
+	if err == nil || detected != "mysql" {
+		t.Errorf("Expected mysql to be detected with a mismatch error, got %s (%v)", detected, err)
+	}
+
+	detected, err = New(db, "sqlite3").DetectDriver()
+	if err != nil || detected != "sqlite3" {
+		t.Errorf("Expected sqlite3 to be detected without errors, got %s (%v)", detected, err)
+	}
+
+	detected, err = New(db, "postgres").DetectDriver()
+	if err != nil || detected != "postgres" {
+		t.Errorf("Expected CockroachDB to be detected as postgres without errors, got %s (%v)", detected, err)
+	}
+
+	detected, err = New(db, "postgres").DetectDriver()
+	if err == nil || detected != "" {
+		t.Errorf("Expected an unknown version to fail detection, got %s (%v)", detected, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,168 @@
+package sqlz
+
+import (
+	"errors"
+	"reflect"
+)
+
+// ErrorKind represents the kind of a database error, normalized across
+// drivers (see MapError)
+type ErrorKind int8
+
+const (
+	// UnknownErrorKind represents errors that were not recognized
+	UnknownErrorKind ErrorKind = iota
+	// UniqueViolation represents a violation of a unique constraint or
+	// index, e.g. a duplicate primary key
+	UniqueViolation
+	// ForeignKeyViolation represents a violation of a foreign key
+	// constraint
+	ForeignKeyViolation
+	// NotNullViolation represents a NULL value in a NOT NULL column
+	NotNullViolation
+	// CheckViolation represents a violation of a CHECK constraint
+	CheckViolation
+	// Deadlock represents a transaction aborted due to a deadlock
+	Deadlock
+	// SerializationFailure represents a transaction aborted as it could
+	// not be serialized with concurrent transactions
+	SerializationFailure
+)
+
+// String returns the name of the error kind
+func (kind ErrorKind) String() string {
+	switch kind {
+	case UniqueViolation:
+		return "unique violation"
+	case ForeignKeyViolation:
+		return "foreign key violation"
+	case NotNullViolation:
+		return "not null violation"
+	case CheckViolation:
+		return "check violation"
+	case Deadlock:
+		return "deadlock"
+	case SerializationFailure:
+		return "serialization failure"
+	default:
+		return "unknown"
+	}
+}
+
+// pgErrorKinds maps PostgreSQL SQLSTATE codes to error kinds
+var pgErrorKinds = map[string]ErrorKind{
+	"23505": UniqueViolation,
+	"23503": ForeignKeyViolation,
+	"23502": NotNullViolation,
+	"23514": CheckViolation,
+	"40P01": Deadlock,
+	"40001": SerializationFailure,
+}
+
+// mysqlErrorKinds maps MySQL error numbers to error kinds
+var mysqlErrorKinds = map[uint64]ErrorKind{
+	1062: UniqueViolation,
+	1586: UniqueViolation,
+	1216: ForeignKeyViolation,
+	1217: ForeignKeyViolation,
+	1451: ForeignKeyViolation,
+	1452: ForeignKeyViolation,
+	1048: NotNullViolation,
+	3819: CheckViolation,
+	1213: Deadlock,
+}
+
+// DBError is a database error normalized across drivers, as returned by
+// MapError. The original error is available via Unwrap (and therefore
+// errors.As).
+type DBError struct {
+	// Kind is the normalized kind of the error
+	Kind ErrorKind
+	// Err is the original error returned by the driver
+	Err error
+}
+
+// Error returns the message of the original error
+func (err *DBError) Error() string {
+	return err.Err.Error()
+}
+
+// Unwrap returns the original error
+func (err *DBError) Unwrap() error {
+	return err.Err
+}
+
+// MapError normalizes an error returned by a PostgreSQL or MySQL driver
+// (or any error wrapping one), so that callers can branch on the kind of
+// the error (e.g. UniqueViolation) without depending on the driver. It
+// returns nil if err is nil or is not one of the recognized kinds. As with
+// TransactionWithRetry, drivers are detected by the shape of their errors:
+// PostgreSQL drivers expose the SQLSTATE code via an SQLState method or a
+// Code field, and the MySQL driver exposes the error number via a Number
+// field. An error that was already mapped is returned as is.
+func MapError(err error) *DBError {
+	original := err
+
+	for ; err != nil; err = errors.Unwrap(err) {
+		if mapped, ok := err.(*DBError); ok {
+			return mapped
+		}
+
+		if withState, ok := err.(interface{ SQLState() string }); ok {
+			if kind, ok := pgErrorKinds[withState.SQLState()]; ok {
+				return &DBError{Kind: kind, Err: original}
+			}
+		}
+
+		val := reflect.ValueOf(err)
+		for val.Kind() == reflect.Ptr && !val.IsNil() {
+			val = val.Elem()
+		}
+
+		if val.Kind() != reflect.Struct {
+			continue
+		}
+
+		if code := val.FieldByName("Code"); code.IsValid() && code.Kind() == reflect.String {
+			if kind, ok := pgErrorKinds[code.String()]; ok {
+				return &DBError{Kind: kind, Err: original}
+			}
+		}
+
+		if number := val.FieldByName("Number"); number.IsValid() {
+			switch number.Kind() {
+			case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+				if kind, ok := mysqlErrorKinds[number.Uint()]; ok {
+					return &DBError{Kind: kind, Err: original}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// SetErrorMapping sets whether errors returned by statements executed
+// through sqlz are normalized with MapError. When enabled, recognized
+// errors are returned as *DBError values (which wrap the original errors),
+// while other errors (e.g. sql.ErrNoRows) are returned as is. Mapping is
+// disabled by default.
+func (db *DB) SetErrorMapping(enabled bool) {
+	db.errorMapping = enabled
+}
+
+// mapErrorOf maps an error with MapError if error mapping was enabled for
+// the DB object the provided database handle originated from
+func mapErrorOf(db interface{}, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if h, ok := db.(*handle); ok && h.db != nil && h.db.errorMapping {
+		if mapped := MapError(err); mapped != nil {
+			return mapped
+		}
+	}
+
+	return err
+}
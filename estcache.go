@@ -0,0 +1,83 @@
+package sqlz
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// estimateCache is a concurrency-safe cache of estimated plans, keyed by
+// the explained SQL and its bindings. Entries expire after a fixed TTL.
+type estimateCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cachedEstimate
+}
+
+// cachedEstimate is an estimated plan held by an estimateCache
+type cachedEstimate struct {
+	plan    EstimatedPlan
+	expires time.Time
+}
+
+func newEstimateCache(ttl time.Duration) *estimateCache {
+	return &estimateCache{
+		ttl:     ttl,
+		entries: make(map[string]cachedEstimate),
+	}
+}
+
+// EnableEstimatedCountCache enables caching the results of
+// SelectStmt.GetEstimatedCount and SelectStmt.GetEstimatedPlan in memory
+// for the provided TTL, so that repeatedly estimating the same statement
+// (with the same bindings) doesn't run EXPLAIN every time. This is useful
+// for approximate totals that are polled often, such as in dashboards.
+// Pass zero or a negative value to disable the cache.
+func (db *DB) EnableEstimatedCountCache(ttl time.Duration) {
+	if ttl > 0 {
+		db.estimateCache = newEstimateCache(ttl)
+	} else {
+		db.estimateCache = nil
+	}
+}
+
+// estimateCacheKey returns the cache key of an explained query and its
+// bindings. Bindings are formatted together with their types, so that e.g.
+// 1 and "1" produce different keys.
+func estimateCacheKey(query string, bindings []interface{}) string {
+	return fmt.Sprintf("%s %#v", query, bindings)
+}
+
+// get returns the cached plan for the provided key, if it hasn't expired
+func (cache *estimateCache) get(key string) (plan EstimatedPlan, ok bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cached, ok := cache.entries[key]
+	if !ok {
+		return plan, false
+	}
+
+	if time.Now().After(cached.expires) {
+		delete(cache.entries, key)
+		return plan, false
+	}
+
+	return cached.plan, true
+}
+
+// set stores a plan in the cache, removing any expired entries
+func (cache *estimateCache) set(key string, plan EstimatedPlan) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	now := time.Now()
+
+	for k, cached := range cache.entries {
+		if now.After(cached.expires) {
+			delete(cache.entries, k)
+		}
+	}
+
+	cache.entries[key] = cachedEstimate{plan: plan, expires: now.Add(cache.ttl)}
+}
@@ -0,0 +1,154 @@
+package sqlz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// columnCache is a concurrency-safe cache of the columns of tables, as
+// looked up by SelectStmt.ExpandStar. Entries never expire.
+type columnCache struct {
+	mu      sync.Mutex
+	columns map[string][]string
+}
+
+func (cache *columnCache) get(table string) ([]string, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cols, ok := cache.columns[table]
+
+	return cols, ok
+}
+
+func (cache *columnCache) set(table string, cols []string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.columns == nil {
+		cache.columns = make(map[string][]string)
+	}
+
+	cache.columns[table] = cols
+}
+
+// ExpandStar replaces "*" in the select list with an explicit list of
+// columns, so that the statement does not silently change when columns are
+// added to the table, and only reads the columns it needs. If columns is
+// empty, the columns of the statement's table are looked up in the
+// database's information schema (or with pragma_table_info in SQLite) in
+// the order of their definition, and cached in the DB object for
+// subsequent calls. Looking columns up requires a statement on a single
+// table, without joins or sub-queries. Statements without "*" in their
+// select list are left as they are.
+func (stmt *SelectStmt) ExpandStar(ctx context.Context, columns []string) *SelectStmt {
+	var hasStar bool
+
+	for _, col := range stmt.Columns {
+		if col == "*" {
+			hasStar = true
+			break
+		}
+	}
+
+	if !hasStar {
+		return stmt
+	}
+
+	if len(columns) == 0 {
+		var err error
+		if columns, err = stmt.tableColumns(ctx); err != nil {
+			stmt.setErr(fmt.Errorf("failed expanding *: %w", err))
+			return stmt
+		}
+	}
+
+	expanded := make([]string, 0, len(stmt.Columns)+len(columns)-1)
+
+	for _, col := range stmt.Columns {
+		if col == "*" {
+			expanded = append(expanded, columns...)
+		} else {
+			expanded = append(expanded, col)
+		}
+	}
+
+	stmt.Columns = expanded
+
+	return stmt
+}
+
+// tableColumns returns the columns of the statement's table, in the order of
+// their definition, from the cache of the DB the statement was created from
+// or otherwise from the database
+func (stmt *SelectStmt) tableColumns(ctx context.Context) (cols []string, err error) {
+	fields := strings.Fields(stmt.Table)
+	if len(fields) == 0 || len(stmt.Joins) > 0 || stmt.FromStmt != nil || stmt.RawSQL != "" {
+		return nil, errors.New("columns can only be looked up for a single table")
+	}
+
+	var cache *columnCache
+	if h, ok := stmt.queryer.(*handle); ok && h.db != nil {
+		cache = &h.db.columnCache
+	}
+
+	table := fields[0]
+
+	if cache != nil {
+		if cached, ok := cache.get(table); ok {
+			return cached, nil
+		}
+	}
+
+	lookupStmt := &SelectStmt{
+		queryer:   stmt.queryer,
+		Statement: &Statement{ErrHandlers: stmt.ErrHandlers},
+	}
+
+	driverName := driverNameOf(stmt.queryer)
+	schema, name := "", table
+
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		schema, name = table[:i], table[i+1:]
+	}
+
+	if isSQLite(driverName) {
+		lookupStmt.RawSQL = "SELECT name FROM pragma_table_info(?) ORDER BY cid"
+		lookupStmt.RawBindings = []interface{}{name}
+	} else {
+		schemaCond := Eq("table_schema", schema)
+
+		if schema == "" {
+			switch {
+			case isMySQL(driverName):
+				schemaCond = Eq("table_schema", Indirect("DATABASE()"))
+			case isSQLServer(driverName):
+				schemaCond = Eq("table_schema", Indirect("SCHEMA_NAME()"))
+			default:
+				schemaCond = Eq("table_schema", Indirect("current_schema()"))
+			}
+		}
+
+		lookupStmt.Table = "information_schema.columns"
+		lookupStmt.Columns = []string{"column_name"}
+		lookupStmt.Conditions = []WhereCondition{Eq("table_name", name), schemaCond}
+		lookupStmt.Ordering = []SQLStmt{Asc("ordinal_position")}
+	}
+
+	if err = lookupStmt.GetAllContext(ctx, &cols); err != nil {
+		return nil, err
+	}
+
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("table %s has no columns or does not exist", table)
+	}
+
+	if cache != nil {
+		cache.set(table, cols)
+	}
+
+	return cols, nil
+}
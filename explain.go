@@ -0,0 +1,791 @@
+package sqlz
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// EstimatedPlan holds the planner's estimates for a SELECT statement, as
+// returned by SelectStmt.GetEstimatedPlan
+type EstimatedPlan struct {
+	// Rows is the estimated number of matching rows
+	Rows int64
+	// TotalCost is the estimated total cost of the query, in the planner's
+	// arbitrary units
+	TotalCost float64
+	// Rounded is Rows rounded to two significant digits (see roundedCount)
+	Rounded int64
+}
+
+// planEstimates matches the estimates of the top node of a PostgreSQL
+// text plan, e.g. "Seq Scan on t  (cost=0.00..35.50 rows=2550 width=4)"
+var planEstimates = regexp.MustCompile(`cost=[0-9.]+\.\.([0-9.]+) rows=([0-9]+)`)
+
+// ExplainToggle represents the state of a boolean EXPLAIN option
+type ExplainToggle int8
+
+const (
+	// ExplainDefault omits the option, leaving it at its default
+	ExplainDefault ExplainToggle = iota
+	// ExplainOn enables the option, e.g. "COSTS true"
+	ExplainOn
+	// ExplainOff disables the option, e.g. "BUFFERS false"
+	ExplainOff
+)
+
+// ExplainOptions controls how EXPLAIN is run when estimating the number of
+// rows matching a statement (see SelectStmt.GetEstimatedCountWithOptions)
+type ExplainOptions struct {
+	// Costs toggles the COSTS option. Estimates are read from the costs of
+	// the plan, so disabling it makes estimation fail.
+	Costs ExplainToggle
+	// Buffers toggles the BUFFERS option
+	Buffers ExplainToggle
+	// Format is the output format of the plan, either "TEXT" (the
+	// default) or "JSON"
+	Format string
+	// Settings are run-time parameters (e.g.
+	// "max_parallel_workers_per_gather") to set with SET LOCAL before
+	// running EXPLAIN, in the order of their names. If the statement was
+	// created from a DB object, EXPLAIN runs in a transaction of its own
+	// which is then rolled back, so the settings do not leak to other
+	// queries. If it was created from a Tx object, the settings remain in
+	// effect until the end of the transaction.
+	Settings map[string]string
+}
+
+// explainPrefix returns the EXPLAIN command for the options, e.g.
+// "EXPLAIN (COSTS true, FORMAT JSON) "
+func (opts ExplainOptions) explainPrefix() (string, error) {
+	var options []string
+
+	for _, toggle := range []struct {
+		name  string
+		value ExplainToggle
+	}{{"COSTS", opts.Costs}, {"BUFFERS", opts.Buffers}} {
+		switch toggle.value {
+		case ExplainOn:
+			options = append(options, toggle.name+" true")
+		case ExplainOff:
+			options = append(options, toggle.name+" false")
+		}
+	}
+
+	switch strings.ToUpper(opts.Format) {
+	case "":
+	case "TEXT", "JSON":
+		options = append(options, "FORMAT "+strings.ToUpper(opts.Format))
+	default:
+		return "", fmt.Errorf("unsupported EXPLAIN format %q", opts.Format)
+	}
+
+	for name := range opts.Settings {
+		if !isSettingName(name) {
+			return "", fmt.Errorf("invalid setting name %q", name)
+		}
+	}
+
+	if len(options) == 0 {
+		return "EXPLAIN ", nil
+	}
+
+	return "EXPLAIN (" + strings.Join(options, ", ") + ") ", nil
+}
+
+// settingStatements returns the SET LOCAL statements for the options'
+// settings, in the order of their names
+func (opts ExplainOptions) settingStatements() []string {
+	names := make([]string, 0, len(opts.Settings))
+	for name := range opts.Settings {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	statements := make([]string, len(names))
+	for i, name := range names {
+		statements[i] = setLocalSQL(name, opts.Settings[name])
+	}
+
+	return statements
+}
+
+// withSettings calls fn with a queryer on which the options' settings are
+// in effect. If settings are required and the queryer is not a transaction,
+// fn is called inside a new transaction, which is rolled back afterwards.
+func (opts ExplainOptions) withSettings(ctx context.Context, queryer Queryer, fn func(Queryer) error) error {
+	if len(opts.Settings) == 0 {
+		return fn(queryer)
+	}
+
+	h, ok := queryer.(*handle)
+	if !ok {
+		return errors.New("EXPLAIN settings require a database object created by sqlz")
+	}
+
+	if db, isDB := h.Ext.(*sqlx.DB); isDB {
+		tx, err := db.BeginTxx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed starting transaction: %w", err)
+		}
+
+		defer tx.Rollback() // nolint: errcheck
+
+		h = &handle{Ext: tx, db: h.db}
+	}
+
+	for _, statement := range opts.settingStatements() {
+		if _, err := h.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed applying EXPLAIN settings: %w", err)
+		}
+	}
+
+	return fn(h)
+}
+
+// GetEstimatedPlan runs EXPLAIN on the statement and returns the planner's
+// estimated number of matching rows and total cost. Like GetCount, the
+// statement's limits, offsets and ordering are disregarded, and the select
+// list is replaced with "SELECT 1". This is much cheaper than GetCount on
+// large tables, but only as accurate as the table's statistics. Raw
+// statements (see DB.RawSelect) are explained as they are. It is only
+// supported on PostgreSQL. Results may be cached, see
+// DB.EnableEstimatedCountCache.
+func (stmt *SelectStmt) GetEstimatedPlan() (plan EstimatedPlan, err error) {
+	return stmt.GetEstimatedPlanContext(context.Background())
+}
+
+// GetEstimatedPlanContext is the same as GetEstimatedPlan, but receives a
+// context.
+func (stmt *SelectStmt) GetEstimatedPlanContext(ctx context.Context) (plan EstimatedPlan, err error) {
+	return stmt.estimatedPlan(ctx, ExplainOptions{}, false)
+}
+
+// estimatedPlan runs EXPLAIN with the provided options on the statement's
+// count query (or, if keepSelectList is true, on the statement itself sans
+// limits and offsets) and parses the planner's estimates from its output
+func (stmt *SelectStmt) estimatedPlan(
+	ctx context.Context,
+	opts ExplainOptions,
+	keepSelectList bool,
+) (plan EstimatedPlan, err error) {
+	plan, err = stmt.explainPlan(ctx, opts, keepSelectList)
+	stmt.HandleError(err)
+
+	return plan, err
+}
+
+// explainPlan is the same as estimatedPlan, but doesn't pass errors to the
+// statement's error handlers
+func (stmt *SelectStmt) explainPlan(
+	ctx context.Context,
+	opts ExplainOptions,
+	keepSelectList bool,
+) (plan EstimatedPlan, err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if !isPostgres(driverNameOf(stmt.queryer)) {
+		return plan, errors.New("estimated plans are only supported on PostgreSQL")
+	}
+
+	explain, err := opts.explainPrefix()
+	if err != nil {
+		return plan, err
+	}
+
+	if err = stmt.beforeExec(ctx); err != nil {
+		return plan, err
+	}
+
+	// raw queries are explained as they are, as their select list cannot be
+	// replaced
+	countStmt := stmt
+	if stmt.RawSQL == "" && keepSelectList {
+		countStmt = stmt.createFilterQuery()
+	} else if stmt.RawSQL == "" {
+		countStmt = stmt.createCountQuery("1")
+	}
+
+	asSQL, bindings := countStmt.ToSQL(true)
+
+	cache := stmt.estimateCache()
+	key := estimateCacheKey(strings.Join(append(opts.settingStatements(), explain+asSQL), "; "), bindings)
+
+	if cache != nil {
+		if cached, ok := cache.get(key); ok {
+			return cached, nil
+		}
+	}
+
+	var lines []string
+
+	err = opts.withSettings(ctx, stmt.queryer, func(queryer Queryer) error {
+		rows, err := queryer.QueryContext(ctx, explain+asSQL, bindings...)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var line string
+			if err = rows.Scan(&line); err != nil {
+				return err
+			}
+
+			lines = append(lines, line)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return plan, err
+	}
+
+	if strings.EqualFold(opts.Format, "json") {
+		plan, err = parseJSONPlan(strings.Join(lines, "\n"))
+	} else {
+		plan, err = parsePlan(lines)
+	}
+
+	if err == nil && cache != nil {
+		cache.set(key, plan)
+	}
+
+	return plan, err
+}
+
+// estimateCache returns the estimated plan cache of the DB the statement
+// was created from, if it is enabled
+func (stmt *SelectStmt) estimateCache() *estimateCache {
+	if h, ok := stmt.queryer.(*handle); ok && h.db != nil {
+		return h.db.estimateCache
+	}
+
+	return nil
+}
+
+// ExplainAnalyze runs EXPLAIN ANALYZE on the statement and returns the
+// resulting plan as text, one line per row, for diagnostics and logging.
+// Note that EXPLAIN ANALYZE actually executes the statement. This is
+// supported on PostgreSQL and MySQL 8. On SQLite, which has no ANALYZE
+// option, the estimated plan from EXPLAIN QUERY PLAN is returned instead,
+// preceded by a note saying so. SQL Server is not supported.
+func (stmt *SelectStmt) ExplainAnalyze(ctx context.Context) (plan string, err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	defer func() { stmt.HandleError(err) }()
+
+	driverName := driverNameOf(stmt.queryer)
+
+	var prefix, note string
+
+	switch {
+	case isSQLServer(driverName):
+		return plan, fmt.Errorf("EXPLAIN is not supported by the %s driver", driverName)
+	case isSQLite(driverName):
+		prefix = "EXPLAIN QUERY PLAN "
+		note = "-- EXPLAIN ANALYZE is not supported by the " + driverName + " driver, showing the estimated plan\n"
+	default:
+		prefix = "EXPLAIN ANALYZE "
+	}
+
+	if err = stmt.beforeExec(ctx); err != nil {
+		return plan, err
+	}
+
+	asSQL, bindings := stmt.ToSQL(true)
+
+	rows, err := stmt.queryer.QueryContext(ctx, prefix+asSQL, bindings...)
+	if err != nil {
+		return plan, err
+	}
+
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return plan, err
+	}
+
+	var lines []string
+
+	for rows.Next() {
+		// plans may span several columns (e.g. in SQLite), which are
+		// joined with spaces
+		vals := make([]sql.NullString, len(cols))
+		dests := make([]interface{}, len(cols))
+
+		for i := range vals {
+			dests[i] = &vals[i]
+		}
+
+		if err = rows.Scan(dests...); err != nil {
+			return plan, err
+		}
+
+		fields := make([]string, len(vals))
+		for i, val := range vals {
+			fields[i] = val.String
+		}
+
+		lines = append(lines, strings.Join(fields, " "))
+	}
+
+	if err = rows.Err(); err != nil {
+		return plan, err
+	}
+
+	return note + strings.Join(lines, "\n"), nil
+}
+
+// EstStrategy is a strategy for estimating the number of rows matching a
+// statement, see DB.SetEstimatedCountStrategy
+type EstStrategy int8
+
+const (
+	// ExplainPlan runs EXPLAIN on the statement and returns the planner's
+	// estimate (see SelectStmt.GetEstimatedPlan). It is only supported on
+	// PostgreSQL.
+	ExplainPlan EstStrategy = iota
+	// PgClassReltuples reads the estimated number of rows of the
+	// statement's table from pg_class. It is only supported on
+	// PostgreSQL, for statements selecting all rows of a single table
+	// (without conditions, joins, grouping and the like).
+	PgClassReltuples
+	// ExactCount counts the matching rows precisely (see
+	// SelectStmt.GetCount), which is supported on all databases, but may be
+	// slow.
+	ExactCount
+)
+
+// defaultEstimateStrategies are the strategies used by GetEstimatedCount
+// unless others were set with DB.SetEstimatedCountStrategy
+var defaultEstimateStrategies = []EstStrategy{PgClassReltuples, ExplainPlan}
+
+// SetEstimatedCountStrategy sets the strategies SelectStmt.GetEstimatedCount
+// uses to estimate the number of rows matching a statement, in the order in
+// which they are tried. A strategy is skipped if it doesn't apply to the
+// statement, fails, or estimates zero rows, in which case the next one is
+// tried. For example, []EstStrategy{ExplainPlan, ExactCount} falls back to
+// counting rows precisely where estimates are unavailable (e.g. on MySQL).
+// Pass an empty list to restore the default of PgClassReltuples followed by
+// ExplainPlan.
+func (db *DB) SetEstimatedCountStrategy(strategies []EstStrategy) {
+	db.estimateStrategies = append([]EstStrategy(nil), strategies...)
+}
+
+// estimateStrategiesOf returns the estimation strategies of the DB the
+// provided queryer belongs to
+func estimateStrategiesOf(q Queryer) []EstStrategy {
+	if h, ok := q.(*handle); ok && h.db != nil && len(h.db.estimateStrategies) > 0 {
+		return h.db.estimateStrategies
+	}
+
+	return defaultEstimateStrategies
+}
+
+// GetEstimatedCount returns an estimate of the number of rows matching the
+// statement. By default, if the statement selects from a single table
+// without filtering or grouping its rows, the estimate is read from the
+// table's statistics in pg_class, which doesn't require planning the query.
+// Otherwise, or if the table has no statistics, EXPLAIN is run on the
+// statement and the planner's estimate is returned (see GetEstimatedPlan).
+// These are only supported on PostgreSQL. Different strategies can be set
+// with DB.SetEstimatedCountStrategy. If no strategy yields an estimate,
+// the error of the last one is returned.
+func (stmt *SelectStmt) GetEstimatedCount() (count int64, err error) {
+	return stmt.GetEstimatedCountContext(context.Background())
+}
+
+// GetEstimatedCountContext is the same as GetEstimatedCount, but receives a
+// context.
+func (stmt *SelectStmt) GetEstimatedCountContext(ctx context.Context) (count int64, err error) {
+	if err = stmt.Err(); err != nil {
+		stmt.HandleError(err)
+		return 0, err
+	}
+
+	var estimated bool
+
+	for _, strategy := range estimateStrategiesOf(stmt.queryer) {
+		strategyCount, strategyErr := stmt.estimateWith(ctx, strategy)
+
+		switch {
+		case strategyErr == nil && strategyCount > 0:
+			return strategyCount, nil
+		case strategyErr == nil:
+			// remember that zero rows were estimated, in case no other
+			// strategy estimates more
+			estimated, err = true, nil
+		case !estimated:
+			err = strategyErr
+		}
+	}
+
+	stmt.HandleError(err)
+
+	return 0, err
+}
+
+// estimateWith estimates the number of rows matching the statement with
+// the provided strategy
+func (stmt *SelectStmt) estimateWith(ctx context.Context, strategy EstStrategy) (count int64, err error) {
+	switch strategy {
+	case ExplainPlan:
+		plan, err := stmt.explainPlan(ctx, ExplainOptions{}, false)
+		return plan.Rows, err
+	case PgClassReltuples:
+		table := stmt.wholeTable()
+		if table == "" || !isPostgres(driverNameOf(stmt.queryer)) {
+			return 0, errors.New("pg_class estimates require selecting all rows of a table on PostgreSQL")
+		}
+
+		count, ok := stmt.tableEstimate(ctx, table)
+		if !ok {
+			return 0, fmt.Errorf("no estimate for table %s in pg_class", table)
+		}
+
+		return count, nil
+	case ExactCount:
+		return stmt.GetCountContext(ctx)
+	default:
+		return 0, fmt.Errorf("unknown estimation strategy %d", strategy)
+	}
+}
+
+// EstimatedNonEmpty runs EXPLAIN on the statement once, and returns whether
+// the planner estimates that it matches any rows, along with the estimated
+// number of rows (see GetEstimatedPlan). This saves running both an EXISTS
+// query and a count query when both answers are needed. Note that, like all
+// estimates, the answer may be wrong; PostgreSQL rarely estimates zero rows
+// unless it can prove that none match (e.g. "WHERE false"). It is only
+// supported on PostgreSQL.
+func (stmt *SelectStmt) EstimatedNonEmpty() (nonEmpty bool, count int64, err error) {
+	return stmt.EstimatedNonEmptyContext(context.Background())
+}
+
+// EstimatedNonEmptyContext is the same as EstimatedNonEmpty, but receives a
+// context.
+func (stmt *SelectStmt) EstimatedNonEmptyContext(ctx context.Context) (nonEmpty bool, count int64, err error) {
+	plan, err := stmt.GetEstimatedPlanContext(ctx)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return plan.Rows > 0, plan.Rows, nil
+}
+
+// wholeTable returns the name of the table the statement selects from, if
+// it selects all of the table's rows, i.e. it selects from a single table
+// without conditions, joins, grouping, DISTINCT, set operations or
+// sampling. Otherwise, an empty string is returned.
+func (stmt *SelectStmt) wholeTable() string {
+	if stmt.RawSQL != "" || stmt.FromStmt != nil || len(stmt.ValuesRows) > 0 ||
+		len(stmt.Conditions) > 0 || len(stmt.Joins) > 0 || stmt.IsDistinct ||
+		len(stmt.groupingColumns()) > 0 || len(stmt.GroupingExprs) > 0 || len(stmt.GroupConditions) > 0 ||
+		len(stmt.Unions) > 0 || len(stmt.SetOps) > 0 || stmt.SampleMethod != "" {
+		return ""
+	}
+
+	// the table may have an alias, but must not be a list of tables or an
+	// expression
+	fields := strings.Fields(stmt.Table)
+	if len(fields) == 0 || strings.ContainsAny(stmt.Table, ",()") {
+		return ""
+	}
+
+	return fields[0]
+}
+
+// tableEstimateQuery reads the estimated number of rows of a table from
+// pg_class. Only plain tables and materialized views are considered: views
+// have no estimate, and the estimates of partitioned tables and of tables
+// with inheritance children only include their own rows.
+const tableEstimateQuery = "SELECT reltuples::bigint FROM pg_class " +
+	"WHERE oid = to_regclass(?) AND relkind IN ('r', 'm') AND NOT relhassubclass"
+
+// tableEstimate reads the estimated number of rows of a table from
+// pg_class, after applying the statement's session settings. If the table
+// cannot be found or is not a plain table (see tableEstimateQuery), or has
+// no estimate (negative if it was never analyzed since PostgreSQL 14, and
+// zero before), false is returned. Estimates are cached like estimated
+// plans (see DB.EnableEstimatedCountCache).
+func (stmt *SelectStmt) tableEstimate(ctx context.Context, table string) (count int64, ok bool) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if err := stmt.beforeExec(ctx); err != nil {
+		return 0, false
+	}
+
+	query := rebindFor(stmt.queryer, tableEstimateQuery)
+
+	cache := stmt.estimateCache()
+	key := estimateCacheKey(query, []interface{}{table})
+
+	if cache != nil {
+		if cached, ok := cache.get(key); ok {
+			return cached.Rows, true
+		}
+	}
+
+	err := stmt.queryer.QueryRowxContext(ctx, query, table).Scan(&count)
+	if err != nil || count <= 0 {
+		return 0, false
+	}
+
+	if cache != nil {
+		cache.set(key, EstimatedPlan{Rows: count})
+	}
+
+	return count, true
+}
+
+// GetEstimatedCountWithOptions is the same as GetEstimatedCount, but runs
+// EXPLAIN with the provided options, giving control over the plan used for
+// the estimate (e.g. disabling parallel plans for deterministic estimates).
+func (stmt *SelectStmt) GetEstimatedCountWithOptions(opts ExplainOptions) (count int64, err error) {
+	return stmt.GetEstimatedCountWithOptionsContext(context.Background(), opts)
+}
+
+// GetEstimatedCountWithOptionsContext is the same as
+// GetEstimatedCountWithOptions, but receives a context.
+func (stmt *SelectStmt) GetEstimatedCountWithOptionsContext(
+	ctx context.Context,
+	opts ExplainOptions,
+) (count int64, err error) {
+	plan, err := stmt.estimatedPlan(ctx, opts, false)
+	return plan.Rows, err
+}
+
+// GetEstimatedCountForFilter is the same as GetEstimatedCount, but runs
+// EXPLAIN on the statement as it is, with its select list and ordering,
+// rather than replacing the select list with "SELECT 1". Only limits and
+// offsets are disregarded. Since the select list and ordering may affect
+// which indexes the planner picks (e.g. an index-only scan), this gives an
+// estimate that better reflects the selectivity of the statement's filters
+// and joins in the plan that is actually used to page through its results.
+func (stmt *SelectStmt) GetEstimatedCountForFilter() (count int64, err error) {
+	return stmt.GetEstimatedCountForFilterContext(context.Background())
+}
+
+// GetEstimatedCountForFilterContext is the same as
+// GetEstimatedCountForFilter, but receives a context.
+func (stmt *SelectStmt) GetEstimatedCountForFilterContext(ctx context.Context) (count int64, err error) {
+	plan, err := stmt.estimatedPlan(ctx, ExplainOptions{}, true)
+	return plan.Rows, err
+}
+
+// GetEstimatedCountWithThreshold is the same as GetEstimatedCount, but if
+// the estimated number of rows is below exactBelow, the rows are counted
+// precisely with GetCount instead. This gives accurate counts for small
+// results, where estimates may be far off (e.g. when a table's statistics
+// are stale), while keeping large counts cheap. If rounded is true,
+// estimates (but not precise counts) are rounded to two significant
+// digits (see EstimatedPlan.Rounded).
+func (stmt *SelectStmt) GetEstimatedCountWithThreshold(exactBelow int64, rounded bool) (count int64, err error) {
+	return stmt.GetEstimatedCountWithThresholdContext(context.Background(), exactBelow, rounded)
+}
+
+// GetEstimatedCountWithThresholdContext is the same as
+// GetEstimatedCountWithThreshold, but receives a context.
+func (stmt *SelectStmt) GetEstimatedCountWithThresholdContext(
+	ctx context.Context,
+	exactBelow int64,
+	rounded bool,
+) (count int64, err error) {
+	plan, err := stmt.GetEstimatedPlanContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case plan.Rows < exactBelow:
+		return stmt.GetCountContext(ctx)
+	case rounded:
+		return plan.Rounded, nil
+	default:
+		return plan.Rows, nil
+	}
+}
+
+// GetEstimatedCounts returns the estimated number of rows matching each of
+// the provided statements, in order (see SelectStmt.GetEstimatedCount). If
+// rounded is true, the estimates are rounded to two significant digits (see
+// EstimatedPlan.Rounded). Statements are explained one after the other,
+// stopping at the first one that fails; its error is returned together
+// with its index, and no estimates are returned. It is only supported on
+// PostgreSQL.
+func (db *DB) GetEstimatedCounts(stmts []*SelectStmt, rounded bool) ([]int64, error) {
+	return db.GetEstimatedCountsContext(context.Background(), stmts, rounded)
+}
+
+// GetEstimatedCountsContext is the same as GetEstimatedCounts, but receives
+// a context.
+func (db *DB) GetEstimatedCountsContext(ctx context.Context, stmts []*SelectStmt, rounded bool) ([]int64, error) {
+	counts := make([]int64, len(stmts))
+
+	for i, stmt := range stmts {
+		plan, err := stmt.GetEstimatedPlanContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed estimating statement %d: %w", i, err)
+		}
+
+		if rounded {
+			counts[i] = plan.Rounded
+		} else {
+			counts[i] = plan.Rows
+		}
+	}
+
+	return counts, nil
+}
+
+// parsePlan parses the estimates from the lines of a text plan. The
+// estimates for the whole query are those of the shallowest node that has
+// them. This is usually the top node, but some nodes (e.g. "Finalize
+// GroupAggregate" in some versions) are printed without a row estimate, in
+// which case the first of their shallowest descendants that has one is
+// used. Lines without estimates, such as filter descriptions, are skipped.
+// The plans of CTEs (listed under "CTE name" lines) are nested below the
+// node that uses them, so their estimates are never chosen over those of
+// the main query.
+func parsePlan(lines []string) (plan EstimatedPlan, err error) {
+	var (
+		match  []string
+		indent = -1
+	)
+
+	for _, line := range lines {
+		lineMatch := planEstimates.FindStringSubmatch(line)
+		if lineMatch == nil {
+			continue
+		}
+
+		lineIndent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent == -1 || lineIndent < indent {
+			match, indent = lineMatch, lineIndent
+		}
+	}
+
+	if match == nil {
+		return plan, fmt.Errorf("failed parsing estimates from plan: %s", strings.Join(lines, "\n"))
+	}
+
+	if plan.TotalCost, err = strconv.ParseFloat(match[1], 64); err != nil {
+		return plan, fmt.Errorf("failed parsing plan cost: %w", err)
+	}
+
+	if plan.Rows, err = strconv.ParseInt(match[2], 10, 64); err != nil {
+		return plan, fmt.Errorf("failed parsing plan rows: %w", err)
+	}
+
+	plan.Rounded = roundedCount(plan.Rows)
+
+	return plan, nil
+}
+
+// parseJSONPlan parses the estimates from a plan in JSON format, which are
+// those of the top node
+func parseJSONPlan(doc string) (plan EstimatedPlan, err error) {
+	var explained []struct {
+		Plan struct {
+			TotalCost float64 `json:"Total Cost"`
+			Rows      *int64  `json:"Plan Rows"`
+		}
+	}
+
+	if err = json.Unmarshal([]byte(doc), &explained); err != nil {
+		return plan, fmt.Errorf("failed parsing plan: %w", err)
+	}
+
+	if len(explained) == 0 || explained[0].Plan.Rows == nil {
+		return plan, fmt.Errorf("failed parsing estimates from plan: %s", doc)
+	}
+
+	plan.Rows = *explained[0].Plan.Rows
+	plan.TotalCost = explained[0].Plan.TotalCost
+	plan.Rounded = roundedCount(plan.Rows)
+
+	return plan, nil
+}
+
+// createCountQuery returns a copy of the statement (and of its unions)
+// selecting the provided expression instead of the select list, and
+// disregarding limits, offsets and ordering
+func (stmt *SelectStmt) createCountQuery(selectExpr string) *SelectStmt {
+	if len(stmt.SetOps) > 0 || stmt.RawSQL != "" {
+		// the select list of a set operation determines its result, and a
+		// raw query cannot be modified, so these must be counted from a
+		// sub-query
+		inner := stmt.Clone()
+		inner.LimitTo = 0
+		inner.IsWithTies = false
+		inner.OffsetFrom = 0
+		inner.OffsetRows = 0
+		inner.Ordering = []SQLStmt{}
+
+		return &SelectStmt{
+			Columns:       []string{selectExpr},
+			FromStmt:      inner,
+			FromStmtAlias: "counted",
+			queryer:       stmt.queryer,
+			Statement:     stmt.Statement,
+		}
+	}
+
+	countStmt := stmt.Clone()
+	countStmt.Grouping = stmt.groupingColumns()
+	countStmt.IsAutoGroupBy = false
+	countStmt.Columns = []string{selectExpr}
+	countStmt.SelectExprs = nil
+	countStmt.LimitTo = 0
+	countStmt.IsWithTies = false
+	countStmt.OffsetFrom = 0
+	countStmt.OffsetRows = 0
+	countStmt.Ordering = []SQLStmt{}
+
+	for i, union := range stmt.Unions {
+		countStmt.Unions[i] = union.createCountQuery(selectExpr)
+	}
+
+	return countStmt
+}
+
+// createFilterQuery returns a copy of the statement disregarding limits and
+// offsets, but otherwise unchanged
+func (stmt *SelectStmt) createFilterQuery() *SelectStmt {
+	filterStmt := stmt.Clone()
+	filterStmt.LimitTo = 0
+	filterStmt.IsWithTies = false
+	filterStmt.OffsetFrom = 0
+	filterStmt.OffsetRows = 0
+
+	return filterStmt
+}
+
+// roundedCount rounds an estimated count to two significant digits, since
+// planner estimates are not precise enough to warrant more (e.g. 2549
+// becomes 2500, and 2550 becomes 2600). Counts below 100 are not rounded.
+func roundedCount(count int64) int64 {
+	if count < 100 {
+		return count
+	}
+
+	unit := math.Pow(10, math.Floor(math.Log10(float64(count)))-1)
+
+	return int64(math.Round(float64(count)/unit) * unit)
+}
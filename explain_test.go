@@ -0,0 +1,750 @@
+package sqlz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestGetEstimatedPlan(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT 1 FROM users WHERE active = $1")).
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow("Seq Scan on users  (cost=0.00..35.50 rows=2549 width=4)").
+			AddRow("  Filter: active"))
+
+	plan, err := New(db, "postgres").
+		Select("id", "name").
+		From("users").
+		Where(Eq("active", true)).
+		OrderBy(Asc("name")).
+		Limit(10).
+		GetEstimatedPlan()
+	if err != nil {
+		t.Fatalf("Failed getting estimated plan: %s", err)
+	}
+
+	expected := EstimatedPlan{Rows: 2549, TotalCost: 35.5, Rounded: 2500}
+	if plan != expected {
+		t.Errorf("Expected %+v, got %+v", expected, plan)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	if _, err = New(db, "mysql").Select("*").From("users").GetEstimatedPlan(); err == nil {
+		t.Error("Expected estimated plans on mysql to fail")
+	}
+}
+
+func TestRoundedCount(t *testing.T) {
+	for count, expected := range map[int64]int64{
+		0:       0,
+		99:      99,
+		123:     120,
+		2549:    2500,
+		2550:    2600,
+		1234567: 1200000,
+	} {
+		if got := roundedCount(count); got != expected {
+			t.Errorf("Expected %d to be rounded to %d, got %d", count, expected, got)
+		}
+	}
+}
+
+func TestParsePlan(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected EstimatedPlan
+	}{
+		{
+			"estimates in the root node",
+			[]string{
+				"Seq Scan on users  (cost=0.00..35.50 rows=2550 width=4)",
+				"  Filter: active",
+			},
+			EstimatedPlan{Rows: 2550, TotalCost: 35.5, Rounded: 2600},
+		},
+		{
+			"estimates in the third line",
+			[]string{
+				"Finalize GroupAggregate  (cost=1000.00..2500.00 width=24)",
+				"  Group Key: kind",
+				"  ->  Gather Merge  (cost=1000.00..2400.00 rows=120 width=24)",
+				"        ->  Sort  (cost=900.00..910.00 rows=60 width=24)",
+			},
+			EstimatedPlan{Rows: 120, TotalCost: 2400, Rounded: 120},
+		},
+		{
+			"root and second line lack estimates, deeper nodes in order",
+			[]string{
+				"Finalize GroupAggregate  (cost=1000.00..2500.00 width=24)",
+				"  ->  Gather Merge  (cost=1000.00..2400.00 width=24)",
+				"        ->  Partial HashAggregate  (cost=900.00..950.00 rows=30 width=24)",
+				"              ->  Parallel Seq Scan on items  (cost=0.00..800.00 rows=45000 width=16)",
+			},
+			EstimatedPlan{Rows: 30, TotalCost: 950, Rounded: 30},
+		},
+		{
+			"root and second line lack estimates, deeper node listed first",
+			[]string{
+				"Finalize GroupAggregate  (cost=1000.00..2500.00 width=24)",
+				"  Group Key: kind",
+				"              ->  Parallel Seq Scan on items  (cost=0.00..800.00 rows=45000 width=16)",
+				"        ->  Partial HashAggregate  (cost=900.00..950.00 rows=30 width=24)",
+			},
+			EstimatedPlan{Rows: 30, TotalCost: 950, Rounded: 30},
+		},
+		{
+			"hash join at the root",
+			[]string{
+				"Hash Join  (cost=270.00..1520.75 rows=12345 width=16)",
+				"  Hash Cond: (o.user_id = u.id)",
+				"  ->  Seq Scan on orders o  (cost=0.00..1000.00 rows=50000 width=12)",
+				"        Filter: (total > 100)",
+				"  ->  Hash  (cost=145.00..145.00 rows=10000 width=4)",
+				"        ->  Seq Scan on users u  (cost=0.00..145.00 rows=10000 width=4)",
+			},
+			EstimatedPlan{Rows: 12345, TotalCost: 1520.75, Rounded: 12000},
+		},
+		{
+			"nested loop at the root",
+			[]string{
+				"Nested Loop  (cost=0.29..85.10 rows=7 width=16)",
+				"  ->  Seq Scan on teams t  (cost=0.00..1.05 rows=1 width=4)",
+				"        Filter: (name = 'core'::text)",
+				"  ->  Index Scan using users_team_id_idx on users u  (cost=0.29..84.00 rows=7 width=12)",
+				"        Index Cond: (team_id = t.id)",
+			},
+			EstimatedPlan{Rows: 7, TotalCost: 85.1, Rounded: 7},
+		},
+		{
+			"aggregate without estimates over a hash join",
+			[]string{
+				"Finalize Aggregate  (cost=2000.00..2000.01 width=8)",
+				"  ->  Hash Join  (cost=270.00..1520.75 rows=12345 width=0)",
+				"        Hash Cond: (o.user_id = u.id)",
+				"        ->  Seq Scan on orders o  (cost=0.00..1000.00 rows=50000 width=4)",
+				"        ->  Hash  (cost=145.00..145.00 rows=10000 width=4)",
+			},
+			EstimatedPlan{Rows: 12345, TotalCost: 1520.75, Rounded: 12000},
+		},
+		{
+			"cte scan at the root",
+			[]string{
+				"CTE Scan on recent  (cost=25.00..47.50 rows=5 width=8)",
+				"  Filter: (kind = 'login'::text)",
+				"  CTE recent",
+				"    ->  Seq Scan on events  (cost=0.00..25.00 rows=1000 width=12)",
+				"          Filter: (created > now() - '1 day'::interval)",
+			},
+			EstimatedPlan{Rows: 5, TotalCost: 47.5, Rounded: 5},
+		},
+		{
+			"hash join on a cte listed first",
+			[]string{
+				"Hash Join  (cost=60.00..95.00 rows=250 width=16)",
+				"  Hash Cond: (u.id = r.user_id)",
+				"  CTE recent",
+				"    ->  Seq Scan on events  (cost=0.00..25.00 rows=1000 width=12)",
+				"  ->  Seq Scan on users u  (cost=0.00..22.00 rows=1200 width=8)",
+				"  ->  Hash  (cost=20.00..20.00 rows=1000 width=8)",
+				"        ->  CTE Scan on recent r  (cost=0.00..20.00 rows=1000 width=8)",
+			},
+			EstimatedPlan{Rows: 250, TotalCost: 95, Rounded: 250},
+		},
+		{
+			"aggregate without estimates over a cte",
+			[]string{
+				"Finalize Aggregate  (cost=60.00..60.01 width=8)",
+				"  CTE recent",
+				"    ->  Seq Scan on events  (cost=0.00..25.00 rows=1000 width=12)",
+				"  ->  CTE Scan on recent  (cost=0.00..22.50 rows=5 width=0)",
+				"        Filter: (kind = 'login'::text)",
+			},
+			EstimatedPlan{Rows: 5, TotalCost: 22.5, Rounded: 5},
+		},
+	}
+
+	for _, tst := range tests {
+		t.Run(tst.name, func(t *testing.T) {
+			plan, err := parsePlan(tst.lines)
+			if err != nil {
+				t.Fatalf("Failed parsing plan: %s", err)
+			}
+
+			if plan != tst.expected {
+				t.Errorf("Expected %+v, got %+v", tst.expected, plan)
+			}
+		})
+	}
+
+	if _, err := parsePlan([]string{"Result  (cost=0.00..0.01 width=4)"}); err == nil {
+		t.Error("Expected parsing a plan without row estimates to fail")
+	}
+}
+
+func TestGetEstimatedPlanWithJoins(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"EXPLAIN SELECT 1 FROM orders o INNER JOIN users u ON o.user_id = u.id WHERE o.total > $1",
+	)).
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow("Hash Join  (cost=270.00..1520.75 rows=12345 width=16)").
+			AddRow("  Hash Cond: (o.user_id = u.id)").
+			AddRow("  ->  Seq Scan on orders o  (cost=0.00..1000.00 rows=50000 width=12)").
+			AddRow("        Filter: (total > 100)").
+			AddRow("  ->  Hash  (cost=145.00..145.00 rows=10000 width=4)").
+			AddRow("        ->  Seq Scan on users u  (cost=0.00..145.00 rows=10000 width=4)"))
+
+	count, err := New(db, "postgres").
+		Select("o.id", "u.name").
+		From("orders o").
+		InnerJoin("users u", SQLCond("o.user_id = u.id")).
+		Where(Gt("o.total", 100)).
+		GetEstimatedCount()
+	if err != nil {
+		t.Fatalf("Failed getting estimated count: %s", err)
+	}
+
+	if count != 12345 {
+		t.Errorf("Expected the join's estimate of 12345 rows, got %d", count)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestEstimatedCountCache(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+	dbz.EnableEstimatedCountCache(50 * time.Millisecond)
+
+	query := regexp.QuoteMeta("EXPLAIN SELECT 1 FROM users WHERE active = $1")
+	planRows := func(rows int) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow(fmt.Sprintf("Seq Scan on users  (cost=0.00..35.50 rows=%d width=4)", rows))
+	}
+
+	mock.ExpectQuery(query).WithArgs(true).WillReturnRows(planRows(1000))
+	mock.ExpectQuery(query).WithArgs(false).WillReturnRows(planRows(20))
+	mock.ExpectQuery(query).WithArgs(true).WillReturnRows(planRows(1100))
+
+	count := func(active bool) int64 {
+		count, err := dbz.Select("*").From("users").Where(Eq("active", active)).GetEstimatedCount()
+		if err != nil {
+			t.Fatalf("Failed getting estimated count: %s", err)
+		}
+
+		return count
+	}
+
+	if got := count(true); got != 1000 {
+		t.Errorf("Expected 1000 on a miss, got %d", got)
+	}
+
+	if got := count(true); got != 1000 {
+		t.Errorf("Expected cached 1000 on a hit, got %d", got)
+	}
+
+	if got := count(false); got != 20 {
+		t.Errorf("Expected 20 for different bindings, got %d", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if got := count(true); got != 1100 {
+		t.Errorf("Expected 1100 after expiry, got %d", got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestExplainAnalyze(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN ANALYZE SELECT id FROM users WHERE active = $1 AND age > $2 LIMIT 10")).
+		WithArgs(true, 30).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow("Limit  (cost=0.00..1.20 rows=10 width=4) (actual time=0.010..0.020 rows=10 loops=1)").
+			AddRow("  ->  Seq Scan on users  (cost=0.00..35.50 rows=300 width=4) (actual time=0.009..0.015 rows=10 loops=1)"))
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN QUERY PLAN SELECT id FROM users WHERE active = ?")).
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent", "notused", "detail"}).
+			AddRow(2, 0, 0, "SCAN users"))
+
+	plan, err := New(db, "postgres").Select("id").From("users").
+		Where(Eq("active", true), Gt("age", 30)).
+		Limit(10).
+		ExplainAnalyze(context.Background())
+	if err != nil {
+		t.Fatalf("Failed explaining statement: %s", err)
+	}
+
+	expected := "Limit  (cost=0.00..1.20 rows=10 width=4) (actual time=0.010..0.020 rows=10 loops=1)\n" +
+		"  ->  Seq Scan on users  (cost=0.00..35.50 rows=300 width=4) (actual time=0.009..0.015 rows=10 loops=1)"
+	if plan != expected {
+		t.Errorf("Expected plan %q, got %q", expected, plan)
+	}
+
+	plan, err = New(db, "sqlite3").Select("id").From("users").
+		Where(Eq("active", true)).
+		ExplainAnalyze(context.Background())
+	if err != nil {
+		t.Fatalf("Failed explaining statement on sqlite: %s", err)
+	}
+
+	expected = "-- EXPLAIN ANALYZE is not supported by the sqlite3 driver, showing the estimated plan\n2 0 0 SCAN users"
+	if plan != expected {
+		t.Errorf("Expected plan %q, got %q", expected, plan)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	if _, err = New(db, "sqlserver").Select("*").From("users").ExplainAnalyze(context.Background()); err == nil {
+		t.Error("Expected explaining on sqlserver to fail")
+	}
+}
+
+func TestGetEstimatedCounts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	planRows := func(rows int) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow(fmt.Sprintf("Seq Scan on t  (cost=0.00..35.50 rows=%d width=4)", rows))
+	}
+
+	stmts := []*SelectStmt{
+		dbz.Select("*").From("users").Where(Eq("active", true)),
+		dbz.Select("*").From("orders"),
+		dbz.Select("*").From("events").Where(Gt("created", 100)),
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT 1 FROM users WHERE active = $1")).
+		WithArgs(true).WillReturnRows(planRows(2549))
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT 1 FROM orders")).
+		WillReturnRows(planRows(40))
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT 1 FROM events WHERE created > $1")).
+		WithArgs(100).WillReturnRows(planRows(123456))
+
+	counts, err := dbz.GetEstimatedCounts(stmts, true)
+	if err != nil {
+		t.Fatalf("Failed getting estimated counts: %s", err)
+	}
+
+	if !reflect.DeepEqual(counts, []int64{2500, 40, 120000}) {
+		t.Errorf("Unexpected estimated counts %v", counts)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT 1 FROM users WHERE active = $1")).
+		WithArgs(true).WillReturnRows(planRows(2549))
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT 1 FROM orders")).
+		WillReturnError(errors.New("relation \"orders\" does not exist"))
+
+	counts, err = dbz.GetEstimatedCounts(stmts, false)
+	if err == nil || counts != nil {
+		t.Fatalf("Expected failure on the second statement, got %v", counts)
+	}
+
+	if !strings.Contains(err.Error(), "statement 1") || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Expected error to report the failing statement, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetEstimatedCountWithThreshold(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	explain := regexp.QuoteMeta("EXPLAIN SELECT 1 FROM users WHERE active = $1")
+	planRows := func(rows int) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow(fmt.Sprintf("Seq Scan on users  (cost=0.00..35.50 rows=%d width=4)", rows))
+	}
+
+	mock.ExpectQuery(explain).WithArgs(true).WillReturnRows(planRows(1000))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE active = $1")).
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+	mock.ExpectQuery(explain).WithArgs(true).WillReturnRows(planRows(123456))
+
+	count, err := dbz.Select("*").From("users").Where(Eq("active", true)).
+		GetEstimatedCountWithThreshold(10000, true)
+	if err != nil {
+		t.Fatalf("Failed counting below threshold: %s", err)
+	}
+
+	if count != 12 {
+		t.Errorf("Expected precise count of 12 below threshold, got %d", count)
+	}
+
+	count, err = dbz.Select("*").From("users").Where(Eq("active", true)).
+		GetEstimatedCountWithThreshold(10000, true)
+	if err != nil {
+		t.Fatalf("Failed counting above threshold: %s", err)
+	}
+
+	if count != 120000 {
+		t.Errorf("Expected rounded estimate of 120000 above threshold, got %d", count)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetEstimatedCountWithOptions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+	stmt := dbz.Select("id").From("users").Where(Eq("active", true))
+
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN (COSTS true, BUFFERS false) SELECT 1 FROM users WHERE active = $1")).
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow("Seq Scan on users  (cost=0.00..35.50 rows=2549 width=4)"))
+
+	count, err := stmt.GetEstimatedCountWithOptions(ExplainOptions{Costs: ExplainOn, Buffers: ExplainOff})
+	if err != nil {
+		t.Fatalf("Failed getting estimated count: %s", err)
+	}
+
+	if count != 2549 {
+		t.Errorf("Expected 2549, got %d", count)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("SET LOCAL max_parallel_workers_per_gather = '0'")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("SET LOCAL work_mem = '64MB'")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN (FORMAT JSON) SELECT 1 FROM users WHERE active = $1")).
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow(`[{"Plan": {"Node Type": "Seq Scan", "Total Cost": 35.5, "Plan Rows": 1200}}]`))
+	mock.ExpectRollback()
+
+	count, err = stmt.GetEstimatedCountWithOptions(ExplainOptions{
+		Format: "json",
+		Settings: map[string]string{
+			"work_mem":                        "64MB",
+			"max_parallel_workers_per_gather": "0",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed getting estimated count with settings: %s", err)
+	}
+
+	if count != 1200 {
+		t.Errorf("Expected 1200, got %d", count)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	if _, err := stmt.GetEstimatedCountWithOptions(ExplainOptions{Format: "yaml"}); err == nil {
+		t.Error("Expected an unsupported format to fail")
+	}
+
+	if _, err := stmt.GetEstimatedCountWithOptions(ExplainOptions{
+		Settings: map[string]string{"work_mem; DROP TABLE users": "1"},
+	}); err == nil {
+		t.Error("Expected an invalid setting name to fail")
+	}
+}
+
+func TestGetEstimatedCountForFilter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	// with the select list replaced, the planner may pick an index-only
+	// scan with a different estimate than the plan used for paging
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"EXPLAIN SELECT 1 FROM events e INNER JOIN users u ON e.user_id = u.id WHERE e.created_at > $1",
+	)).
+		WithArgs("2024-01-01").
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow("Nested Loop  (cost=0.85..9000.00 rows=8000 width=0)"))
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"EXPLAIN SELECT e.id, e.name, u.email FROM events e INNER JOIN users u ON e.user_id = u.id " +
+			"WHERE e.created_at > $1 ORDER BY e.created_at DESC",
+	)).
+		WithArgs("2024-01-01").
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow("Sort  (cost=12000.00..12030.00 rows=12000 width=48)").
+			AddRow("  Sort Key: e.created_at DESC").
+			AddRow("  ->  Hash Join  (cost=270.00..11000.00 rows=12000 width=48)"))
+
+	stmt := New(db, "postgres").
+		Select("e.id", "e.name", "u.email").
+		From("events e").
+		InnerJoin("users u", EqCols("e.user_id", "u.id")).
+		Where(Gt("e.created_at", "2024-01-01")).
+		OrderBy(Desc("e.created_at")).
+		Limit(50).
+		Offset(100)
+
+	rewritten, err := stmt.GetEstimatedCount()
+	if err != nil {
+		t.Fatalf("Failed getting estimated count: %s", err)
+	}
+
+	filtered, err := stmt.GetEstimatedCountForFilter()
+	if err != nil {
+		t.Fatalf("Failed getting estimated count for filter: %s", err)
+	}
+
+	if rewritten != 8000 || filtered != 12000 {
+		t.Errorf("Expected estimates of 8000 and 12000 rows, got %d and %d", rewritten, filtered)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetEstimatedCountWholeTable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass($1) AND relkind IN ('r', 'm') AND NOT relhassubclass")).
+		WithArgs("public.events").
+		WillReturnRows(sqlmock.NewRows([]string{"reltuples"}).AddRow(1500000))
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT 1 FROM public.events e WHERE e.kind = $1")).
+		WithArgs("click").
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow("Seq Scan on events e  (cost=0.00..30000.00 rows=42000 width=0)"))
+
+	// a table that was never analyzed falls back to EXPLAIN
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass($1) AND relkind IN ('r', 'm') AND NOT relhassubclass")).
+		WithArgs("fresh").
+		WillReturnRows(sqlmock.NewRows([]string{"reltuples"}).AddRow(-1))
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT 1 FROM fresh")).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow("Seq Scan on fresh  (cost=0.00..22.70 rows=1270 width=0)"))
+
+	count, err := dbz.Select("*").From("public.events e").OrderBy(Desc("id")).Limit(20).GetEstimatedCount()
+	if err != nil {
+		t.Fatalf("Failed getting estimated count: %s", err)
+	}
+
+	if count != 1500000 {
+		t.Errorf("Expected the table's estimate of 1500000 rows, got %d", count)
+	}
+
+	count, err = dbz.Select("*").From("public.events e").Where(Eq("e.kind", "click")).GetEstimatedCount()
+	if err != nil {
+		t.Fatalf("Failed getting estimated count: %s", err)
+	}
+
+	if count != 42000 {
+		t.Errorf("Expected the planner's estimate of 42000 rows, got %d", count)
+	}
+
+	count, err = dbz.Select("*").From("fresh").GetEstimatedCount()
+	if err != nil {
+		t.Fatalf("Failed getting estimated count: %s", err)
+	}
+
+	if count != 1270 {
+		t.Errorf("Expected the planner's estimate of 1270 rows, got %d", count)
+	}
+
+	// cached table estimates are reused
+	dbz.EnableEstimatedCountCache(time.Minute)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass($1) AND relkind IN ('r', 'm') AND NOT relhassubclass")).
+		WithArgs("logs").
+		WillReturnRows(sqlmock.NewRows([]string{"reltuples"}).AddRow(300))
+
+	for i := 0; i < 2; i++ {
+		count, err = dbz.Select("*").From("logs").GetEstimatedCount()
+		if err != nil {
+			t.Fatalf("Failed getting estimated count: %s", err)
+		}
+
+		if count != 300 {
+			t.Errorf("Expected the table's estimate of 300 rows, got %d", count)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestEstimatedNonEmpty(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT 1 FROM users WHERE active = $1")).
+		WithArgs(false).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow("Result  (cost=0.00..0.00 rows=0 width=4)").
+			AddRow("  One-Time Filter: false"))
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT 1 FROM users WHERE active = $1")).
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow("Seq Scan on users  (cost=0.00..35.50 rows=2549 width=4)"))
+
+	nonEmpty, count, err := dbz.Select("*").From("users").Where(Eq("active", false)).EstimatedNonEmpty()
+	if err != nil {
+		t.Fatalf("Failed estimating empty statement: %s", err)
+	}
+
+	if nonEmpty || count != 0 {
+		t.Errorf("Expected an empty estimate, got %t with %d rows", nonEmpty, count)
+	}
+
+	nonEmpty, count, err = dbz.Select("*").From("users").Where(Eq("active", true)).EstimatedNonEmpty()
+	if err != nil {
+		t.Fatalf("Failed estimating non-empty statement: %s", err)
+	}
+
+	if !nonEmpty || count != 2549 {
+		t.Errorf("Expected a non-empty estimate of 2549 rows, got %t with %d rows", nonEmpty, count)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestEstimatedCountStrategy(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	var handled []error
+
+	mysql := New(db, "mysql")
+	mysql.ErrHandlers = append(mysql.ErrHandlers, func(err error) {
+		if err != nil {
+			handled = append(handled, err)
+		}
+	})
+	mysql.SetEstimatedCountStrategy([]EstStrategy{ExplainPlan, ExactCount})
+
+	// EXPLAIN estimates are not supported on MySQL, so rows are counted
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE active = ?")).
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+
+	count, err := mysql.Select("*").From("users").Where(Eq("active", true)).GetEstimatedCount()
+	if err != nil {
+		t.Fatalf("Failed getting estimated count: %s", err)
+	}
+
+	if count != 12 {
+		t.Errorf("Expected fallback to a precise count of 12, got %d", count)
+	}
+
+	if len(handled) > 0 {
+		t.Errorf("Expected failing strategies not to reach error handlers, got %v", handled)
+	}
+
+	postgres := New(db, "postgres")
+	postgres.SetEstimatedCountStrategy([]EstStrategy{PgClassReltuples, ExplainPlan, ExactCount})
+
+	// zero estimates fall through to the next strategy
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass($1) AND relkind IN ('r', 'm') AND NOT relhassubclass")).
+		WithArgs("jobs").
+		WillReturnRows(sqlmock.NewRows([]string{"reltuples"}).AddRow(0))
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT 1 FROM jobs")).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow("Result  (cost=0.00..0.00 rows=0 width=4)"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM jobs")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+
+	count, err = postgres.Select("*").From("jobs").GetEstimatedCount()
+	if err != nil {
+		t.Fatalf("Failed getting estimated count: %s", err)
+	}
+
+	if count != 5 {
+		t.Errorf("Expected fallback to a precise count of 5, got %d", count)
+	}
+
+	// a zero estimate is returned if later strategies fail
+	postgres.SetEstimatedCountStrategy([]EstStrategy{ExplainPlan, PgClassReltuples})
+
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT 1 FROM jobs WHERE id = $1")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow("Result  (cost=0.00..0.00 rows=0 width=4)"))
+
+	count, err = postgres.Select("*").From("jobs").Where(Eq("id", 1)).GetEstimatedCount()
+	if err != nil || count != 0 {
+		t.Errorf("Expected a zero estimate, got %d (%v)", count, err)
+	}
+
+	// the last error is returned if all strategies fail
+	postgres.SetEstimatedCountStrategy([]EstStrategy{PgClassReltuples, EstStrategy(42)})
+
+	if _, err = postgres.Select("*").From("jobs").GetEstimatedCount(); err == nil ||
+		!strings.Contains(err.Error(), "unknown estimation strategy") {
+		t.Errorf("Expected unknown strategy error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
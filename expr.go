@@ -0,0 +1,728 @@
+package sqlz
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DigestExpr is an expression computing an MD5 digest of the values of
+// several columns, useful as a deterministic key for deduplication. It
+// can be used in the select list (via SelectStmt.SelectExpr) and in the
+// GROUP BY clause (via SelectStmt.GroupByExpr).
+type DigestExpr struct {
+	Columns []string
+	Alias   string
+}
+
+// Digest creates an expression computing an MD5 digest of the provided
+// columns, joined with a '|' separator. In PostgreSQL this renders as
+// "md5(concat_ws('|', a, b))".
+func Digest(cols ...string) DigestExpr {
+	return DigestExpr{Columns: cols}
+}
+
+// As sets an alias for the expression, for use in the select list
+func (d DigestExpr) As(alias string) DigestExpr {
+	d.Alias = alias
+	return d
+}
+
+// ToSQL generates SQL for the expression, using PostgreSQL syntax
+func (d DigestExpr) ToSQL(_ bool) (string, []interface{}) {
+	return d.sqlFor("")
+}
+
+func (d DigestExpr) sqlFor(driverName string) (asSQL string, bindings []interface{}) {
+	cols := strings.Join(d.Columns, ", ")
+
+	switch {
+	case isMySQL(driverName):
+		asSQL = "MD5(CONCAT_WS('|', " + cols + "))"
+	case isSQLServer(driverName):
+		asSQL = "CONVERT(VARCHAR(32), HASHBYTES('MD5', CONCAT_WS('|', " + cols + ")), 2)"
+	default:
+		asSQL = "md5(concat_ws('|', " + cols + "))"
+	}
+
+	if d.Alias != "" {
+		asSQL += " AS " + d.Alias
+	}
+
+	return asSQL, nil
+}
+
+// AggregateExpr is an expression calling an aggregate function such as
+// array_agg or string_agg, with optional DISTINCT and ORDER BY clauses
+// inside the call. It can be used in the select list via
+// SelectStmt.SelectExpr.
+type AggregateExpr struct {
+	Func       string
+	Expr       string
+	Separator  string
+	IsDistinct bool
+	Ordering   []OrderColumn
+	Alias      string
+}
+
+// ArrayAgg creates a call to PostgreSQL's array_agg aggregate function
+// for the provided expression
+func ArrayAgg(expr string) AggregateExpr {
+	return AggregateExpr{Func: "array_agg", Expr: expr}
+}
+
+// StringAgg creates a call to the string_agg aggregate function for the
+// provided expression, joining values with the provided separator, which is
+// bound as a parameter (e.g. "string_agg(name, ?)"). In SQLite, this
+// renders as a call to group_concat. In MySQL, it renders as a call to
+// GROUP_CONCAT, where the separator must be a string literal (e.g.
+// "GROUP_CONCAT(name SEPARATOR ', ')"). Use OrderBy to order the joined
+// values, and As to alias the expression.
+func StringAgg(expr, separator string) AggregateExpr {
+	return AggregateExpr{Func: "string_agg", Expr: expr, Separator: separator}
+}
+
+// Distinct only aggregates distinct values of the expression
+func (agg AggregateExpr) Distinct() AggregateExpr {
+	agg.IsDistinct = true
+	return agg
+}
+
+// OrderBy orders the aggregated values. When combined with Distinct, the
+// ordering must be by the aggregated expression itself (e.g.
+// "array_agg(DISTINCT x ORDER BY x)"), as required by PostgreSQL.
+func (agg AggregateExpr) OrderBy(cols ...OrderColumn) AggregateExpr {
+	agg.Ordering = append(append([]OrderColumn{}, agg.Ordering...), cols...)
+	return agg
+}
+
+// As sets an alias for the expression, for use in the select list
+func (agg AggregateExpr) As(alias string) AggregateExpr {
+	agg.Alias = alias
+	return agg
+}
+
+// Err returns an error if the expression is invalid
+func (agg AggregateExpr) Err() error {
+	if agg.IsDistinct {
+		for _, col := range agg.Ordering {
+			if col.Column != agg.Expr {
+				return fmt.Errorf(
+					"%s with DISTINCT must be ordered by %s, not %s",
+					agg.Func, agg.Expr, col.Column,
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ToSQL generates SQL for the expression, using PostgreSQL syntax
+func (agg AggregateExpr) ToSQL(_ bool) (string, []interface{}) {
+	return agg.sqlFor("")
+}
+
+func (agg AggregateExpr) sqlFor(driverName string) (asSQL string, bindings []interface{}) {
+	args := agg.Expr
+	if agg.IsDistinct {
+		args = "DISTINCT " + args
+	}
+
+	separator := "'" + strings.ReplaceAll(agg.Separator, "'", "''") + "'"
+	isGroupConcat := agg.Func == "string_agg" && isMySQL(driverName)
+
+	if agg.Func == "string_agg" && !isGroupConcat {
+		args += ", ?"
+		bindings = append(bindings, agg.Separator)
+	}
+
+	if len(agg.Ordering) > 0 {
+		ordering := make([]string, len(agg.Ordering))
+		for i, col := range agg.Ordering {
+			ordering[i], _ = col.sqlFor(driverName)
+		}
+
+		args += " ORDER BY " + strings.Join(ordering, ", ")
+	}
+
+	if isGroupConcat {
+		asSQL = "GROUP_CONCAT(" + args + " SEPARATOR " + separator + ")"
+	} else if agg.Func == "string_agg" && isSQLite(driverName) {
+		asSQL = "group_concat(" + args + ")"
+	} else {
+		asSQL = agg.Func + "(" + args + ")"
+	}
+
+	if agg.Alias != "" {
+		asSQL += " AS " + agg.Alias
+	}
+
+	return asSQL, bindings
+}
+
+// DistinctCountOverExpr is an expression counting the distinct values of
+// an expression within a window partition
+type DistinctCountOverExpr struct {
+	Expr        string
+	PartitionBy []string
+	Alias       string
+}
+
+// DistinctCountOver creates an expression counting the distinct values of
+// expr in each partition defined by partitionCols. As PostgreSQL doesn't
+// support COUNT(DISTINCT) as a window function, this is emulated by adding
+// the dense ranks of the expression in ascending and descending order:
+// "(dense_rank() OVER (PARTITION BY p ORDER BY x) + dense_rank() OVER
+// (PARTITION BY p ORDER BY x DESC) - 1)". Note that NULL is counted as a
+// distinct value, unlike in COUNT(DISTINCT).
+func DistinctCountOver(expr string, partitionCols ...string) DistinctCountOverExpr {
+	return DistinctCountOverExpr{Expr: expr, PartitionBy: partitionCols}
+}
+
+// As sets an alias for the expression, for use in the select list
+func (d DistinctCountOverExpr) As(alias string) DistinctCountOverExpr {
+	d.Alias = alias
+	return d
+}
+
+// ToSQL generates SQL for the expression
+func (d DistinctCountOverExpr) ToSQL(_ bool) (asSQL string, bindings []interface{}) {
+	var partition string
+	if len(d.PartitionBy) > 0 {
+		partition = "PARTITION BY " + strings.Join(d.PartitionBy, ", ") + " "
+	}
+
+	asSQL = "(dense_rank() OVER (" + partition + "ORDER BY " + d.Expr + ") + " +
+		"dense_rank() OVER (" + partition + "ORDER BY " + d.Expr + " DESC) - 1)"
+
+	if d.Alias != "" {
+		asSQL += " AS " + d.Alias
+	}
+
+	return asSQL, nil
+}
+
+// PercentileExpr is an expression calling the PERCENTILE_CONT or
+// PERCENTILE_DISC ordered-set aggregate functions
+type PercentileExpr struct {
+	Func      string
+	Fraction  float64
+	OrderExpr string
+	Alias     string
+}
+
+// PercentileCont creates a call to the PERCENTILE_CONT aggregate function,
+// which computes a continuous percentile of orderExpr, interpolating
+// between values if needed. For example, PercentileCont(0.5, "x") renders
+// "PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY x)", the median of x. This
+// is not supported on MySQL, SQLite and SQL Server.
+func PercentileCont(fraction float64, orderExpr string) PercentileExpr {
+	return PercentileExpr{Func: "PERCENTILE_CONT", Fraction: fraction, OrderExpr: orderExpr}
+}
+
+// PercentileDisc is the same as PercentileCont, but calls PERCENTILE_DISC,
+// which returns the first value whose position in the ordering equals or
+// exceeds the fraction, rather than interpolating.
+func PercentileDisc(fraction float64, orderExpr string) PercentileExpr {
+	return PercentileExpr{Func: "PERCENTILE_DISC", Fraction: fraction, OrderExpr: orderExpr}
+}
+
+// As sets an alias for the expression, for use in the select list
+func (p PercentileExpr) As(alias string) PercentileExpr {
+	p.Alias = alias
+	return p
+}
+
+// Err returns an error if the fraction is not between 0 and 1
+func (p PercentileExpr) Err() error {
+	if p.Fraction < 0 || p.Fraction > 1 {
+		return fmt.Errorf("%s fraction must be between 0 and 1, got %v", p.Func, p.Fraction)
+	}
+
+	return nil
+}
+
+// ToSQL generates SQL for the expression
+func (p PercentileExpr) ToSQL(_ bool) (asSQL string, bindings []interface{}) {
+	asSQL = p.Func + "(" + strconv.FormatFloat(p.Fraction, 'f', -1, 64) + ") " +
+		"WITHIN GROUP (ORDER BY " + p.OrderExpr + ")"
+
+	if p.Alias != "" {
+		asSQL += " AS " + p.Alias
+	}
+
+	return asSQL, nil
+}
+
+func (p PercentileExpr) checkDialect(driverName string) error {
+	if isMySQL(driverName) || isSQLite(driverName) || isSQLServer(driverName) {
+		return fmt.Errorf("%s aggregates are not supported by the %s driver", p.Func, driverName)
+	}
+
+	return nil
+}
+
+// FunctionExpr is an expression calling an SQL function with a list of
+// arguments, such as COALESCE or NULLIF. It can be used in the select list
+// via SelectStmt.SelectExpr.
+type FunctionExpr struct {
+	Func  string
+	Args  []interface{}
+	Alias string
+}
+
+// Col marks a column name (or any other SQL expression) so that it is
+// injected into a FunctionExpr as-is, rather than bound as a value. It is
+// the same as Indirect without bindings.
+func Col(name string) IndirectValue {
+	return Indirect(name)
+}
+
+// Coalesce creates a call to the COALESCE function, which returns the first
+// of its arguments that is not NULL, aliased as alias (unless empty).
+// Arguments are bound as values, unless wrapped with Col or Indirect, e.g.
+// Coalesce("name", Col("nickname"), "anonymous") renders
+// "COALESCE(nickname, ?) AS name".
+func Coalesce(alias string, exprs ...interface{}) FunctionExpr {
+	return FunctionExpr{Func: "COALESCE", Args: exprs, Alias: alias}
+}
+
+// NullIf creates a call to the NULLIF function, which returns NULL if a
+// equals b, and a otherwise. Arguments are handled as in Coalesce.
+func NullIf(alias string, a, b interface{}) FunctionExpr {
+	return FunctionExpr{Func: "NULLIF", Args: []interface{}{a, b}, Alias: alias}
+}
+
+// ToSQL generates SQL for the expression, with bindings for its arguments
+// in the order they appear
+func (f FunctionExpr) ToSQL(_ bool) (asSQL string, bindings []interface{}) {
+	args, bindings := parseInsertValues(f.Args)
+	asSQL = f.Func + "(" + strings.Join(args, ", ") + ")"
+
+	if f.Alias != "" {
+		asSQL += " AS " + f.Alias
+	}
+
+	return asSQL, bindings
+}
+
+// Lower wraps an expression (a column name, or a value created with Col)
+// with the LOWER function, for use as the left-hand side of a condition,
+// e.g. Eq(Lower(Trim("email")), input) generates "LOWER(TRIM(email)) = ?",
+// with the input still bound as a value. Note that Indirect values with
+// bindings cannot be wrapped, as their bindings would be lost.
+func Lower(expr interface{}) string {
+	return wrapExpr("LOWER", expr)
+}
+
+// Upper wraps an expression with the UPPER function (see Lower)
+func Upper(expr interface{}) string {
+	return wrapExpr("UPPER", expr)
+}
+
+// Trim wraps an expression with the TRIM function, removing leading and
+// trailing spaces (see Lower)
+func Trim(expr interface{}) string {
+	return wrapExpr("TRIM", expr)
+}
+
+func wrapExpr(fn string, expr interface{}) string {
+	if indirect, isIndirect := expr.(IndirectValue); isIndirect {
+		return fn + "(" + indirect.Reference + ")"
+	}
+
+	return fmt.Sprintf("%s(%v)", fn, expr)
+}
+
+// GroupingExpr is an expression for the GROUP BY clause producing several
+// groupings at once, either a ROLLUP of a list of columns or a list of
+// GROUPING SETS. It is added to statements via SelectStmt.GroupByRollup and
+// SelectStmt.GroupingSets.
+type GroupingExpr struct {
+	Kind string
+	Sets [][]string
+}
+
+// ToSQL generates SQL for the expression, using PostgreSQL syntax
+func (g GroupingExpr) ToSQL(_ bool) (string, []interface{}) {
+	return g.sqlFor("")
+}
+
+func (g GroupingExpr) sqlFor(driverName string) (string, []interface{}) {
+	if g.Kind == "ROLLUP" {
+		cols := strings.Join(g.Sets[0], ", ")
+		if isMySQL(driverName) {
+			return cols + " WITH ROLLUP", nil
+		}
+
+		return "ROLLUP(" + cols + ")", nil
+	}
+
+	sets := make([]string, len(g.Sets))
+	for i, set := range g.Sets {
+		sets[i] = "(" + strings.Join(set, ", ") + ")"
+	}
+
+	return g.Kind + " (" + strings.Join(sets, ", ") + ")", nil
+}
+
+// Err returns an error if the expression has no columns to group by
+func (g GroupingExpr) Err() error {
+	if len(g.Sets) == 0 || (g.Kind == "ROLLUP" && len(g.Sets[0]) == 0) {
+		return fmt.Errorf("%s requires at least one column", g.Kind)
+	}
+
+	return nil
+}
+
+func (g GroupingExpr) checkDialect(driverName string) error {
+	if isSQLite(driverName) || (g.Kind != "ROLLUP" && isMySQL(driverName)) {
+		return fmt.Errorf("%s is not supported by the %s driver", g.Kind, driverName)
+	}
+
+	return nil
+}
+
+// FilteredAggExpr is an expression calling an aggregate function only on
+// the rows matching a condition. It can be used in the select list via
+// SelectStmt.SelectExpr.
+type FilteredAggExpr struct {
+	Agg       string
+	Condition WhereCondition
+	Alias     string
+}
+
+// AggFilter creates an expression calling the aggregate agg (e.g.
+// "COUNT(*)" or "SUM(amount)") only on rows matching cond, aliased as alias
+// (unless empty). In PostgreSQL this renders as "agg FILTER (WHERE cond)".
+// Other databases don't support FILTER clauses, so the condition is moved
+// into the aggregate's argument: COUNT(*) renders as "SUM(CASE WHEN cond
+// THEN 1 ELSE 0 END)", and other aggregates, e.g. SUM(amount), render as
+// "SUM(CASE WHEN cond THEN amount END)".
+func AggFilter(agg string, cond WhereCondition, alias string) FilteredAggExpr {
+	return FilteredAggExpr{Agg: agg, Condition: cond, Alias: alias}
+}
+
+// ToSQL generates SQL for the expression, using PostgreSQL syntax
+func (f FilteredAggExpr) ToSQL(_ bool) (string, []interface{}) {
+	return f.sqlFor("")
+}
+
+func (f FilteredAggExpr) sqlFor(driverName string) (asSQL string, bindings []interface{}) {
+	condSQL, bindings := condSQL(f.Condition, driverName)
+
+	paren := strings.Index(f.Agg, "(")
+	emulate := (isMySQL(driverName) || isSQLite(driverName) || isSQLServer(driverName)) &&
+		paren > 0 && strings.HasSuffix(f.Agg, ")")
+
+	if emulate {
+		fn := strings.TrimSpace(f.Agg[:paren])
+		arg := strings.TrimSpace(f.Agg[paren+1 : len(f.Agg)-1])
+
+		if strings.EqualFold(fn, "COUNT") && (arg == "*" || arg == "1") {
+			asSQL = "SUM(CASE WHEN " + condSQL + " THEN 1 ELSE 0 END)"
+		} else {
+			asSQL = fn + "(CASE WHEN " + condSQL + " THEN " + arg + " END)"
+		}
+	} else {
+		asSQL = f.Agg + " FILTER (WHERE " + condSQL + ")"
+	}
+
+	if f.Alias != "" {
+		asSQL += " AS " + f.Alias
+	}
+
+	return asSQL, bindings
+}
+
+// NowExpr is an expression evaluating to the current timestamp on the
+// database server. It can be used as a value in UpdateStmt.Set,
+// InsertStmt.Values and ConflictClause.Set, and as the right-hand side of
+// comparison conditions (e.g. Lt("expires_at", Now())). It is always
+// rendered as SQL, never bound as a parameter.
+type NowExpr struct{}
+
+// Now creates an expression evaluating to the current timestamp on the
+// database server. It renders as "NOW()" in PostgreSQL,
+// "CURRENT_TIMESTAMP" in MySQL and SQL Server, and "datetime('now')" in
+// SQLite.
+func Now() NowExpr {
+	return NowExpr{}
+}
+
+// ToSQL generates SQL for the expression, using PostgreSQL syntax
+func (n NowExpr) ToSQL(_ bool) (string, []interface{}) {
+	return n.sqlFor("")
+}
+
+func (n NowExpr) sqlFor(driverName string) (string, []interface{}) {
+	switch {
+	case isMySQL(driverName), isSQLServer(driverName):
+		return "CURRENT_TIMESTAMP", nil
+	case isSQLite(driverName):
+		return "datetime('now')", nil
+	default:
+		return "NOW()", nil
+	}
+}
+
+// DefaultValue is a value setting a column to its default value, see
+// Default
+type DefaultValue struct{}
+
+// Default creates a value that sets a column to its default value, by
+// rendering the DEFAULT keyword instead of binding a parameter. It can be
+// used as a value in InsertStmt.Values (and the other methods providing
+// values to insert), UpdateStmt.Set and ConflictClause.Set, e.g.
+// Values(Default(), "john") generates "VALUES (DEFAULT, ?)". This is useful
+// when values are provided positionally, and a column cannot simply be left
+// out. SQLite doesn't support the DEFAULT keyword in values, so statements
+// using it fail there.
+func Default() DefaultValue {
+	return DefaultValue{}
+}
+
+// ToSQL generates SQL for the value
+func (DefaultValue) ToSQL(_ bool) (string, []interface{}) {
+	return "DEFAULT", nil
+}
+
+// hasDefault returns true if any of the provided values is a DefaultValue
+func hasDefault(vals ...interface{}) bool {
+	for _, val := range vals {
+		if _, isDefault := val.(DefaultValue); isDefault {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WindowExpr is an expression calling a window function (or an aggregate
+// function used as one) over a window, e.g. "SUM(amount) OVER (PARTITION BY
+// account ORDER BY d ROWS UNBOUNDED PRECEDING)". It can be used in the
+// select list via SelectStmt.SelectExpr.
+type WindowExpr struct {
+	Func       string
+	Partition  []string
+	Ordering   []OrderColumn
+	FrameMode  string
+	FrameStart string
+	FrameEnd   string
+	Alias      string
+}
+
+// Over creates a window expression calling the provided function, e.g.
+// Over("SUM(amount)") or Over("ROW_NUMBER()").
+func Over(fn string) WindowExpr {
+	return WindowExpr{Func: fn}
+}
+
+// PartitionBy sets the PARTITION BY clause of the window
+func (w WindowExpr) PartitionBy(cols ...string) WindowExpr {
+	w.Partition = append(append([]string{}, w.Partition...), cols...)
+	return w
+}
+
+// OrderBy sets the ORDER BY clause of the window
+func (w WindowExpr) OrderBy(cols ...OrderColumn) WindowExpr {
+	w.Ordering = append(append([]OrderColumn{}, w.Ordering...), cols...)
+	return w
+}
+
+// Frame sets the frame clause of the window. The mode is "ROWS", "RANGE" or
+// "GROUPS", and the start and end are frame bounds such as "UNBOUNDED
+// PRECEDING", "CURRENT ROW", "UNBOUNDED FOLLOWING", "3 PRECEDING" or "1
+// FOLLOWING". If end is empty, only the start is rendered (e.g. "ROWS
+// UNBOUNDED PRECEDING"), otherwise "ROWS BETWEEN start AND end" is.
+func (w WindowExpr) Frame(mode, start, end string) WindowExpr {
+	w.FrameMode = strings.ToUpper(strings.TrimSpace(mode))
+	w.FrameStart = strings.ToUpper(strings.TrimSpace(start))
+	w.FrameEnd = strings.ToUpper(strings.TrimSpace(end))
+
+	return w
+}
+
+// As sets an alias for the expression, for use in the select list
+func (w WindowExpr) As(alias string) WindowExpr {
+	w.Alias = alias
+	return w
+}
+
+// frameBound matches a valid window frame bound
+var frameBound = regexp.MustCompile(`^(UNBOUNDED PRECEDING|UNBOUNDED FOLLOWING|CURRENT ROW|[0-9]+ (PRECEDING|FOLLOWING))$`)
+
+// Err returns an error if the expression's frame is invalid
+func (w WindowExpr) Err() error {
+	if w.FrameMode == "" {
+		return nil
+	}
+
+	switch w.FrameMode {
+	case "ROWS", "RANGE", "GROUPS":
+	default:
+		return fmt.Errorf("invalid window frame mode %q", w.FrameMode)
+	}
+
+	for _, bound := range []string{w.FrameStart, w.FrameEnd} {
+		if bound != "" && !frameBound.MatchString(bound) {
+			return fmt.Errorf("invalid window frame bound %q", bound)
+		}
+	}
+
+	if w.FrameStart == "" {
+		return errors.New("window frame requires a start bound")
+	}
+
+	return nil
+}
+
+// ToSQL generates SQL for the expression, using PostgreSQL syntax
+func (w WindowExpr) ToSQL(_ bool) (string, []interface{}) {
+	return w.sqlFor("")
+}
+
+func (w WindowExpr) sqlFor(driverName string) (asSQL string, bindings []interface{}) {
+	var window []string
+
+	if len(w.Partition) > 0 {
+		window = append(window, "PARTITION BY "+strings.Join(w.Partition, ", "))
+	}
+
+	if len(w.Ordering) > 0 {
+		ordering := make([]string, len(w.Ordering))
+		for i, col := range w.Ordering {
+			ordering[i], _ = col.sqlFor(driverName)
+		}
+
+		window = append(window, "ORDER BY "+strings.Join(ordering, ", "))
+	}
+
+	if w.FrameMode != "" && w.FrameEnd != "" {
+		window = append(window, w.FrameMode+" BETWEEN "+w.FrameStart+" AND "+w.FrameEnd)
+	} else if w.FrameMode != "" {
+		window = append(window, w.FrameMode+" "+w.FrameStart)
+	}
+
+	asSQL = w.Func + " OVER (" + strings.Join(window, " ") + ")"
+
+	if w.Alias != "" {
+		asSQL += " AS " + w.Alias
+	}
+
+	return asSQL, nil
+}
+
+// CastValue is a value bound with an explicit type cast on PostgreSQL, see
+// Cast
+type CastValue struct {
+	Value interface{}
+	Type  string
+}
+
+// Cast wraps a value so that its placeholder is rendered with an explicit
+// cast to the provided SQL type on PostgreSQL (e.g. "$1::uuid"), which is
+// sometimes required for pgx to resolve the type of a parameter. The value
+// itself is still bound as a normal parameter. It can be used anywhere a
+// value is accepted, such as in conditions (e.g. Eq("id", Cast(id,
+// "uuid"))), InsertStmt.Values and UpdateStmt.Set. On other drivers, the
+// cast is dropped. Types that are not plain type names (e.g. "numeric(10,
+// 2)" or "text[]" are fine) are quoted as identifiers.
+func Cast(value interface{}, sqlType string) CastValue {
+	return CastValue{value, sqlType}
+}
+
+// castType matches type names that can be rendered as they are
+var castType = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_ .]*(\([0-9, ]+\))?(\[\])*$`)
+
+func (c CastValue) sqlFor(driverName string) (string, []interface{}) {
+	if !isPostgres(driverName) {
+		return "?", []interface{}{c.Value}
+	}
+
+	sqlType := strings.TrimSpace(c.Type)
+	if !castType.MatchString(sqlType) {
+		sqlType = `"` + strings.ReplaceAll(sqlType, `"`, `""`) + `"`
+	}
+
+	return "?::" + sqlType, []interface{}{c.Value}
+}
+
+// ConcatExpr is an expression concatenating strings, see Concat
+type ConcatExpr struct {
+	Parts []interface{}
+	Alias string
+}
+
+// Concat creates an expression concatenating the provided parts, which
+// renders as "a || b" on PostgreSQL and SQLite, and as "CONCAT(a, b)" on
+// MySQL and SQL Server (where, unlike with "||", NULL parts are treated as
+// empty strings on SQL Server, which also requires at least two parts).
+// Column references and other SQL expressions must be wrapped with Indirect
+// (e.g. Indirect("u.first") or Indirect("lower(name)")), or be expressions
+// such as those created by this package; all other parts, including plain
+// strings, are bound as parameters. It can be used in the select list via
+// SelectStmt.SelectExpr, and as the value of conditions (e.g.
+// Eq("full_name", Concat(Indirect("first"), " ", Indirect("last")))).
+func Concat(parts ...interface{}) ConcatExpr {
+	return ConcatExpr{Parts: parts}
+}
+
+// As sets an alias for the expression, for use in the select list
+func (c ConcatExpr) As(alias string) ConcatExpr {
+	c.Alias = alias
+	return c
+}
+
+// ToSQL generates SQL for the expression, using PostgreSQL syntax
+func (c ConcatExpr) ToSQL(_ bool) (string, []interface{}) {
+	return c.sqlFor("")
+}
+
+// Err returns an error if the expression has no parts
+func (c ConcatExpr) Err() error {
+	if len(c.Parts) == 0 {
+		return errors.New("no parts provided to Concat")
+	}
+
+	return nil
+}
+
+func (c ConcatExpr) checkDialect(driverName string) error {
+	if isSQLServer(driverName) && len(c.Parts) < 2 {
+		return errors.New("CONCAT requires at least two parts on SQL Server")
+	}
+
+	return nil
+}
+
+func (c ConcatExpr) sqlFor(driverName string) (asSQL string, bindings []interface{}) {
+	parts := make([]string, len(c.Parts))
+
+	for i, part := range c.Parts {
+		switch p := part.(type) {
+		case SQLStmt:
+			var partBindings []interface{}
+			parts[i], partBindings = exprSQL(p, driverName)
+			bindings = append(bindings, partBindings...)
+		default:
+			parts[i] = "?"
+			bindings = append(bindings, p)
+		}
+	}
+
+	if isMySQL(driverName) || isSQLServer(driverName) {
+		asSQL = "CONCAT(" + strings.Join(parts, ", ") + ")"
+	} else {
+		asSQL = strings.Join(parts, " || ")
+	}
+
+	if c.Alias != "" {
+		asSQL += " AS " + c.Alias
+	}
+
+	return asSQL, bindings
+}
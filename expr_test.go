@@ -0,0 +1,412 @@
+package sqlz
+
+import (
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestDigest(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"postgres digest in select list and group by",
+				dbz.Select("COUNT(*)").SelectExpr(Digest("a", "b").As("key")).From("table").GroupByExpr(Digest("a", "b")),
+				"SELECT COUNT(*), md5(concat_ws('|', a, b)) AS key FROM table GROUP BY md5(concat_ws('|', a, b))",
+				[]interface{}{},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"mysql digest in select list and group by",
+				dbz.Select("COUNT(*)").SelectExpr(Digest("a", "b").As("key")).From("table").GroupByExpr(Digest("a", "b")),
+				"SELECT COUNT(*), MD5(CONCAT_WS('|', a, b)) AS key FROM table GROUP BY MD5(CONCAT_WS('|', a, b))",
+				[]interface{}{},
+			},
+		}
+	})
+
+	runDriverTests(t, "sqlserver", func(dbz *DB) []test {
+		return []test{
+			{
+				"sql server digest in select list",
+				dbz.Select().SelectExpr(Digest("a", "b").As("key")).From("table").Where(Eq("a", 1)),
+				"SELECT CONVERT(VARCHAR(32), HASHBYTES('MD5', CONCAT_WS('|', a, b)), 2) AS key FROM table WHERE a = @p1",
+				[]interface{}{1},
+			},
+		}
+	})
+}
+
+func TestAggregates(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"distinct ordered array aggregation",
+				dbz.Select("category").
+					SelectExpr(ArrayAgg("tag").Distinct().OrderBy(Asc("tag")).As("tags")).
+					From("table").
+					GroupBy("category"),
+				"SELECT category, array_agg(DISTINCT tag ORDER BY tag ASC) AS tags FROM table GROUP BY category",
+				[]interface{}{},
+			},
+
+			{
+				"distinct ordered string aggregation",
+				dbz.Select().SelectExpr(StringAgg("name", ", ").Distinct().OrderBy(Desc("name"))).From("table"),
+				"SELECT string_agg(DISTINCT name, $1 ORDER BY name DESC) FROM table",
+				[]interface{}{", "},
+			},
+		}
+	})
+
+	runDriverTests(t, "sqlite3", func(dbz *DB) []test {
+		return []test{
+			{
+				"string aggregation",
+				dbz.Select("category").SelectExpr(StringAgg("name", ", ").As("names")).From("table").GroupBy("category"),
+				"SELECT category, group_concat(name, ?) AS names FROM table GROUP BY category",
+				[]interface{}{", "},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"distinct ordered string aggregation",
+				dbz.Select().SelectExpr(StringAgg("name", ", ").Distinct().OrderBy(Desc("name"))).From("table"),
+				"SELECT GROUP_CONCAT(DISTINCT name ORDER BY name DESC SEPARATOR ', ') FROM table",
+				[]interface{}{},
+			},
+		}
+	})
+
+	if err := ArrayAgg("tag").Distinct().OrderBy(Asc("created_at")).Err(); err == nil {
+		t.Error("Expected distinct aggregation ordered by another expression to fail")
+	}
+}
+
+func TestDistinctCountOver(t *testing.T) {
+	runTests(t, func(dbz *DB) []test {
+		return []test{
+			{
+				"distinct count over a partition",
+				dbz.Select("id", "team").SelectExpr(DistinctCountOver("user_id", "team").As("users")).From("table"),
+				"SELECT id, team, (dense_rank() OVER (PARTITION BY team ORDER BY user_id) + " +
+					"dense_rank() OVER (PARTITION BY team ORDER BY user_id DESC) - 1) AS users FROM table",
+				[]interface{}{},
+			},
+		}
+	})
+}
+
+func TestPercentile(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"median and percentile",
+				dbz.Select("team").
+					SelectExpr(PercentileCont(0.5, "duration").As("median"), PercentileDisc(0.95, "duration DESC")).
+					From("table").
+					GroupBy("team"),
+				"SELECT team, PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY duration) AS median, " +
+					"PERCENTILE_DISC(0.95) WITHIN GROUP (ORDER BY duration DESC) FROM table GROUP BY team",
+				[]interface{}{},
+			},
+		}
+	})
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	if New(db, "mysql").Select().SelectExpr(PercentileCont(0.5, "x")).From("table").Err() == nil {
+		t.Error("Expected percentile on mysql to fail")
+	}
+
+	if New(db, "postgres").Select().SelectExpr(PercentileCont(1.5, "x")).From("table").Err() == nil {
+		t.Error("Expected percentile with an invalid fraction to fail")
+	}
+}
+
+func TestNullFunctions(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with coalesce",
+				dbz.Select("id").SelectExpr(Coalesce("name", Col("nickname"), "anonymous")).From("users").Where(Eq("active", true)),
+				"SELECT id, COALESCE(nickname, $1) AS name FROM users WHERE active = $2",
+				[]interface{}{"anonymous", true},
+			},
+
+			{
+				"select with coalesce and nullif",
+				dbz.Select("id").
+					SelectExpr(
+						Coalesce("name", Col("nickname"), Col("full_name"), "anonymous"),
+						NullIf("score", Col("score"), 0),
+					).
+					From("users"),
+				"SELECT id, COALESCE(nickname, full_name, $1) AS name, NULLIF(score, $2) AS score FROM users",
+				[]interface{}{"anonymous", 0},
+			},
+		}
+	})
+}
+
+func TestAggFilter(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with native aggregate filters",
+				dbz.Select("user_id").
+					SelectExpr(
+						AggFilter("COUNT(*)", Eq("status", "failed"), "failures"),
+						AggFilter("SUM(amount)", Gt("amount", 100), "large_total"),
+					).
+					From("payments").
+					Where(Eq("year", 2020)).
+					GroupBy("user_id"),
+				"SELECT user_id, COUNT(*) FILTER (WHERE status = $1) AS failures, " +
+					"SUM(amount) FILTER (WHERE amount > $2) AS large_total " +
+					"FROM payments WHERE year = $3 GROUP BY user_id",
+				[]interface{}{"failed", 100, 2020},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with emulated aggregate filters",
+				dbz.Select("user_id").
+					SelectExpr(
+						AggFilter("COUNT(*)", Eq("status", "failed"), "failures"),
+						AggFilter("SUM(amount)", Gt("amount", 100), "large_total"),
+					).
+					From("payments").
+					Where(Eq("year", 2020)).
+					GroupBy("user_id"),
+				"SELECT user_id, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS failures, " +
+					"SUM(CASE WHEN amount > ? THEN amount END) AS large_total " +
+					"FROM payments WHERE year = ? GROUP BY user_id",
+				[]interface{}{"failed", 100, 2020},
+			},
+		}
+	})
+}
+
+func TestNow(t *testing.T) {
+	cases := []struct {
+		driverName string
+		update     string
+		insert     string
+		selectSQL  string
+	}{
+		{
+			"postgres",
+			"UPDATE sessions SET seen_at = NOW() WHERE id = $1",
+			"INSERT INTO sessions (id, created_at) VALUES ($1, NOW())",
+			"SELECT * FROM sessions WHERE expires_at < NOW()",
+		},
+		{
+			"mysql",
+			"UPDATE sessions SET seen_at = CURRENT_TIMESTAMP WHERE id = ?",
+			"INSERT INTO sessions (id, created_at) VALUES (?, CURRENT_TIMESTAMP)",
+			"SELECT * FROM sessions WHERE expires_at < CURRENT_TIMESTAMP",
+		},
+		{
+			"sqlite3",
+			"UPDATE sessions SET seen_at = datetime('now') WHERE id = ?",
+			"INSERT INTO sessions (id, created_at) VALUES (?, datetime('now'))",
+			"SELECT * FROM sessions WHERE expires_at < datetime('now')",
+		},
+	}
+
+	for _, c := range cases {
+		runDriverTests(t, c.driverName, func(dbz *DB) []test {
+			return []test{
+				{
+					"update with now on " + c.driverName,
+					dbz.Update("sessions").Set("seen_at", Now()).Where(Eq("id", 1)),
+					c.update,
+					[]interface{}{1},
+				},
+				{
+					"insert with now on " + c.driverName,
+					dbz.InsertInto("sessions").Columns("id", "created_at").Values(1, Now()),
+					c.insert,
+					[]interface{}{1},
+				},
+				{
+					"condition with now on " + c.driverName,
+					dbz.Select("*").From("sessions").Where(Lt("expires_at", Now())),
+					c.selectSQL,
+					[]interface{}{},
+				},
+			}
+		})
+	}
+}
+
+func TestStringFunctions(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"case and whitespace insensitive equality",
+				dbz.Select("id").From("users").Where(Eq(Lower(Trim(Col("email"))), "john@example.com")),
+				"SELECT id FROM users WHERE LOWER(TRIM(email)) = $1",
+				[]interface{}{"john@example.com"},
+			},
+			{
+				"wrapped columns in groups",
+				dbz.Select("id").From("users").Where(Or(
+					Eq(Upper("code"), "ABC"),
+					Like(Lower(Trim("u.name")), "john%"),
+				)),
+				"SELECT id FROM users WHERE UPPER(code) = $1 OR LOWER(TRIM(u.name)) LIKE $2",
+				[]interface{}{"ABC", "john%"},
+			},
+		}
+	})
+}
+
+func TestWindowFrame(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"running total with a rows frame",
+				dbz.Select("d", "amount").
+					SelectExpr(Over("SUM(amount)").OrderBy(Asc("d")).Frame("ROWS", "UNBOUNDED PRECEDING", "").As("running_total")).
+					From("ledger"),
+				"SELECT d, amount, SUM(amount) OVER (ORDER BY d ASC ROWS UNBOUNDED PRECEDING) AS running_total FROM ledger",
+				[]interface{}{},
+			},
+
+			{
+				"partitioned moving average with a frame range",
+				dbz.Select("account", "d").
+					SelectExpr(
+						Over("AVG(amount)").
+							PartitionBy("account").
+							OrderBy(Asc("d")).
+							Frame("rows", "6 preceding", "current row").
+							As("weekly_avg"),
+					).
+					From("ledger"),
+				"SELECT account, d, AVG(amount) OVER (PARTITION BY account ORDER BY d ASC ROWS BETWEEN 6 PRECEDING AND CURRENT ROW) AS weekly_avg FROM ledger",
+				[]interface{}{},
+			},
+		}
+	})
+
+	for _, w := range []WindowExpr{
+		Over("SUM(x)").Frame("CHUNKS", "UNBOUNDED PRECEDING", ""),
+		Over("SUM(x)").Frame("ROWS", "UNBOUNDED PRECEDING; DROP TABLE x", ""),
+		Over("SUM(x)").Frame("RANGE", "", "CURRENT ROW"),
+	} {
+		if w.Err() == nil {
+			t.Errorf("Expected frame %s %s %s to be invalid", w.FrameMode, w.FrameStart, w.FrameEnd)
+		}
+	}
+}
+
+func TestCast(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"cast in where condition",
+				dbz.Select("*").From("table").Where(Eq("id", Cast("abc", "uuid"))),
+				"SELECT * FROM table WHERE id = $1::uuid",
+				[]interface{}{"abc"},
+			},
+
+			{
+				"cast in insert values",
+				dbz.InsertInto("table").Columns("id", "amount").Values(Cast("abc", "uuid"), Cast(3, "numeric(10, 2)")),
+				"INSERT INTO table (id, amount) VALUES ($1::uuid, $2::numeric(10, 2))",
+				[]interface{}{"abc", 3},
+			},
+
+			{
+				"cast in update set",
+				dbz.Update("table").Set("tags", Cast("{a}", "text[]")).Where(Eq("id", 1)),
+				"UPDATE table SET tags = $1::text[] WHERE id = $2",
+				[]interface{}{"{a}", 1},
+			},
+
+			{
+				"unsafe cast type is quoted",
+				dbz.Select("*").From("table").Where(Eq("id", Cast(1, `int; DROP "x"`))),
+				`SELECT * FROM table WHERE id = $1::"int; DROP ""x"""`,
+				[]interface{}{1},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"cast dropped in where condition",
+				dbz.Select("*").From("table").Where(Eq("id", Cast("abc", "uuid"))),
+				"SELECT * FROM table WHERE id = ?",
+				[]interface{}{"abc"},
+			},
+
+			{
+				"cast dropped in update set",
+				dbz.Update("table").Set("id", Cast("abc", "uuid")),
+				"UPDATE table SET id = ?",
+				[]interface{}{"abc"},
+			},
+		}
+	})
+}
+
+func TestConcat(t *testing.T) {
+	for driverName, expected := range map[string]string{
+		"postgres":  "SELECT u.first || $1 || u.last AS full_name FROM users u WHERE name = lower(first) || $2",
+		"sqlite3":   "SELECT u.first || ? || u.last AS full_name FROM users u WHERE name = lower(first) || ?",
+		"mysql":     "SELECT CONCAT(u.first, ?, u.last) AS full_name FROM users u WHERE name = CONCAT(lower(first), ?)",
+		"sqlserver": "SELECT CONCAT(u.first, @p1, u.last) AS full_name FROM users u WHERE name = CONCAT(lower(first), @p2)",
+	} {
+		driverName, expected := driverName, expected
+
+		runDriverTests(t, driverName, func(dbz *DB) []test {
+			return []test{
+				{
+					driverName + " concat",
+					dbz.Select().From("users u").
+						SelectExpr(Concat(Indirect("u.first"), " ", Indirect("u.last")).As("full_name")).
+						Where(Eq("name", Concat(Indirect("lower(first)"), 7))),
+					expected,
+					[]interface{}{" ", 7},
+				},
+			}
+		})
+	}
+
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"plain strings are bound",
+				dbz.Select("*").From("users").Where(Eq("code", Concat("status", Indirect("id")))),
+				"SELECT * FROM users WHERE code = $1 || id",
+				[]interface{}{"status"},
+			},
+		}
+	})
+
+	if err := New(nil, "postgres").Select().From("users").SelectExpr(Concat()).Err(); err == nil {
+		t.Error("Expected Concat without parts to fail")
+	}
+
+	if err := New(nil, "sqlserver").Select("*").From("users").Where(Eq("name", Concat(Indirect("first")))).Err(); err == nil {
+		t.Error("Expected Concat with a single part to fail on SQL Server")
+	}
+}
@@ -0,0 +1,166 @@
+//go:build go1.18
+// +build go1.18
+
+package sqlz
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// SelectAll executes the SELECT statement and returns all results as a
+// slice of T. If T is a struct (or a pointer to one), columns are mapped to
+// its fields the same way as in SelectStmt.GetAllStructs, and columns with
+// no matching field are ignored. Otherwise, T must be a type that a single
+// column can be scanned into (e.g. int64, string or sql.NullString), and
+// the statement must select exactly one column.
+func SelectAll[T any](stmt *SelectStmt) ([]T, error) {
+	return SelectAllContext[T](context.Background(), stmt)
+}
+
+// SelectAllContext is the same as SelectAll, but receives a context.
+func SelectAllContext[T any](ctx context.Context, stmt *SelectStmt) (results []T, err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	defer func() { stmt.HandleError(err) }()
+
+	if err = stmt.beforeExec(ctx); err != nil {
+		return nil, err
+	}
+
+	asSQL, bindings := stmt.ToSQL(true)
+
+	rows, err := stmt.queryer.QueryContext(ctx, asSQL, bindings...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	results = []T{}
+
+	if isStructType(reflect.TypeOf(results).Elem()) {
+		if err = scanStructs(rows, &results, false); err != nil {
+			return nil, err
+		}
+
+		return results, nil
+	}
+
+	for rows.Next() {
+		var result T
+		if err = rows.Scan(&result); err != nil {
+			return nil, err
+		}
+
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// DuplicateKeys sets how SelectMap handles rows with the same key
+type DuplicateKeys int8
+
+const (
+	// DuplicateKeysError makes SelectMap fail on duplicate keys (default)
+	DuplicateKeysError DuplicateKeys = iota
+	// DuplicateKeysKeepLast makes SelectMap keep the last row of every key
+	DuplicateKeysKeepLast
+)
+
+// SelectMap executes the SELECT statement and returns its results as a map
+// of V, which must be a struct (or a pointer to one) scanned as in
+// SelectAll, keyed by the value of the provided field, which is either the
+// name of a column mapped to the struct (e.g. "id") or the name of the
+// struct field. The type of the field must be assignable to K. By default,
+// SelectMap fails if multiple rows have the same key; pass
+// DuplicateKeysKeepLast to keep the last one instead.
+func SelectMap[K comparable, V any](stmt *SelectStmt, keyField string, onDuplicate ...DuplicateKeys) (map[K]V, error) {
+	return SelectMapContext[K, V](context.Background(), stmt, keyField, onDuplicate...)
+}
+
+// SelectMapContext is the same as SelectMap, but receives a context.
+func SelectMapContext[K comparable, V any](
+	ctx context.Context,
+	stmt *SelectStmt,
+	keyField string,
+	onDuplicate ...DuplicateKeys,
+) (map[K]V, error) {
+	index, err := keyFieldIndex(reflect.TypeOf((*V)(nil)).Elem(), reflect.TypeOf((*K)(nil)).Elem(), keyField)
+	if err != nil {
+		stmt.HandleError(err)
+		return nil, err
+	}
+
+	values, err := SelectAllContext[V](ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	keepLast := len(onDuplicate) > 0 && onDuplicate[len(onDuplicate)-1] == DuplicateKeysKeepLast
+
+	results := make(map[K]V, len(values))
+	for _, value := range values {
+		key := reflectx.FieldByIndexesReadOnly(reflect.Indirect(reflect.ValueOf(value)), index).Interface().(K)
+
+		if _, exists := results[key]; exists && !keepLast {
+			err = fmt.Errorf("duplicate key %v in %s", key, keyField)
+			stmt.HandleError(err)
+			return nil, err
+		}
+
+		results[key] = value
+	}
+
+	return results, nil
+}
+
+// keyFieldIndex returns the index of the field of a struct type (or a
+// pointer to one) to use as the key of SelectMap, by column or field name,
+// and ensures its values are assignable to the key type
+func keyFieldIndex(valueType, keyType reflect.Type, keyField string) ([]int, error) {
+	if !isStructType(valueType) {
+		return nil, fmt.Errorf("cannot map values of type %s by field, must be a struct", valueType)
+	}
+
+	structType := reflectx.Deref(valueType)
+
+	var field reflect.StructField
+	if fi, ok := structMapper.TypeMap(structType).Names[keyField]; ok {
+		field = fi.Field
+		field.Index = fi.Index
+	} else if field, ok = structType.FieldByName(keyField); !ok {
+		return nil, fmt.Errorf("no field %s in %s", keyField, structType)
+	}
+
+	if !field.Type.AssignableTo(keyType) {
+		return nil, fmt.Errorf("field %s of type %s cannot be used as key of type %s", keyField, field.Type, keyType)
+	}
+
+	return field.Index, nil
+}
+
+// isStructType returns true if values of the provided type (or of the
+// type it points to) should be scanned field by field, rather than as a
+// single column. Structs that can scan a column themselves (e.g.
+// sql.NullString) or that database drivers scan directly (time.Time) are
+// not.
+func isStructType(typ reflect.Type) bool {
+	typ = reflectx.Deref(typ)
+	if typ.Kind() != reflect.Struct || typ == reflect.TypeOf(time.Time{}) {
+		return false
+	}
+
+	return !reflect.PtrTo(typ).Implements(reflect.TypeOf((*sql.Scanner)(nil)).Elem())
+}
@@ -0,0 +1,124 @@
+//go:build go1.18
+// +build go1.18
+
+package sqlz
+
+import (
+	"regexp"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestSelectAll(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM users WHERE created_by = $1")).
+		WithArgs("admin").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "full_name", "email", "created_by"}).
+			AddRow(1, "John Doe", "john@example.com", "admin").
+			AddRow(2, "Jane Doe", nil, "admin"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM users ORDER BY id ASC")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3))
+
+	users, err := SelectAll[scanUser](dbz.Select("*").From("users").Where(Eq("created_by", "admin")))
+	if err != nil {
+		t.Fatalf("Failed selecting users: %s", err)
+	}
+
+	if len(users) != 2 || users[0].UserID != 1 || users[0].FullName != "John Doe" ||
+		users[0].Email == nil || users[1].UserID != 2 || users[1].Email != nil {
+		t.Errorf("Unexpected users: %+v", users)
+	}
+
+	ids, err := SelectAll[int64](dbz.Select("id").From("users").OrderBy(Asc("id")))
+	if err != nil {
+		t.Fatalf("Failed selecting ids: %s", err)
+	}
+
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 {
+		t.Errorf("Unexpected ids: %v", ids)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSelectMap(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	usersQuery := regexp.QuoteMeta("SELECT id, full_name FROM users")
+	usersRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "full_name"}).
+			AddRow(1, "John Doe").
+			AddRow(2, "Jane Doe")
+	}
+	duplicateRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "full_name"}).
+			AddRow(1, "John Doe").
+			AddRow(2, "John Doe")
+	}
+
+	mock.ExpectQuery(usersQuery).WillReturnRows(usersRows())
+	mock.ExpectQuery(usersQuery).WillReturnRows(usersRows())
+	mock.ExpectQuery(usersQuery).WillReturnRows(duplicateRows())
+	mock.ExpectQuery(usersQuery).WillReturnRows(duplicateRows())
+
+	byID, err := SelectMap[int64, scanUser](dbz.Select("id", "full_name").From("users"), "id")
+	if err != nil {
+		t.Fatalf("Failed selecting users by id: %s", err)
+	}
+
+	if len(byID) != 2 || byID[1].FullName != "John Doe" || byID[2].FullName != "Jane Doe" {
+		t.Errorf("Unexpected users by id: %+v", byID)
+	}
+
+	byName, err := SelectMap[string, *scanUser](dbz.Select("id", "full_name").From("users"), "FullName")
+	if err != nil {
+		t.Fatalf("Failed selecting users by name: %s", err)
+	}
+
+	if len(byName) != 2 || byName["John Doe"].UserID != 1 || byName["Jane Doe"].UserID != 2 {
+		t.Errorf("Unexpected users by name: %+v", byName)
+	}
+
+	if _, err := SelectMap[string, scanUser](dbz.Select("id", "full_name").From("users"), "full_name"); err == nil {
+		t.Error("Expected duplicate keys to fail")
+	}
+
+	byName, err = SelectMap[string, *scanUser](
+		dbz.Select("id", "full_name").From("users"),
+		"full_name",
+		DuplicateKeysKeepLast,
+	)
+	if err != nil {
+		t.Fatalf("Failed selecting users by name: %s", err)
+	}
+
+	if len(byName) != 1 || byName["John Doe"].UserID != 2 {
+		t.Errorf("Expected the last duplicate to be kept, got %+v", byName)
+	}
+
+	if _, err := SelectMap[string, scanUser](dbz.Select("id").From("users"), "id"); err == nil {
+		t.Error("Expected a key field of a different type to fail")
+	}
+
+	if _, err := SelectMap[int64, int64](dbz.Select("id").From("users"), "id"); err == nil {
+		t.Error("Expected mapping non-struct values to fail")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
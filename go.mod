@@ -1,6 +1,6 @@
 module github.com/ido50/sqlz
 
-go 1.15
+go 1.18
 
 require (
 	github.com/jmoiron/sqlx v1.2.0
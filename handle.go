@@ -0,0 +1,264 @@
+package sqlz
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// handle wraps the sqlx.DB or sqlx.Tx object on which statements are
+// executed. Every query executed through it is timed and passed to the
+// query logger and slow query handler of the DB object it originated from
+// (if any).
+type handle struct {
+	Ext
+	db *DB
+}
+
+func (db *DB) handle() *handle {
+	return &handle{Ext: db.DB, db: db}
+}
+
+func (tx *Tx) handle() *handle {
+	return &handle{Ext: tx.Tx, db: tx.db}
+}
+
+// DriverName returns the name of the driver used by the underlying
+// database object
+func (h *handle) DriverName() string {
+	return driverNameOf(h.Ext)
+}
+
+// Rebind transforms a query from QUESTION to the bindvar type of the
+// underlying database driver, or to the placeholder format set with
+// DB.SetPlaceholderFormat
+func (h *handle) Rebind(query string) string {
+	if h.db != nil && h.db.placeholderFormat != DriverPlaceholders {
+		return h.db.placeholderFormat.rebind(query)
+	}
+
+	if h.db != nil && h.db.nativePgx && isPostgres(h.DriverName()) && hasOrdinalPlaceholders(query) {
+		return query
+	}
+
+	return Rebind(h.DriverName(), query)
+}
+
+// args transforms the arguments of a query into those sent to the
+// database: named arguments are moved last, booleans are converted to
+// integers if DB.SetBoolAsInt was enabled, slices are wrapped as arrays on
+// PostgreSQL, and the DB's argument transformer is applied if
+// DB.SetTransformArgsBeforeExec was enabled
+func (h *handle) args(query string, args []interface{}) []interface{} {
+	args = wrapArrays(h.DriverName(), h.convertBools(namedArgsLast(args)))
+
+	if h.db != nil && h.db.argTransformer != nil && h.db.argTransformExec {
+		args = transformArgs(h.db.argTransformer, query, args)
+	}
+
+	return args
+}
+
+// sentArgs returns the arguments sent to the database when executing a
+// query with the provided arguments on the provided execer
+func sentArgs(execer interface{}, query string, args []interface{}) []interface{} {
+	if h, ok := execer.(*handle); ok {
+		return h.args(query, args)
+	}
+
+	return args
+}
+
+// Query implements the sqlx.Queryer interface
+func (h *handle) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return h.QueryContext(context.Background(), query, args...)
+}
+
+// Queryx implements the sqlx.Queryer interface
+func (h *handle) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
+	return h.QueryxContext(context.Background(), query, args...)
+}
+
+// QueryRowx implements the sqlx.Queryer interface
+func (h *handle) QueryRowx(query string, args ...interface{}) *sqlx.Row {
+	return h.QueryRowxContext(context.Background(), query, args...)
+}
+
+// Exec implements the sqlx.Execer interface
+func (h *handle) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return h.ExecContext(context.Background(), query, args...)
+}
+
+// QueryContext implements the sqlx.QueryerContext interface
+func (h *handle) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	args = h.args(query, args)
+
+	start := time.Now()
+	defer func() {
+		h.log(start, query, args, err)
+		err = mapErrorOf(h, err)
+	}()
+
+	stmt, release, err := h.prepared(ctx, query)
+	if err != nil {
+		return nil, err
+	} else if stmt != nil {
+		defer release()
+		return stmt.QueryContext(ctx, args...)
+	}
+
+	return h.Ext.QueryContext(ctx, query, args...)
+}
+
+// QueryxContext implements the sqlx.QueryerContext interface
+func (h *handle) QueryxContext(ctx context.Context, query string, args ...interface{}) (rows *sqlx.Rows, err error) {
+	args = h.args(query, args)
+
+	start := time.Now()
+	defer func() {
+		h.log(start, query, args, err)
+		err = mapErrorOf(h, err)
+	}()
+
+	stmt, release, err := h.prepared(ctx, query)
+	if err != nil {
+		return nil, err
+	} else if stmt != nil {
+		defer release()
+		return stmt.QueryxContext(ctx, args...)
+	}
+
+	return h.Ext.QueryxContext(ctx, query, args...)
+}
+
+// QueryRowxContext implements the sqlx.QueryerContext interface
+func (h *handle) QueryRowxContext(ctx context.Context, query string, args ...interface{}) (row *sqlx.Row) {
+	args = h.args(query, args)
+
+	start := time.Now()
+	defer func() { h.log(start, query, args, row.Err()) }()
+
+	stmt, release, err := h.prepared(ctx, query)
+	if err != nil {
+		// an sqlx.Row cannot be created with an error from outside the
+		// sqlx package, so execute the query directly and let the row
+		// carry the error (if it happens again)
+		return h.Ext.QueryRowxContext(ctx, query, args...)
+	} else if stmt != nil {
+		defer release()
+		return stmt.QueryRowxContext(ctx, args...)
+	}
+
+	return h.Ext.QueryRowxContext(ctx, query, args...)
+}
+
+// ExecContext implements the sqlx.ExecerContext interface
+func (h *handle) ExecContext(ctx context.Context, query string, args ...interface{}) (res sql.Result, err error) {
+	args = h.args(query, args)
+
+	start := time.Now()
+	defer func() {
+		h.log(start, query, args, err)
+		err = mapErrorOf(h, err)
+	}()
+
+	stmt, release, err := h.prepared(ctx, query)
+	if err != nil {
+		return nil, err
+	} else if stmt != nil {
+		defer release()
+		return stmt.ExecContext(ctx, args...)
+	}
+
+	return h.Ext.ExecContext(ctx, query, args...)
+}
+
+// prepared returns a prepared statement for the query from the DB's
+// statement cache. If the cache is disabled, a nil statement is returned.
+// Otherwise, the returned function must be called once the statement is
+// no longer used.
+func (h *handle) prepared(ctx context.Context, query string) (*sqlx.Stmt, func(), error) {
+	if h.db == nil || h.db.stmtCache == nil {
+		return nil, nil, nil
+	}
+
+	cache := h.db.stmtCache
+
+	cached, err := cache.acquire(ctx, h.db.DB, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	release := func() { cache.release(cached) }
+
+	if tx, ok := h.Ext.(*sqlx.Tx); ok {
+		return tx.StmtxContext(ctx, cached.stmt), release, nil
+	}
+
+	return cached.stmt, release, nil
+}
+
+// convertBools converts boolean arguments to 1 and 0, if the DB was
+// configured to do so with DB.SetBoolAsInt
+func (h *handle) convertBools(args []interface{}) []interface{} {
+	if h.db == nil || !h.db.boolAsInt {
+		return args
+	}
+
+	converted := make([]interface{}, len(args))
+
+	for i, arg := range args {
+		if b, ok := arg.(bool); ok {
+			if b {
+				arg = 1
+			} else {
+				arg = 0
+			}
+		}
+
+		converted[i] = arg
+	}
+
+	return converted
+}
+
+func (h *handle) log(start time.Time, query string, args []interface{}, err error) {
+	if h.db == nil {
+		return
+	}
+
+	duration := time.Since(start)
+
+	// arguments that were transformed before execution are not transformed
+	// again
+	if h.db.argTransformer != nil && !h.db.argTransformExec &&
+		(h.db.queryLogger != nil || h.db.slowQueryHandler != nil) {
+		args = transformArgs(h.db.argTransformer, query, args)
+	}
+
+	if h.db.queryLogger != nil {
+		h.db.queryLogger(query, args, duration, err)
+	}
+
+	if h.db.slowQueryHandler != nil && h.db.slowQueryThreshold > 0 && duration >= h.db.slowQueryThreshold {
+		h.db.slowQueryHandler(query, args, duration)
+	}
+}
+
+// isTx returns true if the provided database object is a transaction
+func isTx(db interface{}) bool {
+	_, ok := db.(*sqlx.Tx)
+	return ok
+}
+
+// rebindFor transforms a query from QUESTION to the bindvar type used by
+// the provided database object, if it is capable of rebinding
+func rebindFor(db interface{}, query string) string {
+	if rebinder, ok := db.(interface{ Rebind(string) string }); ok {
+		return rebinder.Rebind(query)
+	}
+
+	return query
+}
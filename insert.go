@@ -3,8 +3,11 @@ package sqlz
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -16,11 +19,16 @@ type InsertStmt struct {
 	InsVals         []interface{}
 	InsMultipleVals [][]interface{}
 	SelectStmt      *SelectStmt
+	NotExistsStmt   *SelectStmt
 	Table           string
 	Return          []string
 	Conflicts       []*ConflictClause
+	DupKeyUpdates   []string
+	IsDefaultValues bool
 	execer          Ext
 	sqliteConflict  string
+	mapCols         []string
+	upsertCols      []string
 }
 
 // InsertInto creates a new InsertStmt object for the
@@ -28,8 +36,8 @@ type InsertStmt struct {
 func (db *DB) InsertInto(table string) *InsertStmt {
 	return &InsertStmt{
 		Table:     table,
-		execer:    db.DB,
-		Statement: &Statement{db.ErrHandlers},
+		execer:    db.handle(),
+		Statement: &Statement{ErrHandlers: db.ErrHandlers},
 	}
 }
 
@@ -38,8 +46,8 @@ func (db *DB) InsertInto(table string) *InsertStmt {
 func (tx *Tx) InsertInto(table string) *InsertStmt {
 	return &InsertStmt{
 		Table:     table,
-		execer:    tx.Tx,
-		Statement: &Statement{tx.ErrHandlers},
+		execer:    tx.handle(),
+		Statement: &Statement{ErrHandlers: tx.ErrHandlers},
 	}
 }
 
@@ -58,13 +66,70 @@ func (stmt *InsertStmt) Values(vals ...interface{}) *InsertStmt {
 	return stmt
 }
 
-// ValueMap receives a map of columns and values to insert
+// ValueMap receives a map of columns and values to insert. Columns are
+// sorted alphabetically, so that the same map always generates the same
+// SQL. ValueMap may be called multiple times to insert multiple rows, in
+// which case all maps must have the same keys, otherwise the statement
+// fails.
 func (stmt *InsertStmt) ValueMap(vals map[string]interface{}) *InsertStmt {
-	for _, col := range sortKeys(vals) {
-		stmt.InsCols = append(stmt.InsCols, col)
-		stmt.InsVals = append(stmt.InsVals, vals[col])
+	cols := sortKeys(vals)
+
+	row := make([]interface{}, len(cols))
+	for i, col := range cols {
+		row[i] = vals[col]
+	}
+
+	if stmt.mapCols == nil {
+		stmt.mapCols = cols
+		stmt.InsCols = append(stmt.InsCols, cols...)
+		stmt.InsVals = append(stmt.InsVals, row...)
+
+		return stmt
+	}
+
+	if strings.Join(cols, ",") != strings.Join(stmt.mapCols, ",") {
+		stmt.setErr(fmt.Errorf(
+			"value map has columns (%s), expected (%s)",
+			strings.Join(cols, ", "), strings.Join(stmt.mapCols, ", "),
+		))
+
+		return stmt
 	}
 
+	// subsequent maps turn the statement into a multi-row insert
+	if len(stmt.InsVals) > 0 {
+		stmt.InsMultipleVals = append(stmt.InsMultipleVals, stmt.InsVals)
+		stmt.InsVals = nil
+	}
+
+	stmt.InsMultipleVals = append(stmt.InsMultipleVals, row)
+
+	return stmt
+}
+
+// AddRow adds a row of values to insert (based on the columns provided via
+// Columns), turning the statement into a multi-row insert (see
+// ValueMultiple). It is meant to be called once per row, e.g. in a loop.
+// If the number of values doesn't match the number of columns, the
+// statement fails.
+func (stmt *InsertStmt) AddRow(vals ...interface{}) *InsertStmt {
+	if len(vals) != len(stmt.InsCols) {
+		stmt.setErr(fmt.Errorf(
+			"row %d has %d values, expected %d",
+			len(stmt.InsMultipleVals)+1, len(vals), len(stmt.InsCols),
+		))
+
+		return stmt
+	}
+
+	// values previously provided with Values are the first row
+	if len(stmt.InsVals) > 0 {
+		stmt.InsMultipleVals = append(stmt.InsMultipleVals, stmt.InsVals)
+		stmt.InsVals = nil
+	}
+
+	stmt.InsMultipleVals = append(stmt.InsMultipleVals, vals)
+
 	return stmt
 }
 
@@ -74,16 +139,249 @@ func (stmt *InsertStmt) ValueMultiple(vals [][]interface{}) *InsertStmt {
 	return stmt
 }
 
+// RowsFromStructs receives a slice of structs (or of pointers to structs)
+// and inserts a row for each of them, using the same multi-row insert as
+// ValueMultiple. The columns are derived from the fields of the first
+// struct, mapped by their "db" tags (or otherwise by their snake_cased
+// names) in the order in which they are declared, including the fields of
+// embedded structs. All structs must map to the same columns, otherwise
+// the statement fails when executed.
+func (stmt *InsertStmt) RowsFromStructs(rows interface{}) *InsertStmt {
+	cols, vals, err := structRows(reflect.ValueOf(rows))
+	if err != nil {
+		stmt.setErr(err)
+		return stmt
+	}
+
+	stmt.InsCols = append(stmt.InsCols, cols...)
+	stmt.InsMultipleVals = append(stmt.InsMultipleVals, vals...)
+
+	return stmt
+}
+
+// InsertStruct creates a new InsertStmt object for the provided table,
+// inserting the values of a struct's fields (see InsertStmt.Struct).
+func (db *DB) InsertStruct(table string, v interface{}) *InsertStmt {
+	return db.InsertInto(table).Struct(v)
+}
+
+// InsertStruct creates a new InsertStmt object for the provided table,
+// inserting the values of a struct's fields (see InsertStmt.Struct).
+func (tx *Tx) InsertStruct(table string, v interface{}) *InsertStmt {
+	return tx.InsertInto(table).Struct(v)
+}
+
+// Struct sets the columns and values to insert from the fields of a
+// struct (or a pointer to a struct), mapped the same way as in
+// RowsFromStructs. Fields tagged with the omitempty option (e.g.
+// db:"created_at,omitempty") are not inserted if they hold their zero
+// value, so the database defaults of their columns apply. If a slice of
+// structs is provided, a row is inserted for each of them, and a field with
+// the omitempty option is only left out if it is zero in all of them.
+func (stmt *InsertStmt) Struct(v interface{}) *InsertStmt {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Slice {
+		cols, vals, err := structValues(val, true)
+		if err != nil {
+			stmt.setErr(err)
+			return stmt
+		}
+
+		stmt.InsCols = append(stmt.InsCols, cols...)
+		stmt.InsVals = append(stmt.InsVals, vals...)
+
+		return stmt
+	}
+
+	cols, rows, err := structRows(val)
+	if err != nil {
+		stmt.setErr(err)
+		return stmt
+	}
+
+	// only keep columns which are not empty in at least one row
+	used := make(map[string]bool, len(cols))
+
+	for i := 0; i < val.Len(); i++ {
+		rowCols, _, _ := structValues(val.Index(i), true)
+		for _, col := range rowCols {
+			used[col] = true
+		}
+	}
+
+	for i, row := range rows {
+		var keptVals []interface{}
+
+		for j, col := range cols {
+			if used[col] {
+				keptVals = append(keptVals, row[j])
+			}
+		}
+
+		rows[i] = keptVals
+	}
+
+	for _, col := range cols {
+		if used[col] {
+			stmt.InsCols = append(stmt.InsCols, col)
+		}
+	}
+
+	stmt.InsMultipleVals = append(stmt.InsMultipleVals, rows...)
+
+	return stmt
+}
+
+// structRows returns the columns and values of a slice of structs, failing
+// if the structs don't all map to the same columns
+func structRows(val reflect.Value) (cols []string, vals [][]interface{}, err error) {
+	if val.Kind() != reflect.Slice || val.Len() == 0 {
+		return nil, nil, errors.New("RowsFromStructs requires a non-empty slice of structs")
+	}
+
+	vals = make([][]interface{}, val.Len())
+
+	for i := 0; i < val.Len(); i++ {
+		rowCols, rowVals, err := structValues(val.Index(i), false)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if i == 0 {
+			cols = rowCols
+		} else if strings.Join(rowCols, ",") != strings.Join(cols, ",") {
+			return nil, nil, fmt.Errorf("row %d has different fields than the first row", i)
+		}
+
+		vals[i] = rowVals
+	}
+
+	return cols, vals, nil
+}
+
+// DefaultValues inserts a single row in which all columns are set to their
+// default values, generating "INSERT INTO t DEFAULT VALUES" (or "INSERT
+// INTO t () VALUES ()" in MySQL). Any columns and values provided to the
+// statement are ignored.
+func (stmt *InsertStmt) DefaultValues() *InsertStmt {
+	stmt.IsDefaultValues = true
+	return stmt
+}
+
 // FromSelect sets a SELECT statements that will supply the rows to be inserted.
+// The SELECT statement replaces the VALUES clause, and its bindings are
+// carried over to the INSERT statement. If columns were defined via Columns,
+// their number must match the number of columns selected by the SELECT
+// statement (unless it selects "*"), otherwise the statement fails when
+// executed.
 func (stmt *InsertStmt) FromSelect(selStmt *SelectStmt) *InsertStmt {
 	stmt.SelectStmt = selStmt
+
+	if err := selStmt.Err(); err != nil {
+		stmt.setErr(err)
+	}
+
+	return stmt
+}
+
+// WhereNotExists makes the statement conditional: the values provided via
+// Values are only inserted if the provided sub-query returns no results.
+// This generates an INSERT ... SELECT statement, e.g. "INSERT INTO t (a, b)
+// SELECT ?, ? WHERE NOT EXISTS (SELECT 1 FROM t WHERE a = ?)", with the
+// bindings of the values followed by those of the sub-query.
+func (stmt *InsertStmt) WhereNotExists(subStmt *SelectStmt) *InsertStmt {
+	stmt.NotExistsStmt = subStmt
+	return stmt
+}
+
+// WhenNotExists is a shorthand for WhereNotExists that only inserts the
+// values if no row of the statement's table matches the provided condition,
+// e.g. WhenNotExists(Eq("email", email)) generates "INSERT INTO t (email,
+// name) SELECT ?, ? WHERE NOT EXISTS (SELECT 1 FROM t WHERE email = ?)".
+// Unlike ON CONFLICT, this is supported by all databases, and doesn't
+// require a unique index, but it is subject to race conditions between
+// concurrent transactions.
+func (stmt *InsertStmt) WhenNotExists(cond WhereCondition) *InsertStmt {
+	if cond == nil {
+		stmt.setErr(errors.New("conditional insert requires a condition"))
+		return stmt
+	}
+
+	return stmt.WhereNotExists(&SelectStmt{
+		Columns:    []string{"1"},
+		Table:      stmt.Table,
+		Conditions: []WhereCondition{cond},
+		queryer:    stmt.execer,
+		Statement:  &Statement{},
+	})
+}
+
+// selectArityErr returns an error if the number of columns to insert
+// doesn't match the number of columns selected by the statement's SELECT
+// statement. Comma-separated columns provided as a single string (e.g.
+// Select("id, name")) are counted separately. The check is skipped if the
+// number of selected columns cannot be determined, e.g. when selecting "*"
+// or with raw SQL.
+func (stmt *InsertStmt) selectArityErr() error {
+	sel := stmt.SelectStmt
+	if sel == nil || len(stmt.InsCols) == 0 || sel.RawSQL != "" {
+		return nil
+	}
+
+	selected := len(sel.SelectExprs)
+
+	for _, cols := range sel.Columns {
+		for _, col := range splitTopLevel(cols, ',') {
+			if col == "*" || strings.HasSuffix(col, ".*") {
+				return nil
+			}
+
+			selected++
+		}
+	}
+
+	if selected == 0 {
+		return nil
+	}
+
+	if selected != len(stmt.InsCols) {
+		return fmt.Errorf("insert has %d columns but select has %d columns", len(stmt.InsCols), selected)
+	}
+
+	return nil
+}
+
+// ApplyIf calls fn with the statement if cond is true, and returns its
+// result; otherwise the statement is returned unchanged (see
+// SelectStmt.ApplyIf).
+func (stmt *InsertStmt) ApplyIf(cond bool, fn func(*InsertStmt) *InsertStmt) *InsertStmt {
+	if !cond {
+		return stmt
+	}
+
+	return fn(stmt)
+}
+
+// WithTimeout sets a timeout for executing the INSERT statement (see
+// SelectStmt.WithTimeout).
+func (stmt *InsertStmt) WithTimeout(timeout time.Duration) *InsertStmt {
+	stmt.timeout = timeout
+	return stmt
+}
+
+// Comment adds a comment with the provided tag to the start of the INSERT
+// statement's SQL (see SelectStmt.Comment).
+func (stmt *InsertStmt) Comment(tag string) *InsertStmt {
+	stmt.comment = tag
 	return stmt
 }
 
 // Returning sets a RETURNING clause to receive values back from the
 // database once executing the INSERT statement. Note that GetRow or
 // GetAll must be used to execute the query rather than Exec to get
-// back the values.
+// back the values. On SQL Server, an OUTPUT clause with the columns
+// qualified by the INSERTED pseudo-table is generated instead, e.g.
+// "OUTPUT INSERTED.id", before the values.
 func (stmt *InsertStmt) Returning(cols ...string) *InsertStmt {
 	stmt.Return = append(stmt.Return, cols...)
 	return stmt
@@ -95,6 +393,20 @@ func (stmt *InsertStmt) OnConflictDoNothing() *InsertStmt {
 	return stmt.OnConflict(OnConflict().DoNothing())
 }
 
+// OnConflictDoNothingFor sets an "ON CONFLICT (target) WHERE where DO
+// NOTHING" clause on the statement. The WHERE predicate (which may be nil)
+// selects a partial unique index to infer as the conflict target, e.g.
+// OnConflictDoNothingFor([]string{"email"}, Eq("deleted", false)) for an
+// index created with "... (email) WHERE deleted = false".
+func (stmt *InsertStmt) OnConflictDoNothingFor(target []string, where WhereCondition) *InsertStmt {
+	conflict := OnConflict(target...).DoNothing()
+	if where != nil {
+		conflict.Where(where)
+	}
+
+	return stmt.OnConflict(conflict)
+}
+
 // OrIgnore enables the "OR IGNORE" conflict resolution for SQLIte inserts
 func (stmt *InsertStmt) OrIgnore() *InsertStmt {
 	stmt.sqliteConflict = "IGNORE"
@@ -131,6 +443,212 @@ func (stmt *InsertStmt) OnConflict(clause *ConflictClause) *InsertStmt {
 	return stmt
 }
 
+// Upsert makes the statement update existing rows instead of failing when
+// the inserted values conflict with them on conflictCols (which must have
+// a unique index), setting each of updateCols to its inserted value. The
+// clause is generated according to the driver: "ON CONFLICT (cols) DO
+// UPDATE SET col = EXCLUDED.col" in PostgreSQL and SQLite, and "ON
+// DUPLICATE KEY UPDATE col = VALUES(col)" in MySQL, where conflictCols are
+// ignored as any unique index may conflict. If updateCols is empty,
+// conflicting rows are left as they are. The conflict columns may span
+// several columns (e.g. a composite unique index on "(tenant_id, email)"),
+// but must all be inserted by the statement; otherwise, the statement
+// fails. In MySQL, leaving conflicting
+// rows as they are requires at least one conflict column, which is set to
+// itself, while elsewhere updating them does. SQL Server has no upsert syntax, so the statement fails there;
+// use DB.MergeInto instead.
+func (stmt *InsertStmt) Upsert(conflictCols []string, updateCols []string) *InsertStmt {
+	if isSQLServer(driverNameOf(stmt.execer)) {
+		stmt.setErr(errors.New("upserts are not supported on SQL Server, use MergeInto instead"))
+		return stmt
+	}
+
+	// the conflict columns are checked against the inserted columns by Err,
+	// as these may be set after calling Upsert
+	stmt.upsertCols = append(stmt.upsertCols, conflictCols...)
+
+	if isMySQL(driverNameOf(stmt.execer)) {
+		if len(updateCols) == 0 && len(conflictCols) == 0 {
+			stmt.setErr(errors.New("upserts without update columns require a conflict column on MySQL"))
+			return stmt
+		}
+
+		if len(updateCols) == 0 {
+			// MySQL has no DO NOTHING, so a column is set to itself instead
+			stmt.DupKeyUpdates = append(stmt.DupKeyUpdates, conflictCols[0]+" = "+conflictCols[0])
+		}
+
+		for _, col := range updateCols {
+			stmt.DupKeyUpdates = append(stmt.DupKeyUpdates, col+" = VALUES("+col+")")
+		}
+
+		return stmt
+	}
+
+	if len(updateCols) > 0 && len(conflictCols) == 0 {
+		stmt.setErr(errors.New("upserts with update columns require a conflict column"))
+		return stmt
+	}
+
+	conflict := OnConflict(conflictCols...)
+	if len(updateCols) == 0 {
+		return stmt.OnConflict(conflict.DoNothing())
+	}
+
+	conflict.DoUpdate()
+
+	for _, col := range updateCols {
+		conflict.Set(col, Indirect("EXCLUDED."+col))
+	}
+
+	return stmt.OnConflict(conflict)
+}
+
+// ReturningPrevious turns an upsert (a single-row INSERT statement with an
+// ON CONFLICT DO UPDATE clause) into a WITH statement that returns both the
+// new and previous values of the provided columns. The previous values are
+// read by an auxiliary statement (named "previous") that finds the existing
+// row using the conflict targets, and are returned as "previous_<column>"
+// (NULL if the row did not exist). The existing row is locked by the
+// auxiliary statement (SELECT ... FOR UPDATE), so that concurrent writers
+// cannot change it between reading its previous values and updating it.
+// However, if the row doesn't exist yet, there is nothing to lock, and a
+// row inserted concurrently will be updated while its previous values are
+// returned as NULL. For example, incrementing a counter:
+//
+//	dbz.InsertInto("counters").
+//		Columns("id", "value").
+//		Values(1, 1).
+//		OnConflict(OnConflict("id").DoUpdate().Set("value", Indirect("counters.value + EXCLUDED.value"))).
+//		ReturningPrevious("value")
+//
+// Only PostgreSQL is supported, an error is returned for other drivers.
+func (stmt *InsertStmt) ReturningPrevious(cols ...string) (*WithStmt, error) {
+	if driverName := driverNameOf(stmt.execer); !isPostgres(driverName) {
+		return nil, fmt.Errorf("returning previous values is not supported for driver %q", driverName)
+	}
+
+	var conflict *ConflictClause
+
+	for _, c := range stmt.Conflicts {
+		if c.Action == DoUpdate && len(c.Targets) > 0 {
+			conflict = c
+			break
+		}
+	}
+
+	if conflict == nil {
+		return nil, errors.New("returning previous values requires an ON CONFLICT DO UPDATE clause with targets")
+	}
+
+	if len(stmt.InsVals) == 0 || len(stmt.InsVals) != len(stmt.InsCols) {
+		return nil, errors.New("returning previous values requires a single row with named columns")
+	}
+
+	conds := make([]WhereCondition, len(conflict.Targets))
+
+	for i, target := range conflict.Targets {
+		index := -1
+
+		for j, col := range stmt.InsCols {
+			if col == target {
+				index = j
+				break
+			}
+		}
+
+		if index == -1 {
+			return nil, fmt.Errorf("conflict target %q is not one of the inserted columns", target)
+		}
+
+		conds[i] = Eq(target, stmt.InsVals[index])
+	}
+
+	previous := &SelectStmt{
+		Columns:    append([]string{}, cols...),
+		Table:      stmt.Table,
+		Conditions: conds,
+		Locks:      []*LockClause{{Strength: LockForUpdate}},
+		queryer:    stmt.execer,
+		Statement:  stmt.Statement,
+	}
+
+	upsert := *stmt
+	upsert.Return = append([]string{}, stmt.Return...)
+
+	for _, col := range cols {
+		upsert.Return = append(
+			upsert.Return,
+			col,
+			"(SELECT "+col+" FROM previous) AS previous_"+col,
+		)
+	}
+
+	return &WithStmt{
+		AuxStmts: []AuxStmt{{previous, "previous"}},
+		MainStmt: &upsert,
+		execer:   stmt.execer,
+	}, nil
+}
+
+// Err returns the first error encountered while building the statement, if
+// any. On SQLite, it also returns an error if any of the values is
+// Default(), which SQLite doesn't support.
+func (stmt *InsertStmt) Err() error {
+	if err := stmt.Statement.Err(); err != nil {
+		return err
+	}
+
+	if err := stmt.selectArityErr(); err != nil {
+		return err
+	}
+
+	if err := stmt.upsertColsErr(); err != nil {
+		return err
+	}
+
+	if !isSQLite(driverNameOf(stmt.execer)) {
+		return nil
+	}
+
+	usesDefault := hasDefault(stmt.InsVals...)
+
+	for _, row := range stmt.InsMultipleVals {
+		usesDefault = usesDefault || hasDefault(row...)
+	}
+
+	for _, conflict := range stmt.Conflicts {
+		usesDefault = usesDefault || hasDefault(conflict.SetVals...)
+	}
+
+	if usesDefault {
+		return errors.New("DEFAULT values are not supported on SQLite")
+	}
+
+	return nil
+}
+
+// upsertColsErr returns an error if a conflict column provided to Upsert is
+// not one of the inserted columns
+func (stmt *InsertStmt) upsertColsErr() error {
+	if len(stmt.InsCols) == 0 {
+		return nil
+	}
+
+	inserted := make(map[string]bool, len(stmt.InsCols))
+	for _, col := range stmt.InsCols {
+		inserted[col] = true
+	}
+
+	for _, col := range stmt.upsertCols {
+		if !inserted[col] {
+			return fmt.Errorf("conflict column %s is not one of the inserted columns", col)
+		}
+	}
+
+	return nil
+}
+
 // ToSQL generates the INSERT statement's SQL and returns a list of
 // bindings. It is used internally by Exec, GetRow and GetAll, but is
 // exported if you wish to use it directly.
@@ -141,24 +659,46 @@ func (stmt *InsertStmt) ToSQL(rebind bool) (asSQL string, bindings []interface{}
 		clauses[0] = fmt.Sprintf("INSERT OR %s", stmt.sqliteConflict)
 	}
 
-	if len(stmt.InsCols) > 0 {
+	if len(stmt.InsCols) > 0 && !stmt.IsDefaultValues {
 		clauses = append(clauses, "("+strings.Join(stmt.InsCols, ", ")+")")
 	}
 
+	// SQL Server returns values with an OUTPUT clause before the values
+	if len(stmt.Return) > 0 && isSQLServer(driverNameOf(stmt.execer)) {
+		clauses = append(clauses, outputClause("INSERTED", stmt.Return))
+	}
+
 	switch {
+	case stmt.IsDefaultValues && isMySQL(driverNameOf(stmt.execer)):
+		clauses = append(clauses, "() VALUES ()")
+	case stmt.IsDefaultValues:
+		clauses = append(clauses, "DEFAULT VALUES")
 	case stmt.SelectStmt != nil:
 		selectSQL, selectBindings := stmt.SelectStmt.ToSQL(false)
 		clauses = append(clauses, selectSQL)
 		bindings = append(bindings, selectBindings...)
+	case len(stmt.InsVals) > 0 && stmt.NotExistsStmt != nil:
+		placeholders, bindingsToAdd := parseInsertValuesFor(stmt.InsVals, driverNameOf(stmt.execer))
+		bindings = append(bindings, bindingsToAdd...)
+
+		selectClause := "SELECT " + strings.Join(placeholders, ", ")
+		if isMySQL(driverNameOf(stmt.execer)) {
+			// MySQL only allows a WHERE clause after a FROM clause
+			selectClause += " FROM DUAL"
+		}
+
+		existsSQL, existsBindings := NotExists(stmt.NotExistsStmt).Parse()
+		bindings = append(bindings, existsBindings...)
+		clauses = append(clauses, selectClause+" WHERE "+existsSQL)
 	case len(stmt.InsVals) > 0:
-		placeholders, bindingsToAdd := parseInsertValues(stmt.InsVals)
+		placeholders, bindingsToAdd := parseInsertValuesFor(stmt.InsVals, driverNameOf(stmt.execer))
 		bindings = append(bindings, bindingsToAdd...)
 		clauses = append(clauses, "VALUES ("+strings.Join(placeholders, ", ")+")")
 	case len(stmt.InsMultipleVals) > 0:
 		var multipleValues []string
 
 		for _, insVals := range stmt.InsMultipleVals {
-			placeholders, bindingsToAdd := parseInsertValues(insVals)
+			placeholders, bindingsToAdd := parseInsertValuesFor(insVals, driverNameOf(stmt.execer))
 			bindings = append(bindings, bindingsToAdd...)
 			multipleValues = append(multipleValues, "("+strings.Join(placeholders, ", ")+")")
 		}
@@ -167,47 +707,102 @@ func (stmt *InsertStmt) ToSQL(rebind bool) (asSQL string, bindings []interface{}
 	}
 
 	for _, conflict := range stmt.Conflicts {
-		conflictSQL, conflictBindings := conflict.ToSQL()
+		conflictSQL, conflictBindings := conflict.sqlFor(driverNameOf(stmt.execer))
 		clauses = append(clauses, conflictSQL)
 		bindings = append(bindings, conflictBindings...)
 	}
 
-	if len(stmt.Return) > 0 {
+	if len(stmt.DupKeyUpdates) > 0 {
+		clauses = append(clauses, "ON DUPLICATE KEY UPDATE "+strings.Join(stmt.DupKeyUpdates, ", "))
+	}
+
+	if len(stmt.Return) > 0 && !isSQLServer(driverNameOf(stmt.execer)) {
 		clauses = append(clauses, "RETURNING "+strings.Join(stmt.Return, ", "))
 	}
 
 	asSQL = strings.Join(clauses, " ")
 
 	if rebind {
-		if db, ok := stmt.execer.(*sqlx.DB); ok {
-			asSQL = db.Rebind(asSQL)
-		} else if tx, ok := stmt.execer.(*sqlx.Tx); ok {
-			asSQL = tx.Rebind(asSQL)
-		}
+		asSQL = stmt.commented(stmt.execer, rebindFor(stmt.execer, asSQL))
 	}
 
 	return asSQL, bindings
 }
 
+// ToSQLChecked generates the INSERT statement's SQL like ToSQL, but fails
+// if the number of placeholders doesn't match the number of bindings (see
+// SelectStmt.ToSQLChecked).
+func (stmt *InsertStmt) ToSQLChecked(rebind bool) (asSQL string, bindings []interface{}, err error) {
+	return checkedSQL(stmt, rebind)
+}
+
+// Args returns the values bound to the statement (see SelectStmt.Args)
+func (stmt *InsertStmt) Args() []interface{} {
+	_, bindings := stmt.ToSQL(false)
+	return bindings
+}
+
 // Exec executes the INSERT statement, returning the standard
 // sql.Result struct and an error if the query failed.
 func (stmt *InsertStmt) Exec() (res sql.Result, err error) {
-	asSQL, bindings := stmt.ToSQL(true)
-	res, err = stmt.execer.Exec(asSQL, bindings...)
-	stmt.Statement.HandleError(err)
-
-	return res, err
+	return stmt.ExecContext(context.Background())
 }
 
 // ExecContext executes the INSERT statement, returning the standard
 // sql.Result struct and an error if the query failed.
 func (stmt *InsertStmt) ExecContext(ctx context.Context) (res sql.Result, err error) {
+	res, _, _, err = stmt.ExecWithSQLContext(ctx)
+	return res, err
+}
+
+// ExecWithSQL executes the INSERT statement like Exec, but also returns the
+// SQL and arguments that were sent to the database, after placeholders
+// were rebound and arguments converted, e.g. for audit logging of the
+// exact statements executed. If the statement fails before being sent,
+// the SQL is empty.
+func (stmt *InsertStmt) ExecWithSQL() (res sql.Result, asSQL string, args []interface{}, err error) {
+	return stmt.ExecWithSQLContext(context.Background())
+}
+
+// ExecWithSQLContext is the same as ExecWithSQL, but receives a context.
+func (stmt *InsertStmt) ExecWithSQLContext(ctx context.Context) (
+	res sql.Result,
+	asSQL string,
+	args []interface{},
+	err error,
+) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if err = stmt.Err(); err != nil {
+		stmt.HandleError(err)
+		return res, "", nil, err
+	}
+
 	asSQL, bindings := stmt.ToSQL(true)
+	args = sentArgs(stmt.execer, asSQL, bindings)
 
 	res, err = stmt.execer.ExecContext(ctx, asSQL, bindings...)
 	stmt.Statement.HandleError(err)
 
-	return res, err
+	return res, asSQL, args, err
+}
+
+// ExecAffected executes the INSERT statement, returning the number of
+// rows it affected. If the driver cannot report that number, the returned
+// error wraps ErrRowsAffectedUnavailable. Note that with ON DUPLICATE KEY
+// UPDATE, MySQL counts every inserted row as 1, every updated row as 2, and
+// every row left unchanged as 0 (or 1 when connected with the
+// CLIENT_FOUND_ROWS flag, e.g. "clientFoundRows=true" in go-sql-driver's
+// DSN).
+func (stmt *InsertStmt) ExecAffected() (int64, error) {
+	return stmt.ExecAffectedContext(context.Background())
+}
+
+// ExecAffectedContext executes the INSERT statement, returning the number
+// of rows it affected. See ExecAffected for more information.
+func (stmt *InsertStmt) ExecAffectedContext(ctx context.Context) (int64, error) {
+	return rowsAffected(stmt.ExecContext(ctx))
 }
 
 // GetRow executes an INSERT statement with a RETURNING clause
@@ -216,9 +811,7 @@ func (stmt *InsertStmt) ExecContext(ctx context.Context) (res sql.Result, err er
 // only one column is returned, or a struct if multiple columns
 // are returned)
 func (stmt *InsertStmt) GetRow(into interface{}) error {
-	asSQL, bindings := stmt.ToSQL(true)
-
-	return sqlx.Get(stmt.execer, into, asSQL, bindings...)
+	return stmt.GetRowContext(context.Background(), into)
 }
 
 // GetRowContext executes an INSERT statement with a RETURNING clause
@@ -227,23 +820,38 @@ func (stmt *InsertStmt) GetRow(into interface{}) error {
 // only one column is returned, or a struct if multiple columns
 // are returned)
 func (stmt *InsertStmt) GetRowContext(ctx context.Context, into interface{}) error {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if err := stmt.Err(); err != nil {
+		stmt.HandleError(err)
+		return err
+	}
+
 	asSQL, bindings := stmt.ToSQL(true)
 
-	return sqlx.GetContext(ctx, stmt.execer, into, asSQL, bindings...)
+	return mapErrorOf(stmt.execer, sqlx.GetContext(ctx, stmt.execer, into, asSQL, bindings...))
 }
 
 // GetAll executes an INSERT statement with a RETURNING clause
 // expected to return multiple rows, and loads the result into
 // the provided slice variable
 func (stmt *InsertStmt) GetAll(into interface{}) error {
-	asSQL, bindings := stmt.ToSQL(true)
-	return sqlx.Select(stmt.execer, into, asSQL, bindings...)
+	return stmt.GetAllContext(context.Background(), into)
 }
 
 // GetAllContext executes an INSERT statement with a RETURNING clause
 // expected to return multiple rows, and loads the result into
 // the provided slice variable
 func (stmt *InsertStmt) GetAllContext(ctx context.Context, into interface{}) error {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if err := stmt.Err(); err != nil {
+		stmt.HandleError(err)
+		return err
+	}
+
 	asSQL, bindings := stmt.ToSQL(true)
 	return sqlx.SelectContext(ctx, stmt.execer, into, asSQL, bindings...)
 }
@@ -266,6 +874,9 @@ type ConflictClause struct {
 	SetCols []string
 	SetVals []interface{}
 	Updates map[string]interface{}
+	// TargetConditions is the predicate of a partial unique index to infer
+	// as the conflict target
+	TargetConditions []WhereCondition
 }
 
 // OnConflict gets a list of targets and creates a new ConflictClause object
@@ -275,6 +886,15 @@ func OnConflict(targets ...string) *ConflictClause {
 	}
 }
 
+// Where adds conditions to the conflict target, used to infer a partial
+// unique index (e.g. "ON CONFLICT (email) WHERE deleted = false"). It is
+// only meaningful when the clause has targets. If multiple conditions are
+// passed, they are considered AND conditions.
+func (conflict *ConflictClause) Where(conds ...WhereCondition) *ConflictClause {
+	conflict.TargetConditions = append(conflict.TargetConditions, conds...)
+	return conflict
+}
+
 // DoNothing sets the conflict clause's action as DO NOTHING
 func (conflict *ConflictClause) DoNothing() *ConflictClause {
 	conflict.Action = DoNothing
@@ -327,11 +947,21 @@ func (conflict *ConflictClause) SetIf(col string, val interface{}, b bool) *Conf
 
 // ToSQL generates the SQL code for the conflict clause
 func (conflict *ConflictClause) ToSQL() (asSQL string, bindings []interface{}) {
+	return conflict.sqlFor("")
+}
+
+func (conflict *ConflictClause) sqlFor(driverName string) (asSQL string, bindings []interface{}) {
 	words := []string{"ON CONFLICT"}
 	if len(conflict.Targets) > 0 {
 		words = append(words, "("+strings.Join(conflict.Targets, ", ")+")")
 	}
 
+	if len(conflict.TargetConditions) > 0 {
+		whereSQL, whereBindings := parseConditions(conflict.TargetConditions, driverName)
+		words = append(words, "WHERE "+whereSQL)
+		bindings = append(bindings, whereBindings...)
+	}
+
 	switch conflict.Action {
 	case DoNothing:
 		words = append(words, "DO NOTHING")
@@ -356,6 +986,15 @@ func (conflict *ConflictClause) ToSQL() (asSQL string, bindings []interface{}) {
 				}
 
 				updates = append(updates, col+" = "+fn.Name+"("+strings.Join(args, ", ")+")")
+			} else if now, isNow := val.(NowExpr); isNow {
+				nowSQL, _ := now.sqlFor(driverName)
+				updates = append(updates, col+" = "+nowSQL)
+			} else if _, isDefault := val.(DefaultValue); isDefault {
+				updates = append(updates, col+" = DEFAULT")
+			} else if cast, isCast := val.(CastValue); isCast {
+				castSQL, castBindings := cast.sqlFor(driverName)
+				updates = append(updates, col+" = "+castSQL)
+				bindings = append(bindings, castBindings...)
 			} else if indirect, isIndirect := val.(IndirectValue); isIndirect {
 				updates = append(updates, col+" = "+indirect.Reference)
 				bindings = append(bindings, indirect.Bindings...)
@@ -373,8 +1012,23 @@ func (conflict *ConflictClause) ToSQL() (asSQL string, bindings []interface{}) {
 
 // parseInsertValues adds placeholders and binding for every insert value, by parsing the type of the insert value
 func parseInsertValues(insVals []interface{}) (placeholders []string, bindingsToAdd []interface{}) {
+	return parseInsertValuesFor(insVals, "")
+}
+
+// parseInsertValuesFor is the same as parseInsertValues, but renders
+// driver-specific values such as Now() in the dialect of the provided driver
+func parseInsertValuesFor(insVals []interface{}, driverName string) (placeholders []string, bindingsToAdd []interface{}) {
 	for _, val := range insVals {
-		if indirect, isIndirect := val.(IndirectValue); isIndirect {
+		if now, isNow := val.(NowExpr); isNow {
+			nowSQL, _ := now.sqlFor(driverName)
+			placeholders = append(placeholders, nowSQL)
+		} else if _, isDefault := val.(DefaultValue); isDefault {
+			placeholders = append(placeholders, "DEFAULT")
+		} else if cast, isCast := val.(CastValue); isCast {
+			castSQL, castBindings := cast.sqlFor(driverName)
+			placeholders = append(placeholders, castSQL)
+			bindingsToAdd = append(bindingsToAdd, castBindings...)
+		} else if indirect, isIndirect := val.(IndirectValue); isIndirect {
 			placeholders = append(placeholders, indirect.Reference)
 			bindingsToAdd = append(bindingsToAdd, indirect.Bindings...)
 		} else if builder, isBuilder := val.(JSONBBuilder); isBuilder {
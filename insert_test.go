@@ -1,6 +1,23 @@
 package sqlz
 
-import "testing"
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+type Timestamps struct {
+	CreatedAt int
+}
+
+type InsertUser struct {
+	ID   int    `db:"id"`
+	Name string `db:"full_name"`
+	Timestamps
+}
 
 func TestInsert(t *testing.T) {
 	runTests(t, func(dbz *DB) []test {
@@ -42,6 +59,24 @@ func TestInsert(t *testing.T) {
 				[]interface{}{},
 			},
 
+			{
+				"conditional insert of values",
+				dbz.InsertInto("table").Columns("one", "two").Values(1, Indirect("NOW()")).
+					WhereNotExists(dbz.Select("1").From("table").Where(Eq("one", 1))),
+				"INSERT INTO table (one, two) SELECT ?, NOW() WHERE NOT EXISTS (SELECT 1 FROM table WHERE one = ?)",
+				[]interface{}{1, 1},
+			},
+
+			{
+				"conditional insert from a select query",
+				dbz.InsertInto("table").Columns("one", "two").FromSelect(
+					dbz.Select().SelectExpr(Indirect("?", 1), Indirect("?", 2)).
+						Where(NotExists(dbz.Select("1").From("table").Where(Eq("one", 1)))),
+				),
+				"INSERT INTO table (one, two) SELECT ?, ? WHERE NOT EXISTS (SELECT 1 FROM table WHERE one = ?)",
+				[]interface{}{1, 2, 1},
+			},
+
 			{
 				"insert with on conflict do update",
 				dbz.InsertInto("table").Columns("name").Values("My Name").
@@ -91,6 +126,593 @@ func TestInsert(t *testing.T) {
 				"INSERT INTO table (id, name) VALUES (?, ?), (?, ?), (?, ?)",
 				[]interface{}{1, "My Name", 2, "John", 3, "Golang"},
 			},
+
+			{
+				"insert multiple rows from structs",
+				dbz.InsertInto("users").RowsFromStructs([]InsertUser{
+					{ID: 1, Name: "John", Timestamps: Timestamps{CreatedAt: 100}},
+					{ID: 2, Name: "Jane", Timestamps: Timestamps{CreatedAt: 200}},
+					{ID: 3, Name: "Jack", Timestamps: Timestamps{CreatedAt: 300}},
+				}),
+				"INSERT INTO users (id, full_name, created_at) VALUES (?, ?, ?), (?, ?, ?), (?, ?, ?)",
+				[]interface{}{1, "John", 100, 2, "Jane", 200, 3, "Jack", 300},
+			},
+		}
+	})
+}
+
+func TestInsertReturningPrevious(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		stmt, err := dbz.InsertInto("counters").
+			Columns("id", "value").
+			Values(3, 1).
+			OnConflict(OnConflict("id").DoUpdate().Set("value", Indirect("counters.value + EXCLUDED.value"))).
+			ReturningPrevious("value")
+		if err != nil {
+			t.Fatalf("Failed creating statement: %s", err)
+		}
+
+		return []test{
+			{
+				"upsert returning previous and new values",
+				stmt,
+				"WITH previous AS (SELECT value FROM counters WHERE id = $1 FOR UPDATE) " +
+					"INSERT INTO counters (id, value) VALUES ($2, $3) " +
+					"ON CONFLICT (id) DO UPDATE SET value = counters.value + EXCLUDED.value " +
+					"RETURNING value, (SELECT value FROM previous) AS previous_value",
+				[]interface{}{3, 3, 1},
+			},
+		}
+	})
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	_, err = New(db, "mysql").InsertInto("counters").
+		Columns("id", "value").
+		Values(3, 1).
+		OnConflict(OnConflict("id").DoUpdate().Set("value", 1)).
+		ReturningPrevious("value")
+	if err == nil {
+		t.Error("Expected returning previous values to fail for mysql")
+	}
+}
+
+func TestInsertRowsFromInconsistentStructs(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	stmt := New(db, "postgres").InsertInto("users").RowsFromStructs([]interface{}{
+		InsertUser{ID: 1, Name: "John"},
+		Timestamps{CreatedAt: 100},
+	})
+	if stmt.Err() == nil {
+		t.Error("Expected rows from structs with different fields to fail")
+	}
+}
+
+func TestInsertFromSelect(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(
+		"INSERT INTO archive (id, name) SELECT id, name FROM events WHERE created_at < $1",
+	)).WithArgs(1000).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM events WHERE created_at < $1")).
+		WithArgs(1000).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	err = dbz.Transactional(func(tx *Tx) error {
+		_, err := tx.InsertInto("archive").
+			Columns("id", "name").
+			FromSelect(tx.Select("id", "name").From("events").Where(Lt("created_at", 1000))).
+			Exec()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.DeleteFrom("events").Where(Lt("created_at", 1000)).Exec()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed moving rows: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	stmt := dbz.InsertInto("archive").
+		Columns("id", "name", "created_at").
+		FromSelect(dbz.Select("id", "name").From("events"))
+	if stmt.Err() == nil {
+		t.Error("Expected insert with mismatching select columns to fail")
+	}
+
+	// comma-separated columns are counted separately
+	stmt = dbz.InsertInto("archive").
+		Columns("id", "name").
+		FromSelect(dbz.Select("id, name").From("events"))
+	if err := stmt.Err(); err != nil {
+		t.Errorf("Expected comma-separated select columns to match, got %s", err)
+	}
+
+	// columns may be provided after the select statement
+	stmt = dbz.InsertInto("archive").
+		FromSelect(dbz.Select("id", "COALESCE(name, '') AS name").From("events")).
+		Columns("id").
+		Columns("name")
+	if err := stmt.Err(); err != nil {
+		t.Errorf("Expected columns provided after the select statement to match, got %s", err)
+	}
+}
+
+func TestInsertWhereNotExistsMySQL(t *testing.T) {
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"conditional insert of values",
+				dbz.InsertInto("table").Columns("one", "two").Values(1, 2).
+					WhereNotExists(dbz.Select("1").From("table").Where(Eq("one", 1))),
+				"INSERT INTO table (one, two) SELECT ?, ? FROM DUAL WHERE NOT EXISTS (SELECT 1 FROM table WHERE one = ?)",
+				[]interface{}{1, 2, 1},
+			},
+		}
+	})
+}
+
+func TestInsertWhenNotExists(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"conditional insert on a key",
+				dbz.InsertInto("users").Columns("email", "name").Values("a@b.c", "A").
+					WhenNotExists(Eq("email", "a@b.c")),
+				"INSERT INTO users (email, name) SELECT $1, $2 WHERE NOT EXISTS (SELECT 1 FROM users WHERE email = $3)",
+				[]interface{}{"a@b.c", "A", "a@b.c"},
+			},
+
+			{
+				"conditional insert on a compound condition with returning",
+				dbz.InsertInto("users").Columns("email", "org", "name").Values("a@b.c", 3, "A").
+					WhenNotExists(And(Eq("email", "a@b.c"), Eq("org", 3))).
+					Returning("id"),
+				"INSERT INTO users (email, org, name) SELECT $1, $2, $3 " +
+					"WHERE NOT EXISTS (SELECT 1 FROM users WHERE email = $4 AND org = $5) RETURNING id",
+				[]interface{}{"a@b.c", 3, "A", "a@b.c", 3},
+			},
 		}
 	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"conditional insert on a key",
+				dbz.InsertInto("users").Columns("email", "name").Values("a@b.c", "A").
+					WhenNotExists(Eq("email", "a@b.c")),
+				"INSERT INTO users (email, name) SELECT ?, ? FROM DUAL WHERE NOT EXISTS (SELECT 1 FROM users WHERE email = ?)",
+				[]interface{}{"a@b.c", "A", "a@b.c"},
+			},
+		}
+	})
+
+	if err := New(nil, "postgres").InsertInto("users").Values(1).WhenNotExists(nil).Err(); err == nil {
+		t.Error("Expected conditional insert without a condition to fail")
+	}
+}
+
+func TestInsertUpsert(t *testing.T) {
+	stmt := func(dbz *DB) *InsertStmt {
+		return dbz.InsertInto("users").
+			Columns("email", "name", "age").
+			Values("john@example.com", "John", 30).
+			Upsert([]string{"email"}, []string{"name", "age"})
+	}
+
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"postgres upsert",
+				stmt(dbz),
+				"INSERT INTO users (email, name, age) VALUES ($1, $2, $3) " +
+					"ON CONFLICT (email) DO UPDATE SET name = EXCLUDED.name, age = EXCLUDED.age",
+				[]interface{}{"john@example.com", "John", 30},
+			},
+
+			{
+				"postgres upsert without updates",
+				dbz.InsertInto("users").Columns("email").Values("john@example.com").Upsert([]string{"email"}, nil),
+				"INSERT INTO users (email) VALUES ($1) ON CONFLICT (email) DO NOTHING",
+				[]interface{}{"john@example.com"},
+			},
+		}
+	})
+
+	runDriverTests(t, "sqlite3", func(dbz *DB) []test {
+		return []test{
+			{
+				"sqlite upsert",
+				stmt(dbz),
+				"INSERT INTO users (email, name, age) VALUES (?, ?, ?) " +
+					"ON CONFLICT (email) DO UPDATE SET name = EXCLUDED.name, age = EXCLUDED.age",
+				[]interface{}{"john@example.com", "John", 30},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"mysql upsert",
+				stmt(dbz),
+				"INSERT INTO users (email, name, age) VALUES (?, ?, ?) " +
+					"ON DUPLICATE KEY UPDATE name = VALUES(name), age = VALUES(age)",
+				[]interface{}{"john@example.com", "John", 30},
+			},
+
+			{
+				"mysql upsert without updates",
+				dbz.InsertInto("users").Columns("email").Values("john@example.com").Upsert([]string{"email"}, nil),
+				"INSERT INTO users (email) VALUES (?) ON DUPLICATE KEY UPDATE email = email",
+				[]interface{}{"john@example.com"},
+			},
+		}
+	})
+
+	if err := New(nil, "mysql").InsertInto("users").Columns("email").Values("john@example.com").
+		Upsert(nil, nil).Err(); err == nil {
+		t.Error("Expected an upsert without update or conflict columns to fail on MySQL")
+	}
+
+	if err := stmt(New(nil, "sqlserver")).Err(); err == nil {
+		t.Error("Expected upserts to fail on SQL Server")
+	}
+
+	for _, driverName := range []string{"postgres", "sqlite3"} {
+		if err := New(nil, driverName).InsertInto("users").Columns("email", "name").Values("john@example.com", "John").
+			Upsert(nil, []string{"name"}).Err(); err == nil {
+			t.Errorf("Expected an upsert with update columns but no conflict columns to fail on %s", driverName)
+		}
+	}
+}
+
+func TestInsertUpsertCompositeTarget(t *testing.T) {
+	stmt := func(dbz *DB) *InsertStmt {
+		return dbz.InsertInto("members").
+			Columns("tenant_id", "email", "role").
+			Values(1, "john@example.com", "admin").
+			Upsert([]string{"tenant_id", "email"}, []string{"role"})
+	}
+
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"postgres upsert on two columns",
+				stmt(dbz),
+				"INSERT INTO members (tenant_id, email, role) VALUES ($1, $2, $3) " +
+					"ON CONFLICT (tenant_id, email) DO UPDATE SET role = EXCLUDED.role",
+				[]interface{}{1, "john@example.com", "admin"},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"mysql upsert on two columns",
+				stmt(dbz),
+				"INSERT INTO members (tenant_id, email, role) VALUES (?, ?, ?) " +
+					"ON DUPLICATE KEY UPDATE role = VALUES(role)",
+				[]interface{}{1, "john@example.com", "admin"},
+			},
+		}
+	})
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	for _, driverName := range []string{"postgres", "mysql"} {
+		err := New(db, driverName).InsertInto("members").
+			Columns("email", "role").
+			Values("john@example.com", "admin").
+			Upsert([]string{"tenant_id", "email"}, []string{"role"}).
+			Err()
+		if err == nil {
+			t.Errorf("Expected %s upsert on a column that isn't inserted to fail", driverName)
+		}
+
+		// columns may be set after calling Upsert
+		err = New(db, driverName).InsertInto("members").
+			Upsert([]string{"tenant_id", "email"}, []string{"role"}).
+			Columns("email", "role").
+			Values("john@example.com", "admin").
+			Err()
+		if err == nil {
+			t.Errorf("Expected %s upsert before Columns on a column that isn't inserted to fail", driverName)
+		}
+
+		err = New(db, driverName).InsertInto("members").
+			Upsert([]string{"tenant_id", "email"}, []string{"role"}).
+			ValueMap(map[string]interface{}{"tenant_id": 1, "email": "john@example.com", "role": "admin"}).
+			Err()
+		if err != nil {
+			t.Errorf("Expected %s upsert before ValueMap on inserted columns to pass, got %s", driverName, err)
+		}
+	}
+}
+
+func TestInsertValueMap(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+	row := map[string]interface{}{"id": 1, "name": "John", "email": "john@example.com", "age": 30, "active": true}
+
+	expectedSQL := "INSERT INTO users (active, age, email, id, name) VALUES ($1, $2, $3, $4, $5)"
+
+	for i := 0; i < 20; i++ {
+		asSQL, bindings := dbz.InsertInto("users").ValueMap(row).ToSQL(true)
+		if asSQL != expectedSQL {
+			t.Fatalf("Expected %s, got %s", expectedSQL, asSQL)
+		}
+
+		if bindings[0] != true || bindings[1] != 30 || bindings[2] != "john@example.com" || bindings[3] != 1 || bindings[4] != "John" {
+			t.Fatalf("Expected bindings aligned with sorted columns, got %v", bindings)
+		}
+	}
+
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"multiple value maps",
+				dbz.InsertInto("users").
+					ValueMap(map[string]interface{}{"name": "John", "id": 1}).
+					ValueMap(map[string]interface{}{"id": 2, "name": "Jane"}),
+				"INSERT INTO users (id, name) VALUES ($1, $2), ($3, $4)",
+				[]interface{}{1, "John", 2, "Jane"},
+			},
+		}
+	})
+
+	stmt := dbz.InsertInto("users").
+		ValueMap(map[string]interface{}{"id": 1, "name": "John"}).
+		ValueMap(map[string]interface{}{"id": 2, "email": "jane@example.com"})
+	if stmt.Err() == nil {
+		t.Error("Expected value maps with different keys to fail")
+	}
+}
+
+type InsertAccount struct {
+	ID        int64  `db:"id,omitempty"`
+	Email     string `db:"email"`
+	Plan      string `db:"plan,omitempty"`
+	Password  string `db:"-"`
+	CreatedAt int64  `db:"created_at,omitempty"`
+}
+
+func TestInsertStruct(t *testing.T) {
+	runTests(t, func(dbz *DB) []test {
+		return []test{
+			{
+				"insert struct with empty fields omitted",
+				dbz.InsertStruct("accounts", InsertAccount{Email: "john@example.com", Password: "secret", CreatedAt: 100}),
+				"INSERT INTO accounts (email, created_at) VALUES (?, ?)",
+				[]interface{}{"john@example.com", int64(100)},
+			},
+
+			{
+				"insert pointer to struct",
+				dbz.InsertStruct("accounts", &InsertAccount{ID: 3, Email: "jane@example.com", Plan: "pro"}).Returning("id"),
+				"INSERT INTO accounts (id, email, plan) VALUES (?, ?, ?) RETURNING id",
+				[]interface{}{int64(3), "jane@example.com", "pro"},
+			},
+
+			{
+				"insert slice of structs",
+				dbz.InsertStruct("accounts", []InsertAccount{
+					{Email: "john@example.com", Plan: "pro"},
+					{Email: "jane@example.com"},
+				}),
+				"INSERT INTO accounts (email, plan) VALUES (?, ?), (?, ?)",
+				[]interface{}{"john@example.com", "pro", "jane@example.com", ""},
+			},
+		}
+	})
+}
+
+func TestInsertDefaultValues(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"insert default values with returning clause",
+				dbz.InsertInto("sessions").DefaultValues().Returning("id", "created_at"),
+				"INSERT INTO sessions DEFAULT VALUES RETURNING id, created_at",
+				[]interface{}{},
+			},
+		}
+	})
+
+	runDriverTests(t, "sqlite3", func(dbz *DB) []test {
+		return []test{
+			{
+				"insert default values",
+				dbz.InsertInto("sessions").DefaultValues(),
+				"INSERT INTO sessions DEFAULT VALUES",
+				[]interface{}{},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"insert empty row",
+				dbz.InsertInto("sessions").DefaultValues(),
+				"INSERT INTO sessions () VALUES ()",
+				[]interface{}{},
+			},
+		}
+	})
+}
+
+func TestInsertOnConflictPartialIndex(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"conflict target only",
+				dbz.InsertInto("users").
+					Columns("email", "name").
+					Values("john@example.com", "John").
+					OnConflictDoNothingFor([]string{"email"}, nil),
+				"INSERT INTO users (email, name) VALUES ($1, $2) ON CONFLICT (email) DO NOTHING",
+				[]interface{}{"john@example.com", "John"},
+			},
+
+			{
+				"conflict target with partial index predicate",
+				dbz.InsertInto("users").
+					Columns("email", "name").
+					Values("john@example.com", "John").
+					OnConflictDoNothingFor([]string{"email"}, Eq("deleted", false)).
+					Returning("id"),
+				"INSERT INTO users (email, name) VALUES ($1, $2) " +
+					"ON CONFLICT (email) WHERE deleted = $3 DO NOTHING RETURNING id",
+				[]interface{}{"john@example.com", "John", false},
+			},
+
+			{
+				"update on conflict with partial index predicate",
+				dbz.InsertInto("users").
+					Columns("email", "name").
+					Values("john@example.com", "John").
+					OnConflict(
+						OnConflict("email").
+							Where(Eq("deleted", false), Eq("tenant", 3)).
+							DoUpdate().
+							Set("name", "Johnny"),
+					),
+				"INSERT INTO users (email, name) VALUES ($1, $2) " +
+					"ON CONFLICT (email) WHERE deleted = $3 AND tenant = $4 DO UPDATE SET name = $5",
+				[]interface{}{"john@example.com", "John", false, 3, "Johnny"},
+			},
+		}
+	})
+}
+
+func TestInsertAddRow(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	stmt := dbz.InsertInto("users").Columns("id", "name")
+	for i, name := range []string{"John", "Jane", "Jim"} {
+		stmt.AddRow(i+1, name)
+	}
+
+	if err := stmt.Err(); err != nil {
+		t.Fatalf("Failed adding rows: %s", err)
+	}
+
+	asSQL, bindings := stmt.ToSQL(true)
+	if expected := "INSERT INTO users (id, name) VALUES ($1, $2), ($3, $4), ($5, $6)"; asSQL != expected {
+		t.Errorf("Expected %s, got %s", expected, asSQL)
+	}
+
+	if groups := strings.Count(asSQL, "("); groups != 4 {
+		t.Errorf("Expected 3 placeholder groups, got %d", groups-1)
+	}
+
+	if !reflect.DeepEqual(bindings, []interface{}{1, "John", 2, "Jane", 3, "Jim"}) {
+		t.Errorf("Unexpected bindings %v", bindings)
+	}
+
+	asSQL, _ = dbz.InsertInto("users").Columns("id", "name").Values(1, "John").AddRow(2, "Jane").ToSQL(true)
+	if expected := "INSERT INTO users (id, name) VALUES ($1, $2), ($3, $4)"; asSQL != expected {
+		t.Errorf("Expected Values to be the first row, got %s", asSQL)
+	}
+
+	if err := dbz.InsertInto("users").Columns("id", "name").AddRow(1).Err(); err == nil {
+		t.Error("Expected a row with missing values to fail")
+	}
+}
+
+func TestInsertOutputSQLServer(t *testing.T) {
+	runDriverTests(t, "sqlserver", func(dbz *DB) []test {
+		return []test{
+			{
+				"insert with output clause",
+				dbz.InsertInto("users").Columns("email", "name").Values("john@example.com", "John").Returning("id", "created_at"),
+				"INSERT INTO users (email, name) OUTPUT INSERTED.id, INSERTED.created_at VALUES (@p1, @p2)",
+				[]interface{}{"john@example.com", "John"},
+			},
+
+			{
+				"insert from select with output clause",
+				dbz.InsertInto("archive").Columns("id").FromSelect(dbz.Select("id").From("users").Where(Eq("active", false))).Returning("id"),
+				"INSERT INTO archive (id) OUTPUT INSERTED.id SELECT id FROM users WHERE active = @p1",
+				[]interface{}{false},
+			},
+		}
+	})
+}
+
+func TestInsertDefaultValue(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"insert with a default value",
+				dbz.InsertInto("users").Columns("id", "name", "created_at").Values(Default(), "john", Default()),
+				"INSERT INTO users (id, name, created_at) VALUES (DEFAULT, $1, DEFAULT)",
+				[]interface{}{"john"},
+			},
+
+			{
+				"insert multiple rows with default values",
+				dbz.InsertInto("users").Columns("name", "role").
+					ValueMultiple([][]interface{}{{"john", Default()}, {"jane", "admin"}}),
+				"INSERT INTO users (name, role) VALUES ($1, DEFAULT), ($2, $3)",
+				[]interface{}{"john", "jane", "admin"},
+			},
+
+			{
+				"upsert resetting a column to its default",
+				dbz.InsertInto("users").Columns("email", "role").Values("a@b.c", "admin").
+					OnConflict(OnConflict("email").DoUpdate().Set("role", Default())),
+				"INSERT INTO users (email, role) VALUES ($1, $2) ON CONFLICT (email) DO UPDATE SET role = DEFAULT",
+				[]interface{}{"a@b.c", "admin"},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"insert with a value map and a default value",
+				dbz.InsertInto("users").ValueMap(map[string]interface{}{"name": "john", "role": Default()}),
+				"INSERT INTO users (name, role) VALUES (?, DEFAULT)",
+				[]interface{}{"john"},
+			},
+		}
+	})
+
+	if err := New(nil, "sqlite3").InsertInto("users").Columns("id", "name").Values(Default(), "john").Err(); err == nil {
+		t.Error("Expected DEFAULT value to fail on SQLite")
+	}
 }
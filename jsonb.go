@@ -66,3 +66,32 @@ func (b JSONBBuilder) Parse() (asSQL string, bindings []interface{}) {
 
 	return asSQL + strings.Join(placeholders, ", ") + ")", bindings
 }
+
+// JSONArrayCondition represents a condition checking that a JSON array
+// column contains a scalar value
+type JSONArrayCondition struct {
+	Column string
+	Value  interface{}
+}
+
+// JSONBArrayContains creates a condition checking that the JSON array in
+// the provided column contains the provided scalar value. In PostgreSQL,
+// this renders as "col @> to_jsonb(?)". In MySQL, it renders as
+// "JSON_CONTAINS(col, JSON_ARRAY(?))".
+func JSONBArrayContains(col string, value interface{}) JSONArrayCondition {
+	return JSONArrayCondition{col, value}
+}
+
+// Parse implements the WhereCondition interface, generating SQL from
+// the condition using PostgreSQL syntax
+func (cond JSONArrayCondition) Parse() (asSQL string, bindings []interface{}) {
+	return cond.sqlFor("")
+}
+
+func (cond JSONArrayCondition) sqlFor(driverName string) (asSQL string, bindings []interface{}) {
+	if isMySQL(driverName) {
+		return "JSON_CONTAINS(" + cond.Column + ", JSON_ARRAY(?))", []interface{}{cond.Value}
+	}
+
+	return cond.Column + " @> to_jsonb(?)", []interface{}{cond.Value}
+}
@@ -27,3 +27,27 @@ func TestJSONBBuilder(t *testing.T) {
 		}
 	})
 }
+
+func TestJSONBArrayContains(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"jsonb array contains a scalar",
+				dbz.Select("*").From("table").Where(JSONBArrayContains("tags", "go"), Eq("id", 1)),
+				"SELECT * FROM table WHERE tags @> to_jsonb($1) AND id = $2",
+				[]interface{}{"go", 1},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"json array contains a scalar",
+				dbz.Select("*").From("table").Where(Or(JSONBArrayContains("tags", "go"), Eq("id", 1))),
+				"SELECT * FROM table WHERE JSON_CONTAINS(tags, JSON_ARRAY(?)) OR id = ?",
+				[]interface{}{"go", 1},
+			},
+		}
+	})
+}
@@ -0,0 +1,340 @@
+package sqlz
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MergeStmt represents a MERGE statement, which inserts, updates or deletes
+// rows of a target table based on whether they match the rows of a source
+// (a table, a sub-query or a list of values). MERGE is supported by
+// PostgreSQL (15 and above) and SQL Server, but not by MySQL and SQLite.
+type MergeStmt struct {
+	*Statement
+	Table          string
+	Source         string
+	SourceStmt     *SelectStmt
+	SourceColumns  []string
+	SourceRows     [][]interface{}
+	Conditions     []WhereCondition
+	MatchedUpdates map[string]interface{}
+	MatchedDelete  bool
+	InsertColumns  []string
+	InsertValues   []interface{}
+	execer         Ext
+}
+
+// MergeInto creates a new MergeStmt object for the provided target table
+func (db *DB) MergeInto(table string) *MergeStmt {
+	return &MergeStmt{
+		Table:     table,
+		execer:    db.handle(),
+		Statement: &Statement{ErrHandlers: db.ErrHandlers},
+	}
+}
+
+// MergeInto creates a new MergeStmt object for the provided target table
+func (tx *Tx) MergeInto(table string) *MergeStmt {
+	return &MergeStmt{
+		Table:     table,
+		execer:    tx.handle(),
+		Statement: &Statement{ErrHandlers: tx.ErrHandlers},
+	}
+}
+
+// MergeFromValues creates a MERGE statement that upserts the provided rows
+// into the target table. Every row holds values for the provided columns,
+// in order, and rows are matched with existing ones by keyCols (which must
+// be included in cols). Matched rows have their non-key columns updated,
+// and rows that weren't matched are inserted, e.g.:
+//
+//	MERGE INTO t USING (VALUES (?, ?), (?, ?)) AS s(id, name) ON t.id = s.id
+//	WHEN MATCHED THEN UPDATE SET name = s.name
+//	WHEN NOT MATCHED THEN INSERT (id, name) VALUES (s.id, s.name)
+//
+// The types of the source columns are resolved from their values, and
+// PostgreSQL treats parameters of unknown type as text, which fails when
+// they are assigned to or compared with typed columns of the target table.
+// To set the types, wrap the values of the first row with Cast (see
+// UsingValues).
+func (db *DB) MergeFromValues(target string, keyCols, cols []string, rows [][]interface{}) *MergeStmt {
+	return db.MergeInto(target).fromValues(keyCols, cols, rows)
+}
+
+// MergeFromValues creates a MERGE statement that upserts the provided rows
+// into the target table (see DB.MergeFromValues)
+func (tx *Tx) MergeFromValues(target string, keyCols, cols []string, rows [][]interface{}) *MergeStmt {
+	return tx.MergeInto(target).fromValues(keyCols, cols, rows)
+}
+
+func (stmt *MergeStmt) fromValues(keyCols, cols []string, rows [][]interface{}) *MergeStmt {
+	isKey := make(map[string]bool, len(keyCols))
+	for _, col := range keyCols {
+		isKey[col] = true
+	}
+
+	var found int
+
+	updates := make(map[string]interface{})
+	values := make([]interface{}, len(cols))
+
+	for i, col := range cols {
+		if isKey[col] {
+			found++
+		} else {
+			updates[col] = Indirect("s." + col)
+		}
+
+		values[i] = Indirect("s." + col)
+	}
+
+	if len(keyCols) == 0 || found != len(keyCols) {
+		stmt.setErr(errors.New("key columns must be a non-empty subset of the merged columns"))
+		return stmt
+	}
+
+	// the target's alias (if any) is the last word of the table name
+	target := stmt.Table[strings.LastIndex(stmt.Table, " ")+1:]
+
+	conds := make([]WhereCondition, len(keyCols))
+	for i, col := range keyCols {
+		conds[i] = SQLCond(target + "." + col + " = s." + col)
+	}
+
+	stmt.UsingValues("s", cols, rows).On(conds...)
+
+	if len(updates) > 0 {
+		stmt.WhenMatchedUpdate(updates)
+	}
+
+	return stmt.WhenNotMatchedInsert(cols, values...)
+}
+
+// Using sets the source of the MERGE statement to a table, which may be
+// aliased (e.g. "src s")
+func (stmt *MergeStmt) Using(table string) *MergeStmt {
+	stmt.Source = table
+	return stmt
+}
+
+// UsingSelect sets the source of the MERGE statement to the results of a
+// sub-query, referenced by the provided alias
+func (stmt *MergeStmt) UsingSelect(selStmt *SelectStmt, alias string) *MergeStmt {
+	stmt.Source = alias
+	stmt.SourceStmt = selStmt
+
+	return stmt
+}
+
+// UsingValues sets the source of the MERGE statement to a list of rows,
+// generating "USING (VALUES (?, ?), ...) AS alias(col1, col2)". Every row
+// must hold a value for each of the provided columns, in order. To set
+// the types of the columns in PostgreSQL, wrap the values of the first row
+// with Cast, e.g. []interface{}{Cast(id, "bigint"), Cast(at, "timestamptz")}.
+func (stmt *MergeStmt) UsingValues(alias string, cols []string, rows [][]interface{}) *MergeStmt {
+	if len(rows) == 0 {
+		stmt.setErr(errors.New("no rows provided to merge"))
+		return stmt
+	}
+
+	for i, row := range rows {
+		if len(row) != len(cols) {
+			stmt.setErr(fmt.Errorf("row %d has %d values, expected %d", i, len(row), len(cols)))
+			return stmt
+		}
+	}
+
+	stmt.Source = alias
+	stmt.SourceColumns = cols
+	stmt.SourceRows = rows
+
+	return stmt
+}
+
+// WithTimeout sets a timeout for executing the MERGE statement (see
+// SelectStmt.WithTimeout).
+func (stmt *MergeStmt) WithTimeout(timeout time.Duration) *MergeStmt {
+	stmt.timeout = timeout
+	return stmt
+}
+
+// Comment adds a comment with the provided tag to the start of the MERGE
+// statement's SQL (see SelectStmt.Comment).
+func (stmt *MergeStmt) Comment(tag string) *MergeStmt {
+	stmt.comment = tag
+	return stmt
+}
+
+// On sets the conditions matching rows of the source with rows of the
+// target table. If multiple conditions are passed, they are considered
+// AND conditions.
+func (stmt *MergeStmt) On(conds ...WhereCondition) *MergeStmt {
+	stmt.Conditions = append(stmt.Conditions, conds...)
+	return stmt
+}
+
+// WhenMatchedUpdate updates the columns of matched rows of the target
+// table. Use Indirect to reference columns of the source.
+func (stmt *MergeStmt) WhenMatchedUpdate(updates map[string]interface{}) *MergeStmt {
+	if stmt.MatchedUpdates == nil {
+		stmt.MatchedUpdates = make(map[string]interface{})
+	}
+
+	for col, val := range updates {
+		stmt.MatchedUpdates[col] = val
+	}
+
+	return stmt
+}
+
+// WhenMatchedDelete deletes matched rows of the target table, instead of
+// updating them
+func (stmt *MergeStmt) WhenMatchedDelete() *MergeStmt {
+	stmt.MatchedDelete = true
+	return stmt
+}
+
+// WhenNotMatchedInsert inserts a row to the target table for every row of
+// the source that wasn't matched. Use Indirect to reference columns of the
+// source.
+func (stmt *MergeStmt) WhenNotMatchedInsert(cols []string, vals ...interface{}) *MergeStmt {
+	stmt.InsertColumns = cols
+	stmt.InsertValues = vals
+
+	return stmt
+}
+
+// Err returns the first error encountered while building the statement, or
+// an error if the database driver doesn't support MERGE statements. In
+// MySQL and SQLite, InsertStmt.Upsert can be used instead to insert or
+// update rows.
+func (stmt *MergeStmt) Err() error {
+	if err := stmt.Statement.Err(); err != nil {
+		return err
+	}
+
+	if driverName := driverNameOf(stmt.execer); isMySQL(driverName) || isSQLite(driverName) {
+		return fmt.Errorf(
+			"MERGE statements are not supported by the %s driver, use InsertStmt.Upsert instead",
+			driverName,
+		)
+	}
+
+	if len(stmt.InsertValues) != len(stmt.InsertColumns) {
+		return fmt.Errorf(
+			"MERGE insert has %d columns but %d values",
+			len(stmt.InsertColumns), len(stmt.InsertValues),
+		)
+	}
+
+	return nil
+}
+
+// ToSQL generates the MERGE statement's SQL and returns a list of
+// bindings. It is used internally by Exec, but is exported if you
+// wish to use it directly.
+func (stmt *MergeStmt) ToSQL(rebind bool) (asSQL string, bindings []interface{}) {
+	driverName := driverNameOf(stmt.execer)
+	clauses := []string{"MERGE INTO " + stmt.Table}
+
+	switch {
+	case stmt.SourceStmt != nil:
+		selectSQL, selectBindings := stmt.SourceStmt.ToSQL(false)
+		clauses = append(clauses, "USING ("+selectSQL+") AS "+stmt.Source)
+		bindings = append(bindings, selectBindings...)
+	case len(stmt.SourceRows) > 0:
+		rows := make([]string, len(stmt.SourceRows))
+
+		for i, row := range stmt.SourceRows {
+			placeholders, rowBindings := parseInsertValuesFor(row, driverName)
+			rows[i] = "(" + strings.Join(placeholders, ", ") + ")"
+			bindings = append(bindings, rowBindings...)
+		}
+
+		clauses = append(clauses, "USING (VALUES "+strings.Join(rows, ", ")+") AS "+
+			stmt.Source+"("+strings.Join(stmt.SourceColumns, ", ")+")")
+	default:
+		clauses = append(clauses, "USING "+stmt.Source)
+	}
+
+	if len(stmt.Conditions) > 0 {
+		onClause, onBindings := parseConditions(stmt.Conditions, driverName)
+		clauses = append(clauses, "ON "+onClause)
+		bindings = append(bindings, onBindings...)
+	}
+
+	if stmt.MatchedDelete {
+		clauses = append(clauses, "WHEN MATCHED THEN DELETE")
+	} else if len(stmt.MatchedUpdates) > 0 {
+		var updates []string
+
+		for _, col := range sortKeys(stmt.MatchedUpdates) {
+			placeholders, updateBindings := parseInsertValuesFor([]interface{}{stmt.MatchedUpdates[col]}, driverName)
+			updates = append(updates, col+" = "+placeholders[0])
+			bindings = append(bindings, updateBindings...)
+		}
+
+		clauses = append(clauses, "WHEN MATCHED THEN UPDATE SET "+strings.Join(updates, ", "))
+	}
+
+	if len(stmt.InsertColumns) > 0 {
+		placeholders, insertBindings := parseInsertValuesFor(stmt.InsertValues, driverName)
+		clauses = append(clauses, "WHEN NOT MATCHED THEN INSERT ("+strings.Join(stmt.InsertColumns, ", ")+
+			") VALUES ("+strings.Join(placeholders, ", ")+")")
+		bindings = append(bindings, insertBindings...)
+	}
+
+	asSQL = strings.Join(clauses, " ")
+
+	// SQL Server requires MERGE statements to be terminated with a semicolon
+	if isSQLServer(driverName) {
+		asSQL += ";"
+	}
+
+	if rebind {
+		asSQL = stmt.commented(stmt.execer, rebindFor(stmt.execer, asSQL))
+	}
+
+	return asSQL, bindings
+}
+
+// ToSQLChecked generates the MERGE statement's SQL like ToSQL, but fails
+// if the number of placeholders doesn't match the number of bindings
+func (stmt *MergeStmt) ToSQLChecked(rebind bool) (asSQL string, bindings []interface{}, err error) {
+	return checkedSQL(stmt, rebind)
+}
+
+// Args returns the values bound to the statement (see SelectStmt.Args)
+func (stmt *MergeStmt) Args() []interface{} {
+	_, bindings := stmt.ToSQL(false)
+	return bindings
+}
+
+// Exec executes the MERGE statement, returning the standard sql.Result
+// struct and an error if the query failed.
+func (stmt *MergeStmt) Exec() (res sql.Result, err error) {
+	return stmt.ExecContext(context.Background())
+}
+
+// ExecContext executes the MERGE statement, returning the standard
+// sql.Result struct and an error if the query failed.
+func (stmt *MergeStmt) ExecContext(ctx context.Context) (res sql.Result, err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if err = stmt.Err(); err != nil {
+		stmt.HandleError(err)
+		return res, err
+	}
+
+	asSQL, bindings := stmt.ToSQL(true)
+
+	res, err = stmt.execer.ExecContext(ctx, asSQL, bindings...)
+	stmt.HandleError(err)
+
+	return res, err
+}
@@ -0,0 +1,98 @@
+package sqlz
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestMerge(t *testing.T) {
+	rows := [][]interface{}{
+		{1, "one", 10},
+		{2, "two", 20},
+	}
+
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"merge-upsert from values",
+				dbz.MergeFromValues("items", []string{"id"}, []string{"id", "name", "rank"}, rows),
+				"MERGE INTO items USING (VALUES ($1, $2, $3), ($4, $5, $6)) AS s(id, name, rank) " +
+					"ON items.id = s.id " +
+					"WHEN MATCHED THEN UPDATE SET name = s.name, rank = s.rank " +
+					"WHEN NOT MATCHED THEN INSERT (id, name, rank) VALUES (s.id, s.name, s.rank)",
+				[]interface{}{1, "one", 10, 2, "two", 20},
+			},
+
+			{
+				"merge-upsert from typed values",
+				dbz.MergeFromValues("items", []string{"id"}, []string{"id", "name"}, [][]interface{}{
+					{Cast(1, "bigint"), "one"},
+					{2, "two"},
+				}),
+				"MERGE INTO items USING (VALUES ($1::bigint, $2), ($3, $4)) AS s(id, name) " +
+					"ON items.id = s.id " +
+					"WHEN MATCHED THEN UPDATE SET name = s.name " +
+					"WHEN NOT MATCHED THEN INSERT (id, name) VALUES (s.id, s.name)",
+				[]interface{}{1, "one", 2, "two"},
+			},
+
+			{
+				"merge from a table",
+				dbz.MergeInto("items i").
+					Using("staged s").
+					On(SQLCond("i.id = s.id"), Eq("s.batch", 4)).
+					WhenMatchedUpdate(map[string]interface{}{"name": Indirect("s.name"), "synced": true}).
+					WhenNotMatchedInsert([]string{"id", "name", "synced"}, Indirect("s.id"), Indirect("s.name"), true),
+				"MERGE INTO items i USING staged s ON i.id = s.id AND s.batch = $1 " +
+					"WHEN MATCHED THEN UPDATE SET name = s.name, synced = $2 " +
+					"WHEN NOT MATCHED THEN INSERT (id, name, synced) VALUES (s.id, s.name, $3)",
+				[]interface{}{4, true, true},
+			},
+		}
+	})
+
+	runDriverTests(t, "sqlserver", func(dbz *DB) []test {
+		return []test{
+			{
+				"merge-upsert from values",
+				dbz.MergeFromValues("items t", []string{"id"}, []string{"id", "name", "rank"}, rows),
+				"MERGE INTO items t USING (VALUES (@p1, @p2, @p3), (@p4, @p5, @p6)) AS s(id, name, rank) " +
+					"ON t.id = s.id " +
+					"WHEN MATCHED THEN UPDATE SET name = s.name, rank = s.rank " +
+					"WHEN NOT MATCHED THEN INSERT (id, name, rank) VALUES (s.id, s.name, s.rank);",
+				[]interface{}{1, "one", 10, 2, "two", 20},
+			},
+
+			{
+				"merge from a table",
+				dbz.MergeInto("items i").
+					Using("staged s").
+					On(SQLCond("i.id = s.id")).
+					WhenMatchedUpdate(map[string]interface{}{"name": Indirect("s.name"), "synced": true}).
+					WhenNotMatchedInsert([]string{"id", "name", "synced"}, Indirect("s.id"), Indirect("s.name"), true),
+				"MERGE INTO items i USING staged s ON i.id = s.id " +
+					"WHEN MATCHED THEN UPDATE SET name = s.name, synced = @p1 " +
+					"WHEN NOT MATCHED THEN INSERT (id, name, synced) VALUES (s.id, s.name, @p2);",
+				[]interface{}{true, true},
+			},
+		}
+	})
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	if stmt := New(db, "postgres").MergeFromValues("items", []string{"key"}, []string{"id", "name"}, rows); stmt.Err() == nil {
+		t.Error("Expected merging with a key column that isn't merged to fail")
+	}
+
+	for _, driverName := range []string{"mysql", "sqlite3"} {
+		_, err = New(db, driverName).MergeFromValues("items", []string{"id"}, []string{"id", "name", "rank"}, rows).Exec()
+		if err == nil || !strings.Contains(err.Error(), "Upsert") {
+			t.Errorf("Expected merging on %s to fail suggesting an upsert, got %v", driverName, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package sqlz
+
+import "database/sql"
+
+// The Must* methods in this file are variants of the statement execution
+// methods that panic instead of returning an error. They are meant for test
+// code, migrations and other setup code where any error should abort, and
+// should not be used in regular application code.
+
+// mustNot panics if the provided error is not nil
+func mustNot(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// mustResult panics if the provided error is not nil, and otherwise returns
+// the provided result
+func mustResult(res sql.Result, err error) sql.Result {
+	mustNot(err)
+	return res
+}
+
+// MustGetRow is like GetRow, but panics on error, including sql.ErrNoRows.
+func (stmt *SelectStmt) MustGetRow(into interface{}) {
+	mustNot(stmt.GetRow(into))
+}
+
+// MustGetAll is like GetAll, but panics on error.
+func (stmt *SelectStmt) MustGetAll(into interface{}) {
+	mustNot(stmt.GetAll(into))
+}
+
+// MustExec is like Exec, but panics on error.
+func (stmt *InsertStmt) MustExec() sql.Result {
+	return mustResult(stmt.Exec())
+}
+
+// MustGetRow is like GetRow, but panics on error, including sql.ErrNoRows.
+func (stmt *InsertStmt) MustGetRow(into interface{}) {
+	mustNot(stmt.GetRow(into))
+}
+
+// MustGetAll is like GetAll, but panics on error.
+func (stmt *InsertStmt) MustGetAll(into interface{}) {
+	mustNot(stmt.GetAll(into))
+}
+
+// MustExec is like Exec, but panics on error.
+func (stmt *UpdateStmt) MustExec() sql.Result {
+	return mustResult(stmt.Exec())
+}
+
+// MustGetRow is like GetRow, but panics on error, including sql.ErrNoRows.
+func (stmt *UpdateStmt) MustGetRow(into interface{}) {
+	mustNot(stmt.GetRow(into))
+}
+
+// MustGetAll is like GetAll, but panics on error.
+func (stmt *UpdateStmt) MustGetAll(into interface{}) {
+	mustNot(stmt.GetAll(into))
+}
+
+// MustExec is like Exec, but panics on error.
+func (stmt *DeleteStmt) MustExec() sql.Result {
+	return mustResult(stmt.Exec())
+}
+
+// MustGetRow is like GetRow, but panics on error, including sql.ErrNoRows.
+func (stmt *DeleteStmt) MustGetRow(into interface{}) {
+	mustNot(stmt.GetRow(into))
+}
+
+// MustGetAll is like GetAll, but panics on error.
+func (stmt *DeleteStmt) MustGetAll(into interface{}) {
+	mustNot(stmt.GetAll(into))
+}
+
+// MustExec is like Exec, but panics on error.
+func (stmt *MergeStmt) MustExec() sql.Result {
+	return mustResult(stmt.Exec())
+}
+
+// MustExec is like Exec, but panics on error.
+func (stmt *TruncateStmt) MustExec() sql.Result {
+	return mustResult(stmt.Exec())
+}
+
+// MustExec is like Exec, but panics on error.
+func (stmt *WithStmt) MustExec() sql.Result {
+	return mustResult(stmt.Exec())
+}
+
+// MustGetRow is like GetRow, but panics on error, including sql.ErrNoRows.
+func (stmt *WithStmt) MustGetRow(into interface{}) {
+	mustNot(stmt.GetRow(into))
+}
+
+// MustGetAll is like GetAll, but panics on error.
+func (stmt *WithStmt) MustGetAll(into interface{}) {
+	mustNot(stmt.GetAll(into))
+}
@@ -0,0 +1,81 @@
+package sqlz
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func expectPanic(t *testing.T, name string, f func()) {
+	t.Helper()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected %s to panic", name)
+		}
+	}()
+
+	f()
+}
+
+func TestMust(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+	failure := errors.New("connection reset")
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM users WHERE id = $1")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM users WHERE id = $1")).
+		WithArgs(2).
+		WillReturnError(failure)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM users WHERE id = $1")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM users WHERE id = $1")).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM users")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM users")).
+		WillReturnError(failure)
+
+	res := dbz.DeleteFrom("users").Where(Eq("id", 1)).MustExec()
+	if affected, _ := res.RowsAffected(); affected != 1 {
+		t.Errorf("Expected 1 affected row, got %d", affected)
+	}
+
+	expectPanic(t, "MustExec", func() {
+		dbz.DeleteFrom("users").Where(Eq("id", 2)).MustExec()
+	})
+
+	var id int64
+	dbz.Select("id").From("users").Where(Eq("id", 1)).MustGetRow(&id)
+	if id != 1 {
+		t.Errorf("Expected id 1, got %d", id)
+	}
+
+	expectPanic(t, "MustGetRow", func() {
+		dbz.Select("id").From("users").Where(Eq("id", 2)).MustGetRow(&id)
+	})
+
+	var ids []int64
+	dbz.Select("id").From("users").MustGetAll(&ids)
+	if len(ids) != 2 {
+		t.Errorf("Expected 2 ids, got %d", len(ids))
+	}
+
+	expectPanic(t, "MustGetAll", func() {
+		dbz.Select("id").From("users").MustGetAll(&ids)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
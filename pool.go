@@ -0,0 +1,66 @@
+package sqlz
+
+// Reset clears all clauses, conditions and bindings of the statement, so
+// that it can be reused to build a new query instead of allocating a new
+// one, and returns it for chaining. The statement remains attached to the
+// DB or Tx it was created with, and keeps its error handlers, but any error
+// or timeout set on it is discarded. A reset statement generates the same
+// SQL as a statement freshly created with Select() would. Statements
+// previously cloned from it are not affected.
+func (stmt *SelectStmt) Reset() *SelectStmt {
+	var errHandlers []func(err error)
+	if stmt.Statement != nil {
+		errHandlers = stmt.Statement.ErrHandlers
+	}
+
+	// slices that are commonly appended to are truncated rather than
+	// discarded, so that their backing arrays are reused
+	*stmt = SelectStmt{
+		queryer:         stmt.queryer,
+		Columns:         stmt.Columns[:0],
+		SelectExprs:     stmt.SelectExprs[:0],
+		Joins:           stmt.Joins[:0],
+		Conditions:      stmt.Conditions[:0],
+		Ordering:        stmt.Ordering[:0],
+		Grouping:        stmt.Grouping[:0],
+		GroupConditions: stmt.GroupConditions[:0],
+		Statement:       &Statement{ErrHandlers: errHandlers},
+	}
+
+	return stmt
+}
+
+// SelectPooled is the same as Select, but takes the statement from a pool
+// of reusable statements rather than allocating a new one, which reduces
+// garbage collection pressure when building many queries in a tight loop.
+// Once the statement was executed (or is otherwise no longer needed), it
+// should be returned to the pool with Release, after which it must not be
+// used anymore.
+func (db *DB) SelectPooled(cols ...string) *SelectStmt {
+	stmt, ok := db.selectPool.Get().(*SelectStmt)
+	if !ok {
+		return db.Select(cols...)
+	}
+
+	stmt.Columns = append(stmt.Columns, cols...)
+
+	return stmt
+}
+
+// Release resets a statement created with SelectPooled and returns it to
+// the pool, so that it can be reused by subsequent calls to SelectPooled.
+// The statement must not be used after it was released. Statements that
+// were not created by this DB (e.g. statements of a transaction, or of
+// another DB) are not returned to the pool, as they would execute outside
+// of it.
+func (db *DB) Release(stmt *SelectStmt) {
+	if stmt == nil {
+		return
+	}
+
+	if h, ok := stmt.queryer.(*handle); !ok || h.db != db || h.Ext != db.DB {
+		return
+	}
+
+	db.selectPool.Put(stmt.Reset())
+}
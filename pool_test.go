@@ -0,0 +1,129 @@
+package sqlz
+
+import (
+	"context"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func buildPooledQuery(stmt *SelectStmt, i int) *SelectStmt {
+	return stmt.
+		From("users u").
+		LeftJoin("orders o", Eq("o.user_id", Indirect("u.id"))).
+		Where(Eq("u.active", true), Gt("u.id", i)).
+		GroupBy("u.id").
+		Having(Gt("COUNT(o.id)", 1)).
+		OrderBy(Desc("u.id")).
+		Limit(10)
+}
+
+func TestSelectReset(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	reused := dbz.Select("u.id", "COUNT(o.id)").
+		From("accounts").
+		Distinct().
+		Where(Eq("deleted", false)).
+		Offset(20).
+		Lock(ForUpdate())
+
+	for i := 0; i < 3; i++ {
+		reused.Reset()
+		reused.Columns = append(reused.Columns, "u.id", "COUNT(o.id)")
+
+		expectedSQL, expectedBindings := buildPooledQuery(dbz.Select("u.id", "COUNT(o.id)"), i).ToSQL(true)
+		resultingSQL, resultingBindings := buildPooledQuery(reused, i).ToSQL(true)
+
+		if resultingSQL != expectedSQL {
+			t.Errorf("Expected reset statement to generate %s, got %s", expectedSQL, resultingSQL)
+		}
+
+		if len(resultingBindings) != len(expectedBindings) {
+			t.Fatalf("Expected %d bindings, got %d", len(expectedBindings), len(resultingBindings))
+		}
+
+		for j := range expectedBindings {
+			if resultingBindings[j] != expectedBindings[j] {
+				t.Errorf("Expected binding %d to be %v, got %v", j+1, expectedBindings[j], resultingBindings[j])
+			}
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		stmt := buildPooledQuery(dbz.SelectPooled("u.id", "COUNT(o.id)"), i)
+
+		expectedSQL, _ := buildPooledQuery(dbz.Select("u.id", "COUNT(o.id)"), i).ToSQL(true)
+		if resultingSQL, _ := stmt.ToSQL(true); resultingSQL != expectedSQL {
+			t.Errorf("Expected pooled statement to generate %s, got %s", expectedSQL, resultingSQL)
+		}
+
+		dbz.Release(stmt)
+	}
+}
+
+func TestReleaseForeignStatement(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	mock.ExpectBegin()
+
+	tx, err := dbz.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Failed starting transaction: %s", err)
+	}
+
+	txStmt := tx.Select("*").From("users")
+	dbz.Release(txStmt)
+
+	otherStmt := New(db, "postgres").Select("*").From("users")
+	dbz.Release(otherStmt)
+
+	for i := 0; i < 3; i++ {
+		stmt := dbz.SelectPooled("*")
+		if stmt == txStmt || stmt == otherStmt {
+			t.Fatal("Expected statements of other handles not to be pooled")
+		}
+	}
+}
+
+func BenchmarkSelectFresh(b *testing.B) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		b.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		buildPooledQuery(dbz.Select("u.id", "COUNT(o.id)"), i)
+	}
+}
+
+func BenchmarkSelectPooled(b *testing.B) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		b.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		dbz.Release(buildPooledQuery(dbz.SelectPooled("u.id", "COUNT(o.id)"), i))
+	}
+}
@@ -0,0 +1,430 @@
+package sqlz
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// structMapper maps result columns to struct fields by their "db" tags,
+// falling back to the snake_cased names of the fields
+var structMapper = reflectx.NewMapperFunc("db", toSnakeCase)
+
+// GetAllStructs executes the SELECT statement and loads all the results
+// into the provided pointer to a slice of structs (or of pointers to
+// structs). Result columns are mapped to struct fields by their "db" tags,
+// or otherwise by their snake_cased names (e.g. a field named UserID is
+// mapped to a column named user_id). Fields of embedded structs are mapped
+// as if they belonged to the outer struct, and pointer fields are set to
+// nil when the column is NULL. If strict is true, an error is returned if
+// a result column has no matching field; otherwise such columns are
+// ignored.
+func (stmt *SelectStmt) GetAllStructs(into interface{}, strict bool) error {
+	return stmt.GetAllStructsContext(context.Background(), into, strict)
+}
+
+// GetAllStructsContext is the same as GetAllStructs, but receives a
+// context.
+func (stmt *SelectStmt) GetAllStructsContext(ctx context.Context, into interface{}, strict bool) (err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	defer func() { stmt.HandleError(err) }()
+
+	if err = stmt.beforeExec(ctx); err != nil {
+		return err
+	}
+
+	asSQL, bindings := stmt.ToSQL(true)
+
+	rows, err := stmt.queryer.QueryContext(ctx, asSQL, bindings...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	return scanStructs(rows, into, strict)
+}
+
+// GetRowStruct executes an INSERT statement with a RETURNING clause and
+// loads the returned row into the provided pointer to a struct, mapping
+// columns to fields the same way SelectStmt.GetAllStructs does. An error is
+// returned if a returned column has no matching field, or if the database
+// driver doesn't support RETURNING clauses.
+func (stmt *InsertStmt) GetRowStruct(into interface{}) error {
+	return stmt.GetRowStructContext(context.Background(), into)
+}
+
+// GetRowStructContext is the same as GetRowStruct, but receives a context.
+func (stmt *InsertStmt) GetRowStructContext(ctx context.Context, into interface{}) (err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	defer func() { stmt.HandleError(err) }()
+
+	if err = stmt.Err(); err != nil {
+		return err
+	}
+
+	return returningStruct(ctx, stmt.execer, stmt, into)
+}
+
+// GetRowStruct executes an UPDATE statement with a RETURNING clause and
+// loads the first returned row into the provided pointer to a struct (see
+// InsertStmt.GetRowStruct).
+func (stmt *UpdateStmt) GetRowStruct(into interface{}) error {
+	return stmt.GetRowStructContext(context.Background(), into)
+}
+
+// GetRowStructContext is the same as GetRowStruct, but receives a context.
+func (stmt *UpdateStmt) GetRowStructContext(ctx context.Context, into interface{}) (err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	defer func() { stmt.HandleError(err) }()
+
+	if err = stmt.Err(); err != nil {
+		return err
+	}
+
+	return returningStruct(ctx, stmt.execer, stmt, into)
+}
+
+// GetRowStruct executes a DELETE statement with a RETURNING clause and
+// loads the first returned row into the provided pointer to a struct (see
+// InsertStmt.GetRowStruct).
+func (stmt *DeleteStmt) GetRowStruct(into interface{}) error {
+	return stmt.GetRowStructContext(context.Background(), into)
+}
+
+// GetRowStructContext is the same as GetRowStruct, but receives a context.
+func (stmt *DeleteStmt) GetRowStructContext(ctx context.Context, into interface{}) (err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	defer func() { stmt.HandleError(err) }()
+
+	if err = stmt.Err(); err != nil {
+		return err
+	}
+
+	return returningStruct(ctx, stmt.execer, stmt, into)
+}
+
+// GetAllStructs executes an INSERT statement with a RETURNING clause (e.g.
+// Returning("*")) and loads all returned rows into the provided pointer to
+// a slice of structs (or of pointers to structs), mapping columns to fields
+// the same way SelectStmt.GetAllStructs does. An error is returned if the
+// database driver doesn't support RETURNING clauses.
+func (stmt *InsertStmt) GetAllStructs(into interface{}, strict bool) error {
+	return stmt.GetAllStructsContext(context.Background(), into, strict)
+}
+
+// GetAllStructsContext is the same as GetAllStructs, but receives a
+// context.
+func (stmt *InsertStmt) GetAllStructsContext(ctx context.Context, into interface{}, strict bool) (err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	defer func() { stmt.HandleError(err) }()
+
+	if err = stmt.Err(); err != nil {
+		return err
+	}
+
+	return returningStructs(ctx, stmt.execer, stmt, into, strict)
+}
+
+// GetAllStructs executes an UPDATE statement with a RETURNING clause and
+// loads all returned rows into the provided pointer to a slice of structs
+// (see InsertStmt.GetAllStructs).
+func (stmt *UpdateStmt) GetAllStructs(into interface{}, strict bool) error {
+	return stmt.GetAllStructsContext(context.Background(), into, strict)
+}
+
+// GetAllStructsContext is the same as GetAllStructs, but receives a
+// context.
+func (stmt *UpdateStmt) GetAllStructsContext(ctx context.Context, into interface{}, strict bool) (err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	defer func() { stmt.HandleError(err) }()
+
+	if err = stmt.Err(); err != nil {
+		return err
+	}
+
+	return returningStructs(ctx, stmt.execer, stmt, into, strict)
+}
+
+// GetAllStructs executes a DELETE statement with a RETURNING clause and
+// loads all returned rows into the provided pointer to a slice of structs
+// (see InsertStmt.GetAllStructs).
+func (stmt *DeleteStmt) GetAllStructs(into interface{}, strict bool) error {
+	return stmt.GetAllStructsContext(context.Background(), into, strict)
+}
+
+// GetAllStructsContext is the same as GetAllStructs, but receives a
+// context.
+func (stmt *DeleteStmt) GetAllStructsContext(ctx context.Context, into interface{}, strict bool) (err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	defer func() { stmt.HandleError(err) }()
+
+	if err = stmt.Err(); err != nil {
+		return err
+	}
+
+	return returningStructs(ctx, stmt.execer, stmt, into, strict)
+}
+
+// returningRows executes a statement with a RETURNING clause, returning
+// the returned rows
+func returningRows(ctx context.Context, execer Ext, stmt SQLStmt) (*sql.Rows, error) {
+	if driverName := driverNameOf(execer); isMySQL(driverName) {
+		return nil, fmt.Errorf("RETURNING clauses are not supported by the %s driver", driverName)
+	}
+
+	asSQL, bindings := stmt.ToSQL(true)
+
+	return execer.QueryContext(ctx, asSQL, bindings...)
+}
+
+// returningStruct executes a statement with a RETURNING clause, loading
+// the first returned row into the provided pointer to a struct
+func returningStruct(ctx context.Context, execer Ext, stmt SQLStmt, into interface{}) error {
+	rows, err := returningRows(ctx, execer, stmt)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	return scanStruct(rows, into)
+}
+
+// returningStructs executes a statement with a RETURNING clause, loading
+// all returned rows into the provided pointer to a slice of structs
+func returningStructs(ctx context.Context, execer Ext, stmt SQLStmt, into interface{}, strict bool) error {
+	rows, err := returningRows(ctx, execer, stmt)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	return scanStructs(rows, into, strict)
+}
+
+// scanStruct loads the first row into the provided pointer to a struct. If
+// there are no rows, sql.ErrNoRows is returned.
+func scanStruct(rows *sql.Rows, into interface{}) error {
+	val := reflect.ValueOf(into)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return errors.New("destination must be a pointer to a struct")
+	}
+
+	traversals, err := structTraversals(rows, val.Elem().Type(), true)
+	if err != nil {
+		return err
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		return sql.ErrNoRows
+	}
+
+	if err := rows.Scan(structDests(val.Elem(), traversals)...); err != nil {
+		return err
+	}
+
+	return rows.Close()
+}
+
+// scanStructs loads all rows into the provided pointer to a slice of structs
+func scanStructs(rows *sql.Rows, into interface{}, strict bool) error {
+	val := reflect.ValueOf(into)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Slice {
+		return errors.New("destination must be a pointer to a slice of structs")
+	}
+
+	slice := val.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+
+	structType := reflectx.Deref(elemType)
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("destination must be a pointer to a slice of structs, got slice of %s", elemType)
+	}
+
+	traversals, err := structTraversals(rows, structType, strict)
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		elem := reflect.New(structType)
+
+		if err := rows.Scan(structDests(elem.Elem(), traversals)...); err != nil {
+			return err
+		}
+
+		if isPtr {
+			slice.Set(reflect.Append(slice, elem))
+		} else {
+			slice.Set(reflect.Append(slice, elem.Elem()))
+		}
+	}
+
+	return rows.Err()
+}
+
+// structTraversals returns the field indexes of a struct type matching the
+// columns of the rows. Columns with no matching field have no indexes, and
+// cause an error if strict is true.
+func structTraversals(rows *sql.Rows, structType reflect.Type, strict bool) ([][]int, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	traversals := structMapper.TraversalsByName(structType, cols)
+
+	if strict {
+		for i, traversal := range traversals {
+			if len(traversal) == 0 {
+				return nil, fmt.Errorf("column %s has no matching field in %s", cols[i], structType)
+			}
+		}
+	}
+
+	return traversals, nil
+}
+
+// structDests returns scan destinations for the fields of a struct value,
+// discarding columns with no matching field
+func structDests(elem reflect.Value, traversals [][]int) []interface{} {
+	dests := make([]interface{}, len(traversals))
+
+	for i, traversal := range traversals {
+		if len(traversal) == 0 {
+			dests[i] = new(interface{})
+			continue
+		}
+
+		dests[i] = reflectx.FieldByIndexes(elem, traversal).Addr().Interface()
+	}
+
+	return dests
+}
+
+// structValues returns the column names and values of the exported fields
+// of a struct (or a pointer to a struct), in the order they are declared.
+// Fields of exported embedded structs are included as if they belonged to
+// the outer struct, and fields tagged with db:"-" are skipped. If omitEmpty
+// is true, fields with the omitempty option (e.g. db:"name,omitempty") are
+// skipped as well when they hold their zero value.
+func structValues(val reflect.Value, omitEmpty bool) (cols []string, vals []interface{}, err error) {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil, nil, errors.New("expected a struct, got nil")
+		}
+
+		val = val.Elem()
+	}
+
+	if val.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("expected a struct, got %s", val.Type())
+	}
+
+	typ := val.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		opts := strings.Split(field.Tag.Get("db"), ",")
+		tag := opts[0]
+		if tag == "-" {
+			continue
+		}
+
+		if field.Anonymous && tag == "" && reflectx.Deref(field.Type).Kind() == reflect.Struct {
+			fieldVal := val.Field(i)
+			if fieldVal.Kind() == reflect.Ptr && fieldVal.IsNil() {
+				continue
+			}
+
+			embeddedCols, embeddedVals, err := structValues(fieldVal, omitEmpty)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			cols = append(cols, embeddedCols...)
+			vals = append(vals, embeddedVals...)
+
+			continue
+		}
+
+		if omitEmpty && hasOption(opts[1:], "omitempty") && val.Field(i).IsZero() {
+			continue
+		}
+
+		if tag == "" {
+			tag = toSnakeCase(field.Name)
+		}
+
+		cols = append(cols, tag)
+		vals = append(vals, val.Field(i).Interface())
+	}
+
+	return cols, vals, nil
+}
+
+// hasOption returns true if the options of a struct tag include the
+// provided option
+func hasOption(opts []string, opt string) bool {
+	for _, o := range opts {
+		if o == opt {
+			return true
+		}
+	}
+
+	return false
+}
+
+// toSnakeCase converts a Go field name to snake case, keeping acronyms
+// together (e.g. "UserID" becomes "user_id", "HTTPServer" becomes
+// "http_server")
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+
+	var b strings.Builder
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				(unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+		}
+
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,231 @@
+package sqlz
+
+import (
+	"regexp"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+type scanAudit struct {
+	CreatedBy string
+}
+
+type scanUser struct {
+	scanAudit
+	UserID   int64 `db:"id"`
+	FullName string
+	Email    *string
+}
+
+func TestGetAllStructs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	query := regexp.QuoteMeta("SELECT * FROM users")
+	cols := []string{"id", "full_name", "email", "created_by", "extra"}
+
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows(cols).
+		AddRow(1, "John Doe", "john@example.com", "admin", 1).
+		AddRow(2, "Jane Doe", nil, "system", 2))
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows(cols).
+		AddRow(1, "John Doe", "john@example.com", "admin", 1))
+
+	var users []scanUser
+	if err := dbz.Select("*").From("users").GetAllStructs(&users, false); err != nil {
+		t.Fatalf("Failed scanning structs: %s", err)
+	}
+
+	if len(users) != 2 {
+		t.Fatalf("Expected 2 users, got %d", len(users))
+	}
+
+	if users[0].UserID != 1 || users[0].FullName != "John Doe" || users[0].CreatedBy != "admin" ||
+		users[0].Email == nil || *users[0].Email != "john@example.com" {
+		t.Errorf("Unexpected first user: %+v", users[0])
+	}
+
+	if users[1].UserID != 2 || users[1].Email != nil || users[1].CreatedBy != "system" {
+		t.Errorf("Unexpected second user: %+v", users[1])
+	}
+
+	var strictUsers []*scanUser
+	if err := dbz.Select("*").From("users").GetAllStructs(&strictUsers, true); err == nil {
+		t.Error("Expected strict scanning with an unmapped column to fail")
+	}
+}
+
+func TestToSnakeCase(t *testing.T) {
+	for name, expected := range map[string]string{
+		"ID":         "id",
+		"UserID":     "user_id",
+		"FullName":   "full_name",
+		"HTTPServer": "http_server",
+		"Address2":   "address2",
+	} {
+		if got := toSnakeCase(name); got != expected {
+			t.Errorf("Expected %s to become %s, got %s", name, expected, got)
+		}
+	}
+}
+
+func TestGetRowStruct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"INSERT INTO users (full_name, created_by) VALUES ($1, $2) RETURNING id, full_name, email, created_by",
+	)).
+		WithArgs("John Doe", "admin").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "full_name", "email", "created_by"}).
+			AddRow(1, "John Doe", nil, "admin"))
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"UPDATE users SET email = $1 WHERE id = $2 RETURNING id, full_name, email, created_by",
+	)).
+		WithArgs("john@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "full_name", "email", "created_by"}).
+			AddRow(1, "John Doe", "john@example.com", "admin"))
+
+	var inserted scanUser
+
+	err = dbz.InsertInto("users").
+		Columns("full_name", "created_by").
+		Values("John Doe", "admin").
+		Returning("id", "full_name", "email", "created_by").
+		GetRowStruct(&inserted)
+	if err != nil {
+		t.Fatalf("Failed inserting row: %s", err)
+	}
+
+	if inserted.UserID != 1 || inserted.FullName != "John Doe" || inserted.Email != nil || inserted.CreatedBy != "admin" {
+		t.Errorf("Unexpected inserted row: %+v", inserted)
+	}
+
+	var updated scanUser
+
+	err = dbz.Update("users").
+		Set("email", "john@example.com").
+		Where(Eq("id", 1)).
+		Returning("id", "full_name", "email", "created_by").
+		GetRowStruct(&updated)
+	if err != nil {
+		t.Fatalf("Failed updating row: %s", err)
+	}
+
+	if updated.Email == nil || *updated.Email != "john@example.com" {
+		t.Errorf("Unexpected updated row: %+v", updated)
+	}
+
+	err = New(db, "mysql").Update("users").
+		Set("email", "john@example.com").
+		Returning("id").
+		GetRowStruct(&updated)
+	if err == nil {
+		t.Error("Expected returning a struct on mysql to fail")
+	}
+}
+
+func TestReturningAllColumns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+	cols := []string{"id", "full_name", "email", "created_by"}
+
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO users (full_name, created_by) VALUES ($1, $2) RETURNING *")).
+		WithArgs("John Doe", "admin").
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(1, "John Doe", nil, "admin"))
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE users SET created_by = $1 WHERE created_by = $2 RETURNING *")).
+		WithArgs("system", "admin").
+		WillReturnRows(sqlmock.NewRows(cols).
+			AddRow(1, "John Doe", nil, "system").
+			AddRow(2, "Jane Doe", "jane@example.com", "system"))
+	mock.ExpectQuery(regexp.QuoteMeta("DELETE FROM users WHERE created_by = $1 RETURNING *")).
+		WithArgs("system").
+		WillReturnRows(sqlmock.NewRows(cols).
+			AddRow(1, "John Doe", nil, "system").
+			AddRow(2, "Jane Doe", "jane@example.com", "system"))
+
+	var inserted scanUser
+
+	err = dbz.InsertInto("users").
+		Columns("full_name", "created_by").
+		Values("John Doe", "admin").
+		Returning("*").
+		GetRowStruct(&inserted)
+	if err != nil {
+		t.Fatalf("Failed inserting row: %s", err)
+	}
+
+	if inserted.UserID != 1 || inserted.FullName != "John Doe" || inserted.CreatedBy != "admin" {
+		t.Errorf("Unexpected inserted row: %+v", inserted)
+	}
+
+	var updated []scanUser
+
+	err = dbz.Update("users").
+		Set("created_by", "system").
+		Where(Eq("created_by", "admin")).
+		Returning("*").
+		GetAllStructs(&updated, true)
+	if err != nil {
+		t.Fatalf("Failed updating rows: %s", err)
+	}
+
+	if len(updated) != 2 || updated[0].UserID != 1 || updated[1].Email == nil || *updated[1].Email != "jane@example.com" {
+		t.Errorf("Unexpected updated rows: %+v", updated)
+	}
+
+	var deleted []*scanUser
+
+	err = dbz.DeleteFrom("users").
+		Where(Eq("created_by", "system")).
+		Returning("*").
+		GetAllStructs(&deleted, true)
+	if err != nil {
+		t.Fatalf("Failed deleting rows: %s", err)
+	}
+
+	if len(deleted) != 2 || deleted[1].UserID != 2 || deleted[1].CreatedBy != "system" {
+		t.Errorf("Unexpected deleted rows: %+v", deleted)
+	}
+
+	// SQL Server returns the rows with an OUTPUT clause
+	mock.ExpectQuery(regexp.QuoteMeta("DELETE FROM users OUTPUT DELETED.* WHERE created_by = @p1")).
+		WithArgs("system").
+		WillReturnRows(sqlmock.NewRows(cols).
+			AddRow(1, "John Doe", nil, "system"))
+
+	var output []scanUser
+
+	err = New(db, "sqlserver").DeleteFrom("users").
+		Where(Eq("created_by", "system")).
+		Returning("*").
+		GetAllStructs(&output, true)
+	if err != nil {
+		t.Fatalf("Failed deleting rows on sqlserver: %s", err)
+	}
+
+	if len(output) != 1 || output[0].UserID != 1 {
+		t.Errorf("Unexpected deleted rows: %+v", output)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	err = New(db, "mysql").DeleteFrom("users").Returning("*").GetAllStructs(&output, true)
+	if err == nil {
+		t.Error("Expected returning structs on mysql to fail")
+	}
+}
@@ -2,8 +2,14 @@ package sqlz
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -40,25 +46,50 @@ func (j JoinType) IsLateral() bool {
 	return j == InnerLateralJoin || j == LeftLateralJoin || j == RightLateralJoin
 }
 
+// SetOperation represents an INTERSECT or EXCEPT operation between the
+// results of a SELECT statement and those of another statement
+type SetOperation struct {
+	Op   string
+	Stmt *SelectStmt
+}
+
 // SelectStmt represents a SELECT statement
 type SelectStmt struct {
 	Table           string
+	FromStmt        *SelectStmt
+	FromStmtAlias   string
+	ValuesAlias     string
+	ValuesColumns   []string
+	ValuesRows      [][]interface{}
 	LimitTo         int64
 	OffsetFrom      int64
 	OffsetRows      int64
 	IsDistinct      bool
 	IsUnionAll      bool
+	IsAutoGroupBy   bool
+	IsWithTies      bool
 	orderWithNulls  orderWithNulls
 	queryer         Queryer
 	DistinctColumns []string
 	Columns         []string
+	SelectExprs     []SQLStmt
 	Joins           []JoinClause
 	Conditions      []WhereCondition
 	Ordering        []SQLStmt
 	Grouping        []string
+	GroupingExprs   []SQLStmt
 	GroupConditions []WhereCondition
 	Unions          []*SelectStmt
+	SetOps          []SetOperation
 	Locks           []*LockClause
+	IndexHints      []IndexHint
+	SampleMethod    string
+	SamplePercent   float64
+	SessionSettings map[string]string
+	RawSQL          string
+	RawBindings     []interface{}
+	Prefixes        []IndirectValue
+	Suffixes        []IndirectValue
 	*Statement
 }
 
@@ -68,9 +99,25 @@ type JoinClause struct {
 	Type       JoinType
 	Table      string
 	ResultSet  *SelectStmt
+	Bindings   []interface{}
 	Conditions []WhereCondition
 }
 
+// JoinTable returns a table reference with an alias, e.g. JoinTable("users",
+// "u2") returns "users u2", for use with the join methods (e.g. LeftJoin)
+// and From. Aliases are required when joining the same table more than
+// once, as in self-joins, and columns of each joined instance are then
+// referenced through its alias in ON and WHERE conditions (e.g.
+// Eq("u2.id", Indirect("u1.manager_id"))). The AS keyword is omitted, as
+// some databases don't allow it for table aliases.
+func JoinTable(table, alias string) string {
+	if alias == "" {
+		return table
+	}
+
+	return table + " " + alias
+}
+
 // LockClause represents a row or table level locking for a SELECT statement
 type LockClause struct {
 	Strength LockStrength
@@ -96,6 +143,26 @@ func (lock *LockClause) OfTables(tables ...string) *LockClause {
 	return lock
 }
 
+// IndexHint represents an index hint on the table of a SELECT statement
+// (see SelectStmt.UseIndex)
+type IndexHint struct {
+	Kind    IndexHintKind
+	Indexes []string
+}
+
+// IndexHintKind represents the kind of an IndexHint
+type IndexHintKind string
+
+const (
+	// UseIndexHint suggests that the planner use one of the indexes
+	UseIndexHint IndexHintKind = "USE"
+	// ForceIndexHint tells the planner to use one of the indexes unless a
+	// table scan is the only way to run the query
+	ForceIndexHint IndexHintKind = "FORCE"
+	// IgnoreIndexHint tells the planner not to use the indexes
+	IgnoreIndexHint IndexHintKind = "IGNORE"
+)
+
 // LockStrength represents the strength of a LockClause
 type LockStrength int8
 
@@ -128,6 +195,9 @@ const (
 type OrderColumn struct {
 	Column string
 	Desc   bool
+	// Nulls is either "FIRST" or "LAST" to sort NULL values of the
+	// column first or last, or empty for the database's default
+	Nulls string
 }
 
 type orderWithNulls struct {
@@ -135,8 +205,27 @@ type orderWithNulls struct {
 	First   bool
 }
 
+// NullsFirst sorts NULL values of the column before other values
+func (o OrderColumn) NullsFirst() OrderColumn {
+	o.Nulls = "FIRST"
+	return o
+}
+
+// NullsLast sorts NULL values of the column after other values
+func (o OrderColumn) NullsLast() OrderColumn {
+	o.Nulls = "LAST"
+	return o
+}
+
 // ToSQL generates SQL for an OrderColumn
 func (o OrderColumn) ToSQL(_ bool) (string, []interface{}) {
+	return o.sqlFor("")
+}
+
+// sqlFor generates SQL for an OrderColumn. MySQL and SQL Server don't
+// support NULLS FIRST and NULLS LAST, so NULL placement is emulated by
+// ordering by whether the column is NULL first.
+func (o OrderColumn) sqlFor(driverName string) (string, []interface{}) {
 	str := o.Column
 	if o.Desc {
 		str += " DESC"
@@ -144,19 +233,52 @@ func (o OrderColumn) ToSQL(_ bool) (string, []interface{}) {
 		str += " ASC"
 	}
 
-	return str, nil
+	if o.Nulls == "" {
+		return str, nil
+	}
+
+	switch {
+	case isMySQL(driverName):
+		isNull := o.Column + " IS NULL"
+		if o.Nulls == "FIRST" {
+			isNull += " DESC"
+		}
+
+		return isNull + ", " + str, nil
+	case isSQLServer(driverName):
+		isNull := "CASE WHEN " + o.Column + " IS NULL THEN 0 ELSE 1 END"
+		if o.Nulls == "LAST" {
+			isNull = "CASE WHEN " + o.Column + " IS NULL THEN 1 ELSE 0 END"
+		}
+
+		return isNull + ", " + str, nil
+	default:
+		return str + " NULLS " + o.Nulls, nil
+	}
 }
 
 // Asc creates an OrderColumn for the provided
 // column in ascending order
 func Asc(col string) OrderColumn {
-	return OrderColumn{col, false}
+	return OrderColumn{Column: col}
 }
 
 // Desc creates an OrderColumn for the provided
 // column in descending order
 func Desc(col string) OrderColumn {
-	return OrderColumn{col, true}
+	return OrderColumn{Column: col, Desc: true}
+}
+
+// OrderAsc is the same as Asc, and reads better in calls to
+// OrderByColumns
+func OrderAsc(col string) OrderColumn {
+	return Asc(col)
+}
+
+// OrderDesc is the same as Desc, and reads better in calls to
+// OrderByColumns
+func OrderDesc(col string) OrderColumn {
+	return Desc(col)
 }
 
 // Select creates a new SelectStmt object, selecting
@@ -166,8 +288,8 @@ func Desc(col string) OrderColumn {
 func (db *DB) Select(cols ...string) *SelectStmt {
 	return &SelectStmt{
 		Columns:   append([]string{}, cols...),
-		queryer:   db.DB,
-		Statement: &Statement{db.ErrHandlers},
+		queryer:   db.handle(),
+		Statement: &Statement{ErrHandlers: db.ErrHandlers},
 	}
 }
 
@@ -178,13 +300,132 @@ func (db *DB) Select(cols ...string) *SelectStmt {
 func (tx *Tx) Select(cols ...string) *SelectStmt {
 	return &SelectStmt{
 		Columns:   append([]string{}, cols...),
-		queryer:   tx.Tx,
-		Statement: &Statement{tx.ErrHandlers},
+		queryer:   tx.handle(),
+		Statement: &Statement{ErrHandlers: tx.ErrHandlers},
+	}
+}
+
+// RawSelect creates a new SelectStmt object for a query written in raw SQL,
+// with "?" placeholders for the provided bindings. This is useful for
+// queries the builder can't express, while still rebinding placeholders
+// for the database driver (e.g. to "$1" in PostgreSQL) and executing with
+// the usual methods, such as GetRow, GetAll, GetCount and
+// GetEstimatedCount. Builder methods that modify the query (e.g. Where)
+// have no effect on a raw statement.
+func (db *DB) RawSelect(query string, bindings ...interface{}) *SelectStmt {
+	return &SelectStmt{
+		RawSQL:      query,
+		RawBindings: append([]interface{}{}, bindings...),
+		queryer:     db.handle(),
+		Statement:   &Statement{ErrHandlers: db.ErrHandlers},
+	}
+}
+
+// RawSelect creates a new SelectStmt object for a query written in raw SQL
+// (see DB.RawSelect)
+func (tx *Tx) RawSelect(query string, bindings ...interface{}) *SelectStmt {
+	return &SelectStmt{
+		RawSQL:      query,
+		RawBindings: append([]interface{}{}, bindings...),
+		queryer:     tx.handle(),
+		Statement:   &Statement{ErrHandlers: tx.ErrHandlers},
+	}
+}
+
+// Clone returns a deep copy of the statement, so that a base statement can
+// be branched into several variants (e.g. one fetching a page of results
+// and one counting all of them) without them affecting each other. Clauses,
+// conditions and bindings are copied, as are sub-queries used in the FROM
+// clause, joins, unions and set operations. Conditions and expressions
+// themselves are values, and are shared.
+func (stmt *SelectStmt) Clone() *SelectStmt {
+	if stmt == nil {
+		return nil
+	}
+
+	clone := *stmt
+	clone.FromStmt = stmt.FromStmt.Clone()
+	clone.ValuesColumns = cloneStrings(stmt.ValuesColumns)
+	clone.ValuesRows = append([][]interface{}(nil), stmt.ValuesRows...)
+	clone.DistinctColumns = cloneStrings(stmt.DistinctColumns)
+	clone.Columns = cloneStrings(stmt.Columns)
+	clone.SelectExprs = append([]SQLStmt(nil), stmt.SelectExprs...)
+	clone.Conditions = append([]WhereCondition(nil), stmt.Conditions...)
+	clone.Ordering = append([]SQLStmt(nil), stmt.Ordering...)
+	clone.Grouping = cloneStrings(stmt.Grouping)
+	clone.GroupingExprs = append([]SQLStmt(nil), stmt.GroupingExprs...)
+	clone.GroupConditions = append([]WhereCondition(nil), stmt.GroupConditions...)
+	clone.RawBindings = append([]interface{}(nil), stmt.RawBindings...)
+	clone.Prefixes = append([]IndirectValue(nil), stmt.Prefixes...)
+	clone.Suffixes = append([]IndirectValue(nil), stmt.Suffixes...)
+
+	if stmt.Joins != nil {
+		clone.Joins = make([]JoinClause, len(stmt.Joins))
+		for i, join := range stmt.Joins {
+			join.ResultSet = join.ResultSet.Clone()
+			join.Bindings = append([]interface{}(nil), join.Bindings...)
+			join.Conditions = append([]WhereCondition(nil), join.Conditions...)
+			clone.Joins[i] = join
+		}
+	}
+
+	if stmt.Unions != nil {
+		clone.Unions = make([]*SelectStmt, len(stmt.Unions))
+		for i, union := range stmt.Unions {
+			clone.Unions[i] = union.Clone()
+		}
+	}
+
+	if stmt.SetOps != nil {
+		clone.SetOps = make([]SetOperation, len(stmt.SetOps))
+		for i, op := range stmt.SetOps {
+			clone.SetOps[i] = SetOperation{Op: op.Op, Stmt: op.Stmt.Clone()}
+		}
+	}
+
+	if stmt.Locks != nil {
+		clone.Locks = make([]*LockClause, len(stmt.Locks))
+		for i, lock := range stmt.Locks {
+			lockClone := *lock
+			lockClone.Tables = cloneStrings(lock.Tables)
+			clone.Locks[i] = &lockClone
+		}
+	}
+
+	if stmt.IndexHints != nil {
+		clone.IndexHints = make([]IndexHint, len(stmt.IndexHints))
+		for i, hint := range stmt.IndexHints {
+			hint.Indexes = cloneStrings(hint.Indexes)
+			clone.IndexHints[i] = hint
+		}
+	}
+
+	if stmt.SessionSettings != nil {
+		clone.SessionSettings = make(map[string]string, len(stmt.SessionSettings))
+		for name, value := range stmt.SessionSettings {
+			clone.SessionSettings[name] = value
+		}
+	}
+
+	if stmt.Statement != nil {
+		statement := *stmt.Statement
+		statement.ErrHandlers = append([]func(err error){}, stmt.Statement.ErrHandlers...)
+		clone.Statement = &statement
 	}
+
+	return &clone
+}
+
+// cloneStrings returns a copy of a slice of strings
+func cloneStrings(in []string) []string {
+	return append([]string(nil), in...)
 }
 
 // Distinct marks the statements as a SELECT DISTINCT
-// statement
+// statement. If columns are provided, this is the same as DistinctOn.
+// Distinct and DistinctOn replace each other, so the one called last wins:
+// calling Distinct() after DistinctOn("a") generates a plain SELECT
+// DISTINCT.
 func (stmt *SelectStmt) Distinct(cols ...string) *SelectStmt {
 	stmt.DistinctColumns = append([]string{}, cols...)
 	stmt.IsDistinct = true
@@ -192,17 +433,164 @@ func (stmt *SelectStmt) Distinct(cols ...string) *SelectStmt {
 	return stmt
 }
 
+// DistinctOn marks the statement as a SELECT DISTINCT ON (cols) statement,
+// which is only supported by PostgreSQL. It replaces any previous call to
+// Distinct or DistinctOn.
+func (stmt *SelectStmt) DistinctOn(cols ...string) *SelectStmt {
+	if len(cols) == 0 {
+		stmt.setErr(errors.New("no columns provided to DistinctOn"))
+		return stmt
+	}
+
+	return stmt.Distinct(cols...)
+}
+
+// SelectExpr adds SQL expressions (e.g. Digest) to the select list, after
+// the columns provided to Select. Bindings of the expressions, if any, are
+// added in the order the expressions appear. If an expression is invalid,
+// or not supported by the database driver, the statement fails when
+// executed.
+func (stmt *SelectStmt) SelectExpr(exprs ...SQLStmt) *SelectStmt {
+	for _, expr := range exprs {
+		if err := exprErr(expr, driverNameOf(stmt.queryer)); err != nil {
+			stmt.setErr(err)
+		}
+	}
+
+	stmt.SelectExprs = append(stmt.SelectExprs, exprs...)
+
+	return stmt
+}
+
+// WithTotalCount adds the window aggregate "COUNT(*) OVER () AS alias" to
+// the select list, so that every row also holds the total number of rows
+// matching the statement, disregarding its limit and offset, as window
+// functions are computed before LIMIT/OFFSET are applied. This allows
+// fetching a page of results and the total for pagination in a single
+// query, rather than calling GetCount as well. Note that with GROUP BY, the
+// window counts groups rather than rows, and that columns must be provided
+// to Select, as the select list doesn't default to "*" when it contains an
+// expression.
+func (stmt *SelectStmt) WithTotalCount(alias string) *SelectStmt {
+	if alias == "" {
+		stmt.setErr(errors.New("no alias provided to WithTotalCount"))
+		return stmt
+	}
+
+	return stmt.SelectExpr(Indirect("COUNT(*) OVER () AS " + alias))
+}
+
 // From sets the table to select from
 func (stmt *SelectStmt) From(table string) *SelectStmt {
 	stmt.Table = table
 	return stmt
 }
 
+// FromValues sets the statement to select from a list of rows provided by
+// the caller, e.g. for joining them with tables. Every row must hold a
+// value for each of the provided columns, in order, and all values are
+// bound as parameters. This generates "FROM (VALUES (?, ?), (?, ?)) AS
+// alias(col1, col2)", except in MySQL where the rows are selected with
+// UNION ALL instead, e.g. "FROM (SELECT ? AS col1, ? AS col2 UNION ALL
+// SELECT ?, ?) AS alias". PostgreSQL resolves the type of a VALUES column
+// from its values, and parameters of unknown type default to text, so
+// comparing the columns with typed ones (e.g. in join conditions) may fail;
+// to set the column types, wrap the values of the first row with Cast, e.g.
+// []interface{}{Cast(1, "int"), Cast(day, "date")}.
+func (stmt *SelectStmt) FromValues(alias string, cols []string, rows [][]interface{}) *SelectStmt {
+	if len(rows) == 0 {
+		stmt.setErr(errors.New("no rows provided to FromValues"))
+		return stmt
+	}
+
+	for i, row := range rows {
+		if len(row) != len(cols) {
+			stmt.setErr(fmt.Errorf("row %d has %d values, expected %d", i, len(row), len(cols)))
+			return stmt
+		}
+	}
+
+	stmt.ValuesAlias = alias
+	stmt.ValuesColumns = cols
+	stmt.ValuesRows = rows
+
+	return stmt
+}
+
+// TopNPerGroupWindow returns a new statement selecting up to n rows per
+// group from the results of the current statement, where groups are
+// defined by partitionCols (e.g. "a, b") and rows are ranked within each
+// group by orderCol (e.g. "created_at DESC"). The current statement is
+// wrapped as a subquery that numbers its rows with ROW_NUMBER(), exposed
+// as the "rn" column, and the outer query filters rows where rn <= n.
+// This is an alternative to top-N queries using LATERAL joins.
+func (stmt *SelectStmt) TopNPerGroupWindow(partitionCols, orderCol string, n int) *SelectStmt {
+	return stmt.windowed(
+		Lte("rn", n),
+		Indirect("ROW_NUMBER() OVER (PARTITION BY "+partitionCols+" ORDER BY "+orderCol+") AS rn"),
+	)
+}
+
+// LatestPerGroup returns a new statement selecting the latest row of each
+// group from the results of the current statement, along with the number
+// of rows in the group. Groups are defined by partitionCols (e.g. "a, b"),
+// and the latest row is the one with the highest value of latestByCol.
+// Like TopNPerGroupWindow, the current statement is wrapped as a subquery
+// computing the window columns "rn" and "group_count", and the outer query
+// filters rows where rn = 1. Unlike DISTINCT ON, this allows counting the
+// rows of each group in the same query.
+func (stmt *SelectStmt) LatestPerGroup(partitionCols, latestByCol string) *SelectStmt {
+	return stmt.windowed(
+		SQLCond("rn = 1"),
+		Indirect("ROW_NUMBER() OVER (PARTITION BY "+partitionCols+" ORDER BY "+latestByCol+" DESC) AS rn"),
+		Indirect("COUNT(*) OVER (PARTITION BY "+partitionCols+") AS group_count"),
+	)
+}
+
+// Qualify returns a new statement selecting the rows of the current
+// statement that match a condition on window functions, emulating the
+// QUALIFY clause of Snowflake and others, e.g.
+// Qualify(Eq("ROW_NUMBER() OVER (PARTITION BY a ORDER BY b DESC)", 1)).
+// As the condition cannot be used in the statement's WHERE clause, the
+// current statement is wrapped as a subquery that evaluates it as the
+// "qualified" column, and the outer query filters rows where it is 1.
+func (stmt *SelectStmt) Qualify(cond WhereCondition) *SelectStmt {
+	condSQL, condBindings := condSQL(cond, driverNameOf(stmt.queryer))
+
+	return stmt.windowed(
+		SQLCond("qualified = 1"),
+		Indirect("CASE WHEN "+condSQL+" THEN 1 ELSE 0 END AS qualified", condBindings...),
+	)
+}
+
+// windowed wraps a copy of the statement as a subquery that also selects
+// the provided window expressions, returning a statement selecting from
+// it the rows matching the provided condition
+func (stmt *SelectStmt) windowed(cond WhereCondition, windowExprs ...SQLStmt) *SelectStmt {
+	inner := stmt.Clone()
+
+	if len(inner.Columns) == 0 && len(inner.SelectExprs) == 0 {
+		inner.Columns = []string{"*"}
+	}
+
+	inner.SelectExprs = append(inner.SelectExprs, windowExprs...)
+
+	return &SelectStmt{
+		FromStmt:      inner,
+		FromStmtAlias: "ranked",
+		Conditions:    []WhereCondition{cond},
+		queryer:       stmt.queryer,
+		Statement:     stmt.Statement,
+	}
+}
+
 // Join creates a new join with the supplied type, on the
 // supplied table or result set (a sub-select statement),
 // using the provided conditions. Since conditions in a
 // JOIN clause usually compare two columns, use sqlz.Indirect
-// in your conditions.
+// in your conditions. Multiple conditions, or a single composite And
+// condition, are joined with AND (e.g. "ON a.x = b.x AND b.active = ?"),
+// with their values bound in order.
 func (stmt *SelectStmt) Join(
 	joinType JoinType,
 	table string,
@@ -226,7 +614,10 @@ func (stmt *SelectStmt) LeftJoin(table string, conds ...WhereCondition) *SelectS
 }
 
 // RightJoin is a wrapper of Join for creating a RIGHT JOIN on a table
-// with the provided conditions
+// with the provided conditions. On SQLite, which doesn't support RIGHT
+// JOIN, it is emulated with a LEFT JOIN whose operands are swapped. This
+// doesn't affect the order of explicitly selected columns, but with
+// "SELECT *" the columns of the joined table come first.
 func (stmt *SelectStmt) RightJoin(table string, conds ...WhereCondition) *SelectStmt {
 	return stmt.Join(RightJoin, table, nil, conds...)
 }
@@ -285,13 +676,101 @@ func (stmt *SelectStmt) InnerLateralJoin(rs *SelectStmt, as string, conds ...Whe
 	return stmt.Join(InnerLateralJoin, as, rs, conds...)
 }
 
+// UnnestJoin is a wrapper of Join for creating an INNER LATERAL JOIN on the
+// elements of an array column, expanded into rows using PostgreSQL's unnest
+// function. Every element is available as alias.column in the rest of the
+// query, e.g. UnnestJoin("tags", "t", "tag") joins "unnest(tags) AS t(tag)"
+// and allows conditions such as Eq("t.tag", "something").
+func (stmt *SelectStmt) UnnestJoin(arrayCol, alias, column string) *SelectStmt {
+	return stmt.Join(
+		InnerLateralJoin,
+		fmt.Sprintf("unnest(%s) AS %s(%s)", arrayCol, alias, column),
+		nil,
+		SQLCond("true"),
+	)
+}
+
 // Where creates one or more WHERE conditions for the SELECT statement.
 // If multiple conditions are passed, they are considered AND conditions.
 func (stmt *SelectStmt) Where(conditions ...WhereCondition) *SelectStmt {
+	conditions, err := emptyInPolicyOf(stmt.queryer).apply(conditions)
+	if err != nil {
+		stmt.setErr(err)
+	}
+
+	if err := condErr(conditions, driverNameOf(stmt.queryer)); err != nil {
+		stmt.setErr(err)
+	}
+
 	stmt.Conditions = append(stmt.Conditions, conditions...)
+
+	return stmt
+}
+
+// WhereStruct adds a WHERE condition for every set field of the provided
+// struct of search filters (see ConditionFromStruct). If no field is set,
+// the WHERE clause is unchanged.
+func (stmt *SelectStmt) WhereStruct(v interface{}) *SelectStmt {
+	conds, err := structConditions(reflect.ValueOf(v), false)
+	if err != nil {
+		stmt.setErr(err)
+		return stmt
+	}
+
+	if len(conds) == 0 {
+		return stmt
+	}
+
+	return stmt.Where(conds...)
+}
+
+// WhereGroup builds a group of conditions with the provided function, and
+// adds it to the WHERE clause as a single parenthesized condition. For
+// example, Where(Eq("a", 1)).WhereGroup(func(g *ConditionGroup) {
+// g.And(Eq("b", 2)).Or(Eq("c", 3)) }) generates "WHERE a = ? AND (b = ? OR
+// c = ?)". If the function adds no conditions, the WHERE clause is
+// unchanged.
+func (stmt *SelectStmt) WhereGroup(fn func(g *ConditionGroup)) *SelectStmt {
+	g := &ConditionGroup{}
+	fn(g)
+
+	if cond := g.Condition(); cond != nil {
+		stmt.Where(cond)
+	}
+
 	return stmt
 }
 
+// ApplyIf calls fn with the statement if cond is true, and returns its
+// result; otherwise the statement is returned unchanged. This allows
+// optional clauses (e.g. search filters) to be added without breaking the
+// chain of method calls:
+//
+//	dbz.Select("*").From("users").
+//		ApplyIf(name != "", func(stmt *SelectStmt) *SelectStmt {
+//			return stmt.Where(ILike("name", "%"+name+"%"))
+//		})
+func (stmt *SelectStmt) ApplyIf(cond bool, fn func(*SelectStmt) *SelectStmt) *SelectStmt {
+	if !cond {
+		return stmt
+	}
+
+	return fn(stmt)
+}
+
+// ExcludeSoftDeleted adds a WHERE condition excluding rows that were soft
+// deleted (see DeleteStmt.Soft). By default, rows are considered deleted
+// if the provided column is not NULL. If a value is provided, only rows
+// where the column equals that value are selected instead, which is useful
+// for boolean flags, e.g. ExcludeSoftDeleted("deleted", false).
+func (stmt *SelectStmt) ExcludeSoftDeleted(column string, active ...interface{}) *SelectStmt {
+	if len(active) > 0 {
+		return stmt.Where(Eq(column, active[0]))
+	}
+
+	return stmt.Where(IsNull(column))
+}
+
 // WithNullsFirst modifies ORDER BY clauses to sort NULL values first.
 func (stmt *SelectStmt) WithNullsFirst() *SelectStmt {
 	stmt.orderWithNulls.Enabled = true
@@ -315,163 +794,1154 @@ func (stmt *SelectStmt) OrderBy(cols ...SQLStmt) *SelectStmt {
 	return stmt
 }
 
-// GroupBy sets a GROUP BY clause with the provided columns.
-func (stmt *SelectStmt) GroupBy(cols ...string) *SelectStmt {
-	stmt.Grouping = append(stmt.Grouping, cols...)
-	return stmt
+// OrderByRaw adds a raw SQL expression to the ORDER BY clause, for ordering
+// by expressions that cannot be otherwise expressed, e.g.
+// OrderByRaw("ST_Distance(geom, ST_MakePoint(?, ?))", lon, lat). Question
+// mark placeholders in the expression are bound to the provided values,
+// which, like the expression itself, take their place among the other
+// ORDER BY items in the order in which they were added, and come after the
+// bindings of the WHERE and HAVING clauses.
+func (stmt *SelectStmt) OrderByRaw(sql string, args ...interface{}) *SelectStmt {
+	return stmt.OrderBy(Indirect(sql, args...))
 }
 
-// Having sets HAVING conditions for aggregated values. Usage is the
-// same as Where.
-func (stmt *SelectStmt) Having(conditions ...WhereCondition) *SelectStmt {
-	stmt.GroupConditions = append(stmt.GroupConditions, conditions...)
-	return stmt
+// PaginateOption is an option for PaginateAfter, see NullableCursor
+type PaginateOption struct {
+	nullable bool
+	idCol    string
+	afterID  interface{}
 }
 
-// Limit limits the amount of results returned to the provided value
-// (this is a LIMIT clause). In some database systems, Offset with two
-// values should be used instead.
-func (stmt *SelectStmt) Limit(limit int64) *SelectStmt {
-	stmt.LimitTo = limit
-	return stmt
+// NullableCursor declares the column provided to PaginateAfter as
+// nullable. As "col > NULL" is never true, and NULL values aren't unique,
+// rows are then paginated by the column and idCol, a unique non-nullable
+// column (e.g. the primary key) that orders rows with the same value of the
+// column (including NULL), and afterID is the value of idCol in the last
+// row of the previous page (or nil for the first page). The value provided
+// to PaginateAfter is then nil if the column was NULL in that row. NULL
+// values are placed according to the NULLS FIRST/LAST ordering of the
+// column (see OrderColumn.NullsFirst and SelectStmt.WithNullsFirst), or
+// the database's default ordering (last in ascending order on PostgreSQL,
+// first elsewhere), and are always made explicit for the column in the
+// ORDER BY clause. Paginating after a value without afterID fails.
+func NullableCursor(idCol string, afterID interface{}) PaginateOption {
+	return PaginateOption{nullable: true, idCol: idCol, afterID: afterID}
 }
 
-// Offset skips the provided number of results. In supporting database
-// systems, you can provide a limit on the number of the returned
-// results as the second parameter
-func (stmt *SelectStmt) Offset(start int64, rows ...int64) *SelectStmt {
-	stmt.OffsetFrom = start
-	if len(rows) > 0 {
-		stmt.OffsetRows = rows[0]
+// PaginateAfter paginates the statement's results with keyset (seek)
+// pagination on the provided column: it returns up to limit rows whose
+// value of the column comes after the provided value, which should be the
+// column's value in the last row of the previous page (or nil for the first
+// page). The column is made the leading column of the ORDER BY clause
+// (ascending, unless it is already ordered descending there), so that
+// previously added ordering columns act as tie-breakers within it. If the
+// statement is a DISTINCT ON statement, the leading columns of the ORDER BY
+// clause must be the DISTINCT ON columns, and the column must be one of
+// them, otherwise the statement fails when executed. If the column is
+// nullable, pass the NullableCursor option.
+func (stmt *SelectStmt) PaginateAfter(col string, after interface{}, limit int64, opts ...PaginateOption) *SelectStmt {
+	leading, isOrdered := OrderColumn{}, false
+	if len(stmt.Ordering) > 0 {
+		leading, isOrdered = stmt.Ordering[0].(OrderColumn)
+		isOrdered = isOrdered && leading.Column == col
 	}
 
-	return stmt
-}
+	if !isOrdered {
+		leading = Asc(col)
+		stmt.Ordering = append([]SQLStmt{leading}, stmt.Ordering...)
+	}
 
-// Lock sets a LOCK clause on the SELECT statement.
-func (stmt *SelectStmt) Lock(lock *LockClause) *SelectStmt {
-	stmt.Locks = append(stmt.Locks, lock)
-	return stmt
-}
+	var opt PaginateOption
+	for _, o := range opts {
+		if o.nullable {
+			opt = o
+		}
+	}
 
-// ForUpdate adds a "FOR UPDATE" lock clause on the statement
-func ForUpdate() *LockClause {
-	return &LockClause{Strength: LockForUpdate}
-}
+	switch {
+	case opt.nullable:
+		stmt.paginateNullable(leading, after, opt)
+	case after != nil:
+		if leading.Desc {
+			stmt.Where(Lt(col, after))
+		} else {
+			stmt.Where(Gt(col, after))
+		}
+	}
 
-// ForNoKeyUpdate adds a "FOR NO KEY UPDATE" lock clause on the statement
-func ForNoKeyUpdate() *LockClause {
-	return &LockClause{Strength: LockForNoKeyUpdate}
-}
+	stmt.LimitTo = limit
 
-// ForShare adds a "FOR SHARE" lock clause on the statement
-func ForShare() *LockClause {
-	return &LockClause{Strength: LockForShare}
-}
+	if err := stmt.checkDistinctOnOrdering(col); err != nil {
+		stmt.setErr(err)
+	}
 
-// ForKeyShare adds a "FOR KEY SHARE" lock clause on the statement
-func ForKeyShare() *LockClause {
-	return &LockClause{Strength: LockForKeyShare}
+	return stmt
 }
 
-// ToSQL generates the SELECT statement's SQL and returns a list of
-// bindings. It is used internally by GetRow and GetAll, but is
-// exported if you wish to use it directly.
-// nolint: gocognit, gocyclo
-func (stmt *SelectStmt) ToSQL(rebind bool) (asSQL string, bindings []interface{}) {
-	var clauses = []string{"SELECT"}
+// paginateNullable adds the ordering and conditions of keyset pagination
+// on a nullable column, ordered by the provided leading column of the
+// ORDER BY clause, see NullableCursor
+func (stmt *SelectStmt) paginateNullable(leading OrderColumn, after interface{}, opt PaginateOption) {
+	if opt.idCol == "" {
+		stmt.setErr(errors.New("no id column provided to NullableCursor"))
+		return
+	}
 
-	if stmt.IsDistinct {
-		clauses = append(clauses, "DISTINCT")
-		if len(stmt.DistinctColumns) > 0 {
-			clauses = append(clauses, "ON ("+strings.Join(stmt.DistinctColumns, ", ")+")")
-		}
+	if after != nil && opt.afterID == nil {
+		stmt.setErr(errors.New("NullableCursor requires the id of the last row when paginating after a value"))
+		return
 	}
 
-	if len(stmt.Columns) == 0 {
-		clauses = append(clauses, "*")
-	} else {
-		clauses = append(clauses, strings.Join(stmt.Columns, ", "))
+	nullsFirst := leading.Nulls == "FIRST"
+	switch {
+	case leading.Nulls != "":
+	case stmt.orderWithNulls.Enabled:
+		nullsFirst = stmt.orderWithNulls.First
+	default:
+		// PostgreSQL considers NULL values larger than any other value,
+		// other databases consider them smaller
+		nullsFirst = isPostgres(driverNameOf(stmt.queryer)) == leading.Desc
 	}
 
-	if len(stmt.Table) > 0 {
-		clauses = append(clauses, fmt.Sprintf("FROM %s", stmt.Table))
+	// the NULL placement is always made explicit on the column itself, as
+	// the NULLS FIRST/LAST suffix of WithNullsFirst/WithNullsLast applies
+	// to the last column of the ORDER BY clause
+	leading = leading.NullsLast()
+	if nullsFirst {
+		leading = leading.NullsFirst()
 	}
 
-	for _, join := range stmt.Joins {
-		onClause, joinBindings := parseConditions(join.Conditions)
+	stmt.Ordering[0] = leading
+	if len(stmt.Ordering) < 2 || stmt.Ordering[1] != Asc(opt.idCol) {
+		stmt.Ordering = append(
+			[]SQLStmt{leading, Asc(opt.idCol)},
+			stmt.Ordering[1:]...,
+		)
+	}
 
-		if join.ResultSet != nil {
-			rsSQL, rsBindings := join.ResultSet.ToSQL(false)
-			clauses = append(clauses, join.Type.String()+" ("+rsSQL+") "+join.Table+" ON "+onClause)
-			bindings = append(bindings, rsBindings...)
+	if opt.afterID == nil {
+		return
+	}
+
+	col := leading.Column
+	nextID := Gt(opt.idCol, opt.afterID)
+
+	if after == nil {
+		// the previous page ended within the NULL values, which are
+		// followed by the non-NULL values if they come first
+		if nullsFirst {
+			stmt.Where(Or(And(IsNull(col), nextID), IsNotNull(col)))
 		} else {
-			clauses = append(clauses, join.Type.String()+" "+join.Table+" ON "+onClause)
+			stmt.Where(IsNull(col), nextID)
 		}
 
-		// add the join condition bindings (this MUST happen after adding the clause
-		// itself, because if the join is on a result set then the result set's bindings
-		// need to come first
-		bindings = append(bindings, joinBindings...)
+		return
 	}
 
-	if len(stmt.Conditions) > 0 {
-		whereClause, whereBindings := parseConditions(stmt.Conditions)
-		bindings = append(bindings, whereBindings...)
-		clauses = append(clauses, fmt.Sprintf("WHERE %s", whereClause))
+	next := Gt(col, after)
+	if leading.Desc {
+		next = Lt(col, after)
+	}
+
+	if nullsFirst {
+		stmt.Where(Or(next, And(Eq(col, after), nextID)))
+	} else {
+		stmt.Where(Or(next, And(Eq(col, after), nextID), IsNull(col)))
 	}
+}
 
-	if len(stmt.Grouping) > 0 {
-		clauses = append(clauses, fmt.Sprintf("GROUP BY %s", strings.Join(stmt.Grouping, ", ")))
+// checkDistinctOnOrdering returns an error if the statement is a DISTINCT
+// ON statement whose ORDER BY clause doesn't begin with the DISTINCT ON
+// columns (in any order), as required by PostgreSQL, or if they don't
+// include the provided column
+func (stmt *SelectStmt) checkDistinctOnOrdering(col string) error {
+	if len(stmt.DistinctColumns) == 0 {
+		return nil
 	}
 
-	if len(stmt.GroupConditions) > 0 {
-		groupByClause, groupBindings := parseConditions(stmt.GroupConditions)
-		bindings = append(bindings, groupBindings...)
-		clauses = append(clauses, fmt.Sprintf("HAVING %s", groupByClause))
+	distinct := make(map[string]bool, len(stmt.DistinctColumns))
+	for _, distinctCol := range stmt.DistinctColumns {
+		distinct[distinctCol] = true
 	}
 
-	if len(stmt.Ordering) > 0 {
-		var ordering []string
+	if !distinct[col] {
+		return fmt.Errorf("cannot paginate by %s, which is not a DISTINCT ON column", col)
+	}
 
-		for _, order := range stmt.Ordering {
-			o, _ := order.ToSQL(false)
-			ordering = append(ordering, o)
+	if len(stmt.Ordering) < len(distinct) {
+		return errors.New("ORDER BY clause must begin with the DISTINCT ON columns")
+	}
+
+	for _, order := range stmt.Ordering[:len(distinct)] {
+		if orderCol, ok := order.(OrderColumn); !ok || !distinct[orderCol.Column] {
+			return errors.New("ORDER BY clause must begin with the DISTINCT ON columns")
 		}
+	}
 
-		clauses = append(clauses, fmt.Sprintf("ORDER BY %s", strings.Join(ordering, ", ")))
+	return nil
+}
 
-		if stmt.orderWithNulls.Enabled {
-			if stmt.orderWithNulls.First {
-				clauses = append(clauses, "NULLS FIRST")
-			} else {
-				clauses = append(clauses, "NULLS LAST")
+// OrderByAlias adds an alias from the select list (e.g. "total" in
+// "COUNT(*) AS total", either in Columns or in SelectExpr expressions) to
+// the ORDER BY clause, in ascending order. The alias is looked up when the
+// statement is generated, so columns may be added after calling this
+// method, but if the alias is not in the select list by then, the statement
+// fails when executed.
+func (stmt *SelectStmt) OrderByAlias(alias string) *SelectStmt {
+	stmt.Ordering = append(stmt.Ordering, aliasOrder{Asc(alias)})
+	return stmt
+}
+
+// OrderByAliasDesc is the same as OrderByAlias, but in descending order
+func (stmt *SelectStmt) OrderByAliasDesc(alias string) *SelectStmt {
+	stmt.Ordering = append(stmt.Ordering, aliasOrder{Desc(alias)})
+	return stmt
+}
+
+// aliasOrder is an ORDER BY item referencing an alias from the select list
+type aliasOrder struct {
+	OrderColumn
+}
+
+// hasAlias returns whether an expression in the select list has the
+// provided alias
+func (stmt *SelectStmt) hasAlias(alias, driverName string) bool {
+	suffix := " as " + strings.ToLower(alias)
+
+	for _, col := range stmt.Columns {
+		for _, part := range splitTopLevel(col, ',') {
+			if strings.HasSuffix(strings.ToLower(part), suffix) {
+				return true
 			}
 		}
 	}
 
-	if stmt.LimitTo > 0 {
-		clauses = append(clauses, fmt.Sprintf("LIMIT %d", stmt.LimitTo))
+	for _, selectExpr := range stmt.SelectExprs {
+		exprSQL, _ := exprSQL(selectExpr, driverName)
+		if strings.HasSuffix(strings.ToLower(exprSQL), suffix) {
+			return true
+		}
 	}
 
-	if stmt.OffsetFrom > 0 {
-		offset := fmt.Sprintf("%d", stmt.OffsetFrom)
-		if stmt.OffsetRows > 0 {
-			offset += fmt.Sprintf(" %d", stmt.OffsetRows)
-		}
+	return false
+}
 
-		clauses = append(clauses, "OFFSET "+offset)
+// WithTimeout sets a timeout for executing the statement. When executed,
+// the statement's context (context.Background() for methods that don't
+// receive one) is given a deadline that far in the future, unless it
+// already has a sooner one.
+func (stmt *SelectStmt) WithTimeout(timeout time.Duration) *SelectStmt {
+	stmt.timeout = timeout
+	return stmt
+}
+
+// Comment adds a comment with the provided tag to the start of the
+// statement's SQL, e.g. "/* route=/users */ SELECT ...", which allows
+// correlating queries seen by the database (e.g. in pg_stat_statements or
+// the slow query log) with application code. The comment follows the DB's
+// default comment, if any (see DB.SetQueryComment). Sequences that would
+// end the comment are broken up. The comment is only added to the outermost
+// statement, i.e. when SQL is generated with rebinding (as it is when the
+// statement is executed), and not to sub-queries.
+func (stmt *SelectStmt) Comment(tag string) *SelectStmt {
+	stmt.comment = tag
+	return stmt
+}
+
+// OrderByColumns adds the provided columns to the ORDER BY clause, in
+// order, after any columns previously added with OrderBy. Each column has
+// its own direction and NULL placement, e.g.
+// OrderByColumns(OrderAsc("a"), OrderDesc("b").NullsLast()).
+func (stmt *SelectStmt) OrderByColumns(cols ...OrderColumn) *SelectStmt {
+	for _, col := range cols {
+		stmt.Ordering = append(stmt.Ordering, col)
 	}
 
-	for _, lock := range stmt.Locks {
-		var lockStrength string
+	return stmt
+}
 
-		switch lock.Strength {
-		case LockForUpdate:
+// OrderByRandom orders the results randomly, generating "ORDER BY RANDOM()"
+// in PostgreSQL and SQLite, "ORDER BY RAND()" in MySQL and "ORDER BY
+// NEWID()" in SQL Server. It is usually combined with Limit to select a
+// random sample of rows. Note that the database has to generate a random
+// value for every matching row and sort all of them, which is slow on
+// large tables. In PostgreSQL, consider TABLESAMPLE for those.
+func (stmt *SelectStmt) OrderByRandom() *SelectStmt {
+	stmt.Ordering = append(stmt.Ordering, randomOrder{})
+	return stmt
+}
+
+// randomOrder is an ORDER BY item ordering results randomly
+type randomOrder struct{}
+
+// ToSQL generates SQL for the ordering, using PostgreSQL syntax
+func (randomOrder) ToSQL(_ bool) (string, []interface{}) {
+	return randomOrder{}.sqlFor("")
+}
+
+func (randomOrder) sqlFor(driverName string) (string, []interface{}) {
+	switch {
+	case isMySQL(driverName):
+		return "RAND()", nil
+	case isSQLServer(driverName):
+		return "NEWID()", nil
+	default:
+		return "RANDOM()", nil
+	}
+}
+
+// OrderByField orders the results by the position of the column's value in
+// the provided list of values, e.g. to return rows fetched by their IDs in
+// the order the IDs were ranked by a search service. This generates
+// "FIELD(col, ?, ?, ?)" in MySQL and "array_position(ARRAY[?, ?,
+// ?]::text[], col::text)" in PostgreSQL (comparing text representations,
+// as the types of the bound values cannot be inferred), and an equivalent
+// CASE expression elsewhere. The values are bound as parameters. Rows whose
+// value is not in the list are sorted last, except in MySQL, where they are
+// sorted first.
+func (stmt *SelectStmt) OrderByField(col string, values []interface{}) *SelectStmt {
+	if len(values) == 0 {
+		stmt.setErr(errors.New("ordering by field requires at least one value"))
+		return stmt
+	}
+
+	stmt.Ordering = append(stmt.Ordering, fieldOrder{col, append([]interface{}{}, values...)})
+
+	return stmt
+}
+
+// fieldOrder is an ORDER BY item ordering results by the position of a
+// column's value in a list of values
+type fieldOrder struct {
+	Column string
+	Values []interface{}
+}
+
+// ToSQL generates SQL for the ordering, using PostgreSQL syntax
+func (order fieldOrder) ToSQL(_ bool) (string, []interface{}) {
+	return order.sqlFor("")
+}
+
+func (order fieldOrder) sqlFor(driverName string) (string, []interface{}) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(order.Values)), ", ")
+	bindings := append([]interface{}{}, order.Values...)
+
+	switch {
+	case isMySQL(driverName):
+		return "FIELD(" + order.Column + ", " + placeholders + ")", bindings
+	case isSQLite(driverName) || isSQLServer(driverName):
+		whens := make([]string, len(order.Values))
+		for i := range order.Values {
+			whens[i] = "WHEN ? THEN " + strconv.Itoa(i+1)
+		}
+
+		return "CASE " + order.Column + " " + strings.Join(whens, " ") +
+			" ELSE " + strconv.Itoa(len(order.Values)+1) + " END", bindings
+	default:
+		return "array_position(ARRAY[" + placeholders + "]::text[], " + order.Column + "::text)", bindings
+	}
+}
+
+// GroupBy sets a GROUP BY clause with the provided columns.
+func (stmt *SelectStmt) GroupBy(cols ...string) *SelectStmt {
+	stmt.Grouping = append(stmt.Grouping, cols...)
+	return stmt
+}
+
+// AutoGroupBy makes the statement group by every plain column of its
+// select list (see Select), in the order of the select list, if the select
+// list contains an aggregate function (e.g. "COUNT(*)" or "MAX(price)", or
+// an aggregate expression such as ArrayAgg). Columns wrapped in a known
+// aggregate function, window functions (e.g. "... OVER (...)"), stars and
+// literal values are excluded, as are columns already provided to GroupBy,
+// which come first. Aliases are stripped, so that "lower(name) AS lname"
+// groups by "lower(name)". Expressions provided to SelectExpr are never
+// grouped by automatically.
+func (stmt *SelectStmt) AutoGroupBy() *SelectStmt {
+	stmt.IsAutoGroupBy = true
+	return stmt
+}
+
+// aggregateCall matches a call to a known aggregate function
+var aggregateCall = regexp.MustCompile(`(?i)\b(count|sum|avg|min|max|array_agg|string_agg|group_concat|` +
+	`json_agg|jsonb_agg|json_object_agg|jsonb_object_agg|json_arrayagg|json_objectagg|bool_and|bool_or|` +
+	`every|bit_and|bit_or|bit_xor|stddev|stddev_pop|stddev_samp|variance|var_pop|var_samp|` +
+	`percentile_cont|percentile_disc|listagg)\s*\(`)
+
+// windowCall matches a window function call, e.g. "... OVER (...)" or
+// "... OVER w"
+var windowCall = regexp.MustCompile(`(?i)\)\s*over\b`)
+
+// groupingColumns returns the columns of the GROUP BY clause, including
+// those added automatically if AutoGroupBy was used
+func (stmt *SelectStmt) groupingColumns() []string {
+	grouping := append([]string{}, stmt.Grouping...)
+	if !stmt.IsAutoGroupBy {
+		return grouping
+	}
+
+	var plain []string
+
+	aggregated := false
+
+	for _, expr := range stmt.SelectExprs {
+		switch expr.(type) {
+		case AggregateExpr, FilteredAggExpr, PercentileExpr:
+			aggregated = true
+		}
+	}
+
+	for _, cols := range stmt.Columns {
+		for _, col := range splitTopLevel(cols, ',') {
+			col = unaliasedColumn(col)
+
+			switch {
+			case windowCall.MatchString(col):
+			case aggregateCall.MatchString(col):
+				aggregated = true
+			case col == "*" || strings.HasSuffix(col, ".*") || isLiteral(col):
+			default:
+				plain = append(plain, col)
+			}
+		}
+	}
+
+	if !aggregated {
+		return grouping
+	}
+
+	seen := make(map[string]bool, len(grouping)+len(plain))
+	for _, col := range grouping {
+		seen[col] = true
+	}
+
+	for _, col := range plain {
+		if !seen[col] {
+			seen[col] = true
+			grouping = append(grouping, col)
+		}
+	}
+
+	return grouping
+}
+
+// splitTopLevel splits a string on the provided separator, ignoring
+// separators inside parentheses and quotes
+func splitTopLevel(s string, sep byte) (parts []string) {
+	var depth int
+
+	var quote byte
+
+	start := 0
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// unaliasedColumn strips the alias from a select list column, e.g.
+// "lower(name) AS lname" or "MAX(value) value"
+func unaliasedColumn(col string) string {
+	tokens := splitTopLevel(col, ' ')
+
+	var words []string
+
+	for _, token := range tokens {
+		if token != "" {
+			words = append(words, token)
+		}
+	}
+
+	switch {
+	case len(words) > 2 && strings.EqualFold(words[len(words)-2], "as"):
+		words = words[:len(words)-2]
+	case len(words) == 2 && isName(words[1]):
+		words = words[:1]
+	default:
+		return col
+	}
+
+	return strings.Join(words, " ")
+}
+
+// isName returns true if the provided string is a plain (unqualified,
+// unquoted) identifier
+func isName(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if !isNameChar(s[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isLiteral returns true if the provided select list column is a literal
+// number or string, which must not be grouped by (in PostgreSQL and MySQL,
+// a number in the GROUP BY clause refers to a position in the select list)
+func isLiteral(col string) bool {
+	if strings.HasPrefix(col, "'") {
+		return true
+	}
+
+	_, err := strconv.ParseFloat(col, 64)
+
+	return err == nil
+}
+
+// GroupByExpr adds SQL expressions (e.g. Digest) to the GROUP BY clause,
+// after the columns provided to GroupBy.
+func (stmt *SelectStmt) GroupByExpr(exprs ...SQLStmt) *SelectStmt {
+	for _, expr := range exprs {
+		if err := exprErr(expr, driverNameOf(stmt.queryer)); err != nil {
+			stmt.setErr(err)
+		}
+	}
+
+	stmt.GroupingExprs = append(stmt.GroupingExprs, exprs...)
+
+	return stmt
+}
+
+// GroupByRollup adds a ROLLUP grouping to the GROUP BY clause, producing
+// subtotals for every prefix of the provided columns and a grand total,
+// e.g. "GROUP BY ROLLUP(a, b)". It can be combined with GroupBy, whose
+// columns come first. In MySQL, this generates "GROUP BY a, b WITH
+// ROLLUP" instead, which would roll up any other grouping columns too, so
+// there it must be the statement's only grouping; otherwise, the statement
+// fails.
+func (stmt *SelectStmt) GroupByRollup(cols ...string) *SelectStmt {
+	return stmt.GroupByExpr(GroupingExpr{Kind: "ROLLUP", Sets: [][]string{cols}})
+}
+
+// hasRollup returns whether the statement's GROUP BY clause has a ROLLUP
+// grouping
+func (stmt *SelectStmt) hasRollup() bool {
+	for _, expr := range stmt.GroupingExprs {
+		if g, ok := expr.(GroupingExpr); ok && g.Kind == "ROLLUP" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GroupingSets adds a GROUPING SETS grouping to the GROUP BY clause,
+// grouping the results separately by each of the provided sets of columns,
+// e.g. GroupingSets([]string{"a"}, []string{"b"}, nil) generates "GROUP BY
+// GROUPING SETS ((a), (b), ())". It can be combined with GroupBy. This is
+// not supported on MySQL and SQLite.
+func (stmt *SelectStmt) GroupingSets(sets ...[]string) *SelectStmt {
+	return stmt.GroupByExpr(GroupingExpr{Kind: "GROUPING SETS", Sets: sets})
+}
+
+// Having sets HAVING conditions for aggregated values. Usage is the
+// same as Where.
+func (stmt *SelectStmt) Having(conditions ...WhereCondition) *SelectStmt {
+	conditions, err := emptyInPolicyOf(stmt.queryer).apply(conditions)
+	if err != nil {
+		stmt.setErr(err)
+	}
+
+	if err := condErr(conditions, driverNameOf(stmt.queryer)); err != nil {
+		stmt.setErr(err)
+	}
+
+	stmt.GroupConditions = append(stmt.GroupConditions, conditions...)
+
+	return stmt
+}
+
+// Limit limits the amount of results returned to the provided value
+// (this is a LIMIT clause). In some database systems, Offset with two
+// values should be used instead.
+func (stmt *SelectStmt) Limit(limit int64) *SelectStmt {
+	stmt.LimitTo = limit
+	stmt.IsWithTies = false
+	return stmt
+}
+
+// LimitWithTies limits the amount of results returned to the provided
+// value, but also returns any further rows that tie with the last row
+// according to the ORDER BY clause, which is required. This generates
+// "FETCH FIRST n ROWS WITH TIES" on PostgreSQL (13 and above), and "SELECT
+// TOP (n) WITH TIES" on SQL Server, where it cannot be combined with an
+// offset. MySQL and SQLite have no equivalent, so the statement fails
+// there; a window function such as RANK() can be used instead.
+func (stmt *SelectStmt) LimitWithTies(limit int64) *SelectStmt {
+	stmt.LimitTo = limit
+	stmt.IsWithTies = true
+	return stmt
+}
+
+// LimitAll removes any limit previously set on the number of results,
+// including one provided as the second parameter of Offset
+func (stmt *SelectStmt) LimitAll() *SelectStmt {
+	stmt.LimitTo = 0
+	stmt.OffsetRows = 0
+	stmt.IsWithTies = false
+	return stmt
+}
+
+// Offset skips the provided number of results. In supporting database
+// systems, you can provide a limit on the number of the returned
+// results as the second parameter. Offset can be used without Limit; as
+// MySQL and SQLite require a LIMIT clause with OFFSET, there the largest
+// possible limit is used ("LIMIT 18446744073709551615" and "LIMIT -1",
+// respectively).
+func (stmt *SelectStmt) Offset(start int64, rows ...int64) *SelectStmt {
+	stmt.OffsetFrom = start
+	if len(rows) > 0 {
+		stmt.OffsetRows = rows[0]
+	}
+
+	return stmt
+}
+
+// Prefix adds raw SQL before the statement, e.g. a comment or a
+// pg_hint_plan hint such as "/*+ SeqScan(users) */" (MySQL optimizer hints
+// must follow the SELECT keyword, so they cannot be added this way).
+// Question mark placeholders in the SQL are bound to the provided values,
+// ahead of the statement's other bindings. Multiple prefixes are added in
+// the order they are provided.
+func (stmt *SelectStmt) Prefix(sql string, args ...interface{}) *SelectStmt {
+	stmt.Prefixes = append(stmt.Prefixes, Indirect(sql, args...))
+	return stmt
+}
+
+// Suffix adds raw SQL after everything else in the statement, for
+// dialect-specific clauses that are not otherwise supported, such as SQL
+// Server's "OPTION (RECOMPILE)" or ClickHouse's "SETTINGS max_threads = ?".
+// Question mark placeholders in the SQL are bound to the provided values,
+// after the statement's other bindings. Multiple suffixes are added in the
+// order they are provided.
+func (stmt *SelectStmt) Suffix(sql string, args ...interface{}) *SelectStmt {
+	stmt.Suffixes = append(stmt.Suffixes, Indirect(sql, args...))
+	return stmt
+}
+
+// UseIndex adds a hint suggesting that the planner use one of the provided
+// indexes to find rows in the statement's table. On MySQL, this renders
+// "USE INDEX (name)" after the table reference. PostgreSQL has no index
+// hints, so they are not rendered unless pg_hint_plan comments are enabled
+// with DB.SetPgHintPlan, in which case USE and FORCE hints are rendered as
+// "/*+ IndexScan(table name) */" at the start of the query (IGNORE hints
+// have no pg_hint_plan equivalent, and are not rendered). Index hints are
+// not rendered on other databases, nor for statements selecting from
+// sub-queries or VALUES lists.
+func (stmt *SelectStmt) UseIndex(indexes ...string) *SelectStmt {
+	return stmt.indexHint(UseIndexHint, indexes)
+}
+
+// ForceIndex is the same as UseIndex, but renders "FORCE INDEX (name)" on
+// MySQL, telling the planner to use a table scan only if none of the
+// indexes can be used.
+func (stmt *SelectStmt) ForceIndex(indexes ...string) *SelectStmt {
+	return stmt.indexHint(ForceIndexHint, indexes)
+}
+
+// IgnoreIndex is the same as UseIndex, but renders "IGNORE INDEX (name)" on
+// MySQL, telling the planner not to use the indexes. It is not rendered on
+// PostgreSQL, even with pg_hint_plan comments enabled.
+func (stmt *SelectStmt) IgnoreIndex(indexes ...string) *SelectStmt {
+	return stmt.indexHint(IgnoreIndexHint, indexes)
+}
+
+func (stmt *SelectStmt) indexHint(kind IndexHintKind, indexes []string) *SelectStmt {
+	if len(indexes) == 0 {
+		stmt.setErr(errors.New("index hints require at least one index"))
+		return stmt
+	}
+
+	stmt.IndexHints = append(stmt.IndexHints, IndexHint{kind, append([]string{}, indexes...)})
+
+	return stmt
+}
+
+// indexHintsFor returns the index hints to add after the table reference
+// of the statement, e.g. "USE INDEX (a, b)"
+func (stmt *SelectStmt) indexHintsFor(driverName string) string {
+	if !isMySQL(driverName) {
+		return ""
+	}
+
+	hints := make([]string, len(stmt.IndexHints))
+	for i, hint := range stmt.IndexHints {
+		hints[i] = string(hint.Kind) + " INDEX (" + strings.Join(hint.Indexes, ", ") + ")"
+	}
+
+	return strings.Join(hints, " ")
+}
+
+// planHint returns the pg_hint_plan comment for the statement's index
+// hints, if enabled. The hints refer to the table by its alias, if it has
+// one, as pg_hint_plan requires.
+func (stmt *SelectStmt) planHint() string {
+	fields := strings.Fields(stmt.Table)
+	if len(fields) == 0 || stmt.FromStmt != nil || len(stmt.ValuesRows) > 0 || !pgHintPlanOf(stmt.queryer) {
+		return ""
+	}
+
+	table := fields[len(fields)-1]
+
+	var hints []string
+
+	for _, hint := range stmt.IndexHints {
+		if hint.Kind != IgnoreIndexHint {
+			hints = append(hints, "IndexScan("+table+" "+strings.Join(hint.Indexes, " ")+")")
+		}
+	}
+
+	if len(hints) == 0 {
+		return ""
+	}
+
+	return "/*+ " + strings.Join(hints, " ") + " */"
+}
+
+// TableSample selects from a random sample of the statement's table rather
+// than from all of its rows, appending a TABLESAMPLE clause after the table
+// reference, e.g. "FROM events TABLESAMPLE SYSTEM (1)". This is useful for
+// fast approximate analytics over huge tables. The method is either
+// "SYSTEM", which samples whole pages and is very fast but less random, or
+// "BERNOULLI", which samples individual rows. The percent is the
+// percentage of rows to sample, between 0 and 100. This is supported on
+// PostgreSQL and, with the SYSTEM method only, on SQL Server (where it
+// renders "TABLESAMPLE SYSTEM (1 PERCENT)"). On other databases, the
+// statement fails; a random sample of a fixed number of rows can be
+// selected there with OrderByRandom and Limit instead, which is much
+// slower.
+func (stmt *SelectStmt) TableSample(method string, percent float64) *SelectStmt {
+	method = strings.ToUpper(method)
+
+	switch {
+	case method != "SYSTEM" && method != "BERNOULLI":
+		stmt.setErr(fmt.Errorf("unsupported sampling method %q", method))
+	case percent < 0 || percent > 100:
+		stmt.setErr(fmt.Errorf("sampling percentage must be between 0 and 100, got %v", percent))
+	}
+
+	stmt.SampleMethod = method
+	stmt.SamplePercent = percent
+
+	return stmt
+}
+
+// tableSampleFor returns the TABLESAMPLE clause of the statement, if any
+func (stmt *SelectStmt) tableSampleFor(driverName string) string {
+	if stmt.SampleMethod == "" {
+		return ""
+	}
+
+	percent := strconv.FormatFloat(stmt.SamplePercent, 'f', -1, 64)
+	if isSQLServer(driverName) {
+		percent += " PERCENT"
+	}
+
+	return "TABLESAMPLE " + stmt.SampleMethod + " (" + percent + ")"
+}
+
+// Lock sets a LOCK clause on the SELECT statement.
+func (stmt *SelectStmt) Lock(lock *LockClause) *SelectStmt {
+	stmt.Locks = append(stmt.Locks, lock)
+	return stmt
+}
+
+// ForUpdate adds a "FOR UPDATE" lock clause on the statement
+func ForUpdate() *LockClause {
+	return &LockClause{Strength: LockForUpdate}
+}
+
+// ForNoKeyUpdate adds a "FOR NO KEY UPDATE" lock clause on the statement
+func ForNoKeyUpdate() *LockClause {
+	return &LockClause{Strength: LockForNoKeyUpdate}
+}
+
+// ForShare adds a "FOR SHARE" lock clause on the statement
+func ForShare() *LockClause {
+	return &LockClause{Strength: LockForShare}
+}
+
+// ForKeyShare adds a "FOR KEY SHARE" lock clause on the statement
+func ForKeyShare() *LockClause {
+	return &LockClause{Strength: LockForKeyShare}
+}
+
+// LockForShare adds a shared row lock on the statement, so that selected
+// rows cannot be modified by other transactions (but can still be locked
+// for share by them) until the current transaction ends. This renders "FOR
+// SHARE" on PostgreSQL and MySQL 8.0+, and "LOCK IN SHARE MODE" on MySQL
+// databases for which DB.SetLegacyShareLocks was enabled. It can be
+// combined with the SkipLocked and NoWait modifiers, except on legacy MySQL
+// databases. Row locks are not supported on SQLite.
+func (stmt *SelectStmt) LockForShare() *SelectStmt {
+	return stmt.Lock(ForShare())
+}
+
+// LockForUpdateOf adds an exclusive row lock on the statement that only
+// locks the rows of the provided tables, e.g. "FOR UPDATE OF jobs", so that
+// rows of other tables joined in the statement (e.g. reference tables) are
+// not locked needlessly. Tables are referenced by their aliases, if they
+// have them. This is supported on PostgreSQL and MySQL 8.0+, and can be
+// combined with the SkipLocked and NoWait modifiers, as is common in
+// worker queues. Row locks are not supported on SQLite.
+func (stmt *SelectStmt) LockForUpdateOf(tables ...string) *SelectStmt {
+	if len(tables) == 0 {
+		stmt.setErr(errors.New("FOR UPDATE OF requires at least one table"))
+		return stmt
+	}
+
+	return stmt.Lock(ForUpdate().OfTables(tables...))
+}
+
+// NoWait sets the last lock clause added to the statement (e.g. through
+// LockForShare) as a NO WAIT lock.
+func (stmt *SelectStmt) NoWait() *SelectStmt {
+	return stmt.setLockWait(LockNoWait)
+}
+
+// SkipLocked sets the last lock clause added to the statement (e.g. through
+// LockForShare) as a SKIP LOCKED lock.
+func (stmt *SelectStmt) SkipLocked() *SelectStmt {
+	return stmt.setLockWait(LockSkipLocked)
+}
+
+func (stmt *SelectStmt) setLockWait(wait LockWait) *SelectStmt {
+	if len(stmt.Locks) == 0 {
+		stmt.setErr(errors.New("lock modifiers require a lock clause"))
+		return stmt
+	}
+
+	stmt.Locks[len(stmt.Locks)-1].Wait = wait
+
+	return stmt
+}
+
+// Err returns the first error encountered while building the statement, if
+// any. On SQL Server, it also returns an error if a limit or offset is set
+// without an ORDER BY clause, which T-SQL requires for pagination.
+func (stmt *SelectStmt) Err() error {
+	if err := stmt.Statement.Err(); err != nil {
+		return err
+	}
+
+	if isSQLServer(driverNameOf(stmt.queryer)) && len(stmt.Ordering) == 0 &&
+		(stmt.LimitTo > 0 || stmt.OffsetFrom > 0 || stmt.OffsetRows > 0) {
+		return errors.New("limit and offset require an ORDER BY clause on SQL Server")
+	}
+
+	for _, order := range stmt.Ordering {
+		if alias, ok := order.(aliasOrder); ok && !stmt.hasAlias(alias.Column, driverNameOf(stmt.queryer)) {
+			return fmt.Errorf("%s is not an alias in the select list", alias.Column)
+		}
+	}
+
+	if isMySQL(driverNameOf(stmt.queryer)) && stmt.hasRollup() &&
+		(len(stmt.groupingColumns()) > 0 || len(stmt.GroupingExprs) > 1) {
+		return errors.New("WITH ROLLUP cannot be combined with other groupings on MySQL")
+	}
+
+	if len(stmt.Locks) > 0 && isSQLite(driverNameOf(stmt.queryer)) {
+		return errors.New("row locks are not supported on SQLite")
+	}
+
+	if driverName := driverNameOf(stmt.queryer); stmt.SampleMethod != "" &&
+		(isMySQL(driverName) || isSQLite(driverName)) {
+		return fmt.Errorf("TABLESAMPLE is not supported by the %s driver", driverName)
+	}
+
+	if stmt.SampleMethod == "BERNOULLI" && isSQLServer(driverNameOf(stmt.queryer)) {
+		return errors.New("SQL Server only supports the SYSTEM sampling method")
+	}
+
+	if driverName := driverNameOf(stmt.queryer); stmt.IsWithTies {
+		switch {
+		case len(stmt.Ordering) == 0:
+			return errors.New("WITH TIES requires an ORDER BY clause")
+		case isMySQL(driverName) || isSQLite(driverName):
+			return fmt.Errorf("WITH TIES is not supported by the %s driver", driverName)
+		case isSQLServer(driverName) && (stmt.OffsetFrom > 0 || stmt.OffsetRows > 0):
+			return errors.New("WITH TIES cannot be combined with an offset on SQL Server")
+		}
+	}
+
+	if legacyShareLocksOf(stmt.queryer) {
+		for _, lock := range stmt.Locks {
+			if lock.Strength == LockForShare && (lock.Wait != LockDefault || len(lock.Tables) > 0) {
+				return errors.New("LOCK IN SHARE MODE does not support NOWAIT, SKIP LOCKED or OF")
+			}
+		}
+	}
+
+	return nil
+}
+
+// WithSessionSettings sets run-time parameters (e.g. "work_mem" or
+// "max_parallel_workers_per_gather") for the execution of the statement,
+// by issuing a "SET LOCAL name = 'value'" statement for each of them
+// (in the order of their names) before executing it. As SET LOCAL only
+// affects the current transaction, this is only supported on PostgreSQL
+// and requires the statement to be created from a Tx object; the settings
+// remain in effect until the end of the transaction.
+func (stmt *SelectStmt) WithSessionSettings(settings map[string]string) *SelectStmt {
+	if !isPostgres(driverNameOf(stmt.queryer)) {
+		stmt.setErr(errors.New("session settings are only supported on PostgreSQL"))
+		return stmt
+	} else if h, ok := stmt.queryer.(*handle); !ok || !isTx(h.Ext) {
+		stmt.setErr(errors.New("session settings require a transaction"))
+		return stmt
+	}
+
+	if stmt.SessionSettings == nil {
+		stmt.SessionSettings = make(map[string]string, len(settings))
+	}
+
+	for name, value := range settings {
+		if !isSettingName(name) {
+			stmt.setErr(fmt.Errorf("invalid setting name %q", name))
+			return stmt
+		}
+
+		stmt.SessionSettings[name] = value
+	}
+
+	return stmt
+}
+
+// isSettingName returns true if the provided string is a valid name of a
+// run-time parameter, which may be qualified (e.g. "pg_trgm.similarity")
+func isSettingName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i := 0; i < len(name); i++ {
+		if !isNameChar(name[i]) && name[i] != '.' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// beforeExec verifies that the statement can be executed, and applies its
+// session settings (if any)
+func (stmt *SelectStmt) beforeExec(ctx context.Context) error {
+	if err := stmt.Err(); err != nil {
+		return err
+	}
+
+	if len(stmt.SessionSettings) == 0 {
+		return nil
+	}
+
+	execer, ok := stmt.queryer.(Ext)
+	if !ok {
+		return errors.New("session settings require a transaction")
+	}
+
+	names := make([]string, 0, len(stmt.SessionSettings))
+	for name := range stmt.SessionSettings {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		_, err := execer.ExecContext(ctx, setLocalSQL(name, stmt.SessionSettings[name]))
+		if err != nil {
+			return fmt.Errorf("failed setting %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// setLocalSQL returns a "SET LOCAL name = 'value'" statement for a run-time
+// parameter
+func setLocalSQL(name, value string) string {
+	return "SET LOCAL " + name + " = '" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// ToSQL generates the SELECT statement's SQL and returns a list of
+// bindings. It is used internally by GetRow and GetAll, but is
+// exported if you wish to use it directly.
+// nolint: gocognit, gocyclo
+func (stmt *SelectStmt) ToSQL(rebind bool) (asSQL string, bindings []interface{}) {
+	if stmt.RawSQL != "" {
+		asSQL = stmt.RawSQL
+		if rebind {
+			asSQL = stmt.commented(stmt.queryer, rebindFor(stmt.queryer, asSQL))
+		}
+
+		return asSQL, append([]interface{}{}, stmt.RawBindings...)
+	}
+
+	var clauses []string
+
+	for _, prefix := range stmt.Prefixes {
+		clauses = append(clauses, prefix.Reference)
+		bindings = append(bindings, prefix.Bindings...)
+	}
+
+	clauses = append(clauses, "SELECT")
+
+	driverName := driverNameOf(stmt.queryer)
+	withTies := stmt.IsWithTies && stmt.LimitTo > 0
+
+	if stmt.IsDistinct {
+		clauses = append(clauses, "DISTINCT")
+		if len(stmt.DistinctColumns) > 0 {
+			clauses = append(clauses, "ON ("+strings.Join(stmt.DistinctColumns, ", ")+")")
+		}
+	}
+
+	// SQL Server only supports WITH TIES in a TOP clause
+	if withTies && isSQLServer(driverName) {
+		clauses = append(clauses, fmt.Sprintf("TOP (%d) WITH TIES", stmt.LimitTo))
+	}
+
+	columns := append([]string{}, stmt.Columns...)
+
+	for _, expr := range stmt.SelectExprs {
+		colSQL, colBindings := exprSQL(expr, driverName)
+		columns = append(columns, colSQL)
+		bindings = append(bindings, colBindings...)
+	}
+
+	if len(columns) == 0 {
+		clauses = append(clauses, "*")
+	} else {
+		clauses = append(clauses, strings.Join(columns, ", "))
+	}
+
+	if fromSQL, fromBindings := stmt.fromClause(driverName); fromSQL != "" {
+		clauses = append(clauses, fromSQL)
+		bindings = append(bindings, fromBindings...)
+	}
+
+	if len(stmt.Conditions) > 0 {
+		whereClause, whereBindings := parseConditions(stmt.Conditions, driverName)
+		bindings = append(bindings, whereBindings...)
+		clauses = append(clauses, fmt.Sprintf("WHERE %s", whereClause))
+	}
+
+	if grouping := stmt.groupingColumns(); len(grouping) > 0 || len(stmt.GroupingExprs) > 0 {
+
+		for _, expr := range stmt.GroupingExprs {
+			groupSQL, groupBindings := exprSQL(expr, driverName)
+			grouping = append(grouping, groupSQL)
+			bindings = append(bindings, groupBindings...)
+		}
+
+		clauses = append(clauses, fmt.Sprintf("GROUP BY %s", strings.Join(grouping, ", ")))
+	}
+
+	if len(stmt.GroupConditions) > 0 {
+		groupByClause, groupBindings := parseConditions(stmt.GroupConditions, driverName)
+		bindings = append(bindings, groupBindings...)
+		clauses = append(clauses, fmt.Sprintf("HAVING %s", groupByClause))
+	}
+
+	for _, op := range stmt.SetOps {
+		opSQL, opBindings := op.Stmt.ToSQL(false)
+		bindings = append(bindings, opBindings...)
+		clauses = append(clauses, op.Op+" "+opSQL)
+	}
+
+	if len(stmt.Ordering) > 0 {
+		var ordering []string
+
+		for _, order := range stmt.Ordering {
+			orderSQL, orderBindings := exprSQL(order, driverName)
+			ordering = append(ordering, orderSQL)
+			bindings = append(bindings, orderBindings...)
+		}
+
+		clauses = append(clauses, fmt.Sprintf("ORDER BY %s", strings.Join(ordering, ", ")))
+
+		if stmt.orderWithNulls.Enabled {
+			if stmt.orderWithNulls.First {
+				clauses = append(clauses, "NULLS FIRST")
+			} else {
+				clauses = append(clauses, "NULLS LAST")
+			}
+		}
+	}
+
+	if isSQLServer(driverName) && !withTies {
+		// T-SQL has no LIMIT clause, rows are paginated via the OFFSET and
+		// FETCH options of the ORDER BY clause
+		limit := stmt.LimitTo
+		if stmt.OffsetRows > 0 {
+			limit = stmt.OffsetRows
+		}
+
+		if stmt.OffsetFrom > 0 || limit > 0 {
+			clauses = append(clauses, fmt.Sprintf("OFFSET %d ROWS", stmt.OffsetFrom))
+		}
+
+		if limit > 0 {
+			clauses = append(clauses, fmt.Sprintf("FETCH NEXT %d ROWS ONLY", limit))
+		}
+	} else if stmt.LimitTo > 0 && !withTies {
+		clauses = append(clauses, fmt.Sprintf("LIMIT %d", stmt.LimitTo))
+	} else if stmt.OffsetFrom > 0 && isMySQL(driverName) {
+		// MySQL doesn't allow OFFSET without LIMIT, the documented idiom
+		// is to use the largest possible limit
+		clauses = append(clauses, "LIMIT 18446744073709551615")
+	} else if stmt.OffsetFrom > 0 && isSQLite(driverName) {
+		// neither does SQLite, where a negative limit means no limit
+		clauses = append(clauses, "LIMIT -1")
+	}
+
+	if stmt.OffsetFrom > 0 && !isSQLServer(driverName) {
+		offset := fmt.Sprintf("%d", stmt.OffsetFrom)
+		if stmt.OffsetRows > 0 {
+			offset += fmt.Sprintf(" %d", stmt.OffsetRows)
+		}
+
+		clauses = append(clauses, "OFFSET "+offset)
+	}
+
+	if withTies && !isSQLServer(driverName) {
+		clauses = append(clauses, fmt.Sprintf("FETCH FIRST %d ROWS WITH TIES", stmt.LimitTo))
+	}
+
+	for _, lock := range stmt.Locks {
+		var lockStrength string
+
+		switch lock.Strength {
+		case LockForUpdate:
 			lockStrength = "FOR UPDATE"
 		case LockForNoKeyUpdate:
 			lockStrength = "FOR NO KEY UPDATE"
 		case LockForShare:
+			if legacyShareLocksOf(stmt.queryer) {
+				clauses = append(clauses, "LOCK IN SHARE MODE")
+				continue
+			}
+
 			lockStrength = "FOR SHARE"
 		case LockForKeyShare:
 			lockStrength = "FOR KEY SHARE"
@@ -507,30 +1977,124 @@ func (stmt *SelectStmt) ToSQL(rebind bool) (asSQL string, bindings []interface{}
 		}
 	}
 
+	for _, suffix := range stmt.Suffixes {
+		clauses = append(clauses, suffix.Reference)
+		bindings = append(bindings, suffix.Bindings...)
+	}
+
 	asSQL = strings.Join(clauses, " ")
 
 	if rebind {
-		if db, ok := stmt.queryer.(*sqlx.DB); ok {
-			asSQL = db.Rebind(asSQL)
-		} else if tx, ok := stmt.queryer.(*sqlx.Tx); ok {
-			asSQL = tx.Rebind(asSQL)
+		asSQL = stmt.commented(stmt.queryer, rebindFor(stmt.queryer, asSQL))
+
+		// pg_hint_plan only reads hints at the very start of the query
+		if hint := stmt.planHint(); hint != "" {
+			asSQL = hint + " " + asSQL
 		}
 	}
 
 	return asSQL, bindings
 }
 
+// fromClause generates the FROM clause of the statement, including joins.
+// As SQLite doesn't support RIGHT JOIN, such joins are rewritten on SQLite
+// as LEFT JOINs with their operands swapped, i.e. "FROM a RIGHT JOIN b ON
+// cond" becomes "FROM b LEFT JOIN a ON cond". If the RIGHT JOIN follows
+// other joins, its left operand is the parenthesized join of the preceding
+// tables. The order of explicitly selected columns is unaffected, but
+// "SELECT *" returns the columns of the swapped tables in their new order.
+func (stmt *SelectStmt) fromClause(driverName string) (asSQL string, bindings []interface{}) {
+	var from string
+
+	switch {
+	case stmt.FromStmt != nil:
+		fromSQL, fromBindings := stmt.FromStmt.ToSQL(false)
+		from = "(" + fromSQL + ") AS " + stmt.FromStmtAlias
+		bindings = append(bindings, fromBindings...)
+	case len(stmt.ValuesRows) > 0:
+		var valuesBindings []interface{}
+		from, valuesBindings = valuesTable(driverName, stmt.ValuesAlias, stmt.ValuesColumns, stmt.ValuesRows)
+		bindings = append(bindings, valuesBindings...)
+	default:
+		from = stmt.Table
+		if sample := stmt.tableSampleFor(driverName); sample != "" && from != "" {
+			from += " " + sample
+		}
+
+		if hints := stmt.indexHintsFor(driverName); hints != "" && from != "" {
+			from += " " + hints
+		}
+	}
+
+	var joined bool
+
+	for _, join := range stmt.Joins {
+		onClause, joinBindings := parseConditions(join.Conditions, driverName)
+
+		var (
+			target         = join.Table
+			targetBindings = join.Bindings
+		)
+
+		if join.ResultSet != nil {
+			rsSQL, rsBindings := join.ResultSet.ToSQL(false)
+			target = "(" + rsSQL + ") " + join.Table
+			targetBindings = rsBindings
+		}
+
+		if join.Type == RightJoin && isSQLite(driverName) && from != "" {
+			if joined {
+				from = "(" + from + ")"
+			}
+
+			from = target + " " + LeftJoin.String() + " " + from + " ON " + onClause
+			bindings = append(append(append([]interface{}{}, targetBindings...), bindings...), joinBindings...)
+		} else {
+			from = strings.TrimSpace(from + " " + join.Type.String() + " " + target + " ON " + onClause)
+
+			// add the join condition bindings (this MUST happen after adding
+			// the target's bindings, because if the join is on a result set
+			// then the result set's bindings need to come first
+			bindings = append(append(bindings, targetBindings...), joinBindings...)
+		}
+
+		joined = true
+	}
+
+	if from == "" {
+		return "", bindings
+	}
+
+	if stmt.FromStmt != nil || len(stmt.ValuesRows) > 0 || stmt.Table != "" {
+		from = "FROM " + from
+	}
+
+	return from, bindings
+}
+
+// ToSQLChecked is the same as ToSQL, but also returns an error if the
+// number of placeholders in the generated SQL doesn't equal the number of
+// bindings, or if the statement failed to build. This allows failing fast
+// on malformed dynamic queries before executing them.
+func (stmt *SelectStmt) ToSQLChecked(rebind bool) (asSQL string, bindings []interface{}, err error) {
+	return checkedSQL(stmt, rebind)
+}
+
+// Args returns the values bound to the statement, in the order of their
+// placeholders in the SQL generated by ToSQL. This is useful for
+// inspecting or redacting values (e.g. before logging them) without
+// handling the SQL itself.
+func (stmt *SelectStmt) Args() []interface{} {
+	_, bindings := stmt.ToSQL(false)
+	return bindings
+}
+
 // GetRow executes the SELECT statement and loads the first
 // result into the provided variable (which may be a simple
 // variable if only one column was selected, or a struct if
 // multiple columns were selected).
 func (stmt *SelectStmt) GetRow(into interface{}) error {
-	asSQL, bindings := stmt.ToSQL(true)
-
-	err := sqlx.Get(stmt.queryer, into, asSQL, bindings...)
-	stmt.HandleError(err)
-
-	return err
+	return stmt.GetRowContext(context.Background(), into)
 }
 
 // GetRowContext executes the SELECT statement and loads the first
@@ -538,28 +2102,76 @@ func (stmt *SelectStmt) GetRow(into interface{}) error {
 // variable if only one column was selected, or a struct if
 // multiple columns were selected).
 func (stmt *SelectStmt) GetRowContext(ctx context.Context, into interface{}) error {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if err := stmt.beforeExec(ctx); err != nil {
+		stmt.HandleError(err)
+		return err
+	}
+
 	asSQL, bindings := stmt.ToSQL(true)
 
-	err := sqlx.GetContext(ctx, stmt.queryer, into, asSQL, bindings...)
+	err := mapErrorOf(stmt.queryer, sqlx.GetContext(ctx, stmt.queryer, into, asSQL, bindings...))
 	stmt.HandleError(err)
 
 	return err
 }
 
+// GetInt64 executes a SELECT statement expected to return one row with one
+// column, such as "SELECT SUM(x) FROM ...", and returns its value as an
+// int64. If there are no rows, sql.ErrNoRows is returned. Note that
+// aggregates over no rows return NULL, which cannot be loaded into an
+// int64, so wrap them with COALESCE if necessary.
+func (stmt *SelectStmt) GetInt64() (val int64, err error) {
+	return stmt.GetInt64Context(context.Background())
+}
+
+// GetInt64Context is the same as GetInt64, but receives a context.
+func (stmt *SelectStmt) GetInt64Context(ctx context.Context) (val int64, err error) {
+	err = stmt.GetRowContext(ctx, &val)
+	return val, err
+}
+
+// GetFloat64 is the same as GetInt64, but returns a float64.
+func (stmt *SelectStmt) GetFloat64() (val float64, err error) {
+	return stmt.GetFloat64Context(context.Background())
+}
+
+// GetFloat64Context is the same as GetFloat64, but receives a context.
+func (stmt *SelectStmt) GetFloat64Context(ctx context.Context) (val float64, err error) {
+	err = stmt.GetRowContext(ctx, &val)
+	return val, err
+}
+
+// GetString is the same as GetInt64, but returns a string.
+func (stmt *SelectStmt) GetString() (val string, err error) {
+	return stmt.GetStringContext(context.Background())
+}
+
+// GetStringContext is the same as GetString, but receives a context.
+func (stmt *SelectStmt) GetStringContext(ctx context.Context) (val string, err error) {
+	err = stmt.GetRowContext(ctx, &val)
+	return val, err
+}
+
 // GetAll executes the SELECT statement and loads all the
 // results into the provided slice variable.
 func (stmt *SelectStmt) GetAll(into interface{}) error {
-	asSQL, bindings := stmt.ToSQL(true)
-
-	err := sqlx.Select(stmt.queryer, into, asSQL, bindings...)
-	stmt.HandleError(err)
-
-	return err
+	return stmt.GetAllContext(context.Background(), into)
 }
 
 // GetAllContext executes the SELECT statement and loads all the
 // results into the provided slice variable.
 func (stmt *SelectStmt) GetAllContext(ctx context.Context, into interface{}) error {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if err := stmt.beforeExec(ctx); err != nil {
+		stmt.HandleError(err)
+		return err
+	}
+
 	asSQL, bindings := stmt.ToSQL(true)
 
 	err := sqlx.SelectContext(ctx, stmt.queryer, into, asSQL, bindings...)
@@ -573,24 +2185,22 @@ func (stmt *SelectStmt) GetAllContext(ctx context.Context, into interface{}) err
 // total number of matching results. This is useful when
 // paginating results.
 func (stmt *SelectStmt) GetCount() (count int64, err error) {
-	defer stmt.HandleError(err)
+	return stmt.GetCountContext(context.Background())
+}
 
-	countStmt := *stmt
-	countStmt.Columns = []string{"COUNT(*)"}
-	countStmt.LimitTo = 0
-	countStmt.OffsetFrom = 0
-	countStmt.OffsetRows = 0
-	countStmt.Ordering = []SQLStmt{}
+// GetCountContext executes the SELECT statement disregarding limits,
+// offsets, selected columns and ordering; and returns the
+// total number of matching results. This is useful when
+// paginating results.
+func (stmt *SelectStmt) GetCountContext(ctx context.Context) (count int64, err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
 
-	for _, st := range countStmt.Unions {
-		st.Columns = []string{"COUNT(*)"}
-		st.LimitTo = 0
-		st.OffsetFrom = 0
-		st.OffsetRows = 0
-		st.Ordering = []SQLStmt{}
-	}
+	defer stmt.HandleError(err)
+
+	countStmt := stmt.createCountQuery("COUNT(*)")
 
-	rows, err := countStmt.GetAllAsRows()
+	rows, err := countStmt.GetAllAsTimedRowsContext(ctx)
 	if err != nil {
 		return count, err
 	}
@@ -612,14 +2222,37 @@ func (stmt *SelectStmt) GetCount() (count int64, err error) {
 	return count, err
 }
 
-// GetCountContext executes the SELECT statement disregarding limits,
-// offsets, selected columns and ordering; and returns the
-// total number of matching results. This is useful when
-// paginating results.
-func (stmt *SelectStmt) GetCountContext(ctx context.Context) (count int64, err error) {
-	countStmt := *stmt
-	countStmt.Columns = []string{"COUNT(*)"}
+// GetDistinctCount executes the SELECT statement disregarding limits,
+// offsets, selected columns and ordering; and returns the number of
+// distinct values of the provided column among the matching results
+// (i.e. "SELECT COUNT(DISTINCT col) FROM ..."). Statements with a GROUP BY
+// clause, unions or other set operations, and raw statements are not
+// supported, as their results cannot be counted this way.
+func (stmt *SelectStmt) GetDistinctCount(col string) (count int64, err error) {
+	return stmt.GetDistinctCountContext(context.Background(), col)
+}
+
+// GetDistinctCountContext is the same as GetDistinctCount, but receives a
+// context.
+func (stmt *SelectStmt) GetDistinctCountContext(ctx context.Context, col string) (count int64, err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if len(stmt.groupingColumns()) > 0 || len(stmt.GroupingExprs) > 0 ||
+		len(stmt.Unions) > 0 || len(stmt.SetOps) > 0 || stmt.RawSQL != "" {
+		err = errors.New("distinct counts are not supported on grouped, combined or raw statements")
+		stmt.HandleError(err)
+
+		return count, err
+	}
+
+	countStmt := stmt.Clone()
+	countStmt.Columns = []string{"COUNT(DISTINCT " + col + ")"}
+	countStmt.SelectExprs = nil
+	countStmt.IsDistinct = false
+	countStmt.DistinctColumns = nil
 	countStmt.LimitTo = 0
+	countStmt.IsWithTies = false
 	countStmt.OffsetFrom = 0
 	countStmt.OffsetRows = 0
 	countStmt.Ordering = []SQLStmt{}
@@ -629,15 +2262,92 @@ func (stmt *SelectStmt) GetCountContext(ctx context.Context) (count int64, err e
 	return count, err
 }
 
+// GetEstimatedDistinctCount returns an estimate of the number of distinct
+// values of the provided column in the statement's table, based on the
+// statistics collected by PostgreSQL's planner (pg_stats.n_distinct). This
+// is much cheaper than GetDistinctCount on large tables, but ignores the
+// statement's conditions and joins, and is only as accurate as the latest
+// ANALYZE of the table. It is only supported on PostgreSQL, and returns
+// an error if no statistics are available for the column.
+func (stmt *SelectStmt) GetEstimatedDistinctCount(col string) (count int64, err error) {
+	return stmt.GetEstimatedDistinctCountContext(context.Background(), col)
+}
+
+// GetEstimatedDistinctCountContext is the same as GetEstimatedDistinctCount,
+// but receives a context.
+func (stmt *SelectStmt) GetEstimatedDistinctCountContext(ctx context.Context, col string) (count int64, err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if !isPostgres(driverNameOf(stmt.queryer)) {
+		err = errors.New("estimated distinct counts are only supported on PostgreSQL")
+		stmt.HandleError(err)
+
+		return count, err
+	}
+
+	// the table may be aliased or schema-qualified, and the column may be
+	// qualified with the table's name or alias
+	fields := strings.Fields(stmt.Table)
+	if len(fields) == 0 {
+		err = errors.New("estimated distinct counts require a table")
+		stmt.HandleError(err)
+
+		return count, err
+	}
+
+	// the table is resolved with to_regclass, so that unqualified names
+	// follow the search path like they do in the statement itself, rather
+	// than matching same-named tables in other schemas
+	col = col[strings.LastIndex(col, ".")+1:]
+
+	estimateStmt := &SelectStmt{
+		Table: "pg_stats s",
+		Columns: []string{
+			"(CASE WHEN s.n_distinct < 0 THEN -s.n_distinct * c.reltuples ELSE s.n_distinct END)::bigint",
+		},
+		Joins: []JoinClause{{
+			Type:       InnerJoin,
+			Table:      "pg_class c",
+			Conditions: []WhereCondition{SQLCond("c.oid = (quote_ident(s.schemaname) || '.' || quote_ident(s.tablename))::regclass")},
+		}},
+		Conditions: []WhereCondition{
+			SQLCond("c.oid = to_regclass(?)", fields[0]),
+			Eq("s.attname", col),
+		},
+		queryer:   stmt.queryer,
+		Statement: stmt.Statement,
+	}
+
+	err = estimateStmt.GetRowContext(ctx, &count)
+
+	return count, err
+}
+
 // GetAllAsMaps executes the SELECT statement and returns all results as a slice
 // of maps from string to empty interfaces. This is useful for intermediary
 // query where creating a struct type would be redundant
 func (stmt *SelectStmt) GetAllAsMaps() (maps []map[string]interface{}, err error) {
+	return stmt.GetAllAsMapsContext(context.Background())
+}
+
+// GetAllAsMapsContext executes the SELECT statement and returns all results as
+// a slice of maps from string to empty interfaces. This is useful for
+// intermediary query where creating a struct type would be redundant
+func (stmt *SelectStmt) GetAllAsMapsContext(ctx context.Context) (maps []map[string]interface{}, err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
 	defer stmt.HandleError(err)
 
+	if err = stmt.beforeExec(ctx); err != nil {
+		stmt.HandleError(err)
+		return maps, err
+	}
+
 	asSQL, bindings := stmt.ToSQL(true)
 
-	rows, err := stmt.queryer.Queryx(asSQL, bindings...)
+	rows, err := stmt.queryer.QueryxContext(ctx, asSQL, bindings...)
 	if err != nil {
 		return maps, err
 	}
@@ -667,37 +2377,171 @@ func (stmt *SelectStmt) GetAllAsMaps() (maps []map[string]interface{}, err error
 // map from string to empty interfaces. This is useful for intermediary query
 // where creating a struct type would be redundant
 func (stmt *SelectStmt) GetRowAsMap() (results map[string]interface{}, err error) {
+	return stmt.GetRowAsMapContext(context.Background())
+}
+
+// GetRowAsMapContext executes the SELECT statement and returns the first result
+// as a map from string to empty interfaces. This is useful for intermediary
+// query where creating a struct type would be redundant
+func (stmt *SelectStmt) GetRowAsMapContext(ctx context.Context) (results map[string]interface{}, err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if err = stmt.beforeExec(ctx); err != nil {
+		stmt.HandleError(err)
+		return results, err
+	}
+
 	asSQL, bindings := stmt.ToSQL(true)
 	results = make(map[string]interface{})
 
-	err = stmt.queryer.QueryRowx(asSQL, bindings...).MapScan(results)
+	err = stmt.queryer.QueryRowxContext(ctx, asSQL, bindings...).MapScan(results)
 	stmt.HandleError(err)
 
 	return results, err
 }
 
+// GetColumnar executes the SELECT statement and returns the results in
+// column-oriented form, as a map from column names to slices holding the
+// values of the column in every row (in the order of the rows). This is
+// useful for charting libraries that expect data as columns.
+func (stmt *SelectStmt) GetColumnar() (columns map[string][]interface{}, err error) {
+	return stmt.GetColumnarContext(context.Background())
+}
+
+// GetColumnarContext is the same as GetColumnar, but receives a context.
+func (stmt *SelectStmt) GetColumnarContext(ctx context.Context) (columns map[string][]interface{}, err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	rows, err := stmt.GetAllAsTimedRowsContext(ctx)
+	if err != nil {
+		return columns, err
+	}
+
+	defer rows.Close()
+
+	names, err := rows.Columns()
+	if err != nil {
+		stmt.HandleError(err)
+		return columns, err
+	}
+
+	columns = make(map[string][]interface{}, len(names))
+	for _, name := range names {
+		columns[name] = []interface{}{}
+	}
+
+	for rows.Next() {
+		results := make(map[string]interface{}, len(names))
+
+		if err = rows.MapScan(results); err != nil {
+			stmt.HandleError(err)
+			return columns, err
+		}
+
+		for _, name := range names {
+			columns[name] = append(columns[name], results[name])
+		}
+	}
+
+	err = rows.Err()
+	stmt.HandleError(err)
+
+	return columns, err
+}
+
+// Each executes the SELECT statement and calls the provided function for
+// every row in the results, one row at a time, rather than loading all of
+// them into memory. The function receives a scan function, which works
+// like sql.Rows.Scan on the current row. If the function returns an error,
+// iteration stops and that error is returned. The rows are always closed
+// when Each returns, even if the function panics.
+func (stmt *SelectStmt) Each(fn func(scan func(dest ...interface{}) error) error) error {
+	return stmt.EachContext(context.Background(), fn)
+}
+
+// EachContext is the same as Each, but receives a context.
+func (stmt *SelectStmt) EachContext(
+	ctx context.Context,
+	fn func(scan func(dest ...interface{}) error) error,
+) error {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	rows, err := stmt.GetAllAsTimedRowsContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = fn(rows.Scan); err != nil {
+			return err
+		}
+	}
+
+	err = rows.Err()
+	stmt.HandleError(err)
+
+	return err
+}
+
 // GetAllAsRows executes the SELECT statement and returns an sqlx.Rows object
 // to use for iteration. It is the caller's responsibility to close the cursor
 // with Close().
 func (stmt *SelectStmt) GetAllAsRows() (rows *sqlx.Rows, err error) {
-	asSQL, bindings := stmt.ToSQL(true)
-
-	rows, err = stmt.queryer.Queryx(asSQL, bindings...)
-	stmt.HandleError(err)
-
-	return rows, err
+	return stmt.GetAllAsRowsContext(context.Background())
 }
 
 // GetAllAsRowsContext executes the SELECT statement and returns an sqlx.Rows object
 // to use for iteration. It is the caller's responsibility to close the cursor
-// with Close().
+// with Close(). If the statement has a timeout (see WithTimeout), its
+// context is only released once the timeout elapses; use
+// GetAllAsTimedRowsContext to release it when the rows are closed.
 func (stmt *SelectStmt) GetAllAsRowsContext(ctx context.Context) (rows *sqlx.Rows, err error) {
+	timedRows, err := stmt.GetAllAsTimedRowsContext(ctx)
+	if err != nil {
+		return rows, err
+	}
+
+	return timedRows.Rows, nil
+}
+
+// GetAllAsTimedRows is the same as GetAllAsRows, but returns a Rows object
+// that releases the statement's timeout when closed.
+func (stmt *SelectStmt) GetAllAsTimedRows() (rows *Rows, err error) {
+	return stmt.GetAllAsTimedRowsContext(context.Background())
+}
+
+// GetAllAsTimedRowsContext is the same as GetAllAsRowsContext, but returns a
+// Rows object that releases the statement's timeout when closed.
+func (stmt *SelectStmt) GetAllAsTimedRowsContext(ctx context.Context) (rows *Rows, err error) {
+	// the rows are still in use when returning, so the context is cancelled
+	// once they are closed
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+
+	if err = stmt.beforeExec(ctx); err != nil {
+		cancel()
+		stmt.HandleError(err)
+
+		return rows, err
+	}
+
 	asSQL, bindings := stmt.ToSQL(true)
 
-	rows, err = stmt.queryer.QueryxContext(ctx, asSQL, bindings...)
+	sqlxRows, err := stmt.queryer.QueryxContext(ctx, asSQL, bindings...)
+	if err != nil {
+		cancel()
+		stmt.HandleError(err)
+
+		return rows, err
+	}
+
 	stmt.HandleError(err)
 
-	return rows, err
+	return &Rows{sqlxRows, cancel}, nil
 }
 
 // Union adds the 'UNION' command between two or more SELECT statements.
@@ -706,6 +2550,28 @@ func (stmt *SelectStmt) Union(statements ...*SelectStmt) *SelectStmt {
 	return stmt
 }
 
+// Intersect adds an INTERSECT operation, so that only rows returned by
+// both the statement and the provided statement are returned. Unlike
+// Union, the statement's ORDER BY, LIMIT and OFFSET clauses are rendered
+// after the operation, and thus apply to its result. The provided
+// statement should not have such clauses of its own. Note that MySQL only
+// supports INTERSECT since version 8.0.31; on earlier versions, rewrite
+// the query with an inner join or an EXISTS condition.
+func (stmt *SelectStmt) Intersect(other *SelectStmt) *SelectStmt {
+	stmt.SetOps = append(stmt.SetOps, SetOperation{Op: "INTERSECT", Stmt: other})
+	return stmt
+}
+
+// Except adds an EXCEPT operation, so that rows returned by the provided
+// statement are removed from the statement's results. As with Intersect,
+// ORDER BY, LIMIT and OFFSET clauses apply to the result of the operation.
+// MySQL only supports EXCEPT since version 8.0.31; on earlier versions,
+// rewrite the query with a LEFT JOIN or a NOT EXISTS condition.
+func (stmt *SelectStmt) Except(other *SelectStmt) *SelectStmt {
+	stmt.SetOps = append(stmt.SetOps, SetOperation{Op: "EXCEPT", Stmt: other})
+	return stmt
+}
+
 // UnionAll adds the 'UNION ALL' command between two or more SELECT statements.
 func (stmt *SelectStmt) UnionAll(statements ...*SelectStmt) *SelectStmt {
 	stmt.IsUnionAll = true
@@ -1,8 +1,26 @@
 package sqlz
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
 
 func TestSelect(t *testing.T) {
+	name := "john"
+
+	filter := struct {
+		Name   *string `db:"name"`
+		Age    *int    `db:"age"`
+		Active bool    `db:"active"`
+	}{Name: &name}
+
 	runTests(t, func(dbz *DB) []test {
 		return []test{
 			{
@@ -19,6 +37,17 @@ func TestSelect(t *testing.T) {
 				[]interface{}{},
 			},
 
+			{
+				"select with composite join condition",
+				dbz.Select("*").From("a").InnerJoin("b", And(
+					Eq("a.x", Indirect("b.x")),
+					Eq("a.y", Indirect("b.y")),
+					Eq("b.active", true),
+				)).Where(Eq("a.id", 1)),
+				"SELECT * FROM a INNER JOIN b ON a.x = b.x AND a.y = b.y AND b.active = ? WHERE a.id = ?",
+				[]interface{}{true, 1},
+			},
+
 			{
 				"select cols with where clause",
 				dbz.Select("id", "name").From("table").Where(Eq("integer-col", 2), Eq("string-col", "string"), Gt("real-col", 3.2)),
@@ -87,6 +116,197 @@ func TestSelect(t *testing.T) {
 				[]interface{}{2, "bla"},
 			},
 
+			{
+				"select with row comparison",
+				dbz.Select("*").From("table").Where(RowEq([]string{"a", "b"}, []interface{}{1, "two"}), Eq("c", 3)),
+				"SELECT * FROM table WHERE (a, b) = ROW(?, ?) AND c = ?",
+				[]interface{}{1, "two", 3},
+			},
+
+			{
+				"select with a single row comparison",
+				dbz.Select("*").From("table").Where(RowEq([]string{"a", "b"}, []interface{}{1, Indirect("NOW()")})),
+				"SELECT * FROM table WHERE (a, b) = ROW(?, NOW())",
+				[]interface{}{1},
+			},
+
+			{
+				"select with an optional filter applied",
+				dbz.Select("*").From("table").Where(Eq("active", true)).
+					ApplyIf(true, func(stmt *SelectStmt) *SelectStmt {
+						return stmt.Where(ILike("name", "%john%"))
+					}).
+					Limit(10),
+				"SELECT * FROM table WHERE active = ? AND name ILIKE ? LIMIT 10",
+				[]interface{}{true, "%john%"},
+			},
+
+			{
+				"select with an optional filter skipped",
+				dbz.Select("*").From("table").Where(Eq("active", true)).
+					ApplyIf(false, func(stmt *SelectStmt) *SelectStmt {
+						return stmt.Where(ILike("name", "%john%"))
+					}).
+					Limit(10),
+				"SELECT * FROM table WHERE active = ? LIMIT 10",
+				[]interface{}{true},
+			},
+
+			{
+				"select with in condition from a slice",
+				dbz.Select("*").From("table").Where(In("id", []int64{1, 2, 3}), NotIn("type", []string{"a", "b"})),
+				"SELECT * FROM table WHERE id IN (?, ?, ?) AND type NOT IN (?, ?)",
+				[]interface{}{int64(1), int64(2), int64(3), "a", "b"},
+			},
+
+			{
+				"select with in condition from an interface slice",
+				dbz.Select("*").From("table").Where(In("id", []interface{}{1, "b"})),
+				"SELECT * FROM table WHERE id IN (?, ?)",
+				[]interface{}{1, "b"},
+			},
+
+			{
+				"select with in conditions from empty slices",
+				dbz.Select("*").From("table").Where(In("id", []int{}), NotIn("type", []string{}), Eq("a", 1)),
+				"SELECT * FROM table WHERE 1 = 0 AND 1 = 0 AND a = ?",
+				[]interface{}{1},
+			},
+
+			{
+				"select with a map of equality conditions",
+				dbz.Select("*").From("table").Where(WhereMap(map[string]interface{}{"c": 3, "a": 1, "b": nil}), Gt("d", 4)),
+				"SELECT * FROM table WHERE (a = ? AND b IS NULL AND c = ?) AND d > ?",
+				[]interface{}{1, 3, 4},
+			},
+
+			{
+				"select with conditions from non-nil struct fields",
+				dbz.Select("*").From("table").Where(EqStructNonNil(filter)),
+				"SELECT * FROM table WHERE name = ?",
+				[]interface{}{"john"},
+			},
+
+			{
+				"select excluding soft deleted rows",
+				dbz.Select("*").From("table").Where(Eq("id", 1)).ExcludeSoftDeleted("deleted_at"),
+				"SELECT * FROM table WHERE id = ? AND deleted_at IS NULL",
+				[]interface{}{1},
+			},
+
+			{
+				"select excluding soft deleted rows by flag",
+				dbz.Select("*").From("table").ExcludeSoftDeleted("deleted", false),
+				"SELECT * FROM table WHERE deleted = ?",
+				[]interface{}{false},
+			},
+
+			{
+				"select top n per group with a window function",
+				dbz.Select("id", "category").From("table").Where(Eq("active", true)).
+					TopNPerGroupWindow("category", "created_at DESC", 3),
+				"SELECT * FROM (SELECT id, category, ROW_NUMBER() OVER (PARTITION BY category ORDER BY created_at DESC) AS rn " +
+					"FROM table WHERE active = ?) AS ranked WHERE rn <= ?",
+				[]interface{}{true, 3},
+			},
+
+			{
+				"select latest row per group with group count",
+				dbz.Select("*").From("events").Where(Eq("type", "login")).LatestPerGroup("user_id", "created_at"),
+				"SELECT * FROM (SELECT *, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at DESC) AS rn, " +
+					"COUNT(*) OVER (PARTITION BY user_id) AS group_count FROM events WHERE type = ?) AS ranked WHERE rn = 1",
+				[]interface{}{"login"},
+			},
+
+			{
+				"select with qualify emulation",
+				dbz.Select("id", "user_id").From("events").Where(Eq("type", "login")).
+					Qualify(Lte("ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at DESC)", 2)),
+				"SELECT * FROM (SELECT id, user_id, " +
+					"CASE WHEN ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at DESC) <= ? THEN 1 ELSE 0 END AS qualified " +
+					"FROM events WHERE type = ?) AS ranked WHERE qualified = 1",
+				[]interface{}{2, "login"},
+			},
+
+			{
+				"select with inclusive between",
+				dbz.Select("*").From("table").Where(BetweenOpts("num", 1, 10, true, true)),
+				"SELECT * FROM table WHERE num BETWEEN ? AND ?",
+				[]interface{}{1, 10},
+			},
+
+			{
+				"select with exclusive between",
+				dbz.Select("*").From("table").Where(BetweenOpts("num", 1, 10, false, false)),
+				"SELECT * FROM table WHERE num > ? AND num < ?",
+				[]interface{}{1, 10},
+			},
+
+			{
+				"select with low inclusive between",
+				dbz.Select("*").From("table").Where(BetweenOpts("num", 1, 10, true, false), Eq("a", 2)),
+				"SELECT * FROM table WHERE (num >= ? AND num < ?) AND a = ?",
+				[]interface{}{1, 10, 2},
+			},
+
+			{
+				"select with high inclusive between",
+				dbz.Select("*").From("table").Where(BetweenOpts("num", 1, Indirect("other"), false, true)),
+				"SELECT * FROM table WHERE num > ? AND num <= other",
+				[]interface{}{1},
+			},
+
+			{
+				"select with exclusive time range",
+				dbz.Select("*").From("table").Where(BetweenExclusive("created_at", "2020-01-01", "2020-02-01")),
+				"SELECT * FROM table WHERE created_at > ? AND created_at < ?",
+				[]interface{}{"2020-01-01", "2020-02-01"},
+			},
+
+			{
+				"select with half-open time ranges",
+				dbz.Select("*").From("table").Where(Or(
+					BetweenLeftInclusive("created_at", "2020-01-01", "2020-02-01"),
+					BetweenRightInclusive("updated_at", "2020-03-01", "2020-04-01"),
+				)),
+				"SELECT * FROM table WHERE (created_at >= ? AND created_at < ?) OR (updated_at > ? AND updated_at <= ?)",
+				[]interface{}{"2020-01-01", "2020-02-01", "2020-03-01", "2020-04-01"},
+			},
+
+			{
+				"select with not between",
+				dbz.Select("*").From("table").Where(NotBetween("num", 1, 10), Between("other", 2, 3)),
+				"SELECT * FROM table WHERE num NOT BETWEEN ? AND ? AND other BETWEEN ? AND ?",
+				[]interface{}{1, 10, 2, 3},
+			},
+
+			{
+				"select with named condition",
+				dbz.Select("*").From("table").Where(
+					NamedCond("(a = :val OR b = :val) AND c::text <> ':skip'", map[string]interface{}{"val": 3}),
+				),
+				"SELECT * FROM table WHERE (a = ? OR b = ?) AND c::text <> ':skip'",
+				[]interface{}{3, 3},
+			},
+
+			{
+				"select with named condition expanding a slice",
+				dbz.Select("*").From("table").Where(
+					NamedCond("id IN (:ids) AND name = :name", map[string]interface{}{"ids": []int{1, 2, 3}, "name": "a"}),
+				),
+				"SELECT * FROM table WHERE id IN (?, ?, ?) AND name = ?",
+				[]interface{}{1, 2, 3, "a"},
+			},
+
+			{
+				"select with named condition with an empty slice",
+				dbz.Select("*").From("table").Where(
+					NamedCond("id NOT IN (:ids)", map[string]interface{}{"ids": []int{}}),
+				),
+				"SELECT * FROM table WHERE id NOT IN (NULL)",
+				[]interface{}{},
+			},
+
 			{
 				"select for update",
 				dbz.Select("*").From("table").Where(Eq("id", 1)).Lock(ForUpdate()),
@@ -131,6 +351,12 @@ func TestSelect(t *testing.T) {
 				"SELECT a.id, a.value FROM table a RIGHT JOIN LATERAL (SELECT count FROM table WHERE a.value > ?) counts ON a.id = b.id WHERE a.id = ?",
 				[]interface{}{0, 1},
 			},
+			{
+				"select with an unnested array join",
+				dbz.Select("DISTINCT a.id").From("table a").UnnestJoin("a.tags", "t", "tag").Where(Eq("t.tag", "red")),
+				"SELECT DISTINCT a.id FROM table a INNER JOIN LATERAL unnest(a.tags) AS t(tag) ON true WHERE t.tag = ?",
+				[]interface{}{"red"},
+			},
 			{
 				"select with a single union",
 				dbz.Select("a.name").From("table a").Where(Eq("a.name", "a")).Union(
@@ -158,3 +384,2144 @@ func TestSelect(t *testing.T) {
 		}
 	})
 }
+
+func TestSelectToSQLChecked(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	asSQL, bindings, err := dbz.Select("*").From("table").
+		Where(In("id", 1, 2, 3), Eq("name", "a")).
+		ToSQLChecked(true)
+	if err != nil {
+		t.Errorf("Expected expanded IN condition to pass, got %s", err)
+	}
+
+	if asSQL != "SELECT * FROM table WHERE id IN ($1, $2, $3) AND name = $4" || len(bindings) != 4 {
+		t.Errorf("Unexpected checked SQL %s with bindings %v", asSQL, bindings)
+	}
+
+	_, _, err = dbz.Select("*").From("table").
+		Where(SQLCond("id IN (?, ?, ?)", []int{1, 2, 3}), Eq("note", "what?")).
+		ToSQLChecked(true)
+	if err == nil {
+		t.Error("Expected IN condition with unexpanded slice to fail")
+	}
+
+	_, _, err = dbz.Update("table").Set("name", "b").
+		Where(SQLCond("label = 'why?' AND id = ?", 1)).
+		ToSQLChecked(false)
+	if err != nil {
+		t.Errorf("Expected question marks in string literals to be ignored, got %s", err)
+	}
+}
+
+func TestSelectSQLServerPagination(t *testing.T) {
+	runDriverTests(t, "sqlserver", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with limit and offset",
+				dbz.Select("*").From("table").Where(Eq("a", 1)).OrderBy(Asc("id")).Limit(10).Offset(20),
+				"SELECT * FROM table WHERE a = @p1 ORDER BY id ASC OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY",
+				[]interface{}{1},
+			},
+
+			{
+				"select with limit only",
+				dbz.Select("*").From("table").OrderBy(Desc("id")).Limit(5),
+				"SELECT * FROM table ORDER BY id DESC OFFSET 0 ROWS FETCH NEXT 5 ROWS ONLY",
+				[]interface{}{},
+			},
+
+			{
+				"select with offset only",
+				dbz.Select("*").From("table").OrderBy(Desc("id")).Offset(5),
+				"SELECT * FROM table ORDER BY id DESC OFFSET 5 ROWS",
+				[]interface{}{},
+			},
+		}
+	})
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	var rows []int64
+
+	err = New(db, "sqlserver").Select("*").From("table").Limit(10).GetAll(&rows)
+	if err == nil {
+		t.Error("Expected limit without ORDER BY to fail on SQL Server")
+	}
+}
+
+func TestSelectGetDistinctCount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT COUNT(DISTINCT e.user_id) FROM public.events e INNER JOIN users u ON u.id = e.user_id " +
+			"WHERE e.type = $1 AND u.active = $2",
+	)).
+		WithArgs("login", true).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT (CASE WHEN s.n_distinct < 0 THEN -s.n_distinct * c.reltuples ELSE s.n_distinct END)::bigint " +
+			"FROM pg_stats s INNER JOIN pg_class c " +
+			"ON c.oid = (quote_ident(s.schemaname) || '.' || quote_ident(s.tablename))::regclass " +
+			"WHERE c.oid = to_regclass($1) AND s.attname = $2",
+	)).
+		WithArgs("public.events", "user_id").
+		WillReturnRows(sqlmock.NewRows([]string{"n_distinct"}).AddRow(40))
+
+	stmt := dbz.Select("e.*").
+		From("public.events e").
+		InnerJoin("users u", Eq("u.id", Indirect("e.user_id"))).
+		Where(Eq("e.type", "login"), Eq("u.active", true)).
+		OrderBy(Desc("e.created_at")).
+		Limit(10)
+
+	count, err := stmt.GetDistinctCount("e.user_id")
+	if err != nil {
+		t.Fatalf("Failed getting distinct count: %s", err)
+	}
+
+	if count != 42 {
+		t.Errorf("Expected distinct count to be 42, got %d", count)
+	}
+
+	estimate, err := stmt.GetEstimatedDistinctCount("e.user_id")
+	if err != nil {
+		t.Fatalf("Failed getting estimated distinct count: %s", err)
+	}
+
+	if estimate != 40 {
+		t.Errorf("Expected estimated distinct count to be 40, got %d", estimate)
+	}
+
+	if _, err := dbz.Select("user_id").From("events").GroupBy("type").GetDistinctCount("user_id"); err == nil {
+		t.Error("Expected distinct counts of grouped statements to fail")
+	}
+
+	if _, err := dbz.Select("user_id").From("events").
+		Union(dbz.Select("user_id").From("archived_events")).
+		GetDistinctCount("user_id"); err == nil {
+		t.Error("Expected distinct counts of unions to fail")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSelectRightJoinEmulation(t *testing.T) {
+	build := func(dbz *DB) *SelectStmt {
+		return dbz.Select("o.id", "c.name").
+			From("orders o").
+			RightJoin("customers c", Eq("c.id", Indirect("o.customer_id")), Eq("c.active", true)).
+			Where(Eq("c.country", "IL"))
+	}
+
+	for _, driverName := range []string{"mysql", "postgres"} {
+		runDriverTests(t, driverName, func(dbz *DB) []test {
+			expected := "SELECT o.id, c.name FROM orders o RIGHT JOIN customers c ON c.id = o.customer_id AND c.active = ? WHERE c.country = ?"
+			if driverName == "postgres" {
+				expected = "SELECT o.id, c.name FROM orders o RIGHT JOIN customers c ON c.id = o.customer_id AND c.active = $1 WHERE c.country = $2"
+			}
+
+			return []test{
+				{"native right join", build(dbz), expected, []interface{}{true, "IL"}},
+			}
+		})
+	}
+
+	runDriverTests(t, "sqlite3", func(dbz *DB) []test {
+		return []test{
+			{
+				"emulated right join",
+				build(dbz),
+				"SELECT o.id, c.name FROM customers c LEFT JOIN orders o ON c.id = o.customer_id AND c.active = ? WHERE c.country = ?",
+				[]interface{}{true, "IL"},
+			},
+
+			{
+				"emulated right join after another join",
+				dbz.Select("*").
+					From("orders o").
+					InnerJoinRS(dbz.Select("id").From("items").Where(Eq("type", "a")), "i", Eq("i.id", Indirect("o.item_id"))).
+					RightJoin("customers c", Eq("c.id", Indirect("o.customer_id")), Eq("c.active", true)),
+				"SELECT * FROM customers c LEFT JOIN (orders o INNER JOIN (SELECT id FROM items WHERE type = ?) i " +
+					"ON i.id = o.item_id) ON c.id = o.customer_id AND c.active = ?",
+				[]interface{}{"a", true},
+			},
+
+			{
+				// explicitly selected columns keep their order, but with
+				// SELECT * the columns of customers come first
+				"emulated right join selecting all columns",
+				dbz.Select("*").From("orders o").RightJoin("customers c", Eq("c.id", Indirect("o.customer_id"))),
+				"SELECT * FROM customers c LEFT JOIN orders o ON c.id = o.customer_id",
+				[]interface{}{},
+			},
+		}
+	})
+
+	// the bindings of the swapped table must not be appended to in place
+	tableBindings := make([]interface{}, 1, 4)
+	tableBindings[0] = "x"
+
+	stmt := New(nil, "sqlite3").Select("*").From("orders o").Where(Eq("o.active", true))
+	stmt.Joins = append(stmt.Joins, JoinClause{
+		Type:       RightJoin,
+		Table:      "customers_of(?) c",
+		Bindings:   tableBindings,
+		Conditions: []WhereCondition{Eq("c.id", Indirect("o.customer_id")), Eq("c.active", false)},
+	})
+
+	for i := 0; i < 2; i++ {
+		_, bindings := stmt.ToSQL(false)
+		if !reflect.DeepEqual(bindings, []interface{}{"x", false, true}) {
+			t.Errorf("Unexpected bindings %v", bindings)
+		}
+	}
+
+	if extra := tableBindings[:2][1]; extra != nil {
+		t.Errorf("Expected the join's bindings to be left as they were, found %v after them", extra)
+	}
+}
+
+func TestSelectWithSessionSettings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("SET LOCAL max_parallel_workers_per_gather = '4'")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("SET LOCAL work_mem = '256MB'")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM reports WHERE type = $1")).
+		WithArgs("monthly").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectCommit()
+
+	err = dbz.Transactional(func(tx *Tx) error {
+		var ids []int64
+
+		return tx.Select("id").
+			From("reports").
+			Where(Eq("type", "monthly")).
+			WithSessionSettings(map[string]string{
+				"work_mem":                        "256MB",
+				"max_parallel_workers_per_gather": "4",
+			}).
+			GetAll(&ids)
+	})
+	if err != nil {
+		t.Fatalf("Failed executing statement: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	stmt := dbz.Select("id").From("reports").WithSessionSettings(map[string]string{"work_mem": "256MB"})
+	if stmt.Err() == nil {
+		t.Error("Expected session settings outside of a transaction to fail")
+	}
+}
+
+func TestSelectInTuple(t *testing.T) {
+	rows := [][]interface{}{{1, "a"}, {2, "b"}}
+
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with tuple in",
+				dbz.Select("*").From("table").Where(InTuple([]string{"id", "type"}, rows), Eq("active", true)),
+				"SELECT * FROM table WHERE (id, type) IN (($1, $2), ($3, $4)) AND active = $5",
+				[]interface{}{1, "a", 2, "b", true},
+			},
+
+			{
+				"select with empty tuple in",
+				dbz.Select("*").From("table").Where(InTuple([]string{"id", "type"}, nil)),
+				"SELECT * FROM table WHERE 1 = 0",
+				[]interface{}{},
+			},
+		}
+	})
+
+	for _, driverName := range []string{"mysql", "sqlite3"} {
+		runDriverTests(t, driverName, func(dbz *DB) []test {
+			return []test{
+				{
+					"select with expanded tuple in",
+					dbz.Select("*").From("table").Where(InTuple([]string{"id", "type"}, rows), Eq("active", true)),
+					"SELECT * FROM table WHERE ((id = ? AND type = ?) OR (id = ? AND type = ?)) AND active = ?",
+					[]interface{}{1, "a", 2, "b", true},
+				},
+			}
+		})
+	}
+
+	for _, driverName := range []string{"postgres", "mysql"} {
+		stmt := New(nil, driverName).Select("*").From("table").
+			Where(InTuple([]string{"id", "type"}, [][]interface{}{{1, "a"}, {2}}))
+		if stmt.Err() == nil {
+			t.Errorf("Expected a tuple row with missing values to fail on %s", driverName)
+		}
+
+		if asSQL, _ := stmt.ToSQL(false); asSQL != "SELECT * FROM table WHERE 1 = 0" {
+			t.Errorf("Unexpected SQL for an invalid tuple on %s: %s", driverName, asSQL)
+		}
+	}
+}
+
+func TestSelectGetColumnar(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT day, total FROM sales ORDER BY day ASC")).
+		WillReturnRows(sqlmock.NewRows([]string{"day", "total"}).
+			AddRow("mon", 10).
+			AddRow("tue", 20).
+			AddRow("wed", 30))
+
+	columns, err := New(db, "postgres").Select("day", "total").From("sales").OrderBy(Asc("day")).GetColumnar()
+	if err != nil {
+		t.Fatalf("Failed getting columnar results: %s", err)
+	}
+
+	expected := map[string][]interface{}{
+		"day":   {"mon", "tue", "wed"},
+		"total": {10, 20, 30},
+	}
+
+	if !reflect.DeepEqual(columns, expected) {
+		t.Errorf("Expected columnar results %v, got %v", expected, columns)
+	}
+}
+
+func TestSelectOrderByRandom(t *testing.T) {
+	for driverName, expected := range map[string]string{
+		"postgres":  "SELECT * FROM table WHERE active = $1 ORDER BY RANDOM() LIMIT 10",
+		"sqlite3":   "SELECT * FROM table WHERE active = ? ORDER BY RANDOM() LIMIT 10",
+		"mysql":     "SELECT * FROM table WHERE active = ? ORDER BY RAND() LIMIT 10",
+		"sqlserver": "SELECT * FROM table WHERE active = @p1 ORDER BY NEWID() OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY",
+	} {
+		runDriverTests(t, driverName, func(dbz *DB) []test {
+			return []test{
+				{
+					"select random rows",
+					dbz.Select("*").From("table").Where(Eq("active", true)).OrderByRandom().Limit(10),
+					expected,
+					[]interface{}{true},
+				},
+			}
+		})
+	}
+}
+
+func TestSelectSetOperations(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with intersect",
+				dbz.Select("user_id").From("orders").Where(Gt("total", 100)).
+					Intersect(dbz.Select("user_id").From("reviews").Where(Eq("rating", 5))).
+					OrderBy(Asc("user_id")).
+					Limit(10),
+				"SELECT user_id FROM orders WHERE total > $1 " +
+					"INTERSECT SELECT user_id FROM reviews WHERE rating = $2 " +
+					"ORDER BY user_id ASC LIMIT 10",
+				[]interface{}{100, 5},
+			},
+
+			{
+				"select with except",
+				dbz.Select("id").From("users").Where(Eq("active", true)).
+					Except(dbz.Select("user_id").From("bans").Where(Gt("until", 1700000000))).
+					Except(dbz.Select("user_id").From("deletions")),
+				"SELECT id FROM users WHERE active = $1 " +
+					"EXCEPT SELECT user_id FROM bans WHERE until > $2 " +
+					"EXCEPT SELECT user_id FROM deletions",
+				[]interface{}{true, 1700000000},
+			},
+		}
+	})
+
+	runDriverTests(t, "sqlite3", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with intersect",
+				dbz.Select("user_id").From("orders").Where(Gt("total", 100)).
+					Intersect(dbz.Select("user_id").From("reviews").Where(Eq("rating", 5))).
+					OrderBy(Desc("user_id")).
+					Limit(5).
+					Offset(5),
+				"SELECT user_id FROM orders WHERE total > ? " +
+					"INTERSECT SELECT user_id FROM reviews WHERE rating = ? " +
+					"ORDER BY user_id DESC LIMIT 5 OFFSET 5",
+				[]interface{}{100, 5},
+			},
+		}
+	})
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT COUNT(*) FROM (SELECT user_id FROM orders INTERSECT SELECT user_id FROM reviews) AS counted",
+	)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+	count, err := dbz.Select("user_id").From("orders").
+		Intersect(dbz.Select("user_id").From("reviews")).
+		OrderBy(Asc("user_id")).
+		Limit(10).
+		GetCount()
+	if err != nil {
+		t.Fatalf("Failed counting: %s", err)
+	}
+
+	if count != 7 {
+		t.Errorf("Expected count to be 7, got %d", count)
+	}
+}
+
+func TestSelectRaw(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+	query := "SELECT id FROM users WHERE tags && ? AND age > ? ORDER BY id"
+
+	asSQL, bindings := dbz.RawSelect(query, "{a,b}", 18).ToSQL(true)
+	if expected := "SELECT id FROM users WHERE tags && $1 AND age > $2 ORDER BY id"; asSQL != expected {
+		t.Errorf("Expected %s, got %s", expected, asSQL)
+	}
+
+	if !reflect.DeepEqual(bindings, []interface{}{"{a,b}", 18}) {
+		t.Errorf("Unexpected bindings %v", bindings)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM users WHERE tags && $1 AND age > $2 ORDER BY id")).
+		WithArgs("{a,b}", 18).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT COUNT(*) FROM (SELECT id FROM users WHERE tags && $1 AND age > $2 ORDER BY id) AS counted",
+	)).
+		WithArgs("{a,b}", 18).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT id FROM users WHERE tags && $1 AND age > $2 ORDER BY id")).
+		WithArgs("{a,b}", 18).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow("Sort  (cost=40.00..41.00 rows=420 width=8)").
+			AddRow("  ->  Seq Scan on users  (cost=0.00..35.50 rows=420 width=8)"))
+
+	var ids []int64
+	if err := dbz.RawSelect(query, "{a,b}", 18).GetAll(&ids); err != nil {
+		t.Fatalf("Failed executing raw select: %s", err)
+	}
+
+	if !reflect.DeepEqual(ids, []int64{1, 2}) {
+		t.Errorf("Unexpected results %v", ids)
+	}
+
+	count, err := dbz.RawSelect(query, "{a,b}", 18).GetCount()
+	if err != nil || count != 2 {
+		t.Errorf("Expected count 2, got %d (%v)", count, err)
+	}
+
+	estimate, err := dbz.RawSelect(query, "{a,b}", 18).GetEstimatedCount()
+	if err != nil || estimate != 420 {
+		t.Errorf("Expected estimated count 420, got %d (%v)", estimate, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSelectOrderByColumns(t *testing.T) {
+	stmt := func(dbz *DB) *SelectStmt {
+		return dbz.Select("*").From("table").
+			OrderBy(Desc("priority")).
+			OrderByColumns(OrderAsc("name"), OrderDesc("due").NullsLast(), OrderAsc("id").NullsFirst())
+	}
+
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with mixed order columns",
+				stmt(dbz),
+				"SELECT * FROM table ORDER BY priority DESC, name ASC, due DESC NULLS LAST, id ASC NULLS FIRST",
+				[]interface{}{},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with mixed order columns",
+				stmt(dbz),
+				"SELECT * FROM table ORDER BY priority DESC, name ASC, due IS NULL, due DESC, id IS NULL DESC, id ASC",
+				[]interface{}{},
+			},
+		}
+	})
+
+	runDriverTests(t, "sqlserver", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with mixed order columns",
+				stmt(dbz),
+				"SELECT * FROM table ORDER BY priority DESC, name ASC, " +
+					"CASE WHEN due IS NULL THEN 1 ELSE 0 END, due DESC, " +
+					"CASE WHEN id IS NULL THEN 0 ELSE 1 END, id ASC",
+				[]interface{}{},
+			},
+		}
+	})
+}
+
+func TestSelectClone(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	base := dbz.Select("id", "name").
+		From("users u").
+		LeftJoin("teams t", Eq("t.id", Indirect("u.team_id"))).
+		Where(Eq("u.active", true)).
+		OrderBy(Asc("name")).
+		Limit(10)
+
+	expectedSQL, expectedBindings := base.ToSQL(true)
+
+	clone := base.Clone()
+	clone.Columns[0] = "u.id"
+	clone.Joins[0].Conditions[0] = Eq("t.id", 3)
+	clone.Columns = append(clone.Columns, "email")
+	clone.Where(Gt("u.age", 18)).OrderBy(Desc("id")).Limit(20)
+	clone.Union(dbz.Select("id", "name").From("admins"))
+
+	if asSQL, bindings := base.ToSQL(true); asSQL != expectedSQL || !reflect.DeepEqual(bindings, expectedBindings) {
+		t.Errorf("Expected original to remain %s %v, got %s %v", expectedSQL, expectedBindings, asSQL, bindings)
+	}
+
+	expectedCloneSQL := "SELECT u.id, name, email FROM users u LEFT JOIN teams t ON t.id = $1 " +
+		"WHERE u.active = $2 AND u.age > $3 ORDER BY name ASC, id DESC LIMIT 20 " +
+		"UNION SELECT id, name FROM admins"
+	if asSQL, _ := clone.ToSQL(true); asSQL != expectedCloneSQL {
+		t.Errorf("Expected clone to be %s, got %s", expectedCloneSQL, asSQL)
+	}
+}
+
+func TestSelectGroupingSets(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with rollup",
+				dbz.Select("region", "city", "SUM(sales)").From("orders").GroupByRollup("region", "city"),
+				"SELECT region, city, SUM(sales) FROM orders GROUP BY ROLLUP(region, city)",
+				[]interface{}{},
+			},
+
+			{
+				"select with grouping sets and a regular grouping",
+				dbz.Select("year", "region", "city", "SUM(sales)").From("orders").
+					GroupBy("year").
+					GroupingSets([]string{"region"}, []string{"city"}, nil),
+				"SELECT year, region, city, SUM(sales) FROM orders GROUP BY year, GROUPING SETS ((region), (city), ())",
+				[]interface{}{},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with rollup",
+				dbz.Select("region", "city", "SUM(sales)").From("orders").GroupByRollup("region", "city"),
+				"SELECT region, city, SUM(sales) FROM orders GROUP BY region, city WITH ROLLUP",
+				[]interface{}{},
+			},
+		}
+	})
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	stmt := New(db, "mysql").Select("SUM(sales)").From("orders").GroupingSets([]string{"region"}, nil)
+	if stmt.Err() == nil {
+		t.Error("Expected grouping sets on mysql to fail")
+	}
+
+	stmt = New(db, "mysql").Select("SUM(sales)").From("orders").GroupBy("year").GroupByRollup("region", "city")
+	if stmt.Err() == nil {
+		t.Error("Expected rollup with other grouping columns on mysql to fail")
+	}
+
+	stmt = New(db, "postgres").Select("SUM(sales)").From("orders").GroupByRollup()
+	if stmt.Err() == nil {
+		t.Error("Expected rollup without columns to fail")
+	}
+
+	stmt = New(db, "postgres").Select("SUM(sales)").From("orders").GroupingSets()
+	if stmt.Err() == nil {
+		t.Error("Expected grouping sets without sets to fail")
+	}
+}
+
+func TestSelectScalars(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT SUM(total) FROM orders WHERE user_id = $1")).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(1250))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT AVG(total) FROM orders GROUP BY user_id HAVING user_id = $1")).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"avg"}).AddRow(62.5))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT MAX(created) FROM orders")).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow("2020-01-02"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT total FROM orders WHERE id = $1")).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"total"}))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, total FROM orders")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "total"}).AddRow(1, 2))
+
+	sum, err := dbz.Select("SUM(total)").From("orders").Where(Eq("user_id", 3)).GetInt64()
+	if err != nil || sum != 1250 {
+		t.Errorf("Expected sum 1250, got %d (%v)", sum, err)
+	}
+
+	avg, err := dbz.Select("AVG(total)").From("orders").GroupBy("user_id").Having(Eq("user_id", 3)).GetFloat64()
+	if err != nil || avg != 62.5 {
+		t.Errorf("Expected average 62.5, got %v (%v)", avg, err)
+	}
+
+	latest, err := dbz.Select("MAX(created)").From("orders").GetString()
+	if err != nil || latest != "2020-01-02" {
+		t.Errorf("Expected latest 2020-01-02, got %s (%v)", latest, err)
+	}
+
+	if _, err = dbz.Select("total").From("orders").Where(Eq("id", 4)).GetInt64(); !IsNotFound(err) {
+		t.Errorf("Expected sql.ErrNoRows, got %v", err)
+	}
+
+	if _, err = dbz.Select("id", "total").From("orders").GetInt64(); err == nil {
+		t.Error("Expected loading multiple columns into a scalar to fail")
+	}
+}
+
+func TestSelectFromValues(t *testing.T) {
+	rows := [][]interface{}{{1, "a"}, {2, "b"}}
+
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"select from values joined with a table",
+				dbz.Select("u.*", "v.label").
+					FromValues("v", []string{"id", "label"}, rows).
+					InnerJoin("users u", SQLCond("u.id = v.id")).
+					Where(Eq("u.active", true)),
+				"SELECT u.*, v.label FROM (VALUES ($1, $2), ($3, $4)) AS v(id, label) " +
+					"INNER JOIN users u ON u.id = v.id WHERE u.active = $5",
+				[]interface{}{1, "a", 2, "b", true},
+			},
+			{
+				"select from typed values",
+				dbz.Select("*").
+					FromValues("v", []string{"id", "label"}, [][]interface{}{{Cast(1, "int"), "a"}, {2, "b"}}),
+				"SELECT * FROM (VALUES ($1::int, $2), ($3, $4)) AS v(id, label)",
+				[]interface{}{1, "a", 2, "b"},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"select from values joined with a table",
+				dbz.Select("u.*", "v.label").
+					FromValues("v", []string{"id", "label"}, rows).
+					InnerJoin("users u", SQLCond("u.id = v.id")).
+					Where(Eq("u.active", true)),
+				"SELECT u.*, v.label FROM (SELECT ? AS id, ? AS label UNION ALL SELECT ?, ?) AS v " +
+					"INNER JOIN users u ON u.id = v.id WHERE u.active = ?",
+				[]interface{}{1, "a", 2, "b", true},
+			},
+		}
+	})
+}
+
+func TestSelectDistinct(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"plain distinct",
+				dbz.Select("name").Distinct().From("users"),
+				"SELECT DISTINCT name FROM users",
+				[]interface{}{},
+			},
+			{
+				"distinct on",
+				dbz.Select("user_id", "created_at").DistinctOn("user_id").From("events"),
+				"SELECT DISTINCT ON (user_id) user_id, created_at FROM events",
+				[]interface{}{},
+			},
+			{
+				"distinct after distinct on wins",
+				dbz.Select("user_id").DistinctOn("user_id").Distinct().From("events"),
+				"SELECT DISTINCT user_id FROM events",
+				[]interface{}{},
+			},
+			{
+				"distinct on after distinct wins",
+				dbz.Select("user_id").Distinct().DistinctOn("user_id").From("events"),
+				"SELECT DISTINCT ON (user_id) user_id FROM events",
+				[]interface{}{},
+			},
+		}
+	})
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	if err := New(db, "postgres").Select("*").From("events").DistinctOn().Err(); err == nil {
+		t.Error("Expected DistinctOn without columns to fail")
+	}
+}
+
+func TestStatementArgs(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	for name, stmt := range map[string]interface {
+		SQLStmt
+		Args() []interface{}
+	}{
+		"select": dbz.Select("*").From("users").
+			Where(Eq("email", "john@example.com"), In("role", "admin", "owner")).
+			Limit(10),
+		"insert": dbz.InsertInto("users").Columns("email", "password").Values("john@example.com", "secret"),
+		"update": dbz.Update("users").Set("password", "secret").Where(Eq("id", 1)),
+		"delete": dbz.DeleteFrom("users").Where(Eq("email", "john@example.com")),
+	} {
+		_, expected := stmt.ToSQL(true)
+		if got := stmt.Args(); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected args %v, got %v", name, expected, got)
+		}
+	}
+}
+
+func TestSelectOffsetWithoutLimit(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"bare offset",
+				dbz.Select("*").From("events").OrderBy(Asc("id")).Offset(20),
+				"SELECT * FROM events ORDER BY id ASC OFFSET 20",
+				[]interface{}{},
+			},
+			{
+				"limit cleared by limit all",
+				dbz.Select("*").From("events").OrderBy(Asc("id")).Limit(10).Offset(20).LimitAll(),
+				"SELECT * FROM events ORDER BY id ASC OFFSET 20",
+				[]interface{}{},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"offset with maximum limit",
+				dbz.Select("*").From("events").OrderBy(Asc("id")).Offset(20),
+				"SELECT * FROM events ORDER BY id ASC LIMIT 18446744073709551615 OFFSET 20",
+				[]interface{}{},
+			},
+			{
+				"offset with limit",
+				dbz.Select("*").From("events").OrderBy(Asc("id")).Limit(10).Offset(20),
+				"SELECT * FROM events ORDER BY id ASC LIMIT 10 OFFSET 20",
+				[]interface{}{},
+			},
+		}
+	})
+}
+
+func TestSelectInQuery(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with uncorrelated in sub-query",
+				dbz.Select("*").From("users").Where(
+					Eq("active", true),
+					InQuery("id", dbz.Select("user_id").From("orders").Where(Gt("total", 100))),
+					Eq("country", "IL"),
+				),
+				"SELECT * FROM users WHERE active = $1 AND id IN (SELECT user_id FROM orders WHERE total > $2) AND country = $3",
+				[]interface{}{true, 100, "IL"},
+			},
+			{
+				"select with correlated not in sub-query",
+				dbz.Select("*").From("users u").Where(
+					Eq("u.active", true),
+					NotInQuery("u.id", dbz.Select("b.user_id").From("bans b").
+						Where(Eq("b.org_id", Indirect("u.org_id")), Eq("b.reason", "fraud"))),
+				),
+				"SELECT * FROM users u WHERE u.active = $1 AND u.id NOT IN (SELECT b.user_id FROM bans b WHERE b.org_id = u.org_id AND b.reason = $2)",
+				[]interface{}{true, "fraud"},
+			},
+		}
+	})
+}
+
+func TestSelectDistinctOnPaginateAfter(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"latest event per device, first page",
+				dbz.Select("device_id", "created", "payload").From("events").
+					DistinctOn("device_id").
+					OrderBy(Desc("created")).
+					PaginateAfter("device_id", nil, 50),
+				"SELECT DISTINCT ON (device_id) device_id, created, payload FROM events " +
+					"ORDER BY device_id ASC, created DESC LIMIT 50",
+				[]interface{}{},
+			},
+			{
+				"latest event per device, next page",
+				dbz.Select("device_id", "created", "payload").From("events").
+					DistinctOn("device_id").
+					Where(Eq("type", "heartbeat")).
+					OrderBy(Asc("device_id"), Desc("created")).
+					PaginateAfter("device_id", 1234, 50),
+				"SELECT DISTINCT ON (device_id) device_id, created, payload FROM events " +
+					"WHERE type = $1 AND device_id > $2 ORDER BY device_id ASC, created DESC LIMIT 50",
+				[]interface{}{"heartbeat", 1234},
+			},
+		}
+	})
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	err = dbz.Select("*").From("events").DistinctOn("device_id").
+		PaginateAfter("created", "2020-01-01", 50).
+		Err()
+	if err == nil {
+		t.Error("Expected paginating by a column other than the DISTINCT ON column to fail")
+	}
+
+	err = dbz.Select("*").From("events").DistinctOn("device_id").
+		OrderBy(Desc("created")).
+		PaginateAfter("device_id", nil, 50).
+		OrderBy(Asc("id")).
+		Err()
+	if err != nil {
+		t.Errorf("Expected DISTINCT ON column leading the ORDER BY to pass, got %s", err)
+	}
+}
+
+func TestSelectPaginateAfterNullable(t *testing.T) {
+	// rows ordered by (due, id) with NULL due dates: the first page ends on a
+	// non-NULL due date, the second on a NULL one
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"first page",
+				dbz.Select("*").From("tasks").
+					PaginateAfter("due", nil, 2, NullableCursor("id", nil)),
+				"SELECT * FROM tasks ORDER BY due ASC NULLS LAST, id ASC LIMIT 2",
+				[]interface{}{},
+			},
+			{
+				"page after a non-NULL value includes NULL values",
+				dbz.Select("*").From("tasks").
+					PaginateAfter("due", "2020-01-02", 2, NullableCursor("id", 7)),
+				"SELECT * FROM tasks WHERE due > $1 OR (due = $2 AND id > $3) OR due IS NULL " +
+					"ORDER BY due ASC NULLS LAST, id ASC LIMIT 2",
+				[]interface{}{"2020-01-02", "2020-01-02", 7},
+			},
+			{
+				"page after a NULL value",
+				dbz.Select("*").From("tasks").
+					PaginateAfter("due", nil, 2, NullableCursor("id", 9)),
+				"SELECT * FROM tasks WHERE due IS NULL AND id > $1 ORDER BY due ASC NULLS LAST, id ASC LIMIT 2",
+				[]interface{}{9},
+			},
+			{
+				"descending with NULL values first",
+				dbz.Select("*").From("tasks").OrderBy(Desc("due").NullsFirst(), Asc("id")).
+					PaginateAfter("due", "2020-01-02", 2, NullableCursor("id", 7)),
+				"SELECT * FROM tasks WHERE due < $1 OR (due = $2 AND id > $3) " +
+					"ORDER BY due DESC NULLS FIRST, id ASC LIMIT 2",
+				[]interface{}{"2020-01-02", "2020-01-02", 7},
+			},
+			{
+				"page after a NULL value with NULL values first",
+				dbz.Select("*").From("tasks").OrderBy(Desc("due").NullsFirst()).
+					PaginateAfter("due", nil, 2, NullableCursor("id", 9)),
+				"SELECT * FROM tasks WHERE (due IS NULL AND id > $1) OR due IS NOT NULL " +
+					"ORDER BY due DESC NULLS FIRST, id ASC LIMIT 2",
+				[]interface{}{9},
+			},
+		}
+	})
+
+	// NULL values come first in ascending order by default in SQLite
+	runDriverTests(t, "sqlite3", func(dbz *DB) []test {
+		return []test{
+			{
+				"default NULL ordering is made explicit",
+				dbz.Select("*").From("tasks").Where(Eq("done", false)).
+					PaginateAfter("due", "2020-01-02", 2, NullableCursor("id", 7)),
+				"SELECT * FROM tasks WHERE done = ? AND (due > ? OR (due = ? AND id > ?)) " +
+					"ORDER BY due ASC NULLS FIRST, id ASC LIMIT 2",
+				[]interface{}{false, "2020-01-02", "2020-01-02", 7},
+			},
+		}
+	})
+
+	// the statement-level NULL ordering is applied to the column itself
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"statement-level NULL ordering",
+				dbz.Select("*").From("tasks").OrderBy(Asc("due")).WithNullsFirst().
+					PaginateAfter("due", "2020-01-02", 2, NullableCursor("id", 7)),
+				"SELECT * FROM tasks WHERE due > $1 OR (due = $2 AND id > $3) " +
+					"ORDER BY due ASC NULLS FIRST, id ASC NULLS FIRST LIMIT 2",
+				[]interface{}{"2020-01-02", "2020-01-02", 7},
+			},
+		}
+	})
+
+	if err := New(nil, "postgres").Select("*").From("tasks").
+		PaginateAfter("due", nil, 2, NullableCursor("", nil)).Err(); err == nil {
+		t.Error("Expected NullableCursor without an id column to fail")
+	}
+
+	if err := New(nil, "postgres").Select("*").From("tasks").
+		PaginateAfter("due", "2020-01-02", 2, NullableCursor("id", nil)).Err(); err == nil {
+		t.Error("Expected paginating after a value without an id to fail")
+	}
+}
+
+func TestSelectIsNotDistinctFrom(t *testing.T) {
+	for driverName, expected := range map[string]string{
+		"postgres":  "SELECT * FROM accounts WHERE parent_id IS NOT DISTINCT FROM $1 AND name = $2",
+		"mysql":     "SELECT * FROM accounts WHERE parent_id <=> ? AND name = ?",
+		"sqlite3":   "SELECT * FROM accounts WHERE (parent_id = ? OR (parent_id IS NULL AND ? IS NULL)) AND name = ?",
+		"sqlserver": "SELECT * FROM accounts WHERE (parent_id = @p1 OR (parent_id IS NULL AND @p2 IS NULL)) AND name = @p3",
+	} {
+		expected := expected
+		emulated := driverName == "sqlite3" || driverName == "sqlserver"
+
+		runDriverTests(t, driverName, func(dbz *DB) []test {
+			bindings := []interface{}{nil, "root"}
+			if emulated {
+				bindings = []interface{}{nil, nil, "root"}
+			}
+
+			return []test{
+				{
+					"select with null-safe equality on " + driverName,
+					dbz.Select("*").From("accounts").Where(IsNotDistinctFrom("parent_id", nil), Eq("name", "root")),
+					expected,
+					bindings,
+				},
+			}
+		})
+	}
+}
+
+func TestSelectEmptyInPolicy(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	for policy, expected := range map[EmptyInPolicy]string{
+		EmptyInMatchNone: "SELECT * FROM users WHERE 1 = 0 AND (1 = 0 OR active = $1)",
+		EmptyInMatchAll:  "SELECT * FROM users WHERE 1 = 1 AND (1 = 1 OR active = $1)",
+		EmptyInError:     "",
+	} {
+		dbz.SetEmptyInPolicy(policy)
+
+		stmt := dbz.Select("*").From("users").
+			Where(In("id", []int64{}), Or(NotIn("role", []string{}), Eq("active", true)))
+
+		asSQL, _, err := stmt.ToSQLChecked(true)
+		if policy == EmptyInError {
+			if err == nil {
+				t.Error("Expected empty IN conditions to fail with the error policy")
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected error with policy %d: %s", policy, err)
+		} else if asSQL != expected {
+			t.Errorf("Expected %s with policy %d, got %s", expected, policy, asSQL)
+		}
+	}
+
+	dbz.SetEmptyInPolicy(EmptyInError)
+
+	if err := dbz.DeleteFrom("users").Where(NotIn("id", []int64{})).Err(); err == nil {
+		t.Error("Expected empty NOT IN condition in a delete to fail with the error policy")
+	}
+
+	if err := dbz.Select("*").From("users").Where(In("id", 1, 2), NotIn("role", "admin")).Err(); err != nil {
+		t.Errorf("Expected non-empty IN conditions to pass with the error policy, got %s", err)
+	}
+
+	named := NamedCond("id IN (:ids)", map[string]interface{}{"ids": []int64{}})
+	if err := dbz.Select("*").From("users").Where(named).Err(); err == nil {
+		t.Error("Expected named condition with an empty slice to fail with the error policy")
+	}
+
+	dbz.SetEmptyInPolicy(EmptyInMatchAll)
+
+	if asSQL, _ := dbz.Select("*").From("users").Where(named).ToSQL(true); asSQL != "SELECT * FROM users WHERE 1 = 1" {
+		t.Errorf("Expected named condition with an empty slice to match all rows, got %s", asSQL)
+	}
+}
+
+func TestNamedCondMissingParam(t *testing.T) {
+	cond := NamedCond("a = :a AND b = :b", map[string]interface{}{"a": 1})
+	if err := New(nil, "postgres").Select("*").From("table").Where(cond).Err(); err == nil {
+		t.Error("Expected named condition with a missing parameter to fail")
+	}
+
+	cond = NamedCond("a = :a", map[string]interface{}{"a": nil})
+	if err := New(nil, "postgres").Select("*").From("table").Where(cond).Err(); err != nil {
+		t.Errorf("Expected named condition with a nil parameter to pass, got %s", err)
+	}
+}
+
+func TestSelectPrefixSuffix(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with prefix and suffix",
+				dbz.Select("*").From("events").
+					Where(Eq("type", "login")).
+					Limit(10).
+					Prefix("/*+ SeqScan(events) */").
+					Suffix("SETTINGS max_threads = ?", 4),
+				"/*+ SeqScan(events) */ SELECT * FROM events WHERE type = $1 LIMIT 10 SETTINGS max_threads = $2",
+				[]interface{}{"login", 4},
+			},
+		}
+	})
+
+	runDriverTests(t, "sqlserver", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with option suffix",
+				dbz.Select("*").From("events").Where(Eq("type", "login")).Suffix("OPTION (RECOMPILE)"),
+				"SELECT * FROM events WHERE type = @p1 OPTION (RECOMPILE)",
+				[]interface{}{"login"},
+			},
+		}
+	})
+}
+
+func TestSelectBindingOrder(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		stmt := dbz.Select("u.id").SelectExpr(Coalesce("name", Col("u.nickname"), "anonymous"))
+		stmt.FromStmt = dbz.Select("*").From("users").Where(Eq("org_id", 7))
+		stmt.FromStmtAlias = "u"
+
+		return []test{
+			{
+				"bindings follow the order of clauses",
+				stmt.
+					LeftJoin("logs", Eq("logs.kind", "login"), Eq("logs.user_id", Indirect("u.id"))).
+					InnerJoinRS(dbz.Select("user_id").From("roles").Where(Eq("role", "admin")), "r", Eq("r.user_id", Indirect("u.id"))).
+					Where(Gt("logs.created", 100)).
+					GroupBy("u.id", "u.nickname").
+					Having(Gt("COUNT(logs.id)", 5)).
+					OrderBy(Indirect("u.id = ? DESC", 1)),
+				"SELECT u.id, COALESCE(u.nickname, $1) AS name " +
+					"FROM (SELECT * FROM users WHERE org_id = $2) AS u " +
+					"LEFT JOIN logs ON logs.kind = $3 AND logs.user_id = u.id " +
+					"INNER JOIN (SELECT user_id FROM roles WHERE role = $4) r ON r.user_id = u.id " +
+					"WHERE logs.created > $5 GROUP BY u.id, u.nickname HAVING COUNT(logs.id) > $6 " +
+					"ORDER BY u.id = $7 DESC",
+				[]interface{}{"anonymous", 7, "login", "admin", 100, 5, 1},
+			},
+		}
+	})
+}
+
+func TestSelectWhereGroup(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with nested condition groups",
+				dbz.Select("*").From("tickets").
+					Where(Eq("project_id", 1)).
+					WhereGroup(func(g *ConditionGroup) {
+						g.And(Eq("assignee", "john")).OrGroup(func(g *ConditionGroup) {
+							g.And(IsNull("assignee"), Gt("priority", 3))
+						})
+					}).
+					Where(Eq("open", true)),
+				"SELECT * FROM tickets WHERE project_id = $1 AND " +
+					"(assignee = $2 OR (assignee IS NULL AND priority > $3)) AND open = $4",
+				[]interface{}{1, "john", 3, true},
+			},
+			{
+				"select with mixed operators in a group",
+				dbz.Select("*").From("tickets").
+					Where(Eq("project_id", 1)).
+					WhereGroup(func(g *ConditionGroup) {
+						g.And(Eq("a", 1), Eq("b", 2)).Or(Eq("c", 3))
+					}),
+				"SELECT * FROM tickets WHERE project_id = $1 AND ((a = $2 AND b = $3) OR c = $4)",
+				[]interface{}{1, 1, 2, 3},
+			},
+			{
+				"select with empty condition group",
+				dbz.Select("*").From("tickets").WhereGroup(func(g *ConditionGroup) {}),
+				"SELECT * FROM tickets",
+				[]interface{}{},
+			},
+		}
+	})
+}
+
+func TestSelectOrderByAlias(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"order by alias",
+				dbz.Select("user_id", "COUNT(*) AS total").
+					SelectExpr(Coalesce("name", Col("nickname"), "anonymous")).
+					From("events").
+					GroupBy("user_id", "nickname").
+					OrderByAliasDesc("total").
+					OrderByAlias("name"),
+				"SELECT user_id, COUNT(*) AS total, COALESCE(nickname, $1) AS name FROM events " +
+					"GROUP BY user_id, nickname ORDER BY total DESC, name ASC",
+				[]interface{}{"anonymous"},
+			},
+		}
+	})
+
+	runDriverTests(t, "sqlserver", func(dbz *DB) []test {
+		return []test{
+			{
+				"order by alias",
+				dbz.Select("user_id", "COUNT(*) AS total").
+					SelectExpr(Coalesce("name", Col("nickname"), "anonymous")).
+					From("events").
+					GroupBy("user_id", "nickname").
+					OrderByAliasDesc("total").
+					OrderByAlias("name"),
+				"SELECT user_id, COUNT(*) AS total, COALESCE(nickname, @p1) AS name FROM events " +
+					"GROUP BY user_id, nickname ORDER BY total DESC, name ASC",
+				[]interface{}{"anonymous"},
+			},
+		}
+	})
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	if err := New(db, "postgres").Select("id").From("events").OrderByAlias("total").Err(); err == nil {
+		t.Error("Expected ordering by an unknown alias to fail")
+	}
+
+	if err := New(db, "postgres").Select("COUNT(*) as Total").From("events").OrderByAlias("total").Err(); err != nil {
+		t.Errorf("Expected ordering by a known alias to pass, got %s", err)
+	}
+
+	if err := New(db, "postgres").Select("user_id, COUNT(*) AS total, MAX(id) AS latest").From("events").
+		OrderByAlias("total").Err(); err != nil {
+		t.Errorf("Expected ordering by an alias in a comma-separated column to pass, got %s", err)
+	}
+
+	stmt := New(db, "postgres").Select().From("events").OrderByAlias("total")
+	stmt.Columns = append(stmt.Columns, "COUNT(*) AS total")
+
+	if err := stmt.Err(); err != nil {
+		t.Errorf("Expected ordering by an alias added later to pass, got %s", err)
+	}
+}
+
+func TestSelectFullTextMatch(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"full-text match on a single column",
+				dbz.Select("id").From("articles").Where(FullTextMatch([]string{"body"}, "fast cars")),
+				"SELECT id FROM articles WHERE to_tsvector(body) @@ plainto_tsquery($1)",
+				[]interface{}{"fast cars"},
+			},
+			{
+				"web search full-text match on multiple columns",
+				dbz.Select("id").From("articles").Where(
+					Eq("published", true),
+					FullTextMatch([]string{"title", "body"}, `"fast cars" -trucks`).WebSearch(),
+				),
+				"SELECT id FROM articles WHERE published = $1 AND " +
+					"to_tsvector(concat_ws(' ', title, body)) @@ websearch_to_tsquery($2)",
+				[]interface{}{true, `"fast cars" -trucks`},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"full-text match on multiple columns",
+				dbz.Select("id").From("articles").Where(FullTextMatch([]string{"title", "body"}, "fast cars")),
+				"SELECT id FROM articles WHERE MATCH(title, body) AGAINST(? IN NATURAL LANGUAGE MODE)",
+				[]interface{}{"fast cars"},
+			},
+		}
+	})
+
+	runDriverTests(t, "sqlite3", func(dbz *DB) []test {
+		return []test{
+			{
+				"full-text match on an FTS table",
+				dbz.Select("rowid").From("articles_fts").Where(
+					FullTextMatch([]string{"body"}, "fast cars").FTS("articles_fts"),
+				),
+				"SELECT rowid FROM articles_fts WHERE articles_fts MATCH ?",
+				[]interface{}{"fast cars"},
+			},
+		}
+	})
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	stmt := New(db, "sqlite3").Select("id").From("articles").
+		Where(Or(Eq("id", 1), FullTextMatch([]string{"body"}, "fast cars")))
+	if stmt.Err() == nil {
+		t.Error("Expected full-text match without an FTS column to fail in SQLite")
+	}
+}
+
+func TestSelectEach(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	query := regexp.QuoteMeta("SELECT id, name FROM users ORDER BY id ASC")
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name"}).
+			AddRow(1, "a").
+			AddRow(2, "b").
+			AddRow(3, "c")
+	}
+
+	mock.ExpectQuery(query).WillReturnRows(rows())
+	mock.ExpectQuery(query).WillReturnRows(rows())
+	mock.ExpectQuery(query).WillReturnRows(rows())
+
+	stmt := New(db, "postgres").Select("id", "name").From("users").OrderBy(Asc("id"))
+
+	var names []string
+
+	err = stmt.Each(func(scan func(dest ...interface{}) error) error {
+		var id int64
+		var name string
+
+		if err := scan(&id, &name); err != nil {
+			return err
+		}
+
+		names = append(names, name)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed iterating rows: %s", err)
+	}
+
+	if !reflect.DeepEqual(names, []string{"a", "b", "c"}) {
+		t.Errorf("Expected to iterate over all rows, got %v", names)
+	}
+
+	// stop midway
+	stop := errors.New("stop")
+	visited := 0
+
+	err = stmt.Each(func(scan func(dest ...interface{}) error) error {
+		visited++
+		if visited == 2 {
+			return stop
+		}
+
+		return nil
+	})
+	if err != stop {
+		t.Errorf("Expected the callback's error, got %v", err)
+	}
+
+	if visited != 2 {
+		t.Errorf("Expected iteration to stop after 2 rows, visited %d", visited)
+	}
+
+	if inUse := db.Stats().InUse; inUse != 0 {
+		t.Errorf("Expected rows to be closed after stopping, %d connections in use", inUse)
+	}
+
+	// panic midway
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected the callback's panic to propagate")
+			}
+		}()
+
+		_ = stmt.Each(func(scan func(dest ...interface{}) error) error {
+			panic("boom")
+		})
+	}()
+
+	if inUse := db.Stats().InUse; inUse != 0 {
+		t.Errorf("Expected rows to be closed after a panic, %d connections in use", inUse)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSelectColumnComparisons(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{"EqCols", dbz.Select("*").From("a").Where(EqCols("a.x", "a.y")), "SELECT * FROM a WHERE a.x = a.y", []interface{}{}},
+			{"NeCols", dbz.Select("*").From("a").Where(NeCols("a.x", "a.y")), "SELECT * FROM a WHERE a.x <> a.y", []interface{}{}},
+			{"GtCols", dbz.Select("*").From("a").Where(GtCols("a.x", "a.y")), "SELECT * FROM a WHERE a.x > a.y", []interface{}{}},
+			{"GteCols", dbz.Select("*").From("a").Where(GteCols("a.x", "a.y")), "SELECT * FROM a WHERE a.x >= a.y", []interface{}{}},
+			{"LtCols", dbz.Select("*").From("a").Where(LtCols("a.x", "a.y")), "SELECT * FROM a WHERE a.x < a.y", []interface{}{}},
+			{"LteCols", dbz.Select("*").From("a").Where(LteCols("a.x", "a.y")), "SELECT * FROM a WHERE a.x <= a.y", []interface{}{}},
+			{
+				"column comparisons in groups and joins",
+				dbz.Select("p.id").
+					From("products p").
+					InnerJoin("thresholds t", EqCols("t.category", "p.category"), Eq("t.active", true)).
+					Where(Or(GtCols("p.price", "t.threshold"), And(LteCols("p.stock", "t.minimum"), Eq("p.featured", true)))),
+				"SELECT p.id FROM products p INNER JOIN thresholds t ON t.category = p.category AND t.active = $1 " +
+					"WHERE p.price > t.threshold OR (p.stock <= t.minimum AND p.featured = $2)",
+				[]interface{}{true, true},
+			},
+		}
+	})
+}
+
+func TestSelectExpandStar(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"expand star with provided columns",
+				dbz.Select("*").From("users").Where(Eq("id", 1)).
+					ExpandStar(context.Background(), []string{"id", "name", "email"}),
+				"SELECT id, name, email FROM users WHERE id = $1",
+				[]interface{}{1},
+			},
+			{
+				"expand star among other columns",
+				dbz.Select("*", "COUNT(*) OVER () total").From("users").
+					ExpandStar(context.Background(), []string{"id", "name"}),
+				"SELECT id, name, COUNT(*) OVER () total FROM users",
+				[]interface{}{},
+			},
+			{
+				"nothing to expand",
+				dbz.Select("id").From("users").ExpandStar(context.Background(), []string{"id", "name"}),
+				"SELECT id FROM users",
+				[]interface{}{},
+			},
+		}
+	})
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT column_name FROM information_schema.columns " +
+			"WHERE table_name = $1 AND table_schema = current_schema() ORDER BY ordinal_position ASC",
+	)).
+		WithArgs("users").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("id").AddRow("name"))
+
+	// the columns are looked up once, and cached
+	for i := 0; i < 2; i++ {
+		stmt := dbz.Select("*").From("users u").ExpandStar(context.Background(), nil)
+		if err := stmt.Err(); err != nil {
+			t.Fatalf("Failed expanding star: %s", err)
+		}
+
+		if asSQL, _ := stmt.ToSQL(true); asSQL != "SELECT id, name FROM users u" {
+			t.Errorf("Expected looked up columns, got %s", asSQL)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	stmt := dbz.Select("*").From("users u").InnerJoin("orders o", EqCols("o.user_id", "u.id")).
+		ExpandStar(context.Background(), nil)
+	if stmt.Err() == nil {
+		t.Error("Expected looking up columns for a join to fail")
+	}
+}
+
+func TestSelectLockForShare(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"select for share",
+				dbz.Select("*").From("accounts").Where(Eq("id", 1)).LockForShare(),
+				"SELECT * FROM accounts WHERE id = $1 FOR SHARE",
+				[]interface{}{1},
+			},
+
+			{
+				"select for share skipping locked rows",
+				dbz.Select("*").From("jobs").Limit(10).LockForShare().SkipLocked(),
+				"SELECT * FROM jobs LIMIT 10 FOR SHARE SKIP LOCKED",
+				[]interface{}{},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"select for share without waiting",
+				dbz.Select("*").From("accounts").Where(Eq("id", 1)).LockForShare().NoWait(),
+				"SELECT * FROM accounts WHERE id = ? FOR SHARE NOWAIT",
+				[]interface{}{1},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		dbz.SetLegacyShareLocks(true)
+
+		return []test{
+			{
+				"select lock in share mode",
+				dbz.Select("*").From("accounts").Where(Eq("id", 1)).LockForShare(),
+				"SELECT * FROM accounts WHERE id = ? LOCK IN SHARE MODE",
+				[]interface{}{1},
+			},
+
+			{
+				"legacy share locks don't affect exclusive locks",
+				dbz.Select("*").From("accounts").Where(Eq("id", 1)).Lock(ForUpdate()),
+				"SELECT * FROM accounts WHERE id = ? FOR UPDATE",
+				[]interface{}{1},
+			},
+		}
+	})
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	if err := New(db, "sqlite3").Select("*").From("accounts").LockForShare().Err(); err == nil {
+		t.Error("Expected share lock to fail on SQLite")
+	}
+
+	legacy := New(db, "mysql")
+	legacy.SetLegacyShareLocks(true)
+
+	if err := legacy.Select("*").From("accounts").LockForShare().SkipLocked().Err(); err == nil {
+		t.Error("Expected SKIP LOCKED to fail with LOCK IN SHARE MODE")
+	}
+
+	if err := New(db, "postgres").Select("*").From("accounts").NoWait().Err(); err == nil {
+		t.Error("Expected NOWAIT without a lock clause to fail")
+	}
+}
+
+func TestSelectAutoGroupBy(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"plain columns are grouped by",
+				dbz.Select("u.country", "u.city", "COUNT(*) AS total").From("users u").AutoGroupBy(),
+				"SELECT u.country, u.city, COUNT(*) AS total FROM users u GROUP BY u.country, u.city",
+				[]interface{}{},
+			},
+
+			{
+				"aliases are stripped and explicit columns come first",
+				dbz.Select("lower(name) AS lname, type t, count(*), MAX(price) max_price").
+					From("products").
+					GroupBy("type").
+					AutoGroupBy(),
+				"SELECT lower(name) AS lname, type t, count(*), MAX(price) max_price FROM products GROUP BY type, lower(name)",
+				[]interface{}{},
+			},
+
+			{
+				"window functions, stars and literals are excluded",
+				dbz.Select("category", "1 AS one", "SUM(price)", "ROW_NUMBER() OVER (ORDER BY SUM(price)) AS rank").
+					From("products").
+					AutoGroupBy(),
+				"SELECT category, 1 AS one, SUM(price), ROW_NUMBER() OVER (ORDER BY SUM(price)) AS rank FROM products GROUP BY category",
+				[]interface{}{},
+			},
+
+			{
+				"aggregate expressions",
+				dbz.Select("team_id").SelectExpr(ArrayAgg("name").As("names")).From("users").AutoGroupBy(),
+				"SELECT team_id, array_agg(name) AS names FROM users GROUP BY team_id",
+				[]interface{}{},
+			},
+
+			{
+				"no grouping without aggregates",
+				dbz.Select("id", "name").From("users").AutoGroupBy(),
+				"SELECT id, name FROM users",
+				[]interface{}{},
+			},
+		}
+	})
+}
+
+func TestSelectNegate(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"negated and group",
+				dbz.Select("*").From("users").Where(
+					Eq("tenant", 1),
+					Negate(And(Eq("role", "admin"), Gt("age", 30))),
+				),
+				"SELECT * FROM users WHERE tenant = $1 AND NOT (role = $2 AND age > $3)",
+				[]interface{}{1, "admin", 30},
+			},
+
+			{
+				"negated in condition",
+				dbz.Select("*").From("users").Where(Negate(In("id", 1, 2, 3)), Eq("active", true)),
+				"SELECT * FROM users WHERE NOT (id IN ($1, $2, $3)) AND active = $4",
+				[]interface{}{1, 2, 3, true},
+			},
+
+			{
+				"negated exists condition",
+				dbz.Select("*").From("users u").Where(
+					Negate(Exists(dbz.Select("1").From("bans b").Where(SQLCond("b.user_id = u.id"), Eq("b.active", true)))),
+				),
+				"SELECT * FROM users u WHERE NOT (EXISTS (SELECT 1 FROM bans b WHERE b.user_id = u.id AND b.active = $1))",
+				[]interface{}{true},
+			},
+
+			{
+				"not is unaffected",
+				dbz.Select("*").From("users").Where(Not(In("id", 1, 2))),
+				"SELECT * FROM users WHERE NOT(id IN ($1, $2))",
+				[]interface{}{1, 2},
+			},
+		}
+	})
+}
+
+func TestSelectLikePatterns(t *testing.T) {
+	patterns := []interface{}{"%foo%", "bar%"}
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"like any of the patterns",
+				dbz.Select("*").From("files").Where(LikeAnyOf("name", patterns), Eq("deleted", false)),
+				"SELECT * FROM files WHERE (name LIKE ? OR name LIKE ?) AND deleted = ?",
+				[]interface{}{"%foo%", "bar%", false},
+			},
+
+			{
+				"like all of the patterns",
+				dbz.Select("*").From("files").Where(LikeAllOf("name", patterns)),
+				"SELECT * FROM files WHERE (name LIKE ? AND name LIKE ?)",
+				[]interface{}{"%foo%", "bar%"},
+			},
+
+			{
+				"array form is ignored outside of postgres",
+				dbz.Select("*").From("files").Where(LikeAnyOf("name", patterns[:1]).Array()),
+				"SELECT * FROM files WHERE name LIKE ?",
+				[]interface{}{"%foo%"},
+			},
+
+			{
+				"no patterns",
+				dbz.Select("*").From("files").Where(LikeAnyOf("name", nil)),
+				"SELECT * FROM files WHERE 1 = 0",
+				[]interface{}{},
+			},
+		}
+	})
+
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"like any array",
+				dbz.Select("*").From("files").Where(LikeAnyOf("name", patterns).Array()),
+				"SELECT * FROM files WHERE name LIKE ANY (ARRAY[$1, $2])",
+				[]interface{}{"%foo%", "bar%"},
+			},
+
+			{
+				"like all array",
+				dbz.Select("*").From("files").Where(LikeAllOf("name", patterns).Array()),
+				"SELECT * FROM files WHERE name LIKE ALL (ARRAY[$1, $2])",
+				[]interface{}{"%foo%", "bar%"},
+			},
+		}
+	})
+
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		dbz.SetEmptyInPolicy(EmptyInMatchAll)
+
+		return []test{
+			{
+				"no patterns with the match all policy",
+				dbz.Select("*").From("files").Where(LikeAnyOf("name", []interface{}{})),
+				"SELECT * FROM files WHERE 1 = 1",
+				[]interface{}{},
+			},
+		}
+	})
+}
+
+func TestSelectToSQLDebug(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	created := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	var deletedAt *time.Time
+
+	stmt := New(db, "postgres").
+		Select("*").
+		From("users").
+		Where(
+			Eq("name", "O'Brien"),
+			Eq("deleted_at", deletedAt),
+			Gt("score", 9.5),
+			Lt("age", 42),
+			Gte("created_at", created),
+			SQLCond("note <> 'why?'"),
+			Eq("active", true),
+		)
+
+	expected := "SELECT * FROM users WHERE name = 'O''Brien' AND deleted_at = NULL AND score > 9.5 " +
+		"AND age < 42 AND created_at >= '2024-03-01 12:30:00+00:00' AND note <> 'why?' AND active = TRUE"
+	if debug := stmt.ToSQLDebug(); debug != expected {
+		t.Errorf("Expected %s, got %s", expected, debug)
+	}
+
+	// the statement itself still binds its values
+	if asSQL, _ := stmt.ToSQL(true); strings.Contains(asSQL, "O''Brien") {
+		t.Errorf("Expected ToSQL not to interpolate values, got %s", asSQL)
+	}
+
+	mysqlDebug := New(db, "mysql").Select("*").From("files").Where(Eq("path", `C:\it's`)).ToSQLDebug()
+	if expected := `SELECT * FROM files WHERE path = 'C:\\it''s'`; mysqlDebug != expected {
+		t.Errorf("Expected %s, got %s", expected, mysqlDebug)
+	}
+}
+
+func TestSelectIndexHints(t *testing.T) {
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"use index after the table reference",
+				dbz.Select("*").From("users u").UseIndex("idx_email").Where(Eq("u.email", "a@b.c")),
+				"SELECT * FROM users u USE INDEX (idx_email) WHERE u.email = ?",
+				[]interface{}{"a@b.c"},
+			},
+
+			{
+				"force and ignore index before joins",
+				dbz.Select("*").From("users").ForceIndex("idx_a", "idx_b").IgnoreIndex("idx_c").
+					LeftJoin("orders", Eq("orders.user_id", Indirect("users.id"))),
+				"SELECT * FROM users FORCE INDEX (idx_a, idx_b) IGNORE INDEX (idx_c) " +
+					"LEFT JOIN orders ON orders.user_id = users.id",
+				[]interface{}{},
+			},
+		}
+	})
+
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"index hints are not rendered on postgres by default",
+				dbz.Select("*").From("users").UseIndex("idx_email").Where(Eq("email", "a@b.c")),
+				"SELECT * FROM users WHERE email = $1",
+				[]interface{}{"a@b.c"},
+			},
+		}
+	})
+
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		dbz.SetPgHintPlan(true)
+		dbz.SetQueryComment("service=api")
+
+		return []test{
+			{
+				"index hints rendered as a pg_hint_plan comment",
+				dbz.Select("*").From("users AS u").ForceIndex("idx_email").IgnoreIndex("idx_name").
+					Where(Eq("u.email", "a@b.c")),
+				"/*+ IndexScan(u idx_email) */ /* service=api */ SELECT * FROM users AS u WHERE u.email = $1",
+				[]interface{}{"a@b.c"},
+			},
+		}
+	})
+
+	if err := New(nil, "mysql").Select("*").From("users").UseIndex().Err(); err == nil {
+		t.Error("Expected index hint without indexes to fail")
+	}
+}
+
+func TestSelectOrderByField(t *testing.T) {
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"order by field",
+				dbz.Select("*").From("items").Where(In("id", 3, 1, 2)).OrderByField("id", []interface{}{3, 1, 2}),
+				"SELECT * FROM items WHERE id IN (?, ?, ?) ORDER BY FIELD(id, ?, ?, ?)",
+				[]interface{}{3, 1, 2, 3, 1, 2},
+			},
+		}
+	})
+
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"order by array position",
+				dbz.Select("*").From("items").OrderByField("id", []interface{}{3, 1, 2}).OrderBy(Asc("name")).Limit(10),
+				"SELECT * FROM items ORDER BY array_position(ARRAY[$1, $2, $3]::text[], id::text), name ASC LIMIT 10",
+				[]interface{}{3, 1, 2},
+			},
+		}
+	})
+
+	runDriverTests(t, "sqlite3", func(dbz *DB) []test {
+		return []test{
+			{
+				"order by case expression",
+				dbz.Select("*").From("items").OrderByField("id", []interface{}{3, 1}),
+				"SELECT * FROM items ORDER BY CASE id WHEN ? THEN 1 WHEN ? THEN 2 ELSE 3 END",
+				[]interface{}{3, 1},
+			},
+		}
+	})
+
+	if err := New(nil, "mysql").Select("*").From("items").OrderByField("id", nil).Err(); err == nil {
+		t.Error("Expected ordering by field without values to fail")
+	}
+}
+
+func TestSelectWhereStruct(t *testing.T) {
+	type Paging struct {
+		Cursor *int64 `db:"id" op:"gt"`
+	}
+
+	type Filter struct {
+		Paging
+		Name     *string `db:"name" op:"like"`
+		Active   *bool   `db:"is_active"`
+		MinPrice float64 `db:"price" op:"gte"`
+		MaxPrice float64 `db:"price" op:"lt"`
+		Category string
+		Status   string `op:"ne"`
+		Internal string `db:"-"`
+	}
+
+	name, active, cursor := "john%", false, int64(100)
+
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with conditions from set struct fields",
+				dbz.Select("*").From("products").WhereStruct(Filter{
+					Paging:   Paging{&cursor},
+					Name:     &name,
+					Active:   &active,
+					MinPrice: 10,
+					Category: "books",
+					Internal: "ignored",
+				}),
+				"SELECT * FROM products WHERE id > $1 AND name LIKE $2 AND is_active = $3 AND price >= $4 AND category = $5",
+				[]interface{}{int64(100), "john%", false, float64(10), "books"},
+			},
+
+			{
+				"select with conditions from a pointer to a struct",
+				dbz.Select("*").From("products").WhereStruct(&Filter{MaxPrice: 50, Status: "deleted"}).Limit(10),
+				"SELECT * FROM products WHERE price < $1 AND status <> $2 LIMIT 10",
+				[]interface{}{float64(50), "deleted"},
+			},
+
+			{
+				"select with no struct fields set",
+				dbz.Select("*").From("products").WhereStruct(Filter{}),
+				"SELECT * FROM products",
+				[]interface{}{},
+			},
+		}
+	})
+
+	cond, err := ConditionFromStruct(Filter{Category: "books"})
+	if err != nil {
+		t.Fatalf("Failed creating condition from struct: %s", err)
+	}
+
+	if asSQL, _ := cond.Parse(); asSQL != "(category = ?)" {
+		t.Errorf("Expected condition (category = ?), got %s", asSQL)
+	}
+
+	type Invalid struct {
+		Name string `op:"between"`
+	}
+
+	if err := New(nil, "postgres").Select("*").From("t").WhereStruct(Invalid{}).Err(); err == nil {
+		t.Error("Expected unsupported operator to fail")
+	}
+
+	if _, err := ConditionFromStruct(42); err == nil {
+		t.Error("Expected non-struct value to fail")
+	}
+}
+
+func TestEqStructNonNil(t *testing.T) {
+	name, age := "john", 30
+
+	type Filter struct {
+		FullName *string `db:"full_name,omitempty"`
+		UserAge  *int
+		Email    *string
+		Active   bool
+	}
+
+	runTests(t, func(dbz *DB) []test {
+		return []test{
+			{
+				"tag options are ignored and field names are snake-cased",
+				dbz.Select("*").From("users").Where(EqStructNonNil(&Filter{FullName: &name, UserAge: &age, Active: true})),
+				"SELECT * FROM users WHERE full_name = ? AND user_age = ?",
+				[]interface{}{"john", 30},
+			},
+		}
+	})
+
+	var nilFilter *Filter
+
+	for _, obj := range []interface{}{nil, nilFilter, "name"} {
+		if err := New(nil, "postgres").Select("*").From("users").Where(EqStructNonNil(obj)).Err(); err == nil {
+			t.Errorf("Expected EqStructNonNil(%#v) to fail", obj)
+		}
+	}
+}
+
+func TestSelectAliasedSelfJoins(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"self-join with two aliases and parameterized on clauses",
+				dbz.Select("u.name", "m.name manager", "s.name skip_manager").
+					From(JoinTable("users", "u")).
+					LeftJoin(
+						JoinTable("users", "m"),
+						Eq("m.id", Indirect("u.manager_id")),
+						Eq("m.active", true),
+					).
+					LeftJoin(
+						"users s",
+						Eq("s.id", Indirect("m.manager_id")),
+						Gte("s.level", 3),
+					).
+					Where(Eq("u.org_id", 7), Ne("m.id", Indirect("s.id"))),
+				"SELECT u.name, m.name manager, s.name skip_manager FROM users u " +
+					"LEFT JOIN users m ON m.id = u.manager_id AND m.active = $1 " +
+					"LEFT JOIN users s ON s.id = m.manager_id AND s.level >= $2 " +
+					"WHERE u.org_id = $3 AND m.id <> s.id",
+				[]interface{}{true, 3, 7},
+			},
+
+			{
+				"join table without alias",
+				dbz.Select("*").From("users").InnerJoin(JoinTable("orgs", ""), Eq("orgs.id", Indirect("users.org_id"))),
+				"SELECT * FROM users INNER JOIN orgs ON orgs.id = users.org_id",
+				[]interface{}{},
+			},
+		}
+	})
+}
+
+func TestSelectTableSample(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"select from a system sample",
+				dbz.Select("COUNT(*)").From("events").TableSample("system", 1),
+				"SELECT COUNT(*) FROM events TABLESAMPLE SYSTEM (1)",
+				[]interface{}{},
+			},
+
+			{
+				"select from a bernoulli sample of an aliased table",
+				dbz.Select("AVG(e.duration)").From("events e").TableSample("BERNOULLI", 0.5).
+					Where(Eq("e.kind", "click")),
+				"SELECT AVG(e.duration) FROM events e TABLESAMPLE BERNOULLI (0.5) WHERE e.kind = $1",
+				[]interface{}{"click"},
+			},
+		}
+	})
+
+	runDriverTests(t, "sqlserver", func(dbz *DB) []test {
+		return []test{
+			{
+				"select from a sample on sql server",
+				dbz.Select("COUNT(*)").From("events").TableSample("SYSTEM", 10),
+				"SELECT COUNT(*) FROM events TABLESAMPLE SYSTEM (10 PERCENT)",
+				[]interface{}{},
+			},
+		}
+	})
+
+	if err := New(nil, "mysql").Select("*").From("events").TableSample("SYSTEM", 1).Err(); err == nil {
+		t.Error("Expected table sample to fail on MySQL")
+	}
+
+	if err := New(nil, "sqlserver").Select("*").From("events").TableSample("BERNOULLI", 1).Err(); err == nil {
+		t.Error("Expected bernoulli sample to fail on SQL Server")
+	}
+
+	if err := New(nil, "postgres").Select("*").From("events").TableSample("RANDOM", 1).Err(); err == nil {
+		t.Error("Expected unsupported sampling method to fail")
+	}
+
+	if err := New(nil, "postgres").Select("*").From("events").TableSample("SYSTEM", 101).Err(); err == nil {
+		t.Error("Expected invalid sampling percentage to fail")
+	}
+}
+
+func TestSelectLockForUpdateOf(t *testing.T) {
+	stmt := func(dbz *DB) *SelectStmt {
+		return dbz.Select("j.*").
+			From("jobs j").
+			InnerJoin("queues q", Eq("q.id", Indirect("j.queue_id"))).
+			Where(Eq("q.name", "emails")).
+			Limit(10).
+			LockForUpdateOf("j").
+			SkipLocked()
+	}
+
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"postgres lock of a single table skipping locked rows",
+				stmt(dbz),
+				"SELECT j.* FROM jobs j INNER JOIN queues q ON q.id = j.queue_id WHERE q.name = $1 LIMIT 10 " +
+					"FOR UPDATE OF j SKIP LOCKED",
+				[]interface{}{"emails"},
+			},
+
+			{
+				"postgres lock of several tables",
+				dbz.Select("*").From("a").InnerJoin("b", Eq("b.id", Indirect("a.b_id"))).LockForUpdateOf("a", "b").NoWait(),
+				"SELECT * FROM a INNER JOIN b ON b.id = a.b_id FOR UPDATE OF a, b NOWAIT",
+				[]interface{}{},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"mysql lock of a single table skipping locked rows",
+				stmt(dbz),
+				"SELECT j.* FROM jobs j INNER JOIN queues q ON q.id = j.queue_id WHERE q.name = ? LIMIT 10 " +
+					"FOR UPDATE OF j SKIP LOCKED",
+				[]interface{}{"emails"},
+			},
+		}
+	})
+
+	if err := stmt(New(nil, "sqlite3")).Err(); err == nil {
+		t.Error("Expected FOR UPDATE OF to fail on SQLite")
+	}
+
+	if err := New(nil, "postgres").Select("*").From("jobs").LockForUpdateOf().Err(); err == nil {
+		t.Error("Expected FOR UPDATE OF without tables to fail")
+	}
+}
+
+func TestSelectOrderByRaw(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"raw order expressions interleaved with columns",
+				dbz.Select("kind", "COUNT(*)").
+					From("places").
+					Where(Eq("city", "paris")).
+					GroupBy("kind").
+					Having(Gt("COUNT(*)", 2)).
+					OrderBy(Desc("priority")).
+					OrderByRaw("ST_Distance(geom, ST_MakePoint(?, ?))", 2.35, 48.85).
+					OrderByColumns(OrderAsc("name")).
+					OrderByRaw("kind = ? DESC", "museum").
+					Limit(5),
+				"SELECT kind, COUNT(*) FROM places WHERE city = $1 GROUP BY kind HAVING COUNT(*) > $2 " +
+					"ORDER BY priority DESC, ST_Distance(geom, ST_MakePoint($3, $4)), name ASC, kind = $5 DESC LIMIT 5",
+				[]interface{}{"paris", 2, 2.35, 48.85, "museum"},
+			},
+		}
+	})
+}
+
+func TestSelectLimitWithTies(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"postgres limit with ties",
+				dbz.Select("name", "score").From("players").Where(Eq("league", 1)).
+					OrderBy(Desc("score")).LimitWithTies(3),
+				"SELECT name, score FROM players WHERE league = $1 ORDER BY score DESC FETCH FIRST 3 ROWS WITH TIES",
+				[]interface{}{1},
+			},
+
+			{
+				"postgres limit with ties and offset",
+				dbz.Select("name").From("players").OrderBy(Desc("score")).LimitWithTies(3).Offset(6),
+				"SELECT name FROM players ORDER BY score DESC OFFSET 6 FETCH FIRST 3 ROWS WITH TIES",
+				[]interface{}{},
+			},
+
+			{
+				"plain limit replaces limit with ties",
+				dbz.Select("name").From("players").OrderBy(Desc("score")).LimitWithTies(3).Limit(5),
+				"SELECT name FROM players ORDER BY score DESC LIMIT 5",
+				[]interface{}{},
+			},
+		}
+	})
+
+	runDriverTests(t, "sqlserver", func(dbz *DB) []test {
+		return []test{
+			{
+				"sql server top with ties",
+				dbz.Select("name", "score").From("players").Where(Eq("league", 1)).
+					OrderBy(Desc("score")).LimitWithTies(3),
+				"SELECT TOP (3) WITH TIES name, score FROM players WHERE league = @p1 ORDER BY score DESC",
+				[]interface{}{1},
+			},
+		}
+	})
+
+	if err := New(nil, "postgres").Select("*").From("players").LimitWithTies(3).Err(); err == nil {
+		t.Error("Expected WITH TIES without ORDER BY to fail")
+	}
+
+	if err := New(nil, "mysql").Select("*").From("players").OrderBy(Desc("score")).LimitWithTies(3).Err(); err == nil {
+		t.Error("Expected WITH TIES to fail on MySQL")
+	}
+
+	if err := New(nil, "sqlserver").Select("*").From("players").OrderBy(Desc("score")).
+		LimitWithTies(3).Offset(3).Err(); err == nil {
+		t.Error("Expected WITH TIES with an offset to fail on SQL Server")
+	}
+}
+
+func TestSelectWithTotalCount(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"total count with limit and offset",
+				dbz.Select("id", "name").From("users").Where(Eq("active", true)).
+					OrderBy(Asc("id")).Limit(10).Offset(20).WithTotalCount("total_count"),
+				"SELECT id, name, COUNT(*) OVER () AS total_count FROM users WHERE active = $1 ORDER BY id ASC LIMIT 10 OFFSET 20",
+				[]interface{}{true},
+			},
+
+			{
+				"total count after other expressions",
+				dbz.Select("name").From("users").SelectExpr(Indirect("length(name) AS len")).
+					WithTotalCount("total").Limit(5),
+				"SELECT name, length(name) AS len, COUNT(*) OVER () AS total FROM users LIMIT 5",
+				[]interface{}{},
+			},
+		}
+	})
+
+	if err := New(nil, "postgres").Select("id").From("users").WithTotalCount("").Err(); err == nil {
+		t.Error("Expected WithTotalCount without an alias to fail")
+	}
+}
@@ -3,12 +3,18 @@ package sqlz
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
 )
 
 // Ext is a union interface which can bind, query, and exec,
@@ -29,15 +35,43 @@ type Queryer interface {
 // DB is a wrapper around sqlx.DB (which is a wrapper around sql.DB)
 type DB struct {
 	*sqlx.DB
-	ErrHandlers []func(err error)
+	ErrHandlers        []func(err error)
+	queryLogger        QueryLogger
+	slowQueryThreshold time.Duration
+	slowQueryHandler   SlowQueryHandler
+	stmtCache          *stmtCache
+	estimateCache      *estimateCache
+	emptyInPolicy      EmptyInPolicy
+	placeholderFormat  PlaceholderFormat
+	boolAsInt          bool
+	selectPool         sync.Pool
+	columnCache        columnCache
+	errorMapping       bool
+	legacyShareLocks   bool
+	queryComment       string
+	nativePgx          bool
+	pgHintPlan         bool
+	estimateStrategies []EstStrategy
+	argTransformer     ArgTransformer
+	argTransformExec   bool
 }
 
 // Tx is a wrapper around sqlx.Tx (which is a wrapper around sql.Tx)
 type Tx struct {
 	*sqlx.Tx
 	ErrHandlers []func(err error)
+	db          *DB
 }
 
+// QueryLogger is a function that receives every query executed through
+// sqlz, together with its bindings, the time it took to execute, and the
+// error it returned (if any)
+type QueryLogger func(query string, args []interface{}, duration time.Duration, err error)
+
+// SlowQueryHandler is a function that receives queries whose execution
+// exceeded the threshold set with DB.SetSlowQueryThreshold
+type SlowQueryHandler func(query string, args []interface{}, duration time.Duration)
+
 // SQLStmt is an interface representing a general SQL statement. All
 // specific statement types (e.g. SelectStmt, UpdateStmt, etc.)
 // implement this interface
@@ -63,6 +97,85 @@ func Newx(db *sqlx.DB) *DB {
 	return &DB{DB: db}
 }
 
+// SetQueryLogger sets a function to be called after every query executed
+// through sqlz, whether it succeeded or not. The logger receives the query
+// after placeholders were rebound for the database driver (e.g. "$1" for
+// PostgreSQL). Pass nil to disable logging.
+func (db *DB) SetQueryLogger(logger QueryLogger) {
+	db.queryLogger = logger
+}
+
+// SetSlowQueryThreshold sets a function to be called for every query executed
+// through sqlz that takes at least the provided duration. Only the execution
+// of the query itself is measured, the time spent scanning result rows is not.
+// Pass a zero duration to disable slow query detection.
+func (db *DB) SetSlowQueryThreshold(threshold time.Duration, handler SlowQueryHandler) {
+	db.slowQueryThreshold = threshold
+	db.slowQueryHandler = handler
+}
+
+// SetBoolAsInt sets whether boolean arguments of queries executed through
+// sqlz (whether bound in conditions, inserted values or elsewhere) are
+// converted to the integers 1 and 0 before being passed to the driver. This
+// is useful for SQLite, which has no boolean type, with drivers that don't
+// perform this conversion themselves. Arguments are passed as-is by default.
+func (db *DB) SetBoolAsInt(enabled bool) {
+	db.boolAsInt = enabled
+}
+
+// SetNativePgx sets whether queries that already contain ordinal
+// placeholders (e.g. "$1", as in raw queries written for pgx's native
+// interface, see DB.RawSelect) are left as they are when statements are
+// rebound for PostgreSQL drivers, rather than having their question marks
+// translated to ordinals as well. This prevents rebinding such queries a
+// second time, which would renumber question marks that are not
+// placeholders (e.g. PostgreSQL's "?" JSONB operator). Queries with
+// question mark placeholders only are rebound as usual. Note that the
+// package-level Rebind function is not affected by this setting.
+func (db *DB) SetNativePgx(enabled bool) {
+	db.nativePgx = enabled
+}
+
+// SetQueryComment sets a default tag (e.g. "service=foo") to add as a
+// comment to the start of the SQL of all statements created from the DB
+// (and its transactions), before the statement's own tag (see
+// SelectStmt.Comment). Pass an empty string to disable it.
+func (db *DB) SetQueryComment(tag string) {
+	db.queryComment = tag
+}
+
+// SetLegacyShareLocks sets whether shared row locks (see
+// SelectStmt.LockForShare) are rendered as "LOCK IN SHARE MODE" rather than
+// "FOR SHARE". Enable this for MySQL servers older than 8.0, which do not
+// support the latter. It has no effect on other databases.
+func (db *DB) SetLegacyShareLocks(enabled bool) {
+	db.legacyShareLocks = enabled
+}
+
+// legacyShareLocksOf returns true if shared row locks should be rendered
+// with MySQL's legacy "LOCK IN SHARE MODE" syntax for the provided queryer
+func legacyShareLocksOf(q Queryer) bool {
+	h, ok := q.(*handle)
+	return ok && h.db != nil && h.db.legacyShareLocks && isMySQL(driverNameOf(q))
+}
+
+// SetPgHintPlan sets whether index hints (see SelectStmt.UseIndex) are
+// rendered as pg_hint_plan comments on PostgreSQL, e.g. "/*+ IndexScan(t
+// idx) */ SELECT ...". Enable this only if the pg_hint_plan extension is
+// loaded; otherwise, PostgreSQL (which has no index hints) ignores the
+// comments anyway. When disabled, as is the default, index hints are not
+// rendered on PostgreSQL at all.
+func (db *DB) SetPgHintPlan(enabled bool) {
+	db.pgHintPlan = enabled
+}
+
+// pgHintPlanOf returns true if index hints should be rendered as
+// pg_hint_plan comments for the provided queryer
+func pgHintPlanOf(q Queryer) bool {
+	h, ok := q.(*handle)
+	return ok && h.db != nil && h.db.pgHintPlan && isPostgres(driverNameOf(q))
+}
+
 // Transactional runs the provided function inside a transaction. The
 // function must receive an sqlz Tx object, and return an error. If the
 // function returns an error, the transaction is automatically rolled
@@ -90,7 +203,7 @@ func (db *DB) TransactionalContext(
 		return fmt.Errorf("failed starting transaction: %w", err)
 	}
 
-	err = f(&Tx{Tx: tx, ErrHandlers: db.ErrHandlers})
+	err = f(&Tx{Tx: tx, ErrHandlers: db.ErrHandlers, db: db})
 	if err != nil {
 		tx.Rollback() // nolint: errcheck
 		return err
@@ -104,6 +217,82 @@ func (db *DB) TransactionalContext(
 	return nil
 }
 
+// Transaction runs the provided function inside a transaction started with
+// the provided isolation level (e.g. sql.LevelSerializable). It otherwise
+// behaves exactly like TransactionalContext.
+func (db *DB) Transaction(
+	ctx context.Context,
+	isolation sql.IsolationLevel,
+	f func(tx *Tx) error,
+) error {
+	return db.TransactionalContext(ctx, &sql.TxOptions{Isolation: isolation}, f)
+}
+
+// BeginTx starts a new transaction with the provided options, which are
+// passed as-is to the underlying sql.DB. Unlike sqlx's BeginTx, the returned
+// object is an sqlz Tx, so statements created from it use the same driver
+// and settings as the DB. The caller is responsible for committing or
+// rolling back the transaction.
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed starting transaction: %w", err)
+	}
+
+	return &Tx{Tx: tx, ErrHandlers: db.ErrHandlers, db: db}, nil
+}
+
+// retryBackoff is the delay before the first retry of a transaction by
+// TransactionWithRetry, which grows linearly with every retry
+var retryBackoff = 20 * time.Millisecond
+
+// TransactionWithRetry is the same as Transactional, but if the transaction
+// fails due to a serialization failure or a deadlock (which is expected
+// under SERIALIZABLE isolation), it is retried up to maxRetries times,
+// with a small delay between attempts. The function must therefore be safe
+// to run more than once. If all attempts fail, the last error is returned.
+// PostgreSQL errors 40001 and 40P01, and MySQL error 1213, are retried.
+func (db *DB) TransactionWithRetry(maxRetries int, f func(tx *Tx) error, opts ...*sql.TxOptions) error {
+	var lastOpts *sql.TxOptions
+	if len(opts) > 0 {
+		lastOpts = opts[len(opts)-1]
+	}
+
+	return db.TransactionWithRetryContext(context.Background(), lastOpts, maxRetries, f)
+}
+
+// TransactionWithRetryContext is the same as TransactionWithRetry, but
+// receives a context. Retries stop if the context is done.
+func (db *DB) TransactionWithRetryContext(
+	ctx context.Context,
+	opts *sql.TxOptions,
+	maxRetries int,
+	f func(tx *Tx) error,
+) (err error) {
+	for attempt := 0; ; attempt++ {
+		err = db.TransactionalContext(ctx, opts, f)
+		if err == nil || attempt >= maxRetries || !isRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt+1) * retryBackoff):
+		}
+	}
+}
+
+// isRetryable returns true if the error (or any error it wraps) is a
+// serialization failure or a deadlock (see MapError)
+func isRetryable(err error) bool {
+	if mapped := MapError(err); mapped != nil {
+		return mapped.Kind == SerializationFailure || mapped.Kind == Deadlock
+	}
+
+	return false
+}
+
 // WhereCondition is an interface describing conditions
 // that can be used inside an SQL WHERE clause. It defines
 // the Parse function that generates SQL (with placeholders)
@@ -136,11 +325,18 @@ type PreCondition struct {
 	Condition WhereCondition
 }
 
+// NegatedCondition represents a negated condition, see Negate
+type NegatedCondition struct {
+	Condition WhereCondition
+}
+
 // SubqueryCondition is a WHERE condition on the results
-// of a sub-query.
+// of a sub-query. If Left is set, the condition compares it
+// with the results (e.g. "id IN (SELECT ...)").
 type SubqueryCondition struct {
 	Stmt     *SelectStmt
 	Operator string
+	Left     string
 }
 
 // SQLCondition represents a condition written directly in
@@ -151,6 +347,23 @@ type SQLCondition struct {
 	Binds     []interface{}
 }
 
+// invalidCondition is a condition that could not be created, failing the
+// statement it is used in with its error
+type invalidCondition struct {
+	err error
+}
+
+// Parse implements the WhereCondition interface, generating a condition
+// that is always false, should the statement be executed regardless
+func (cond invalidCondition) Parse() (asSQL string, bindings []interface{}) {
+	return "1 = 0", nil
+}
+
+// Err returns the error the condition was created with
+func (cond invalidCondition) Err() error {
+	return cond.err
+}
+
 // IndirectValue represents a reference to a database name
 // (e.g. column, function) that should be used as-is in a
 // query rather than replaced with a placeholder.
@@ -193,6 +406,14 @@ func Not(cond WhereCondition) PreCondition {
 	return PreCondition{"NOT", cond}
 }
 
+// Negate wraps any condition, including AND/OR groups and sub-query
+// conditions such as Exists, in "NOT (...)", e.g. Negate(And(a, b))
+// generates "NOT (a AND b)". The bindings of the condition are kept in
+// order. This is useful for inverting a reusable filter.
+func Negate(cond WhereCondition) NegatedCondition {
+	return NegatedCondition{cond}
+}
+
 // Eq represents a simple equality condition ("=" operator)
 func Eq(col string, value interface{}) SimpleCondition {
 	return SimpleCondition{col, value, "="}
@@ -233,6 +454,41 @@ func NotLike(col string, value interface{}) SimpleCondition {
 	return SimpleCondition{col, value, "NOT LIKE"}
 }
 
+// LikePatternsCondition is a condition matching a column against several
+// LIKE patterns, see LikeAnyOf and LikeAllOf
+type LikePatternsCondition struct {
+	Column   string
+	Patterns []interface{}
+	All      bool
+	AsArray  bool
+}
+
+// LikeAnyOf creates a condition checking that the value of a column matches
+// any of the provided LIKE patterns, expanding to an OR of LIKE conditions
+// with each pattern bound, e.g. "(name LIKE ? OR name LIKE ?)". (Note that
+// LikeAny is an array condition with different semantics.) If there are no
+// patterns, the condition is handled like an IN condition with no values,
+// i.e. according to the policy set with DB.SetEmptyInPolicy.
+func LikeAnyOf(col string, patterns []interface{}) LikePatternsCondition {
+	return LikePatternsCondition{Column: col, Patterns: patterns}
+}
+
+// LikeAllOf is the same as LikeAnyOf, but checks that the value of the
+// column matches all of the patterns, expanding to an AND of LIKE
+// conditions.
+func LikeAllOf(col string, patterns []interface{}) LikePatternsCondition {
+	return LikePatternsCondition{Column: col, Patterns: patterns, All: true}
+}
+
+// Array renders the condition on PostgreSQL with a single LIKE operator
+// over an array of the patterns, e.g. "name LIKE ANY (ARRAY[?, ?])" (or
+// "LIKE ALL"), which is more compact for many patterns. Other drivers
+// ignore this and expand the condition as usual.
+func (cond LikePatternsCondition) Array() LikePatternsCondition {
+	cond.AsArray = true
+	return cond
+}
+
 // ILike represents a wildcard equality condition ("ILIKE" operator)
 func ILike(col string, value interface{}) SimpleCondition {
 	return SimpleCondition{col, value, "ILIKE"}
@@ -248,16 +504,70 @@ func IsNotNull(col string) SimpleCondition {
 	return SimpleCondition{col, nil, "IS NOT NULL"}
 }
 
+// EqCols represents an equality condition between two columns or
+// expressions ("=" operator), e.g. EqCols("a.id", "b.a_id") generates
+// "a.id = b.a_id". Unlike Eq, the right-hand side is not bound as a value.
+// The same goes for NeCols, GtCols, GteCols, LtCols and LteCols.
+func EqCols(left, right string) SimpleCondition {
+	return SimpleCondition{left, Indirect(right), "="}
+}
+
+// NeCols represents a non-equality condition between two columns or
+// expressions ("<>" operator)
+func NeCols(left, right string) SimpleCondition {
+	return SimpleCondition{left, Indirect(right), "<>"}
+}
+
+// GtCols represents a greater-than condition between two columns or
+// expressions (">" operator)
+func GtCols(left, right string) SimpleCondition {
+	return SimpleCondition{left, Indirect(right), ">"}
+}
+
+// GteCols represents a greater-than-or-equals condition between two columns or
+// expressions (">=" operator)
+func GteCols(left, right string) SimpleCondition {
+	return SimpleCondition{left, Indirect(right), ">="}
+}
+
+// LtCols represents a less-than condition between two columns or
+// expressions ("<" operator)
+func LtCols(left, right string) SimpleCondition {
+	return SimpleCondition{left, Indirect(right), "<"}
+}
+
+// LteCols represents a less-than-or-equals condition between two columns or
+// expressions ("<=" operator)
+func LteCols(left, right string) SimpleCondition {
+	return SimpleCondition{left, Indirect(right), "<="}
+}
+
 // Exists creates a sub-query condition checking the sub-query
 // returns results ("EXISTS" operator)
 func Exists(stmt *SelectStmt) SubqueryCondition {
-	return SubqueryCondition{stmt, "EXISTS"}
+	return SubqueryCondition{Stmt: stmt, Operator: "EXISTS"}
 }
 
 // NotExists creates a sub-query condition checking the sub-query
 // does not return results ("NOT EXISTS" operator)
 func NotExists(stmt *SelectStmt) SubqueryCondition {
-	return SubqueryCondition{stmt, "NOT EXISTS"}
+	return SubqueryCondition{Stmt: stmt, Operator: "NOT EXISTS"}
+}
+
+// InQuery creates a sub-query condition checking the column's
+// value is one of the results of the sub-query ("IN" operator),
+// e.g. "id IN (SELECT user_id FROM orders WHERE total > ?)". The
+// sub-query's bindings are placed where it appears in the query.
+func InQuery(col string, stmt *SelectStmt) SubqueryCondition {
+	return SubqueryCondition{Stmt: stmt, Operator: "IN", Left: col}
+}
+
+// NotInQuery creates a sub-query condition checking the column's
+// value is not one of the results of the sub-query ("NOT IN"
+// operator). Note that if the sub-query returns a NULL, the
+// condition never matches.
+func NotInQuery(col string, stmt *SelectStmt) SubqueryCondition {
+	return SubqueryCondition{Stmt: stmt, Operator: "NOT IN", Left: col}
 }
 
 // JSONBOp creates simple conditions with JSONB operators for
@@ -279,6 +589,181 @@ func SQLCond(condition string, binds ...interface{}) SQLCondition {
 	return SQLCondition{condition, binds}
 }
 
+// BetweenCondition is a struct representing BETWEEN and NOT BETWEEN
+// conditions, which are inclusive on both ends
+type BetweenCondition struct {
+	NotBetween bool
+	Left       string
+	Low        interface{}
+	High       interface{}
+}
+
+// Between creates a BETWEEN condition checking that the value of a column
+// is between low and high, inclusive on both ends
+func Between(col string, low, high interface{}) BetweenCondition {
+	return BetweenCondition{false, col, low, high}
+}
+
+// NotBetween creates a NOT BETWEEN condition checking that the value of a
+// column is lower than low or higher than high
+func NotBetween(col string, low, high interface{}) BetweenCondition {
+	return BetweenCondition{true, col, low, high}
+}
+
+// BetweenOpts creates a condition checking that the value of a column is
+// between low and high, where each bound may be inclusive or exclusive.
+// As SQL's BETWEEN is always inclusive, bounds are compared separately
+// (e.g. "col >= ? AND col < ?") unless both are inclusive.
+func BetweenOpts(col string, low, high interface{}, lowInclusive, highInclusive bool) WhereCondition {
+	if lowInclusive && highInclusive {
+		return Between(col, low, high)
+	}
+
+	lowCond, highCond := Gt(col, low), Lt(col, high)
+
+	if lowInclusive {
+		lowCond = Gte(col, low)
+	}
+
+	if highInclusive {
+		highCond = Lte(col, high)
+	}
+
+	return And(lowCond, highCond)
+}
+
+// BetweenExclusive creates a condition checking that the value of a column
+// is between low and high, exclusive on both ends ("col > ? AND col < ?")
+func BetweenExclusive(col string, low, high interface{}) WhereCondition {
+	return BetweenOpts(col, low, high, false, false)
+}
+
+// BetweenLeftInclusive creates a condition checking that the value of a
+// column is in the half-open range [low, high) ("col >= ? AND col < ?").
+// This is usually the right choice for timestamp ranges, as consecutive
+// ranges do not count rows on their boundaries twice.
+func BetweenLeftInclusive(col string, low, high interface{}) WhereCondition {
+	return BetweenOpts(col, low, high, true, false)
+}
+
+// BetweenRightInclusive creates a condition checking that the value of a
+// column is in the half-open range (low, high] ("col > ? AND col <= ?")
+func BetweenRightInclusive(col string, low, high interface{}) WhereCondition {
+	return BetweenOpts(col, low, high, false, true)
+}
+
+// NamedCondition is a condition written directly in SQL with named
+// placeholders, see NamedCond
+type NamedCondition struct {
+	SQLCondition
+	// EmptyLists holds the names of parameters whose values are empty
+	// slices
+	EmptyLists []string
+	// Missing holds the names of parameters with no value
+	Missing []string
+}
+
+// NamedCond is the same as SQLCond, but uses named placeholders of the
+// form ":name" instead of question marks, taking their values from the
+// provided map. Placeholders are replaced by positional ones in order of
+// appearance, so a name may appear multiple times. If a value is a slice
+// (other than []byte), its placeholder expands into a comma-separated list
+// of placeholders, one per element, which is useful for IN conditions, e.g.
+// NamedCond("id IN (:ids)", map[string]interface{}{"ids": []int{1, 2}}).
+// An empty slice expands into NULL, so that both IN and NOT IN match no
+// rows, unless a different policy was set with DB.SetEmptyInPolicy, in
+// which case the whole condition either matches all rows or fails the
+// statement. PostgreSQL-style casts ("::type") and quoted strings are left
+// untouched. If a name is missing from the map, the statement fails when
+// executed.
+func NamedCond(condition string, params map[string]interface{}) NamedCondition {
+	var (
+		b     strings.Builder
+		cond  NamedCondition
+		quote byte
+	)
+
+	for i := 0; i < len(condition); i++ {
+		c := condition[i]
+
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ':' && i+1 < len(condition) && condition[i+1] == ':':
+			b.WriteString("::")
+			i++
+
+			continue
+		case c == ':' && i+1 < len(condition) && isNameChar(condition[i+1]):
+			end := i + 1
+			for end < len(condition) && isNameChar(condition[end]) {
+				end++
+			}
+
+			name := condition[i+1 : end]
+
+			value, ok := params[name]
+			if !ok {
+				cond.Missing = append(cond.Missing, name)
+			}
+
+			placeholders, values := namedValue(value)
+			if placeholders == "" {
+				placeholders = "NULL"
+				cond.EmptyLists = append(cond.EmptyLists, name)
+			}
+
+			b.WriteString(placeholders)
+			cond.Binds = append(cond.Binds, values...)
+			i = end - 1
+
+			continue
+		}
+
+		b.WriteByte(c)
+	}
+
+	cond.Condition = b.String()
+
+	return cond
+}
+
+// Err returns an error if a named parameter of the condition has no value
+func (cond NamedCondition) Err() error {
+	if len(cond.Missing) > 0 {
+		return fmt.Errorf("no value provided for named parameter :%s", cond.Missing[0])
+	}
+
+	return nil
+}
+
+// namedValue returns the placeholders and bindings for a named parameter's
+// value, expanding slices into multiple placeholders (none for an empty
+// slice)
+func namedValue(value interface{}) (placeholders string, bindings []interface{}) {
+	if _, isBytes := value.([]byte); !isBytes {
+		if val := reflect.ValueOf(value); val.Kind() == reflect.Slice {
+			marks := make([]string, val.Len())
+			for i := range marks {
+				marks[i] = "?"
+				bindings = append(bindings, val.Index(i).Interface())
+			}
+
+			return strings.Join(marks, ", "), bindings
+		}
+	}
+
+	return "?", []interface{}{value}
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
 // InCondition is a struct representing IN and NOT IN conditions
 type InCondition struct {
 	NotIn bool
@@ -287,17 +772,446 @@ type InCondition struct {
 }
 
 // In creates an IN condition for matching the value of a column
-// against an array of possible values
+// against an array of possible values. The values may also be provided
+// as a single slice of any element type (e.g. In("id", ids) where ids is
+// an []int64), which is expanded into one placeholder per element. If
+// there are no values, the condition is always false (e.g. "1 = 0"),
+// unless a different policy was set with DB.SetEmptyInPolicy.
 func In(col string, values ...interface{}) InCondition {
 	return InCondition{false, col, values}
 }
 
 // NotIn creates a NOT IN condition for checking that the value
-// of a column is not one of the defined values
+// of a column is not one of the defined values. Values are handled as in
+// In, including when there are none: the condition is then always false,
+// so that an empty list never matches all rows by accident, unless a
+// different policy was set with DB.SetEmptyInPolicy.
 func NotIn(col string, values ...interface{}) InCondition {
 	return InCondition{true, col, values}
 }
 
+// EmptyInPolicy determines how IN and NOT IN conditions with no values are
+// handled, see DB.SetEmptyInPolicy
+type EmptyInPolicy int
+
+const (
+	// EmptyInMatchNone makes IN and NOT IN conditions with no values match
+	// no rows ("1 = 0"). This is the default.
+	EmptyInMatchNone EmptyInPolicy = iota
+	// EmptyInMatchAll makes IN and NOT IN conditions with no values match
+	// all rows ("1 = 1")
+	EmptyInMatchAll
+	// EmptyInError makes statements with IN or NOT IN conditions with no
+	// values fail when executed
+	EmptyInError
+)
+
+// SetEmptyInPolicy sets how IN and NOT IN conditions (see In and NotIn)
+// with no values are handled by statements created from the DB (and its
+// transactions). The policy applies to conditions passed to the Where
+// methods of SELECT, UPDATE and DELETE statements, and to Having, including
+// conditions nested in And, Or and Not. It also applies to LikeAnyOf and
+// LikeAllOf conditions with no patterns, and to NamedCond conditions with
+// empty slice parameters.
+func (db *DB) SetEmptyInPolicy(policy EmptyInPolicy) {
+	db.emptyInPolicy = policy
+}
+
+// emptyInPolicyOf returns the empty IN policy of the DB the provided
+// database object originated from
+func emptyInPolicyOf(db interface{}) EmptyInPolicy {
+	if h, ok := db.(*handle); ok && h.db != nil {
+		return h.db.emptyInPolicy
+	}
+
+	return EmptyInMatchNone
+}
+
+// apply returns the provided conditions with IN and NOT IN conditions that
+// have no values handled according to the policy
+func (policy EmptyInPolicy) apply(conds []WhereCondition) ([]WhereCondition, error) {
+	if policy == EmptyInMatchNone {
+		return conds, nil
+	}
+
+	applied := make([]WhereCondition, len(conds))
+
+	for i, cond := range conds {
+		switch c := cond.(type) {
+		case InCondition:
+			if len(c.values()) > 0 {
+				applied[i] = c
+			} else if policy == EmptyInError {
+				return nil, fmt.Errorf("IN condition on %s has no values", c.Left)
+			} else {
+				applied[i] = SQLCond("1 = 1")
+			}
+		case LikePatternsCondition:
+			if len(c.Patterns) > 0 {
+				applied[i] = c
+			} else if policy == EmptyInError {
+				return nil, fmt.Errorf("LIKE condition on %s has no patterns", c.Column)
+			} else {
+				applied[i] = SQLCond("1 = 1")
+			}
+		case NamedCondition:
+			if len(c.EmptyLists) == 0 {
+				applied[i] = c
+			} else if policy == EmptyInError {
+				return nil, fmt.Errorf("named parameter :%s has no values", c.EmptyLists[0])
+			} else {
+				applied[i] = SQLCond("1 = 1")
+			}
+		case AndOrCondition:
+			inner, err := policy.apply(c.Conditions)
+			if err != nil {
+				return nil, err
+			}
+
+			applied[i] = AndOrCondition{c.Or, inner}
+		case PreCondition:
+			inner, err := policy.apply([]WhereCondition{c.Condition})
+			if err != nil {
+				return nil, err
+			}
+
+			applied[i] = PreCondition{c.Pre, inner[0]}
+		case NegatedCondition:
+			inner, err := policy.apply([]WhereCondition{c.Condition})
+			if err != nil {
+				return nil, err
+			}
+
+			applied[i] = NegatedCondition{inner[0]}
+		default:
+			applied[i] = cond
+		}
+	}
+
+	return applied, nil
+}
+
+// ConditionGroup builds a group of conditions incrementally, joining each
+// condition to the ones before it with AND or OR (see
+// SelectStmt.WhereGroup). Mixing AND and OR never relies on operator
+// precedence: when the operator changes, the conditions before it are
+// parenthesized, e.g. g.And(a, b).Or(c) generates "((a AND b) OR c)".
+type ConditionGroup struct {
+	conds []WhereCondition
+	isOr  bool
+}
+
+// And joins the provided conditions to the group with AND
+func (g *ConditionGroup) And(conds ...WhereCondition) *ConditionGroup {
+	for _, cond := range conds {
+		g.add(false, cond)
+	}
+
+	return g
+}
+
+// Or joins the provided conditions to the group with OR
+func (g *ConditionGroup) Or(conds ...WhereCondition) *ConditionGroup {
+	for _, cond := range conds {
+		g.add(true, cond)
+	}
+
+	return g
+}
+
+// AndGroup builds a nested group with the provided function, and joins it
+// to the group with AND
+func (g *ConditionGroup) AndGroup(fn func(g *ConditionGroup)) *ConditionGroup {
+	return g.addGroup(false, fn)
+}
+
+// OrGroup builds a nested group with the provided function, and joins it
+// to the group with OR
+func (g *ConditionGroup) OrGroup(fn func(g *ConditionGroup)) *ConditionGroup {
+	return g.addGroup(true, fn)
+}
+
+// Condition returns the group as a single condition, or nil if the group
+// is empty
+func (g *ConditionGroup) Condition() WhereCondition {
+	switch len(g.conds) {
+	case 0:
+		return nil
+	case 1:
+		return g.conds[0]
+	default:
+		return AndOrCondition{g.isOr, append([]WhereCondition{}, g.conds...)}
+	}
+}
+
+func (g *ConditionGroup) addGroup(isOr bool, fn func(g *ConditionGroup)) *ConditionGroup {
+	nested := &ConditionGroup{}
+	fn(nested)
+
+	if cond := nested.Condition(); cond != nil {
+		g.add(isOr, cond)
+	}
+
+	return g
+}
+
+func (g *ConditionGroup) add(isOr bool, cond WhereCondition) {
+	if len(g.conds) > 1 && isOr != g.isOr {
+		g.conds = []WhereCondition{g.Condition()}
+	}
+
+	g.conds = append(g.conds, cond)
+	g.isOr = isOr
+}
+
+// RowCondition represents a comparison between a row constructor made of
+// several columns and a composite value (e.g. "(a, b) = ROW(?, ?)")
+type RowCondition struct {
+	Left     []string
+	Operator string
+	Right    []interface{}
+}
+
+// RowEq creates a condition checking a row constructor of the provided
+// columns equals a composite value built from the provided values
+func RowEq(cols []string, values []interface{}) RowCondition {
+	return RowCondition{cols, "=", values}
+}
+
+// WhereMap creates an equality condition for every column in the provided
+// map, joined with AND. Conditions are generated in the order of the column
+// names, so that the generated SQL is deterministic. Nil values generate
+// "col IS NULL" conditions. If the map is empty, a condition that is always
+// true is returned.
+func WhereMap(values map[string]interface{}) WhereCondition {
+	if len(values) == 0 {
+		return SQLCond("1 = 1")
+	}
+
+	conds := make([]WhereCondition, 0, len(values))
+
+	for _, col := range sortKeys(values) {
+		if values[col] == nil {
+			conds = append(conds, IsNull(col))
+		} else {
+			conds = append(conds, Eq(col, values[col]))
+		}
+	}
+
+	return And(conds...)
+}
+
+// EqStructNonNil creates an equality condition for every non-nil pointer
+// field of the provided struct (or pointer to a struct), joined with AND.
+// Nil pointer fields and non-pointer fields are skipped. Column names and
+// embedded structs are handled as in ConditionFromStruct, but "op" tags are
+// ignored, and conditions are generated in the order in which the fields
+// are declared. If no field is set, a condition that is always true is
+// returned. If obj is not a struct, the statement the condition is used in
+// fails when executed.
+func EqStructNonNil(obj interface{}) WhereCondition {
+	conds, err := structConditions(reflect.ValueOf(obj), true)
+	if err != nil {
+		return invalidCondition{err}
+	}
+
+	if len(conds) == 0 {
+		return SQLCond("1 = 1")
+	}
+
+	return And(conds...)
+}
+
+// structOps maps the operators supported in "op" tags of structs provided
+// to ConditionFromStruct to the functions creating their conditions
+var structOps = map[string]func(col string, value interface{}) SimpleCondition{
+	"eq":   Eq,
+	"ne":   Ne,
+	"gt":   Gt,
+	"gte":  Gte,
+	"lt":   Lt,
+	"lte":  Lte,
+	"like": Like,
+}
+
+// ConditionFromStruct creates a condition for every set field of the
+// provided struct (or pointer to a struct), joined with AND, which is
+// useful for turning a struct of optional search filters (e.g. query
+// parameters of a REST endpoint) into a WHERE clause. Nil pointer fields
+// and non-pointer fields holding their zero value are skipped, while
+// non-nil pointers are dereferenced, so that a pointer to a zero value
+// (e.g. false) still filters. Column names are taken from the fields' "db"
+// tags, or otherwise from the snake-cased field names. The comparison
+// operator is taken from the fields' "op" tags, which may be one of eq (the
+// default), ne, gt, gte, lt, lte and like, e.g.:
+//
+//	type Filter struct {
+//		Name     *string `db:"name" op:"like"`
+//		MinPrice float64 `db:"price" op:"gte"`
+//	}
+//
+// Conditions are generated in the order in which the fields are declared,
+// including those of embedded structs. If no field is set, a condition that
+// is always true is returned.
+func ConditionFromStruct(v interface{}) (WhereCondition, error) {
+	conds, err := structConditions(reflect.ValueOf(v), false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(conds) == 0 {
+		return SQLCond("1 = 1"), nil
+	}
+
+	return And(conds...), nil
+}
+
+// structConditions returns the conditions for the set fields of a struct,
+// see ConditionFromStruct. If pointersOnly is true, only equality
+// conditions for non-nil pointer fields are returned, see EqStructNonNil.
+func structConditions(val reflect.Value, pointersOnly bool) (conds []WhereCondition, err error) {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil, errors.New("expected a struct, got nil")
+		}
+
+		val = val.Elem()
+	}
+
+	if val.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected a struct, got %s", val.Kind())
+	}
+
+	typ := val.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		col := strings.Split(field.Tag.Get("db"), ",")[0]
+		if col == "-" {
+			continue
+		}
+
+		fieldVal := val.Field(i)
+
+		if field.Anonymous && col == "" && reflectx.Deref(field.Type).Kind() == reflect.Struct {
+			if fieldVal.Kind() == reflect.Ptr && fieldVal.IsNil() {
+				continue
+			}
+
+			embedded, err := structConditions(fieldVal, pointersOnly)
+			if err != nil {
+				return nil, err
+			}
+
+			conds = append(conds, embedded...)
+
+			continue
+		}
+
+		if col == "" {
+			col = toSnakeCase(field.Name)
+		}
+
+		op := field.Tag.Get("op")
+		if op == "" || pointersOnly {
+			op = "eq"
+		}
+
+		newCond, ok := structOps[op]
+		if !ok {
+			return nil, fmt.Errorf("unsupported operator %q for field %s", op, field.Name)
+		}
+
+		switch {
+		case fieldVal.Kind() == reflect.Ptr && fieldVal.IsNil():
+			continue
+		case fieldVal.Kind() == reflect.Ptr:
+			conds = append(conds, newCond(col, fieldVal.Elem().Interface()))
+		case pointersOnly, fieldVal.IsZero():
+			continue
+		default:
+			conds = append(conds, newCond(col, fieldVal.Interface()))
+		}
+	}
+
+	return conds, nil
+}
+
+// TupleInCondition represents a composite IN condition, checking that the
+// values of multiple columns match one of several rows of values
+type TupleInCondition struct {
+	Left  []string
+	Right [][]interface{}
+}
+
+// InTuple creates a composite IN condition, e.g. "(a, b) IN ((?, ?),
+// (?, ?))". As MySQL and SQLite don't fully support row values in IN
+// conditions, on these databases the condition expands into equality
+// checks, e.g. "((a = ? AND b = ?) OR (a = ? AND b = ?))". If no rows are
+// provided, the condition is always false. Every row must hold a value for
+// each of the columns; otherwise, the statement fails.
+func InTuple(cols []string, rows [][]interface{}) TupleInCondition {
+	return TupleInCondition{cols, rows}
+}
+
+// NotDistinctCondition represents a NULL-safe equality condition, which is
+// true if both sides are equal or both are NULL
+type NotDistinctCondition struct {
+	Left  string
+	Right interface{}
+}
+
+// IsNotDistinctFrom creates a NULL-safe equality condition, e.g. "col IS
+// NOT DISTINCT FROM ?", which unlike Eq also matches when both the column
+// and the value are NULL. In MySQL, this renders with the "<=>" operator.
+// In SQLite and SQL Server, it is emulated as "(col = ? OR (col IS NULL
+// AND ? IS NULL))", binding the value twice.
+func IsNotDistinctFrom(col string, value interface{}) NotDistinctCondition {
+	return NotDistinctCondition{col, value}
+}
+
+// FullTextCondition represents a full-text search condition on one or
+// more columns
+type FullTextCondition struct {
+	Columns     []string
+	Query       interface{}
+	IsWebSearch bool
+	FTSColumn   string
+}
+
+// FullTextMatch creates a full-text search condition, matching the provided
+// columns against a query string, which is bound as a parameter. In
+// PostgreSQL, this renders as "to_tsvector(col) @@ plainto_tsquery(?)"
+// (multiple columns are joined with concat_ws). In MySQL, it renders as
+// "MATCH(col1, col2) AGAINST(? IN NATURAL LANGUAGE MODE)", which requires a
+// FULLTEXT index on those columns. In SQL Server, it renders as
+// "FREETEXT((col1, col2), ?)". SQLite only supports full-text search on FTS
+// virtual tables, so FTS must be used to name the column to match against.
+func FullTextMatch(cols []string, query interface{}) FullTextCondition {
+	return FullTextCondition{Columns: cols, Query: query}
+}
+
+// WebSearch makes the condition parse the query with PostgreSQL's
+// websearch_to_tsquery rather than plainto_tsquery, supporting quoted
+// phrases, "or" and "-" operators. It has no effect in other databases.
+func (ft FullTextCondition) WebSearch() FullTextCondition {
+	ft.IsWebSearch = true
+	return ft
+}
+
+// FTS sets the column of an SQLite FTS virtual table to match against (or
+// the name of the table itself, to match against all of its columns),
+// rendering the condition as "column MATCH ?" in SQLite. It has no effect in
+// other databases.
+func (ft FullTextCondition) FTS(column string) FullTextCondition {
+	ft.FTSColumn = column
+	return ft
+}
+
 // ArrayCondition represents an array comparison condition
 type ArrayCondition struct {
 	Left     interface{}
@@ -380,11 +1294,35 @@ func LikeAny(arr interface{}, value interface{}) ArrayCondition {
 // Parse implements the WhereCondition interface, generating SQL from
 // the condition
 func (simple SimpleCondition) Parse() (asSQL string, bindings []interface{}) {
+	return simple.sqlFor("")
+}
+
+// checkDialect checks expressions used as the value of the condition, such
+// as Concat
+func (simple SimpleCondition) checkDialect(driverName string) error {
+	if concat, isConcat := simple.Right.(ConcatExpr); isConcat {
+		return exprErr(concat, driverName)
+	}
+
+	return nil
+}
+
+func (simple SimpleCondition) sqlFor(driverName string) (asSQL string, bindings []interface{}) {
 	asSQL = simple.Left + " " + simple.Operator
 
 	if simple.Right != nil {
 		placeholder := "?"
-		if indirect, isIndirect := simple.Right.(IndirectValue); isIndirect {
+		if now, isNow := simple.Right.(NowExpr); isNow {
+			placeholder, _ = now.sqlFor(driverName)
+		} else if cast, isCast := simple.Right.(CastValue); isCast {
+			var castBindings []interface{}
+			placeholder, castBindings = cast.sqlFor(driverName)
+			bindings = append(bindings, castBindings...)
+		} else if concat, isConcat := simple.Right.(ConcatExpr); isConcat {
+			var concatBindings []interface{}
+			placeholder, concatBindings = concat.sqlFor(driverName)
+			bindings = append(bindings, concatBindings...)
+		} else if indirect, isIndirect := simple.Right.(IndirectValue); isIndirect {
 			placeholder = indirect.Reference
 			bindings = append(bindings, indirect.Bindings...)
 		} else {
@@ -437,9 +1375,27 @@ func (array ArrayCondition) Parse() (asSQL string, bindings []interface{}) {
 	), bindings
 }
 
+// Parse implements the WhereCondition interface, generating SQL from
+// the condition
+func (between BetweenCondition) Parse() (asSQL string, bindings []interface{}) {
+	placeholders, bindings := parseInsertValues([]interface{}{between.Low, between.High})
+
+	op := " BETWEEN "
+	if between.NotBetween {
+		op = " NOT BETWEEN "
+	}
+
+	return between.Left + op + placeholders[0] + " AND " + placeholders[1], bindings
+}
+
 // Parse implements the WhereCondition interface, generating SQL from
 // the condition
 func (in InCondition) Parse() (asSQL string, bindings []interface{}) {
+	values := in.values()
+	if len(values) == 0 {
+		return "1 = 0", nil
+	}
+
 	asSQL = in.Left
 	if in.NotIn {
 		asSQL += " NOT"
@@ -447,8 +1403,8 @@ func (in InCondition) Parse() (asSQL string, bindings []interface{}) {
 
 	asSQL += " IN ("
 
-	placeholders := make([]string, len(in.Right))
-	for i, val := range in.Right {
+	placeholders := make([]string, len(values))
+	for i, val := range values {
 		placeholders[i] = "?"
 
 		bindings = append(bindings, val)
@@ -459,13 +1415,215 @@ func (in InCondition) Parse() (asSQL string, bindings []interface{}) {
 	return asSQL, bindings
 }
 
+// Parse implements the WhereCondition interface, generating SQL from
+// the condition
+func (cond LikePatternsCondition) Parse() (asSQL string, bindings []interface{}) {
+	return cond.sqlFor("")
+}
+
+func (cond LikePatternsCondition) sqlFor(driverName string) (asSQL string, bindings []interface{}) {
+	if len(cond.Patterns) == 0 {
+		return "1 = 0", nil
+	}
+
+	if cond.AsArray && isPostgres(driverName) {
+		quantifier := "ANY"
+		if cond.All {
+			quantifier = "ALL"
+		}
+
+		placeholders := make([]string, len(cond.Patterns))
+		for i := range placeholders {
+			placeholders[i] = "?"
+		}
+
+		return cond.Column + " LIKE " + quantifier + " (ARRAY[" + strings.Join(placeholders, ", ") + "])",
+			append([]interface{}{}, cond.Patterns...)
+	}
+
+	if len(cond.Patterns) == 1 {
+		return Like(cond.Column, cond.Patterns[0]).sqlFor(driverName)
+	}
+
+	likes := make([]WhereCondition, len(cond.Patterns))
+	for i, pattern := range cond.Patterns {
+		likes[i] = Like(cond.Column, pattern)
+	}
+
+	return AndOrCondition{!cond.All, likes}.sqlFor(driverName)
+}
+
+// values returns the values of the condition, expanding a single slice
+// value into its elements. Byte slices and values implementing
+// driver.Valuer (e.g. pq.Int64Array) are bound as they are.
+func (in InCondition) values() []interface{} {
+	if len(in.Right) != 1 {
+		return in.Right
+	}
+
+	switch in.Right[0].(type) {
+	case []byte, driver.Valuer:
+		return in.Right
+	}
+
+	val := reflect.ValueOf(in.Right[0])
+	if val.Kind() != reflect.Slice {
+		return in.Right
+	}
+
+	values := make([]interface{}, val.Len())
+	for i := range values {
+		values[i] = val.Index(i).Interface()
+	}
+
+	return values
+}
+
+// Parse implements the WhereCondition interface, generating SQL from
+// the condition using PostgreSQL syntax
+func (cond NotDistinctCondition) Parse() (asSQL string, bindings []interface{}) {
+	return cond.sqlFor("")
+}
+
+func (cond NotDistinctCondition) sqlFor(driverName string) (asSQL string, bindings []interface{}) {
+	placeholders, bindings := parseInsertValues([]interface{}{cond.Right})
+	right := placeholders[0]
+
+	switch {
+	case isMySQL(driverName):
+		return cond.Left + " <=> " + right, bindings
+	case isSQLite(driverName) || isSQLServer(driverName):
+		return "(" + cond.Left + " = " + right + " OR (" + cond.Left + " IS NULL AND " + right + " IS NULL))",
+			append(bindings, bindings...)
+	default:
+		return cond.Left + " IS NOT DISTINCT FROM " + right, bindings
+	}
+}
+
+// Parse implements the WhereCondition interface, generating SQL from
+// the condition
+func (ft FullTextCondition) Parse() (asSQL string, bindings []interface{}) {
+	return ft.sqlFor("")
+}
+
+func (ft FullTextCondition) sqlFor(driverName string) (asSQL string, bindings []interface{}) {
+	bindings = []interface{}{ft.Query}
+	cols := strings.Join(ft.Columns, ", ")
+
+	switch {
+	case isMySQL(driverName):
+		return "MATCH(" + cols + ") AGAINST(? IN NATURAL LANGUAGE MODE)", bindings
+	case isSQLite(driverName):
+		return ft.FTSColumn + " MATCH ?", bindings
+	case isSQLServer(driverName):
+		if len(ft.Columns) > 1 {
+			cols = "(" + cols + ")"
+		}
+
+		return "FREETEXT(" + cols + ", ?)", bindings
+	}
+
+	doc := cols
+	if len(ft.Columns) > 1 {
+		doc = "concat_ws(' ', " + cols + ")"
+	}
+
+	parser := "plainto_tsquery"
+	if ft.IsWebSearch {
+		parser = "websearch_to_tsquery"
+	}
+
+	return "to_tsvector(" + doc + ") @@ " + parser + "(?)", bindings
+}
+
+func (ft FullTextCondition) checkDialect(driverName string) error {
+	if isSQLite(driverName) {
+		if ft.FTSColumn == "" {
+			return errors.New("full-text search in SQLite requires an FTS virtual table column")
+		}
+
+		return nil
+	}
+
+	if len(ft.Columns) == 0 {
+		return errors.New("full-text search requires at least one column")
+	}
+
+	return nil
+}
+
+// Parse implements the WhereCondition interface, generating SQL from
+// the condition
+func (tuple TupleInCondition) Parse() (asSQL string, bindings []interface{}) {
+	return tuple.sqlFor("")
+}
+
+// Err returns an error if a row of the condition doesn't have a value for
+// each of its columns
+func (tuple TupleInCondition) Err() error {
+	for i, row := range tuple.Right {
+		if len(row) != len(tuple.Left) {
+			return fmt.Errorf("row %d has %d values, expected %d", i, len(row), len(tuple.Left))
+		}
+	}
+
+	return nil
+}
+
+func (tuple TupleInCondition) sqlFor(driverName string) (asSQL string, bindings []interface{}) {
+	if len(tuple.Right) == 0 || tuple.Err() != nil {
+		return "1 = 0", nil
+	}
+
+	rows := make([]string, len(tuple.Right))
+
+	if isMySQL(driverName) || isSQLite(driverName) {
+		for i, row := range tuple.Right {
+			placeholders, rowBindings := parseInsertValues(row)
+			bindings = append(bindings, rowBindings...)
+
+			checks := make([]string, len(tuple.Left))
+			for j, col := range tuple.Left {
+				checks[j] = col + " = " + placeholders[j]
+			}
+
+			rows[i] = "(" + strings.Join(checks, " AND ") + ")"
+		}
+
+		return "(" + strings.Join(rows, " OR ") + ")", bindings
+	}
+
+	for i, row := range tuple.Right {
+		placeholders, rowBindings := parseInsertValues(row)
+		bindings = append(bindings, rowBindings...)
+		rows[i] = "(" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	return "(" + strings.Join(tuple.Left, ", ") + ") IN (" + strings.Join(rows, ", ") + ")", bindings
+}
+
+// Parse implements the WhereCondition interface, generating SQL from
+// the condition
+func (row RowCondition) Parse() (asSQL string, bindings []interface{}) {
+	placeholders, bindings := parseInsertValues(row.Right)
+
+	return fmt.Sprintf(
+		"(%s) %s ROW(%s)",
+		strings.Join(row.Left, ", "), row.Operator, strings.Join(placeholders, ", "),
+	), bindings
+}
+
 // Parse implements the WhereCondition interface, generating SQL from
 // the condition
 func (andOr AndOrCondition) Parse() (asSQL string, bindings []interface{}) {
+	return andOr.sqlFor("")
+}
+
+func (andOr AndOrCondition) sqlFor(driverName string) (asSQL string, bindings []interface{}) {
 	sqls := make([]string, len(andOr.Conditions))
 
 	for i, cond := range andOr.Conditions {
-		innerSQL, innerBindings := cond.Parse()
+		innerSQL, innerBindings := condSQL(cond, driverName)
 		sqls[i] = innerSQL
 
 		bindings = append(bindings, innerBindings...)
@@ -482,27 +1640,60 @@ func (andOr AndOrCondition) Parse() (asSQL string, bindings []interface{}) {
 // Parse implements the WhereCondition interface, generating SQL from
 // the condition
 func (pre PreCondition) Parse() (asSQL string, bindings []interface{}) {
-	innerSQL, innerBindings := pre.Condition.Parse()
+	return pre.sqlFor("")
+}
+
+func (pre PreCondition) sqlFor(driverName string) (asSQL string, bindings []interface{}) {
+	innerSQL, innerBindings := condSQL(pre.Condition, driverName)
 	bindings = append(bindings, innerBindings...)
 
 	return fmt.Sprintf("%s(%s)", pre.Pre, innerSQL), bindings
 }
 
+// Parse implements the WhereCondition interface, generating SQL from
+// the condition
+func (neg NegatedCondition) Parse() (asSQL string, bindings []interface{}) {
+	return neg.sqlFor("")
+}
+
+func (neg NegatedCondition) sqlFor(driverName string) (asSQL string, bindings []interface{}) {
+	innerSQL, bindings := condSQL(neg.Condition, driverName)
+
+	// AND/OR groups are already parenthesized
+	if _, isGroup := neg.Condition.(AndOrCondition); isGroup {
+		return "NOT " + innerSQL, bindings
+	}
+
+	return "NOT (" + innerSQL + ")", bindings
+}
+
 // Parse implements the WhereCondition interface, generating SQL from
 // the condition
 func (subCond SubqueryCondition) Parse() (asSQL string, bindings []interface{}) {
 	asSQL, bindings = subCond.Stmt.ToSQL(false)
-	return subCond.Operator + " (" + asSQL + ")", bindings
+	asSQL = subCond.Operator + " (" + asSQL + ")"
+
+	if subCond.Left != "" {
+		asSQL = subCond.Left + " " + asSQL
+	}
+
+	return asSQL, bindings
 }
 
-func parseConditions(conds []WhereCondition) (asSQL string, bindings []interface{}) {
+func parseConditions(conds []WhereCondition, driverName string) (asSQL string, bindings []interface{}) {
+	var isGroup bool
+
 	if len(conds) > 1 {
-		asSQL, bindings = (AndOrCondition{false, conds}).Parse()
+		asSQL, bindings = (AndOrCondition{false, conds}).sqlFor(driverName)
+		isGroup = true
 	} else if len(conds) == 1 {
-		asSQL, bindings = conds[0].Parse()
+		asSQL, bindings = condSQL(conds[0], driverName)
+		_, isGroup = conds[0].(AndOrCondition)
 	}
 
-	if strings.HasPrefix(asSQL, "(") {
+	// only strip the parentheses surrounding an AND/OR group, other
+	// conditions (e.g. row comparisons) may legitimately start with one
+	if isGroup {
 		asSQL = strings.TrimPrefix(strings.TrimSuffix(asSQL, ")"), "(")
 	}
 
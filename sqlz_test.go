@@ -1,7 +1,16 @@
 package sqlz
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"gopkg.in/DATA-DOG/go-sqlmock.v1"
 )
@@ -14,12 +23,18 @@ type test struct {
 }
 
 func runTests(t *testing.T, source func(dbz *DB) []test) {
+	runDriverTests(t, "sqlmock", source)
+}
+
+// runDriverTests is the same as runTests, but the mock database pretends to
+// use the provided driver, so that dialect-specific SQL is generated
+func runDriverTests(t *testing.T, driverName string, source func(dbz *DB) []test) {
 	db, _, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("Failed creating mock database: %s", err)
 	}
 
-	for _, tst := range source(New(db, "sqlmock")) {
+	for _, tst := range source(New(db, driverName)) {
 		t.Run(tst.name, func(t *testing.T) {
 			resultingSQL, resultingBindings := tst.stmt.ToSQL(true)
 			if resultingSQL != tst.expectedSQL {
@@ -38,3 +53,842 @@ func runTests(t *testing.T, source func(dbz *DB) []test) {
 		})
 	}
 }
+
+func TestQueryLogger(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	type logEntry struct {
+		query string
+		args  []interface{}
+		err   error
+	}
+
+	var logged []logEntry
+
+	dbz := New(db, "postgres")
+	dbz.SetQueryLogger(func(query string, args []interface{}, _ time.Duration, err error) {
+		logged = append(logged, logEntry{query, args, err})
+	})
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM table WHERE name = $1")).
+		WithArgs("a").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	failure := errors.New("relation does not exist")
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM table WHERE id = $1")).
+		WithArgs(1).
+		WillReturnError(failure)
+
+	var id int64
+	if err := dbz.Select("id").From("table").Where(Eq("name", "a")).GetRow(&id); err != nil {
+		t.Fatalf("Failed executing select: %s", err)
+	}
+
+	if _, err := dbz.DeleteFrom("table").Where(Eq("id", 1)).Exec(); !errors.Is(err, failure) {
+		t.Fatalf("Expected delete to fail with %v, got %v", failure, err)
+	}
+
+	if len(logged) != 2 {
+		t.Fatalf("Expected 2 logged queries, got %d", len(logged))
+	}
+
+	if logged[0].query != "SELECT id FROM table WHERE name = $1" || len(logged[0].args) != 1 || logged[0].err != nil {
+		t.Errorf("Unexpected first log entry: %+v", logged[0])
+	}
+
+	if logged[1].query != "DELETE FROM table WHERE id = $1" || !errors.Is(logged[1].err, failure) {
+		t.Errorf("Unexpected second log entry: %+v", logged[1])
+	}
+}
+
+func TestSlowQueryThreshold(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	var slow []string
+
+	dbz := New(db, "postgres")
+	dbz.SetSlowQueryThreshold(50*time.Millisecond, func(query string, _ []interface{}, d time.Duration) {
+		if d < 50*time.Millisecond {
+			t.Errorf("Slow query handler called with duration %s", d)
+		}
+
+		slow = append(slow, query)
+	})
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE table SET a = $1")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM table")).
+		WillDelayFor(100 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	if _, err := dbz.Update("table").Set("a", 1).Exec(); err != nil {
+		t.Fatalf("Failed executing update: %s", err)
+	}
+
+	var ids []int64
+	if err := dbz.Select("id").From("table").GetAll(&ids); err != nil {
+		t.Fatalf("Failed executing select: %s", err)
+	}
+
+	if len(slow) != 1 || slow[0] != "SELECT id FROM table" {
+		t.Errorf("Expected only the select to be reported as slow, got %v", slow)
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM table")).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE table SET name = $1")).
+		WithArgs("a").
+		WillDelayFor(time.Second).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	var ids []int64
+	if err := dbz.Select("*").From("table").GetAllContext(ctx, &ids); err == nil {
+		t.Error("Expected select to be cancelled")
+	}
+
+	if _, err := dbz.Update("table").Set("name", "a").ExecContext(ctx); err == nil {
+		t.Error("Expected update to be cancelled")
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+	query := regexp.QuoteMeta("SELECT name FROM users WHERE id = $1")
+
+	mock.ExpectQuery(query).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+	mock.ExpectQuery(query).
+		WithArgs(2).
+		WillReturnError(errors.New("connection refused"))
+	mock.ExpectQuery(query).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("not a number"))
+
+	var name string
+
+	err = dbz.Select("name").From("users").Where(Eq("id", 1)).GetRow(&name)
+	if !errors.Is(err, sql.ErrNoRows) || !IsNotFound(err) {
+		t.Errorf("Expected sql.ErrNoRows for a missing row, got %v", err)
+	}
+
+	err = dbz.Select("name").From("users").Where(Eq("id", 2)).GetRow(&name)
+	if err == nil || IsNotFound(err) {
+		t.Errorf("Expected a query failure not to be reported as not found, got %v", err)
+	}
+
+	var number int64
+
+	err = dbz.Select("name").From("users").Where(Eq("id", 3)).GetRow(&number)
+	if err == nil || IsNotFound(err) {
+		t.Errorf("Expected a scan failure not to be reported as not found, got %v", err)
+	}
+
+	if !IsNotFound(fmt.Errorf("loading user: %w", sql.ErrNoRows)) {
+		t.Error("Expected a wrapped sql.ErrNoRows to be reported as not found")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM table")).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE table SET name = $1")).
+		WithArgs("a").
+		WillDelayFor(time.Second).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM table")).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta("DELETE FROM table WHERE id = $1 RETURNING id")).
+		WithArgs(1).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	start := time.Now()
+
+	var ids []int64
+	if err := dbz.Select("*").From("table").WithTimeout(10 * time.Millisecond).GetAll(&ids); err == nil {
+		t.Error("Expected select to time out")
+	}
+
+	if _, err := dbz.Update("table").Set("name", "a").WithTimeout(10 * time.Millisecond).Exec(); err == nil {
+		t.Error("Expected update to time out")
+	}
+
+	// a context with a sooner deadline than the timeout takes precedence
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := dbz.Select("*").From("table").WithTimeout(time.Hour).GetAllContext(ctx, &ids); err == nil {
+		t.Error("Expected select to be cancelled by the context's deadline")
+	}
+
+	var deleted int64
+	if err := dbz.DeleteFrom("table").Where(Eq("id", 1)).Returning("id").
+		WithTimeout(10 * time.Millisecond).GetRow(&deleted); err == nil {
+		t.Error("Expected delete to time out")
+	}
+
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("Expected statements to be cancelled early, took %s", elapsed)
+	}
+
+	// rows outlive the call, their context is cancelled once they're closed
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM table")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	rows, err := dbz.Select("*").From("table").WithTimeout(time.Hour).GetAllAsTimedRows()
+	if err != nil {
+		t.Fatalf("Failed selecting rows: %s", err)
+	}
+
+	var count int
+	for rows.Next() {
+		count++
+	}
+
+	if err := rows.Close(); err != nil || count != 2 {
+		t.Errorf("Expected 2 rows to be read and closed, got %d (%v)", count, err)
+	}
+}
+
+type pgStateError struct{ state string }
+
+func (err pgStateError) Error() string    { return "pq: could not serialize access" }
+func (err pgStateError) SQLState() string { return err.state }
+
+type mysqlNumberError struct{ Number uint16 }
+
+func (err *mysqlNumberError) Error() string { return "Error 1213: Deadlock found" }
+
+func TestTransactionWithRetry(t *testing.T) {
+	defer func(backoff time.Duration) { retryBackoff = backoff }(retryBackoff)
+	retryBackoff = time.Millisecond
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+	query := regexp.QuoteMeta("UPDATE accounts SET balance = $1 WHERE id = $2")
+
+	// a serialization failure followed by success
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs(100, 1).WillReturnError(pgStateError{"40001"})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs(100, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	var attempts int
+
+	update := func(tx *Tx) error {
+		attempts++
+		_, err := tx.Update("accounts").Set("balance", 100).Where(Eq("id", 1)).Exec()
+		return err
+	}
+
+	if err := dbz.TransactionWithRetry(3, update); err != nil {
+		t.Errorf("Expected transaction to succeed after retrying, got %s", err)
+	}
+
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attempts)
+	}
+
+	// a MySQL deadlock on every attempt
+	attempts = 0
+
+	for i := 0; i < 3; i++ {
+		mock.ExpectBegin()
+		mock.ExpectExec(query).WithArgs(100, 1).WillReturnError(&mysqlNumberError{Number: 1213})
+		mock.ExpectRollback()
+	}
+
+	err = dbz.TransactionWithRetry(2, update)
+	if deadlock, ok := err.(*mysqlNumberError); !ok || deadlock.Number != 1213 {
+		t.Errorf("Expected the last deadlock error after exhausting retries, got %v", err)
+	}
+
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+
+	// other errors are not retried
+	attempts = 0
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs(100, 1).WillReturnError(errors.New("syntax error"))
+	mock.ExpectRollback()
+
+	if err := dbz.TransactionWithRetry(3, update); err == nil || attempts != 1 {
+		t.Errorf("Expected a non-retryable error after 1 attempt, got %v after %d", err, attempts)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+// txOptsConn wraps a mock connection and records the options of every
+// transaction started on it, as sqlmock itself ignores them
+type txOptsConn struct {
+	driver.Conn
+	opts []driver.TxOptions
+}
+
+func (c *txOptsConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.opts = append(c.opts, opts)
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *txOptsConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *txOptsConn) Connect(context.Context) (driver.Conn, error) { return c, nil }
+func (c *txOptsConn) Driver() driver.Driver                        { return nil }
+
+func TestTransactionIsolation(t *testing.T) {
+	mockDB, mock, err := sqlmock.NewWithDSN("sqlz_tx_isolation")
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	mockConn, err := mockDB.Driver().Open("sqlz_tx_isolation")
+	if err != nil {
+		t.Fatalf("Failed opening mock connection: %s", err)
+	}
+
+	conn := &txOptsConn{Conn: mockConn}
+	dbz := New(sql.OpenDB(conn), "postgres")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE accounts SET balance = $1 WHERE id = $2")).
+		WithArgs(100, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = dbz.Transaction(context.Background(), sql.LevelSerializable, func(tx *Tx) error {
+		_, err := tx.Update("accounts").Set("balance", 100).Where(Eq("id", 1)).Exec()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed running transaction: %s", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	tx, err := dbz.BeginTx(context.Background(), &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		t.Fatalf("Failed starting transaction: %s", err)
+	}
+
+	sqlStr, _ := tx.Select("*").From("accounts").Where(Eq("id", 1)).ToSQL(true)
+	if sqlStr != "SELECT * FROM accounts WHERE id = $1" {
+		t.Errorf("Expected transaction to keep the postgres dialect, got %s", sqlStr)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Errorf("Failed rolling back transaction: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	expected := []driver.TxOptions{
+		{Isolation: driver.IsolationLevel(sql.LevelSerializable)},
+		{Isolation: driver.IsolationLevel(sql.LevelReadCommitted), ReadOnly: true},
+	}
+	if len(conn.opts) != len(expected) {
+		t.Fatalf("Expected %d transactions, got %d", len(expected), len(conn.opts))
+	}
+
+	for i := range expected {
+		if conn.opts[i] != expected[i] {
+			t.Errorf("Expected transaction %d options %+v, got %+v", i+1, expected[i], conn.opts[i])
+		}
+	}
+}
+
+func TestExecAffected(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE accounts SET active = $1 WHERE balance < $2")).
+		WithArgs(false, 0).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM accounts WHERE active = $1")).
+		WithArgs(false).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO accounts (id, balance) VALUES ($1, $2), ($3, $4)")).
+		WithArgs(1, 10, 2, 20).
+		WillReturnResult(sqlmock.NewResult(2, 2))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM accounts WHERE id = $1")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewErrorResult(errors.New("not supported")))
+
+	affected, err := dbz.Update("accounts").Set("active", false).Where(Lt("balance", 0)).ExecAffected()
+	if err != nil || affected != 4 {
+		t.Errorf("Expected update to affect 4 rows, got %d (%v)", affected, err)
+	}
+
+	affected, err = dbz.DeleteFrom("accounts").Where(Eq("active", false)).ExecAffected()
+	if err != nil || affected != 4 {
+		t.Errorf("Expected delete to affect 4 rows, got %d (%v)", affected, err)
+	}
+
+	affected, err = dbz.InsertInto("accounts").
+		Columns("id", "balance").
+		ValueMultiple([][]interface{}{{1, 10}, {2, 20}}).
+		ExecAffected()
+	if err != nil || affected != 2 {
+		t.Errorf("Expected insert to affect 2 rows, got %d (%v)", affected, err)
+	}
+
+	_, err = dbz.DeleteFrom("accounts").Where(Eq("id", 1)).ExecAffected()
+	if !errors.Is(err, ErrRowsAffectedUnavailable) {
+		t.Errorf("Expected ErrRowsAffectedUnavailable, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBoolAsInt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	var logged []interface{}
+
+	dbz := New(db, "sqlite3")
+	dbz.SetBoolAsInt(true)
+	dbz.SetQueryLogger(func(_ string, args []interface{}, _ time.Duration, _ error) {
+		logged = args
+	})
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO users (name, active, admin) VALUES (?, ?, ?)")).
+		WithArgs("john", 1, 0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT name FROM users WHERE active = ?")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("john"))
+
+	if _, err := dbz.InsertInto("users").Columns("name", "active", "admin").Values("john", true, false).Exec(); err != nil {
+		t.Errorf("Failed inserting: %s", err)
+	}
+
+	if logged[1] != 1 || logged[2] != 0 {
+		t.Errorf("Expected booleans to be converted to 1 and 0, got %v", logged)
+	}
+
+	var names []string
+	if err := dbz.Select("name").From("users").Where(Eq("active", true)).GetAll(&names); err != nil {
+		t.Errorf("Failed selecting: %s", err)
+	}
+
+	// booleans are passed as-is by default
+	pgdb, pgmock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	pgmock.ExpectExec(regexp.QuoteMeta("UPDATE users SET active = $1 WHERE id = $2")).
+		WithArgs(true, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if _, err := New(pgdb, "postgres").Update("users").Set("active", true).Where(Eq("id", 1)).Exec(); err != nil {
+		t.Errorf("Failed updating: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	if err := pgmock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestArgTransformer(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	var logged [][]interface{}
+
+	redact := func(col string, v interface{}) interface{} {
+		if col == "ssn" {
+			return "[REDACTED]"
+		}
+		return v
+	}
+
+	dbz := New(db, "postgres")
+	dbz.SetArgTransformer(redact)
+	dbz.SetQueryLogger(func(_ string, args []interface{}, _ time.Duration, _ error) {
+		logged = append(logged, args)
+	})
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO users (name, ssn) VALUES ($1, $2)")).
+		WithArgs("john", "123-45-6789").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT name FROM users u WHERE u.ssn = $1 LIMIT 1")).
+		WithArgs("123-45-6789").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("john"))
+
+	if _, err := dbz.InsertInto("users").Columns("name", "ssn").Values("john", "123-45-6789").Exec(); err != nil {
+		t.Errorf("Failed inserting: %s", err)
+	}
+
+	var name string
+	if err := dbz.Select("name").From("users u").Where(Eq("u.ssn", "123-45-6789")).Limit(1).GetRow(&name); err != nil {
+		t.Errorf("Failed selecting: %s", err)
+	}
+
+	if len(logged) != 2 {
+		t.Fatalf("Expected 2 logged queries, got %d", len(logged))
+	}
+
+	if logged[0][0] != "john" || logged[0][1] != "[REDACTED]" {
+		t.Errorf("Expected ssn to be redacted in logged insert, got %v", logged[0])
+	}
+
+	if logged[1][0] != "[REDACTED]" {
+		t.Errorf("Expected ssn to be redacted in logged select, got %v", logged[1])
+	}
+
+	// transformed values are sent to the database when opted in
+	dbz.SetArgTransformer(func(col string, v interface{}) interface{} {
+		if s, ok := v.(string); ok && col == "email" {
+			return strings.ToLower(s)
+		}
+		return v
+	})
+	dbz.SetTransformArgsBeforeExec(true)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET email = $1 WHERE id = $2")).
+		WithArgs("john@example.com", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if _, err := dbz.Update("users").Set("email", "John@Example.com").Where(Eq("id", 1)).Exec(); err != nil {
+		t.Errorf("Failed updating: %s", err)
+	}
+
+	if last := logged[len(logged)-1]; last[0] != "john@example.com" {
+		t.Errorf("Expected normalized value to be logged, got %v", last)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestArgHints(t *testing.T) {
+	tests := []struct {
+		query string
+		count int
+		hints []string
+	}{
+		{"SELECT * FROM t WHERE a = ? AND b.c NOT LIKE ? LIMIT ?", 3, []string{"a", "c", ""}},
+		{"SELECT * FROM t WHERE a IN ($1, $2) OR name = 'x = $9' AND d BETWEEN $3 AND $4", 4, []string{"a", "a", "d", "d"}},
+		{"INSERT INTO t (a, b) VALUES (@p1, @p2), (@p3, @p4) RETURNING id", 4, []string{"a", "b", "a", "b"}},
+		{"UPDATE t SET a = ?, b = COALESCE(b, ?) WHERE id = ?", 3, []string{"a", "b", "id"}},
+	}
+
+	for _, test := range tests {
+		if hints := argHints(test.query, test.count); !reflect.DeepEqual(hints, test.hints) {
+			t.Errorf("Expected hints %v for %q, got %v", test.hints, test.query, hints)
+		}
+	}
+}
+
+// pqError mimics the shape of errors returned by github.com/lib/pq
+type pqError struct{ Code pqErrorCode }
+
+type pqErrorCode string
+
+func (err *pqError) Error() string { return "pq: error " + string(err.Code) }
+
+// mysqlError mimics the shape of errors returned by
+// github.com/go-sql-driver/mysql
+type mysqlError struct{ Number uint16 }
+
+func (err *mysqlError) Error() string { return fmt.Sprintf("Error %d", err.Number) }
+
+func TestMapError(t *testing.T) {
+	for _, c := range []struct {
+		err      error
+		expected ErrorKind
+	}{
+		{&pqError{"23505"}, UniqueViolation},
+		{&pqError{"23503"}, ForeignKeyViolation},
+		{&pqError{"23502"}, NotNullViolation},
+		{&pqError{"23514"}, CheckViolation},
+		{&pqError{"40P01"}, Deadlock},
+		{pgStateError{"40001"}, SerializationFailure},
+		{fmt.Errorf("failed inserting: %w", pgStateError{"23505"}), UniqueViolation},
+		{&mysqlError{1062}, UniqueViolation},
+		{&mysqlError{1452}, ForeignKeyViolation},
+		{&mysqlError{1451}, ForeignKeyViolation},
+		{&mysqlError{1048}, NotNullViolation},
+		{&mysqlError{3819}, CheckViolation},
+		{&mysqlError{1213}, Deadlock},
+	} {
+		mapped := MapError(c.err)
+		if mapped == nil {
+			t.Errorf("Expected %v to be mapped to %s, got nil", c.err, c.expected)
+			continue
+		}
+
+		if mapped.Kind != c.expected {
+			t.Errorf("Expected %v to be mapped to %s, got %s", c.err, c.expected, mapped.Kind)
+		}
+
+		if !errors.Is(mapped, c.err) {
+			t.Errorf("Expected mapped error to wrap %v", c.err)
+		}
+	}
+
+	for _, err := range []error{nil, sql.ErrNoRows, &pqError{"42P01"}, &mysqlError{1146}} {
+		if mapped := MapError(err); mapped != nil {
+			t.Errorf("Expected %v not to be mapped, got %s", err, mapped.Kind)
+		}
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+	query := regexp.QuoteMeta("INSERT INTO users (email) VALUES ($1)")
+
+	mock.ExpectExec(query).WithArgs("john@example.com").WillReturnError(&pqError{"23505"})
+	mock.ExpectExec(query).WithArgs("john@example.com").WillReturnError(&pqError{"23505"})
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO users (email) VALUES ($1) RETURNING id")).
+		WithArgs("john@example.com").
+		WillReturnError(&pqError{"23505"})
+
+	_, err = dbz.InsertInto("users").Columns("email").Values("john@example.com").Exec()
+	if _, ok := err.(*pqError); !ok {
+		t.Errorf("Expected the original error without error mapping, got %T", err)
+	}
+
+	dbz.SetErrorMapping(true)
+
+	_, err = dbz.InsertInto("users").Columns("email").Values("john@example.com").Exec()
+
+	var dbErr *DBError
+	if !errors.As(err, &dbErr) || dbErr.Kind != UniqueViolation {
+		t.Errorf("Expected a mapped unique violation, got %v", err)
+	}
+
+	var id int64
+
+	err = dbz.InsertInto("users").Columns("email").Values("john@example.com").Returning("id").GetRow(&id)
+	if !errors.As(err, &dbErr) || dbErr.Kind != UniqueViolation {
+		t.Errorf("Expected a mapped unique violation from GetRow, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestQueryComment(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"select with a comment",
+				dbz.Select("id").From("users").Where(Eq("id", 1)).Comment("route=/users"),
+				"/* route=/users */ SELECT id FROM users WHERE id = $1",
+				[]interface{}{1},
+			},
+
+			{
+				"comment breakout is sanitized",
+				dbz.DeleteFrom("users").Where(Eq("id", 1)).Comment("x */ DROP TABLE users; /*"),
+				"/* x * / DROP TABLE users; / * */ DELETE FROM users WHERE id = $1",
+				[]interface{}{1},
+			},
+
+			{
+				"sub-queries are not commented",
+				dbz.With(dbz.Select("id").From("users").Comment("inner"), "u").
+					Then(dbz.Select("COUNT(*)").From("u")).
+					Comment("outer"),
+				"/* outer */ WITH u AS (SELECT id FROM users) SELECT COUNT(*) FROM u",
+				[]interface{}{},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		dbz.SetQueryComment("service=billing")
+
+		return []test{
+			{
+				"default comment",
+				dbz.Update("invoices").Set("paid", true).Where(Eq("id", 1)),
+				"/* service=billing */ UPDATE invoices SET paid = ? WHERE id = ?",
+				[]interface{}{true, 1},
+			},
+
+			{
+				"default comment followed by the statement's comment",
+				dbz.InsertInto("invoices").Columns("id").Values(1).Comment("job=import"),
+				"/* service=billing job=import */ INSERT INTO invoices (id) VALUES (?)",
+				[]interface{}{1},
+			},
+		}
+	})
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+	dbz.SetQueryComment("service=foo")
+
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN /* service=foo route=/users */ SELECT 1 FROM users WHERE active = $1")).
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow("Seq Scan on users  (cost=0.00..35.50 rows=1234 width=4)"))
+
+	count, err := dbz.Select("*").From("users").Where(Eq("active", true)).Comment("route=/users").GetEstimatedCount()
+	if err != nil {
+		t.Fatalf("Failed getting estimated count: %s", err)
+	}
+
+	if count != 1234 {
+		t.Errorf("Expected an estimate of 1234 rows, got %d", count)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestExecWithSQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "postgres")
+	dbz.SetBoolAsInt(true)
+	dbz.SetQueryComment("audit")
+
+	tests := []struct {
+		name         string
+		exec         func() (sql.Result, string, []interface{}, error)
+		expectedSQL  string
+		expectedArgs []interface{}
+	}{
+		{
+			"insert",
+			dbz.InsertInto("users").Columns("name", "active").Values("john", true).ExecWithSQL,
+			"/* audit */ INSERT INTO users (name, active) VALUES ($1, $2)",
+			[]interface{}{"john", 1},
+		},
+		{
+			"update",
+			dbz.Update("users").Set("active", false).Where(Eq("id", 3)).ExecWithSQL,
+			"/* audit */ UPDATE users SET active = $1 WHERE id = $2",
+			[]interface{}{0, 3},
+		},
+		{
+			"delete",
+			dbz.DeleteFrom("users").Where(Eq("id", 3)).ExecWithSQL,
+			"/* audit */ DELETE FROM users WHERE id = $1",
+			[]interface{}{3},
+		},
+	}
+
+	for _, tt := range tests {
+		args := make([]driver.Value, len(tt.expectedArgs))
+		for i, arg := range tt.expectedArgs {
+			args[i] = arg
+		}
+
+		mock.ExpectExec("^" + regexp.QuoteMeta(tt.expectedSQL) + "$").
+			WithArgs(args...).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, asSQL, sentArgs, err := tt.exec()
+		if err != nil {
+			t.Errorf("%s: failed executing: %s", tt.name, err)
+			continue
+		}
+
+		if asSQL != tt.expectedSQL {
+			t.Errorf("%s: expected SQL %q, got %q", tt.name, tt.expectedSQL, asSQL)
+		}
+
+		if fmt.Sprint(sentArgs) != fmt.Sprint(tt.expectedArgs) {
+			t.Errorf("%s: expected arguments %v, got %v", tt.name, tt.expectedArgs, sentArgs)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	_, asSQL, _, err := dbz.InsertInto("users").Columns("name").AddRow("john", true).ExecWithSQL()
+	if err == nil || asSQL != "" {
+		t.Errorf("Expected invalid statement to fail without SQL, got %q (%v)", asSQL, err)
+	}
+}
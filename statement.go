@@ -1,9 +1,151 @@
 package sqlz
 
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
 // Statement is a base struct for all statement types in the library.
 type Statement struct {
 	// ErrHandlers is a list of error handler functions
 	ErrHandlers []func(err error)
+	// err is the first error encountered while building the statement
+	err error
+	// timeout is the maximum duration of the statement's execution
+	timeout time.Duration
+	// comment is a tag added to the statement's SQL as a comment
+	comment string
+}
+
+// Err returns the first error encountered while building the statement, if
+// any. A statement that failed to build is never executed, its execution
+// methods return this error instead.
+func (stmt *Statement) Err() error {
+	if stmt == nil {
+		return nil
+	}
+
+	return stmt.err
+}
+
+// setErr records an error encountered while building the statement, unless
+// one was already recorded
+func (stmt *Statement) setErr(err error) {
+	if stmt.err == nil {
+		stmt.err = err
+	}
+}
+
+// commented prepends the query comments of the DB the statement was created
+// from (see DB.SetQueryComment) and of the statement itself to its SQL, e.g.
+// "/* service=foo route=/users */ SELECT ..."
+func (stmt *Statement) commented(db interface{}, asSQL string) string {
+	if stmt == nil {
+		return withComments(db, "", asSQL)
+	}
+
+	return withComments(db, stmt.comment, asSQL)
+}
+
+// withComments prepends the query comment of the DB (if any) and the
+// provided statement comment (if not empty) to an SQL query
+func withComments(db interface{}, comment, asSQL string) string {
+	var tags []string
+
+	if h, ok := db.(*handle); ok && h.db != nil && h.db.queryComment != "" {
+		tags = append(tags, sanitizeComment(h.db.queryComment))
+	}
+
+	if comment != "" {
+		tags = append(tags, sanitizeComment(comment))
+	}
+
+	if len(tags) == 0 {
+		return asSQL
+	}
+
+	return "/* " + strings.Join(tags, " ") + " */ " + asSQL
+}
+
+// sanitizeComment makes a tag safe for use inside an SQL comment, breaking
+// up sequences that would end the comment (or start a nested one)
+func sanitizeComment(tag string) string {
+	for strings.Contains(tag, "*/") || strings.Contains(tag, "/*") {
+		tag = strings.ReplaceAll(tag, "*/", "* /")
+		tag = strings.ReplaceAll(tag, "/*", "/ *")
+	}
+
+	return strings.TrimSpace(tag)
+}
+
+// IsNotFound returns true if the error (or any error it wraps) is
+// sql.ErrNoRows, which is returned by GetRow and similar methods when the
+// query matched no rows. This allows callers to distinguish missing rows
+// from actual failures.
+func IsNotFound(err error) bool {
+	return errors.Is(err, sql.ErrNoRows)
+}
+
+// ErrRowsAffectedUnavailable is returned (wrapped) by the ExecAffected
+// methods of statements when the database driver cannot report the number
+// of rows affected by the statement
+var ErrRowsAffectedUnavailable = errors.New("driver cannot report affected rows")
+
+// rowsAffected returns the number of rows affected by an executed statement,
+// wrapping driver errors with ErrRowsAffectedUnavailable
+func rowsAffected(res sql.Result, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrRowsAffectedUnavailable, err)
+	}
+
+	return affected, nil
+}
+
+// contextWithTimeout derives a context from ctx that is cancelled once the
+// statement's timeout (if any) elapses. If ctx has a sooner deadline, that
+// deadline is kept.
+func (stmt *Statement) contextWithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if stmt == nil {
+		return ctx, func() {}
+	}
+
+	return withTimeout(ctx, stmt.timeout)
+}
+
+// withTimeout derives a context from ctx with the provided timeout, unless
+// it is zero
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Rows is an sqlx.Rows object returned by the GetAllAsTimedRows methods of
+// statements, which releases the resources of the statement's timeout (see
+// SelectStmt.WithTimeout) when closed.
+type Rows struct {
+	*sqlx.Rows
+	cancel context.CancelFunc
+}
+
+// Close closes the rows, and cancels the context they were queried with if
+// the statement has a timeout
+func (rows *Rows) Close() error {
+	defer rows.cancel()
+	return rows.Rows.Close()
 }
 
 // HandleError receives an error value, and executes all of the statements
@@ -15,3 +157,52 @@ func (stmt *Statement) HandleError(err error) {
 		}
 	}
 }
+
+// checkedSQL generates SQL for a statement like its ToSQL method does, but
+// also verifies that the number of placeholders in the generated SQL equals
+// the number of bindings. Question marks inside quoted strings and
+// identifiers are not counted, but those used as operators (e.g. the JSONB
+// "?" operator) are.
+func checkedSQL(stmt SQLStmt, rebind bool) (asSQL string, bindings []interface{}, err error) {
+	if withErr, ok := stmt.(interface{ Err() error }); ok {
+		if err = withErr.Err(); err != nil {
+			return "", nil, err
+		}
+	}
+
+	asSQL, bindings = stmt.ToSQL(false)
+
+	if placeholders := countPlaceholders(asSQL); placeholders != positionalArgs(bindings) {
+		return "", nil, fmt.Errorf(
+			"statement has %d placeholders but %d bindings: %s",
+			placeholders, len(bindings), asSQL,
+		)
+	}
+
+	if rebind {
+		asSQL, bindings = stmt.ToSQL(true)
+	}
+
+	return asSQL, bindings, nil
+}
+
+// countPlaceholders counts the question mark placeholders in an SQL query,
+// skipping quoted strings and identifiers
+func countPlaceholders(query string) (count int) {
+	var quote rune
+
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?':
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,160 @@
+package sqlz
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// stmtCache is a concurrency-safe LRU cache of prepared statements, keyed by
+// their SQL. Statements evicted from the cache are closed once they are no
+// longer in use.
+type stmtCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List
+	entries    map[string]*list.Element
+	closed     bool
+}
+
+// cachedStmt is a prepared statement held by a stmtCache
+type cachedStmt struct {
+	query   string
+	stmt    *sqlx.Stmt
+	users   int
+	evicted bool
+}
+
+func newStmtCache(maxEntries int) *stmtCache {
+	return &stmtCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// EnableStmtCache enables caching of prepared statements. Statements executed
+// through sqlz (including inside transactions) are prepared once and reused
+// whenever a statement with identical SQL is executed again. The cache holds
+// up to maxEntries statements, evicting and closing the least recently used
+// ones. Pass zero or a negative value to disable the cache. Cached statements
+// are closed when the cache is disabled or when the DB is closed.
+func (db *DB) EnableStmtCache(maxEntries int) {
+	if db.stmtCache != nil {
+		db.stmtCache.close()
+		db.stmtCache = nil
+	}
+
+	if maxEntries > 0 {
+		db.stmtCache = newStmtCache(maxEntries)
+	}
+}
+
+// Close closes any cached prepared statements, and then the underlying
+// database
+func (db *DB) Close() error {
+	if db.stmtCache != nil {
+		db.stmtCache.close()
+	}
+
+	return db.DB.Close()
+}
+
+// acquire returns a prepared statement for the provided query, preparing it
+// on the database if it isn't cached. The statement must be released when
+// the caller is done using it. Statements are prepared without holding the
+// lock, so that a slow prepare doesn't block other queries; if the same
+// query is prepared concurrently, the statement cached first is used.
+func (cache *stmtCache) acquire(ctx context.Context, db *sqlx.DB, query string) (*cachedStmt, error) {
+	cache.mu.Lock()
+	cached := cache.lookup(query)
+	cache.mu.Unlock()
+
+	if cached != nil {
+		return cached, nil
+	}
+
+	stmt, err := db.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cached := cache.lookup(query); cached != nil {
+		stmt.Close() // nolint: errcheck
+		return cached, nil
+	}
+
+	cached = &cachedStmt{query: query, stmt: stmt, users: 1}
+
+	// the cache was closed while preparing, the statement is closed once
+	// released
+	if cache.closed {
+		cached.evicted = true
+		return cached, nil
+	}
+
+	cache.entries[query] = cache.order.PushFront(cached)
+
+	for cache.order.Len() > cache.maxEntries {
+		cache.evict(cache.order.Back())
+	}
+
+	return cached, nil
+}
+
+// lookup returns the cached statement for the provided query, marking it
+// as in use, or nil if it isn't cached. Must be called while holding the
+// lock.
+func (cache *stmtCache) lookup(query string) *cachedStmt {
+	elem, ok := cache.entries[query]
+	if !ok {
+		return nil
+	}
+
+	cache.order.MoveToFront(elem)
+
+	cached := elem.Value.(*cachedStmt)
+	cached.users++
+
+	return cached
+}
+
+// release marks that a caller is done using a statement returned by acquire
+func (cache *stmtCache) release(cached *cachedStmt) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cached.users--
+	if cached.evicted && cached.users == 0 {
+		cached.stmt.Close() // nolint: errcheck
+	}
+}
+
+// close evicts all statements from the cache
+func (cache *stmtCache) close() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.closed = true
+
+	for cache.order.Len() > 0 {
+		cache.evict(cache.order.Back())
+	}
+}
+
+// evict removes an element from the cache, closing its statement if it is
+// not in use. Must be called while holding the lock.
+func (cache *stmtCache) evict(elem *list.Element) {
+	cached := cache.order.Remove(elem).(*cachedStmt)
+	delete(cache.entries, cached.query)
+
+	cached.evicted = true
+	if cached.users == 0 {
+		cached.stmt.Close() // nolint: errcheck
+	}
+}
@@ -0,0 +1,194 @@
+package sqlz
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingConnector is a minimal database driver that only supports
+// executing prepared statements, counting how many statements were
+// prepared and closed. Preparing a statement takes prepareDelay, to
+// simulate a round trip to the database, and preparing statements
+// containing slowQuery (if set) takes slowDelay.
+type countingConnector struct {
+	mu           sync.Mutex
+	prepareDelay time.Duration
+	slowQuery    string
+	slowDelay    time.Duration
+	prepared     int
+	closed       int
+}
+
+type countingConn struct{ c *countingConnector }
+
+type countingStmt struct{ c *countingConnector }
+
+func (c *countingConnector) Connect(context.Context) (driver.Conn, error) {
+	return &countingConn{c}, nil
+}
+
+func (c *countingConnector) Driver() driver.Driver {
+	return nil
+}
+
+func (c *countingConnector) counts() (prepared, closed int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.prepared, c.closed
+}
+
+func (conn *countingConn) Prepare(query string) (driver.Stmt, error) {
+	conn.c.mu.Lock()
+	delay := conn.c.prepareDelay
+	if conn.c.slowQuery != "" && strings.Contains(query, conn.c.slowQuery) {
+		delay = conn.c.slowDelay
+	}
+	conn.c.mu.Unlock()
+
+	time.Sleep(delay)
+
+	conn.c.mu.Lock()
+	conn.c.prepared++
+	conn.c.mu.Unlock()
+
+	return &countingStmt{conn.c}, nil
+}
+
+func (conn *countingConn) Close() error {
+	return nil
+}
+
+func (conn *countingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions not supported")
+}
+
+func (stmt *countingStmt) Close() error {
+	stmt.c.mu.Lock()
+	stmt.c.closed++
+	stmt.c.mu.Unlock()
+
+	return nil
+}
+
+func (stmt *countingStmt) NumInput() int {
+	return -1
+}
+
+func (stmt *countingStmt) Exec([]driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (stmt *countingStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("queries not supported")
+}
+
+func newCountingDB(prepareDelay time.Duration) (*DB, *countingConnector) {
+	connector := &countingConnector{prepareDelay: prepareDelay}
+
+	db := sql.OpenDB(connector)
+	db.SetMaxOpenConns(1)
+
+	return New(db, "sqlite3"), connector
+}
+
+func TestStmtCache(t *testing.T) {
+	dbz, connector := newCountingDB(0)
+	dbz.EnableStmtCache(2)
+
+	for _, id := range []int{1, 2, 3, 4} {
+		if _, err := dbz.Update("table").Set("a", id).Where(Eq("id", id)).Exec(); err != nil {
+			t.Fatalf("Failed executing update: %s", err)
+		}
+	}
+
+	if prepared, closed := connector.counts(); prepared != 1 || closed != 0 {
+		t.Errorf("Expected identical statements to be prepared once, got %d prepared and %d closed", prepared, closed)
+	}
+
+	for _, table := range []string{"one", "two", "table"} {
+		if _, err := dbz.DeleteFrom(table).Where(Eq("id", 1)).Exec(); err != nil {
+			t.Fatalf("Failed executing delete: %s", err)
+		}
+	}
+
+	// the UPDATE statement and the DELETE on table "one" should've been
+	// evicted and closed
+	if prepared, closed := connector.counts(); prepared != 4 || closed != 2 {
+		t.Errorf("Expected 4 prepared and 2 closed statements, got %d prepared and %d closed", prepared, closed)
+	}
+
+	if err := dbz.Close(); err != nil {
+		t.Fatalf("Failed closing database: %s", err)
+	}
+
+	if prepared, closed := connector.counts(); prepared != closed {
+		t.Errorf("Expected all statements to be closed with the DB, got %d prepared and %d closed", prepared, closed)
+	}
+}
+
+func TestStmtCacheConcurrentPrepare(t *testing.T) {
+	dbz, connector := newCountingDB(0)
+	connector.slowQuery = "uncached"
+	connector.slowDelay = 300 * time.Millisecond
+
+	dbz.DB.SetMaxOpenConns(2)
+	dbz.EnableStmtCache(10)
+
+	defer dbz.Close()
+
+	if _, err := dbz.DeleteFrom("cached").Where(Eq("id", 1)).Exec(); err != nil {
+		t.Fatalf("Failed executing delete: %s", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := dbz.DeleteFrom("uncached").Where(Eq("id", 1)).Exec()
+		done <- err
+	}()
+
+	// a slow prepare must not block queries using cached statements
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	if _, err := dbz.DeleteFrom("cached").Where(Eq("id", 1)).Exec(); err != nil {
+		t.Fatalf("Failed executing delete: %s", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("Expected cached statement to run during a slow prepare, took %s", elapsed)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("Failed executing delete: %s", err)
+	}
+}
+
+func benchmarkExec(b *testing.B, cacheSize int) {
+	dbz, _ := newCountingDB(50 * time.Microsecond)
+	dbz.EnableStmtCache(cacheSize)
+
+	defer dbz.Close()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := dbz.Update("table").Set("a", i).Where(Eq("id", i)).Exec(); err != nil {
+			b.Fatalf("Failed executing update: %s", err)
+		}
+	}
+}
+
+func BenchmarkExecWithoutStmtCache(b *testing.B) {
+	benchmarkExec(b, 0)
+}
+
+func BenchmarkExecWithStmtCache(b *testing.B) {
+	benchmarkExec(b, 10)
+}
@@ -0,0 +1,131 @@
+package sqlz
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// TruncateStmt represents a TRUNCATE statement, removing all rows from
+// one or more tables
+type TruncateStmt struct {
+	*Statement
+	Tables            []string
+	IsRestartIdentity bool
+	IsCascade         bool
+	execer            Ext
+}
+
+// Truncate creates a new TruncateStmt object for the provided tables
+func (db *DB) Truncate(tables ...string) *TruncateStmt {
+	return &TruncateStmt{
+		Tables:    append([]string{}, tables...),
+		execer:    db.handle(),
+		Statement: &Statement{ErrHandlers: db.ErrHandlers},
+	}
+}
+
+// Truncate creates a new TruncateStmt object for the provided tables
+func (tx *Tx) Truncate(tables ...string) *TruncateStmt {
+	return &TruncateStmt{
+		Tables:    append([]string{}, tables...),
+		execer:    tx.handle(),
+		Statement: &Statement{ErrHandlers: tx.ErrHandlers},
+	}
+}
+
+// RestartIdentity resets sequences owned by columns of the truncated
+// tables (RESTART IDENTITY). This is only supported on PostgreSQL, and is
+// ignored by other drivers (MySQL resets AUTO_INCREMENT counters when
+// truncating anyway).
+func (stmt *TruncateStmt) RestartIdentity() *TruncateStmt {
+	stmt.IsRestartIdentity = true
+	return stmt
+}
+
+// Cascade also truncates tables that have foreign keys referencing the
+// truncated tables (CASCADE). This is only supported on PostgreSQL, and is
+// ignored by other drivers.
+func (stmt *TruncateStmt) Cascade() *TruncateStmt {
+	stmt.IsCascade = true
+	return stmt
+}
+
+// WithTimeout sets a timeout for executing the TRUNCATE statement (see
+// SelectStmt.WithTimeout).
+func (stmt *TruncateStmt) WithTimeout(timeout time.Duration) *TruncateStmt {
+	stmt.timeout = timeout
+	return stmt
+}
+
+// Statements returns the SQL statements required to truncate the tables.
+// PostgreSQL truncates all tables in one statement, MySQL and SQL Server
+// truncate one table per statement, and SQLite, which has no TRUNCATE
+// statement, deletes all rows from each table instead.
+func (stmt *TruncateStmt) Statements() []string {
+	driverName := driverNameOf(stmt.execer)
+
+	var perTable string
+
+	switch {
+	case isMySQL(driverName), isSQLServer(driverName):
+		perTable = "TRUNCATE TABLE "
+	case isSQLite(driverName):
+		perTable = "DELETE FROM "
+	default:
+		asSQL := "TRUNCATE " + strings.Join(stmt.Tables, ", ")
+		if stmt.IsRestartIdentity {
+			asSQL += " RESTART IDENTITY"
+		}
+
+		if stmt.IsCascade {
+			asSQL += " CASCADE"
+		}
+
+		return []string{asSQL}
+	}
+
+	statements := make([]string, len(stmt.Tables))
+	for i, table := range stmt.Tables {
+		statements[i] = perTable + table
+	}
+
+	return statements
+}
+
+// ToSQL generates the statement's SQL. If more than one statement is
+// required (see Statements), they are separated by semicolons. TRUNCATE
+// statements have no bindings.
+func (stmt *TruncateStmt) ToSQL(_ bool) (asSQL string, bindings []interface{}) {
+	return strings.Join(stmt.Statements(), "; "), nil
+}
+
+// Exec executes the TRUNCATE statement(s), returning the result of the
+// last one.
+func (stmt *TruncateStmt) Exec() (res sql.Result, err error) {
+	return stmt.ExecContext(context.Background())
+}
+
+// ExecContext executes the TRUNCATE statement(s), returning the result of
+// the last one. When more than one statement is required, execution stops
+// at the first one that fails.
+func (stmt *TruncateStmt) ExecContext(ctx context.Context) (res sql.Result, err error) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if err = stmt.Err(); err != nil {
+		stmt.HandleError(err)
+		return res, err
+	}
+
+	for _, asSQL := range stmt.Statements() {
+		if res, err = stmt.execer.ExecContext(ctx, asSQL); err != nil {
+			break
+		}
+	}
+
+	stmt.HandleError(err)
+
+	return res, err
+}
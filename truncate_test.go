@@ -0,0 +1,66 @@
+package sqlz
+
+import (
+	"regexp"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestTruncate(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"truncate tables",
+				dbz.Truncate("users", "teams"),
+				"TRUNCATE users, teams",
+				[]interface{}{},
+			},
+
+			{
+				"truncate tables with modifiers",
+				dbz.Truncate("users", "teams").RestartIdentity().Cascade(),
+				"TRUNCATE users, teams RESTART IDENTITY CASCADE",
+				[]interface{}{},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"truncate tables one at a time",
+				dbz.Truncate("users", "teams").RestartIdentity().Cascade(),
+				"TRUNCATE TABLE users; TRUNCATE TABLE teams",
+				[]interface{}{},
+			},
+		}
+	})
+
+	runDriverTests(t, "sqlite3", func(dbz *DB) []test {
+		return []test{
+			{
+				"truncate tables by deleting rows",
+				dbz.Truncate("users", "teams"),
+				"DELETE FROM users; DELETE FROM teams",
+				[]interface{}{},
+			},
+		}
+	})
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("TRUNCATE TABLE users")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("TRUNCATE TABLE teams")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if _, err := New(db, "mysql").Truncate("users", "teams").Exec(); err != nil {
+		t.Errorf("Failed truncating tables: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,92 @@
+package sqlz
+
+import (
+	"database/sql"
+)
+
+// TVPCondition represents a condition checking that a column's value is
+// included in a SQL Server table-valued parameter (TVP)
+type TVPCondition struct {
+	Left  string
+	Name  string
+	Value interface{}
+}
+
+// InTVP creates a condition checking that a column's value is one of the
+// values of a table-valued parameter, generating "col IN (SELECT * FROM
+// @name)". This is useful on SQL Server, where long IN lists are slow and
+// limited to 2100 parameters. The value is bound as a named parameter,
+// and is usually an mssql.TVP object from the go-mssqldb driver, e.g.:
+//
+//	InTVP("id", "ids", mssql.TVP{TypeName: "dbo.IDList", Value: rows})
+//
+// The table type must have exactly one column.
+func InTVP(col, name string, tvp interface{}) TVPCondition {
+	return TVPCondition{Left: col, Name: name, Value: tvp}
+}
+
+// Parse implements the WhereCondition interface, generating SQL from
+// the condition
+func (tvp TVPCondition) Parse() (asSQL string, bindings []interface{}) {
+	return tvp.Left + " IN (SELECT * FROM @" + tvp.Name + ")", []interface{}{sql.Named(tvp.Name, tvp.Value)}
+}
+
+// JoinTVP creates a new join with the supplied type on a table-valued
+// parameter (see InTVP), referenced by the provided alias in the join
+// conditions, e.g. "INNER JOIN @name AS alias ON ...".
+func (stmt *SelectStmt) JoinTVP(
+	joinType JoinType,
+	name string,
+	tvp interface{},
+	alias string,
+	conds ...WhereCondition,
+) *SelectStmt {
+	stmt.Joins = append(stmt.Joins, JoinClause{
+		Type:       joinType,
+		Table:      "@" + name + " AS " + alias,
+		Bindings:   []interface{}{sql.Named(name, tvp)},
+		Conditions: append([]WhereCondition{}, conds...),
+	})
+
+	return stmt
+}
+
+// positionalArgs returns the number of bindings that are not named
+// parameters, and thus correspond to placeholders in the SQL
+func positionalArgs(args []interface{}) (count int) {
+	for _, arg := range args {
+		if _, isNamed := arg.(sql.NamedArg); !isNamed {
+			count++
+		}
+	}
+
+	return count
+}
+
+// namedArgsLast moves named parameters to the end of a list of bindings.
+// Drivers such as go-mssqldb name positional parameters by their ordinal
+// position among all arguments (e.g. @p2), while rebinding numbers the
+// placeholders by their position among positional bindings only, so named
+// parameters must not precede positional ones.
+func namedArgsLast(args []interface{}) []interface{} {
+	count := positionalArgs(args)
+	if count == len(args) {
+		return args
+	}
+
+	sorted := make([]interface{}, 0, len(args))
+
+	for _, arg := range args {
+		if _, isNamed := arg.(sql.NamedArg); !isNamed {
+			sorted = append(sorted, arg)
+		}
+	}
+
+	for _, arg := range args {
+		if _, isNamed := arg.(sql.NamedArg); isNamed {
+			sorted = append(sorted, arg)
+		}
+	}
+
+	return sorted
+}
@@ -0,0 +1,75 @@
+package sqlz
+
+import (
+	"database/sql"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+type idList struct {
+	TypeName string
+	Value    []int64
+}
+
+func TestTVP(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	dbz := New(db, "sqlserver")
+	tvp := idList{TypeName: "dbo.IDList", Value: []int64{1, 2, 3}}
+
+	asSQL, bindings := dbz.Select("*").
+		From("users u").
+		Where(Eq("active", true), InTVP("u.id", "ids", tvp), Gt("age", 18)).
+		ToSQL(true)
+
+	expectedSQL := "SELECT * FROM users u WHERE active = @p1 AND u.id IN (SELECT * FROM @ids) AND age > @p2"
+	if asSQL != expectedSQL {
+		t.Errorf("Expected %s, got %s", expectedSQL, asSQL)
+	}
+
+	expectedBindings := []interface{}{true, sql.Named("ids", tvp), 18}
+	if !reflect.DeepEqual(bindings, expectedBindings) {
+		t.Errorf("Expected bindings %v, got %v", expectedBindings, bindings)
+	}
+
+	asSQL, bindings = dbz.Select("u.*").
+		From("users u").
+		JoinTVP(InnerJoin, "ids", tvp, "t", SQLCond("t.id = u.id")).
+		Where(Eq("active", true)).
+		ToSQL(true)
+
+	expectedSQL = "SELECT u.* FROM users u INNER JOIN @ids AS t ON t.id = u.id WHERE active = @p1"
+	if asSQL != expectedSQL {
+		t.Errorf("Expected %s, got %s", expectedSQL, asSQL)
+	}
+
+	expectedBindings = []interface{}{sql.Named("ids", tvp), true}
+	if !reflect.DeepEqual(bindings, expectedBindings) {
+		t.Errorf("Expected bindings %v, got %v", expectedBindings, bindings)
+	}
+
+	// named parameters must be passed after positional ones, so that their
+	// ordinal positions match the rebound placeholders
+	mock.ExpectExec(regexp.QuoteMeta(
+		"DELETE FROM users WHERE active = @p1 AND id IN (SELECT * FROM @ids) AND age > @p2",
+	)).
+		WithArgs(false, 18, sql.Named("ids", "ids-tvp")).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	_, err = dbz.DeleteFrom("users").
+		Where(Eq("active", false), InTVP("id", "ids", "ids-tvp"), Gt("age", 18)).
+		Exec()
+	if err != nil {
+		t.Errorf("Failed deleting with a TVP: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
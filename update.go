@@ -3,8 +3,10 @@ package sqlz
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -19,6 +21,11 @@ type UpdateStmt struct {
 	execer          Ext
 	SelectStmt      *SelectStmt
 	SelectStmtAlias string
+	ValuesKey       string
+	ValuesColumns   []string
+	ValuesRows      [][]interface{}
+	FromTable       string
+	FromConditions  []WhereCondition
 }
 
 // Update creates a new UpdateStmt object for
@@ -27,8 +34,8 @@ func (db *DB) Update(table string) *UpdateStmt {
 	return &UpdateStmt{
 		Table:     table,
 		Updates:   make(map[string]interface{}),
-		execer:    db.DB,
-		Statement: &Statement{db.ErrHandlers},
+		execer:    db.handle(),
+		Statement: &Statement{ErrHandlers: db.ErrHandlers},
 	}
 }
 
@@ -38,8 +45,8 @@ func (tx *Tx) Update(table string) *UpdateStmt {
 	return &UpdateStmt{
 		Table:     table,
 		Updates:   make(map[string]interface{}),
-		execer:    tx.Tx,
-		Statement: &Statement{tx.ErrHandlers},
+		execer:    tx.handle(),
+		Statement: &Statement{ErrHandlers: tx.ErrHandlers},
 	}
 }
 
@@ -60,6 +67,27 @@ func (stmt *UpdateStmt) SetMap(updates map[string]interface{}) *UpdateStmt {
 	return stmt
 }
 
+// SetMapAllowed is the same as SetMap, but only accepts columns included
+// in the allowed list. This is useful when the map originates from user
+// input, e.g. the body of a PATCH request. If the map includes a column
+// that is not allowed, no column is set and the statement fails when
+// executed. Nil values set their columns to NULL.
+func (stmt *UpdateStmt) SetMapAllowed(values map[string]interface{}, allowed []string) *UpdateStmt {
+	isAllowed := make(map[string]bool, len(allowed))
+	for _, col := range allowed {
+		isAllowed[col] = true
+	}
+
+	for _, col := range sortKeys(values) {
+		if !isAllowed[col] {
+			stmt.setErr(fmt.Errorf("column %s is not allowed to be updated", col))
+			return stmt
+		}
+	}
+
+	return stmt.SetMap(values)
+}
+
 // SetIf is the same as Set, but also accepts a boolean value and only does
 // anything if that value is true. This is a convenience method so that
 // conditional updates can be made without having to save the UpdateStmt into
@@ -72,22 +100,113 @@ func (stmt *UpdateStmt) SetIf(col string, value interface{}, b bool) *UpdateStmt
 	return stmt
 }
 
+// Increment adds delta to the current value of a column, e.g.
+// Increment("value", 1) generates "SET value = value + ?". Combine with
+// Returning to receive the new value back from the database.
+func (stmt *UpdateStmt) Increment(col string, delta interface{}) *UpdateStmt {
+	stmt.Updates[col] = Indirect(col+" + ?", delta)
+	return stmt
+}
+
+// IncrementClamped is the same as Increment, but keeps the new value
+// between min and max using the LEAST and GREATEST functions, e.g.
+// "SET value = GREATEST(LEAST(value + ?, ?), ?)". Pass nil for a bound
+// to leave that side unbounded. In SQLite, the MIN and MAX scalar
+// functions are used instead.
+func (stmt *UpdateStmt) IncrementClamped(col string, delta, min, max interface{}) *UpdateStmt {
+	least, greatest := "LEAST", "GREATEST"
+	if isSQLite(driverNameOf(stmt.execer)) {
+		least, greatest = "MIN", "MAX"
+	}
+
+	ref := col + " + ?"
+	bindings := []interface{}{delta}
+
+	if max != nil {
+		ref = least + "(" + ref + ", ?)"
+		bindings = append(bindings, max)
+	}
+
+	if min != nil {
+		ref = greatest + "(" + ref + ", ?)"
+		bindings = append(bindings, min)
+	}
+
+	stmt.Updates[col] = Indirect(ref, bindings...)
+
+	return stmt
+}
+
 // Where creates one or more WHERE conditions for the UPDATE statement.
 // If multiple conditions are passed, they are considered AND conditions.
 func (stmt *UpdateStmt) Where(conditions ...WhereCondition) *UpdateStmt {
+	conditions, err := emptyInPolicyOf(stmt.execer).apply(conditions)
+	if err != nil {
+		stmt.setErr(err)
+	}
+
+	if err := condErr(conditions, driverNameOf(stmt.execer)); err != nil {
+		stmt.setErr(err)
+	}
+
 	stmt.Conditions = append(stmt.Conditions, conditions...)
+
+	return stmt
+}
+
+// ApplyIf calls fn with the statement if cond is true, and returns its
+// result; otherwise the statement is returned unchanged (see
+// SelectStmt.ApplyIf).
+func (stmt *UpdateStmt) ApplyIf(cond bool, fn func(*UpdateStmt) *UpdateStmt) *UpdateStmt {
+	if !cond {
+		return stmt
+	}
+
+	return fn(stmt)
+}
+
+// WithTimeout sets a timeout for executing the UPDATE statement (see
+// SelectStmt.WithTimeout).
+func (stmt *UpdateStmt) WithTimeout(timeout time.Duration) *UpdateStmt {
+	stmt.timeout = timeout
+	return stmt
+}
+
+// Comment adds a comment with the provided tag to the start of the UPDATE
+// statement's SQL (see SelectStmt.Comment).
+func (stmt *UpdateStmt) Comment(tag string) *UpdateStmt {
+	stmt.comment = tag
 	return stmt
 }
 
 // Returning sets a RETURNING clause to receive values back from the
 // database once executing the UPDATE statement. Note that GetRow or
 // GetAll must be used to execute the query rather than Exec to get
-// back the values.
+// back the values. On SQL Server, an OUTPUT clause with the columns
+// qualified by the INSERTED pseudo-table (i.e. the new values) is
+// generated instead, before the FROM and WHERE clauses. Columns qualified
+// by the DELETED pseudo-table (e.g. "DELETED.status") return the previous
+// values.
 func (stmt *UpdateStmt) Returning(cols ...string) *UpdateStmt {
 	stmt.Return = append(stmt.Return, cols...)
 	return stmt
 }
 
+// From updates the table with values from another table, which can be
+// referenced in values passed to Set via Indirect (e.g.
+// Set("c", Indirect("s.c"))) and in WHERE conditions. The optional on
+// conditions relate rows of the two tables. In PostgreSQL this generates
+// "UPDATE t SET c = s.c FROM src s WHERE <on> AND <conditions>", while in
+// MySQL it generates "UPDATE t JOIN src s ON <on> SET c = s.c WHERE
+// <conditions>". Note that in MySQL, updated columns that exist in both
+// tables must be qualified with the table's name or alias.
+func (stmt *UpdateStmt) From(table string, on ...WhereCondition) *UpdateStmt {
+	stmt.FromTable = table
+	stmt.FromConditions = on
+
+	return stmt
+}
+
 // FromSelect allows creating update statements that takes values from the
 // result of a select statement.
 func (stmt *UpdateStmt) FromSelect(selStmt *SelectStmt, alias string) *UpdateStmt {
@@ -97,14 +216,130 @@ func (stmt *UpdateStmt) FromSelect(selStmt *SelectStmt, alias string) *UpdateStm
 	return stmt
 }
 
+// SetFromValues updates multiple rows in one statement, each with its own
+// values. Every map in rows holds the values for one row keyed by column
+// name, and must include keyCol, which identifies the row to update. All
+// rows must have the same columns. In PostgreSQL, this generates
+// "UPDATE t SET col = v.col FROM (VALUES (?, ?), (?, ?)) AS v(key, col)
+// WHERE t.key = v.key", while in MySQL the table is joined with a derived
+// table instead. Note that PostgreSQL may require explicit casts for the
+// values (e.g. Indirect("?::int", 3)), as it cannot infer their types. It
+// can be combined with From, whose table is added to the same FROM clause
+// (or joined as well in MySQL).
+func (stmt *UpdateStmt) SetFromValues(keyCol string, rows []map[string]interface{}) *UpdateStmt {
+	if len(rows) == 0 {
+		stmt.setErr(errors.New("no rows provided to SetFromValues"))
+		return stmt
+	}
+
+	cols := []string{keyCol}
+
+	for _, col := range sortKeys(rows[0]) {
+		if col != keyCol {
+			cols = append(cols, col)
+		}
+	}
+
+	values := make([][]interface{}, len(rows))
+
+	for i, row := range rows {
+		if len(row) != len(cols) {
+			stmt.setErr(fmt.Errorf("row %d does not have the same columns as the first row", i))
+			return stmt
+		}
+
+		values[i] = make([]interface{}, len(cols))
+
+		for j, col := range cols {
+			val, ok := row[col]
+			if !ok {
+				stmt.setErr(fmt.Errorf("row %d is missing column %s", i, col))
+				return stmt
+			}
+
+			values[i][j] = val
+		}
+	}
+
+	stmt.ValuesKey = keyCol
+	stmt.ValuesColumns = cols
+	stmt.ValuesRows = values
+
+	return stmt
+}
+
+// Err returns the first error encountered while building the statement, if
+// any. On SQLite, it also returns an error if any column is set to
+// Default(), which SQLite doesn't support.
+func (stmt *UpdateStmt) Err() error {
+	if err := stmt.Statement.Err(); err != nil {
+		return err
+	}
+
+	if isSQLite(driverNameOf(stmt.execer)) {
+		for _, val := range stmt.Updates {
+			if hasDefault(val) {
+				return errors.New("DEFAULT values are not supported on SQLite")
+			}
+		}
+	}
+
+	return nil
+}
+
 // ToSQL generates the UPDATE statement's SQL and returns a list of
 // bindings. It is used internally by Exec, GetRow and GetAll, but is
 // exported if you wish to use it directly.
+// nolint: gocognit, gocyclo
 func (stmt *UpdateStmt) ToSQL(rebind bool) (asSQL string, bindings []interface{}) {
 	var clauses = []string{fmt.Sprintf("UPDATE %s", stmt.Table)}
 
 	var updates []string
 
+	conditions := stmt.Conditions
+
+	if len(stmt.ValuesRows) > 0 {
+		// the table's alias (if any) is the last word of the table name
+		table := stmt.Table[strings.LastIndex(stmt.Table, " ")+1:]
+		key := stmt.ValuesKey
+
+		if isMySQL(driverNameOf(stmt.execer)) {
+			var valuesSQL string
+			valuesSQL, bindings = stmt.valuesSource()
+
+			clauses = append(clauses, "JOIN "+valuesSQL+" ON "+table+"."+key+" = v."+key)
+
+			for _, col := range stmt.ValuesColumns[1:] {
+				updates = append(updates, table+"."+col+" = v."+col)
+			}
+		} else {
+			for _, col := range stmt.ValuesColumns[1:] {
+				updates = append(updates, col+" = v."+col)
+			}
+
+			conditions = append(
+				[]WhereCondition{SQLCond(table + "." + key + " = v." + key)},
+				conditions...,
+			)
+		}
+	}
+
+	if stmt.FromTable != "" {
+		if isMySQL(driverNameOf(stmt.execer)) {
+			join := "JOIN " + stmt.FromTable
+
+			if len(stmt.FromConditions) > 0 {
+				onClause, onBindings := parseConditions(stmt.FromConditions, driverNameOf(stmt.execer))
+				join += " ON " + onClause
+				bindings = append(bindings, onBindings...)
+			}
+
+			clauses = append(clauses, join)
+		} else {
+			conditions = append(append([]WhereCondition{}, stmt.FromConditions...), conditions...)
+		}
+	}
+
 	// sort updates by column for reproducibility
 	for _, col := range sortKeys(stmt.Updates) {
 		val := stmt.Updates[col]
@@ -122,6 +357,15 @@ func (stmt *UpdateStmt) ToSQL(rebind bool) (asSQL string, bindings []interface{}
 			}
 
 			updates = append(updates, col+" = "+fn.Name+"("+strings.Join(args, ", ")+")")
+		} else if now, isNow := val.(NowExpr); isNow {
+			nowSQL, _ := now.sqlFor(driverNameOf(stmt.execer))
+			updates = append(updates, col+" = "+nowSQL)
+		} else if _, isDefault := val.(DefaultValue); isDefault {
+			updates = append(updates, col+" = DEFAULT")
+		} else if cast, isCast := val.(CastValue); isCast {
+			castSQL, castBindings := cast.sqlFor(driverNameOf(stmt.execer))
+			updates = append(updates, col+" = "+castSQL)
+			bindings = append(bindings, castBindings...)
 		} else if indirect, isIndirect := val.(IndirectValue); isIndirect {
 			updates = append(updates, col+" = "+indirect.Reference)
 			bindings = append(bindings, indirect.Bindings...)
@@ -133,58 +377,169 @@ func (stmt *UpdateStmt) ToSQL(rebind bool) (asSQL string, bindings []interface{}
 
 	clauses = append(clauses, "SET "+strings.Join(updates, ", "))
 
+	// SQL Server returns values with an OUTPUT clause before the FROM and
+	// WHERE clauses
+	if len(stmt.Return) > 0 && isSQLServer(driverNameOf(stmt.execer)) {
+		clauses = append(clauses, outputClause("INSERTED", stmt.Return))
+	}
+
+	// the rows of SetFromValues, the table of From and the sub-query of
+	// FromSelect share a single FROM clause
+	var from []string
+
+	if len(stmt.ValuesRows) > 0 && !isMySQL(driverNameOf(stmt.execer)) {
+		valuesSQL, valuesBindings := stmt.valuesSource()
+		from = append(from, valuesSQL)
+		bindings = append(bindings, valuesBindings...)
+	}
+
+	if stmt.FromTable != "" && !isMySQL(driverNameOf(stmt.execer)) {
+		from = append(from, stmt.FromTable)
+	}
+
 	if stmt.SelectStmt != nil && stmt.SelectStmtAlias != "" {
 		selectSQL, selectBindings := stmt.SelectStmt.ToSQL(false)
-		selectSQL = "(" + selectSQL + ") AS " + stmt.SelectStmtAlias + " "
-
-		clauses = append(clauses, "FROM ")
-		clauses = append(clauses, selectSQL)
+		from = append(from, "("+selectSQL+") AS "+stmt.SelectStmtAlias)
 		bindings = append(bindings, selectBindings...)
 	}
 
-	if len(stmt.Conditions) > 0 {
-		whereClause, whereBindings := parseConditions(stmt.Conditions)
+	if len(from) > 0 {
+		clauses = append(clauses, "FROM "+strings.Join(from, ", "))
+	}
+
+	if len(conditions) > 0 {
+		whereClause, whereBindings := parseConditions(conditions, driverNameOf(stmt.execer))
 		bindings = append(bindings, whereBindings...)
 		clauses = append(clauses, fmt.Sprintf("WHERE %s", whereClause))
 	}
 
-	if len(stmt.Return) > 0 {
+	if len(stmt.Return) > 0 && !isSQLServer(driverNameOf(stmt.execer)) {
 		clauses = append(clauses, "RETURNING "+strings.Join(stmt.Return, ", "))
 	}
 
 	asSQL = strings.Join(clauses, " ")
 
 	if rebind {
-		if db, ok := stmt.execer.(*sqlx.DB); ok {
-			asSQL = db.Rebind(asSQL)
-		} else if tx, ok := stmt.execer.(*sqlx.Tx); ok {
-			asSQL = tx.Rebind(asSQL)
-		}
+		asSQL = stmt.commented(stmt.execer, rebindFor(stmt.execer, asSQL))
 	}
 
 	return asSQL, bindings
 }
 
+// ToSQLChecked generates the UPDATE statement's SQL like ToSQL, but fails
+// if the number of placeholders doesn't match the number of bindings (see
+// SelectStmt.ToSQLChecked).
+func (stmt *UpdateStmt) ToSQLChecked(rebind bool) (asSQL string, bindings []interface{}, err error) {
+	return checkedSQL(stmt, rebind)
+}
+
+// Args returns the values bound to the statement (see SelectStmt.Args)
+func (stmt *UpdateStmt) Args() []interface{} {
+	_, bindings := stmt.ToSQL(false)
+	return bindings
+}
+
+// valuesSource generates the derived table holding the rows provided to
+// SetFromValues, aliased as "v"
+func (stmt *UpdateStmt) valuesSource() (asSQL string, bindings []interface{}) {
+	return valuesTable(driverNameOf(stmt.execer), "v", stmt.ValuesColumns, stmt.ValuesRows)
+}
+
+// valuesTable generates a derived table holding the provided rows, e.g.
+// "(VALUES (?, ?), (?, ?)) AS alias(a, b)". As MySQL only supports VALUES
+// as a table since version 8.0.19, there the rows are selected with UNION
+// ALL instead, e.g. "(SELECT ? AS a, ? AS b UNION ALL SELECT ?, ?) AS
+// alias".
+func valuesTable(driverName, alias string, cols []string, rows [][]interface{}) (asSQL string, bindings []interface{}) {
+	rowsSQL := make([]string, len(rows))
+
+	if isMySQL(driverName) {
+		for i, row := range rows {
+			placeholders, rowBindings := parseInsertValuesFor(row, driverName)
+			bindings = append(bindings, rowBindings...)
+
+			if i == 0 {
+				for j, col := range cols {
+					placeholders[j] += " AS " + col
+				}
+			}
+
+			rowsSQL[i] = "SELECT " + strings.Join(placeholders, ", ")
+		}
+
+		return "(" + strings.Join(rowsSQL, " UNION ALL ") + ") AS " + alias, bindings
+	}
+
+	for i, row := range rows {
+		placeholders, rowBindings := parseInsertValuesFor(row, driverName)
+		bindings = append(bindings, rowBindings...)
+		rowsSQL[i] = "(" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	return "(VALUES " + strings.Join(rowsSQL, ", ") + ") AS " + alias + "(" + strings.Join(cols, ", ") + ")", bindings
+}
+
 // Exec executes the UPDATE statement, returning the standard
 // sql.Result struct and an error if the query failed.
 func (stmt *UpdateStmt) Exec() (res sql.Result, err error) {
-	asSQL, bindings := stmt.ToSQL(true)
-
-	res, err = stmt.execer.Exec(asSQL, bindings...)
-	stmt.HandleError(err)
-
-	return res, err
+	return stmt.ExecContext(context.Background())
 }
 
 // ExecContext executes the UPDATE statement, returning the standard
 // sql.Result struct and an error if the query failed.
 func (stmt *UpdateStmt) ExecContext(ctx context.Context) (res sql.Result, err error) {
+	res, _, _, err = stmt.ExecWithSQLContext(ctx)
+	return res, err
+}
+
+// ExecWithSQL executes the UPDATE statement like Exec, but also returns the
+// SQL and arguments that were sent to the database, after placeholders
+// were rebound and arguments converted, e.g. for audit logging of the
+// exact statements executed. If the statement fails before being sent,
+// the SQL is empty.
+func (stmt *UpdateStmt) ExecWithSQL() (res sql.Result, asSQL string, args []interface{}, err error) {
+	return stmt.ExecWithSQLContext(context.Background())
+}
+
+// ExecWithSQLContext is the same as ExecWithSQL, but receives a context.
+func (stmt *UpdateStmt) ExecWithSQLContext(ctx context.Context) (
+	res sql.Result,
+	asSQL string,
+	args []interface{},
+	err error,
+) {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if err = stmt.Err(); err != nil {
+		stmt.HandleError(err)
+		return res, "", nil, err
+	}
+
 	asSQL, bindings := stmt.ToSQL(true)
+	args = sentArgs(stmt.execer, asSQL, bindings)
 
 	res, err = stmt.execer.ExecContext(ctx, asSQL, bindings...)
 	stmt.HandleError(err)
 
-	return res, err
+	return res, asSQL, args, err
+}
+
+// ExecAffected executes the UPDATE statement, returning the number of
+// rows it affected. If the driver cannot report that number, the returned
+// error wraps ErrRowsAffectedUnavailable. Note that by default, MySQL counts
+// only rows that were actually changed, not rows that were matched by the
+// WHERE clause but already had the new values; connect with the
+// CLIENT_FOUND_ROWS flag (e.g. "clientFoundRows=true" in go-sql-driver's
+// DSN) to count matched rows instead.
+func (stmt *UpdateStmt) ExecAffected() (int64, error) {
+	return stmt.ExecAffectedContext(context.Background())
+}
+
+// ExecAffectedContext executes the UPDATE statement, returning the number
+// of rows it affected. See ExecAffected for more information.
+func (stmt *UpdateStmt) ExecAffectedContext(ctx context.Context) (int64, error) {
+	return rowsAffected(stmt.ExecContext(ctx))
 }
 
 // GetRow executes an UPDATE statement with a RETURNING clause
@@ -193,12 +548,7 @@ func (stmt *UpdateStmt) ExecContext(ctx context.Context) (res sql.Result, err er
 // only one column is returned, or a struct if multiple columns
 // are returned)
 func (stmt *UpdateStmt) GetRow(into interface{}) error {
-	asSQL, bindings := stmt.ToSQL(true)
-
-	err := sqlx.Get(stmt.execer, into, asSQL, bindings...)
-	stmt.HandleError(err)
-
-	return err
+	return stmt.GetRowContext(context.Background(), into)
 }
 
 // GetRowContext executes an UPDATE statement with a RETURNING clause
@@ -207,9 +557,17 @@ func (stmt *UpdateStmt) GetRow(into interface{}) error {
 // only one column is returned, or a struct if multiple columns
 // are returned)
 func (stmt *UpdateStmt) GetRowContext(ctx context.Context, into interface{}) error {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if err := stmt.Err(); err != nil {
+		stmt.HandleError(err)
+		return err
+	}
+
 	asSQL, bindings := stmt.ToSQL(true)
 
-	err := sqlx.GetContext(ctx, stmt.execer, into, asSQL, bindings...)
+	err := mapErrorOf(stmt.execer, sqlx.GetContext(ctx, stmt.execer, into, asSQL, bindings...))
 	stmt.HandleError(err)
 
 	return err
@@ -219,18 +577,21 @@ func (stmt *UpdateStmt) GetRowContext(ctx context.Context, into interface{}) err
 // expected to return multiple rows, and loads the result into
 // the provided slice variable
 func (stmt *UpdateStmt) GetAll(into interface{}) error {
-	asSQL, bindings := stmt.ToSQL(true)
-
-	err := sqlx.Select(stmt.execer, into, asSQL, bindings...)
-	stmt.HandleError(err)
-
-	return err
+	return stmt.GetAllContext(context.Background(), into)
 }
 
 // GetAllContext executes an UPDATE statement with a RETURNING clause
 // expected to return multiple rows, and loads the result into
 // the provided slice variable
 func (stmt *UpdateStmt) GetAllContext(ctx context.Context, into interface{}) error {
+	ctx, cancel := stmt.contextWithTimeout(ctx)
+	defer cancel()
+
+	if err := stmt.Err(); err != nil {
+		stmt.HandleError(err)
+		return err
+	}
+
 	asSQL, bindings := stmt.ToSQL(true)
 
 	err := sqlx.SelectContext(ctx, stmt.execer, into, asSQL, bindings...)
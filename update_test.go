@@ -2,6 +2,8 @@ package sqlz
 
 import (
 	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
 )
 
 func TestUpdate(t *testing.T) {
@@ -28,6 +30,40 @@ func TestUpdate(t *testing.T) {
 				[]interface{}{3, 123, 109234234},
 			},
 
+			{
+				"increment and return the new value",
+				dbz.Update("counters").Increment("value", 5).Where(Eq("id", 12)).Returning("value"),
+				"UPDATE counters SET value = value + ? WHERE id = ? RETURNING value",
+				[]interface{}{5, 12},
+			},
+
+			{
+				"clamped increment",
+				dbz.Update("counters").IncrementClamped("value", -5, 0, 100).Where(Eq("id", 12)),
+				"UPDATE counters SET value = GREATEST(LEAST(value + ?, ?), ?) WHERE id = ?",
+				[]interface{}{-5, 100, 0, 12},
+			},
+
+			{
+				"increment clamped to a maximum",
+				dbz.Update("counters").IncrementClamped("value", 5, nil, 100),
+				"UPDATE counters SET value = LEAST(value + ?, ?)",
+				[]interface{}{5, 100},
+			},
+
+			{
+				"update with an optional column",
+				dbz.Update("table").Set("name", "John").
+					ApplyIf(false, func(stmt *UpdateStmt) *UpdateStmt {
+						return stmt.Set("email", "john@example.com")
+					}).
+					ApplyIf(true, func(stmt *UpdateStmt) *UpdateStmt {
+						return stmt.Where(Eq("id", 1))
+					}),
+				"UPDATE table SET name = ? WHERE id = ?",
+				[]interface{}{"John", 1},
+			},
+
 			{
 				"update with returning clause",
 				dbz.Update("table").Set("something", nil).Where(Eq("id", 123)).Returning("something-else"),
@@ -62,6 +98,170 @@ func TestUpdate(t *testing.T) {
 				"UPDATE table SET something = replace(something, ?, '')",
 				[]interface{}{"prefix/"},
 			},
+
+			{
+				"update with allowed map of updates",
+				dbz.Update("table").SetMapAllowed(
+					map[string]interface{}{"name": "John", "email": nil},
+					[]string{"name", "email", "phone"},
+				).Where(Eq("id", 1)),
+				"UPDATE table SET email = ?, name = ? WHERE id = ?",
+				[]interface{}{nil, "John", 1},
+			},
+		}
+	})
+}
+
+func TestUpdateSetFromValues(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"id": 1, "name": "one", "rank": 10},
+		{"id": 2, "name": "two", "rank": 20},
+		{"id": 3, "name": "three", "rank": 30},
+	}
+
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"bulk update from values",
+				dbz.Update("table").SetFromValues("id", rows).Where(Eq("active", true)),
+				"UPDATE table SET name = v.name, rank = v.rank " +
+					"FROM (VALUES ($1, $2, $3), ($4, $5, $6), ($7, $8, $9)) AS v(id, name, rank) " +
+					"WHERE table.id = v.id AND active = $10",
+				[]interface{}{1, "one", 10, 2, "two", 20, 3, "three", 30, true},
+			},
+
+			{
+				"bulk update from values and another table",
+				dbz.Update("table t").SetFromValues("id", rows).
+					From("owners o", SQLCond("o.id = t.owner_id")).
+					Where(Eq("o.active", true)),
+				"UPDATE table t SET name = v.name, rank = v.rank " +
+					"FROM (VALUES ($1, $2, $3), ($4, $5, $6), ($7, $8, $9)) AS v(id, name, rank), owners o " +
+					"WHERE o.id = t.owner_id AND t.id = v.id AND o.active = $10",
+				[]interface{}{1, "one", 10, 2, "two", 20, 3, "three", 30, true},
+			},
 		}
 	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"bulk update from values",
+				dbz.Update("table t").SetFromValues("id", rows).Set("updated", true).Where(Eq("active", true)),
+				"UPDATE table t JOIN (SELECT ? AS id, ? AS name, ? AS rank " +
+					"UNION ALL SELECT ?, ?, ? UNION ALL SELECT ?, ?, ?) AS v ON t.id = v.id " +
+					"SET t.name = v.name, t.rank = v.rank, updated = ? WHERE active = ?",
+				[]interface{}{1, "one", 10, 2, "two", 20, 3, "three", 30, true, true},
+			},
+		}
+	})
+
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	stmt := New(db, "postgres").Update("table").SetFromValues("id", []map[string]interface{}{
+		{"id": 1, "name": "one"},
+		{"id": 2, "rank": 20},
+	})
+	if stmt.Err() == nil {
+		t.Error("Expected rows with different columns to fail")
+	}
+
+	if _, err = stmt.Exec(); err == nil {
+		t.Error("Expected executing an invalid statement to fail")
+	}
+}
+
+func TestUpdateSetMapAllowed(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed creating mock database: %s", err)
+	}
+
+	stmt := New(db, "postgres").Update("table").SetMapAllowed(
+		map[string]interface{}{"name": "John", "is_admin": true},
+		[]string{"name", "email"},
+	)
+	if stmt.Err() == nil {
+		t.Error("Expected update of a disallowed column to fail")
+	}
+
+	if len(stmt.Updates) != 0 {
+		t.Errorf("Expected no columns to be set, got %v", stmt.Updates)
+	}
+}
+
+func TestUpdateFrom(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"update from another table",
+				dbz.Update("t").
+					Set("c", Indirect("s.c")).
+					Set("d", Indirect("s.d + ?", 1)).
+					From("src s", SQLCond("t.id = s.id"), Eq("s.kind", "a")).
+					Where(Gt("t.version", 3)),
+				"UPDATE t SET c = s.c, d = s.d + $1 FROM src s WHERE t.id = s.id AND s.kind = $2 AND t.version > $3",
+				[]interface{}{1, "a", 3},
+			},
+		}
+	})
+
+	runDriverTests(t, "mysql", func(dbz *DB) []test {
+		return []test{
+			{
+				"update from another table",
+				dbz.Update("t").
+					Set("t.c", Indirect("s.c")).
+					Set("t.d", Indirect("s.d + ?", 1)).
+					From("src s", SQLCond("t.id = s.id"), Eq("s.kind", "a")).
+					Where(Gt("t.version", 3)),
+				"UPDATE t JOIN src s ON t.id = s.id AND s.kind = ? SET t.c = s.c, t.d = s.d + ? WHERE t.version > ?",
+				[]interface{}{"a", 1, 3},
+			},
+		}
+	})
+}
+
+func TestUpdateOutputSQLServer(t *testing.T) {
+	runDriverTests(t, "sqlserver", func(dbz *DB) []test {
+		return []test{
+			{
+				"update with output clause",
+				dbz.Update("orders").Set("status", "shipped").Where(Eq("id", 1)).Returning("id", "DELETED.status AS previous_status"),
+				"UPDATE orders SET status = @p1 OUTPUT INSERTED.id, DELETED.status AS previous_status WHERE id = @p2",
+				[]interface{}{"shipped", 1},
+			},
+		}
+	})
+}
+
+func TestUpdateDefaultValue(t *testing.T) {
+	runDriverTests(t, "postgres", func(dbz *DB) []test {
+		return []test{
+			{
+				"update a column to its default",
+				dbz.Update("users").Set("role", Default()).Set("name", "john").Where(Eq("id", 1)),
+				"UPDATE users SET name = $1, role = DEFAULT WHERE id = $2",
+				[]interface{}{"john", 1},
+			},
+		}
+	})
+
+	runDriverTests(t, "sqlserver", func(dbz *DB) []test {
+		return []test{
+			{
+				"update a column to its default on sql server",
+				dbz.Update("users").SetMap(map[string]interface{}{"role": Default()}).Where(Eq("id", 1)),
+				"UPDATE users SET role = DEFAULT WHERE id = @p1",
+				[]interface{}{1},
+			},
+		}
+	})
+
+	if err := New(nil, "sqlite3").Update("users").Set("role", Default()).Err(); err == nil {
+		t.Error("Expected DEFAULT value to fail on SQLite")
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -25,7 +26,9 @@ type WithStmt struct {
 	// auxiliary statements can be referenced
 	MainStmt SQLStmt
 
-	execer Ext
+	execer  Ext
+	timeout time.Duration
+	comment string
 }
 
 // With creates a new WithStmt object including
@@ -33,7 +36,7 @@ type WithStmt struct {
 func (db *DB) With(stmt SQLStmt, as string) *WithStmt {
 	return &WithStmt{
 		AuxStmts: []AuxStmt{{stmt, as}},
-		execer:   db.DB,
+		execer:   db.handle(),
 	}
 }
 
@@ -42,7 +45,7 @@ func (db *DB) With(stmt SQLStmt, as string) *WithStmt {
 func (tx *Tx) With(stmt SQLStmt, as string) *WithStmt {
 	return &WithStmt{
 		AuxStmts: []AuxStmt{{stmt, as}},
-		execer:   tx.Tx,
+		execer:   tx.handle(),
 	}
 }
 
@@ -58,6 +61,20 @@ func (stmt *WithStmt) Then(mainStmt SQLStmt) *WithStmt {
 	return stmt
 }
 
+// WithTimeout sets a timeout for executing the WITH statement (see
+// SelectStmt.WithTimeout).
+func (stmt *WithStmt) WithTimeout(timeout time.Duration) *WithStmt {
+	stmt.timeout = timeout
+	return stmt
+}
+
+// Comment adds a comment with the provided tag to the start of the WITH
+// statement's SQL (see SelectStmt.Comment).
+func (stmt *WithStmt) Comment(tag string) *WithStmt {
+	stmt.comment = tag
+	return stmt
+}
+
 // ToSQL generates the WITH statement's SQL and returns a list of
 // bindings. It is used internally by Exec, GetRow and GetAll, but is
 // exported if you wish to use it directly.
@@ -78,26 +95,39 @@ func (stmt *WithStmt) ToSQL(rebind bool) (asSQL string, bindings []interface{})
 	clauses = append(clauses, mainSQL)
 	bindings = append(bindings, mainBindings...)
 
-	asSQL = strings.Join(clauses, " ")
-	if db, ok := stmt.execer.(*sqlx.DB); ok {
-		asSQL = db.Rebind(asSQL)
-	} else if tx, ok := stmt.execer.(*sqlx.Tx); ok {
-		asSQL = tx.Rebind(asSQL)
+	asSQL = rebindFor(stmt.execer, strings.Join(clauses, " "))
+	if rebind {
+		asSQL = withComments(stmt.execer, stmt.comment, asSQL)
 	}
 
 	return asSQL, bindings
 }
 
+// ToSQLChecked generates the WITH statement's SQL like ToSQL, but fails
+// if the number of placeholders doesn't match the number of bindings (see
+// SelectStmt.ToSQLChecked).
+func (stmt *WithStmt) ToSQLChecked(rebind bool) (asSQL string, bindings []interface{}, err error) {
+	return checkedSQL(stmt, rebind)
+}
+
+// Args returns the values bound to the statement (see SelectStmt.Args)
+func (stmt *WithStmt) Args() []interface{} {
+	_, bindings := stmt.ToSQL(false)
+	return bindings
+}
+
 // Exec executes the WITH statement, returning the standard
 // sql.Result struct and an error if the query failed.
 func (stmt *WithStmt) Exec() (res sql.Result, err error) {
-	asSQL, bindings := stmt.ToSQL(true)
-	return stmt.execer.Exec(asSQL, bindings...)
+	return stmt.ExecContext(context.Background())
 }
 
 // ExecContext executes the WITH statement, returning the standard
 // sql.Result struct and an error if the query failed.
 func (stmt *WithStmt) ExecContext(ctx context.Context) (res sql.Result, err error) {
+	ctx, cancel := withTimeout(ctx, stmt.timeout)
+	defer cancel()
+
 	asSQL, bindings := stmt.ToSQL(true)
 	return stmt.execer.ExecContext(ctx, asSQL, bindings...)
 }
@@ -108,8 +138,7 @@ func (stmt *WithStmt) ExecContext(ctx context.Context) (res sql.Result, err erro
 // simple variable if only one column is returned, or a
 // struct if multiple columns are returned)
 func (stmt *WithStmt) GetRow(into interface{}) error {
-	asSQL, bindings := stmt.ToSQL(true)
-	return sqlx.Get(stmt.execer, into, asSQL, bindings...)
+	return stmt.GetRowContext(context.Background(), into)
 }
 
 // GetRowContext executes a WITH statement whose main statement has
@@ -118,6 +147,9 @@ func (stmt *WithStmt) GetRow(into interface{}) error {
 // simple variable if only one column is returned, or a
 // struct if multiple columns are returned)
 func (stmt *WithStmt) GetRowContext(ctx context.Context, into interface{}) error {
+	ctx, cancel := withTimeout(ctx, stmt.timeout)
+	defer cancel()
+
 	asSQL, bindings := stmt.ToSQL(true)
 	return sqlx.GetContext(ctx, stmt.execer, into, asSQL, bindings...)
 }
@@ -126,14 +158,16 @@ func (stmt *WithStmt) GetRowContext(ctx context.Context, into interface{}) error
 // a RETURNING clause expected to return multiple rows, and
 // loads the result into the provided slice variable
 func (stmt *WithStmt) GetAll(into interface{}) error {
-	asSQL, bindings := stmt.ToSQL(true)
-	return sqlx.Select(stmt.execer, into, asSQL, bindings...)
+	return stmt.GetAllContext(context.Background(), into)
 }
 
 // GetAllContext executes a WITH statement whose main statement has
 // a RETURNING clause expected to return multiple rows, and
 // loads the result into the provided slice variable
 func (stmt *WithStmt) GetAllContext(ctx context.Context, into interface{}) error {
+	ctx, cancel := withTimeout(ctx, stmt.timeout)
+	defer cancel()
+
 	asSQL, bindings := stmt.ToSQL(true)
 	return sqlx.SelectContext(ctx, stmt.execer, into, asSQL, bindings...)
 }
@@ -142,6 +176,42 @@ func (stmt *WithStmt) GetAllContext(ctx context.Context, into interface{}) error
 // to use for iteration. It is the caller's responsibility to close the cursor
 // with Close().
 func (stmt *WithStmt) GetAllAsRows() (rows *sqlx.Rows, err error) {
+	return stmt.GetAllAsRowsContext(context.Background())
+}
+
+// GetAllAsRowsContext executes the WITH statement and returns an sqlx.Rows
+// object to use for iteration. It is the caller's responsibility to close the
+// cursor with Close(). If the statement has a timeout, its context is only
+// released once the timeout elapses (see SelectStmt.GetAllAsRowsContext).
+func (stmt *WithStmt) GetAllAsRowsContext(ctx context.Context) (rows *sqlx.Rows, err error) {
+	timedRows, err := stmt.GetAllAsTimedRowsContext(ctx)
+	if err != nil {
+		return rows, err
+	}
+
+	return timedRows.Rows, nil
+}
+
+// GetAllAsTimedRows is the same as GetAllAsRows, but returns a Rows object
+// that releases the statement's timeout when closed.
+func (stmt *WithStmt) GetAllAsTimedRows() (rows *Rows, err error) {
+	return stmt.GetAllAsTimedRowsContext(context.Background())
+}
+
+// GetAllAsTimedRowsContext is the same as GetAllAsRowsContext, but returns
+// a Rows object that releases the statement's timeout when closed.
+func (stmt *WithStmt) GetAllAsTimedRowsContext(ctx context.Context) (rows *Rows, err error) {
+	// the rows are still in use when returning, so the context is cancelled
+	// once they are closed
+	ctx, cancel := withTimeout(ctx, stmt.timeout)
+
 	asSQL, bindings := stmt.ToSQL(true)
-	return stmt.execer.Queryx(asSQL, bindings...)
+
+	sqlxRows, err := stmt.execer.QueryxContext(ctx, asSQL, bindings...)
+	if err != nil {
+		cancel()
+		return rows, err
+	}
+
+	return &Rows{sqlxRows, cancel}, nil
 }